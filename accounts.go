@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// accountSummaryEnv names the file a run started by --profiles writes its
+// stats line to, so that the parent can report every account at the end.
+const accountSummaryEnv = "BITWARDEN_CLEANUP_SUMMARY_FILE"
+
+// accountCredentials are not passed on from the parent: a session key or
+// password belongs to one account. Profiles set their own with the *-env
+// keys of the config file.
+var accountCredentials = []string{"BW_SESSION", "BW_PASSWORD", "BW_CLIENTID", "BW_CLIENTSECRET"}
+
+// accountRun is the outcome of the cleanup against one profile.
+type accountRun struct {
+	profile string
+	status  int
+	summary *statsLine
+}
+
+// parseProfiles expands the value of --profiles to profile names; "all"
+// stands for every profile of the config file.
+func parseProfiles(value string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	config, path, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, fmt.Errorf("--profiles needs a config file at %s", path)
+	}
+	if len(names) == 1 && names[0] == "all" {
+		names = names[:0]
+		for name := range config.profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("--profiles all: %s has no [profiles.<name>] tables", path)
+		}
+		return names, nil
+	}
+	for _, name := range names {
+		if _, ok := config.profiles[name]; !ok {
+			return nil, fmt.Errorf("no profile %q in %s", name, path)
+		}
+	}
+	return names, nil
+}
+
+// runProfiles runs this command line once per profile, one after the other,
+// each in its own process so that the accounts share no session, server or
+// cached vault data. A failed account does not stop the others; Ctrl-C does.
+func runProfiles(profiles []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error locating %s: %w", filepath.Base(os.Args[0]), err)
+	}
+	summaries, err := os.CreateTemp("", "bitwarden-cleanup-accounts-*.jsonl")
+	if err != nil {
+		return fmt.Errorf("error creating summary file: %w", err)
+	}
+	summaries.Close()
+	defer os.Remove(summaries.Name())
+
+	// The runs handle Ctrl-C themselves; this process only waits for them.
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	args := withoutProfileFlags(os.Args[1:])
+	var runs []accountRun
+	for i, profile := range profiles {
+		console.infof(emojiStart, "Account %d/%d: profile %s", i+1, len(profiles), profile)
+		if err := os.Truncate(summaries.Name(), 0); err != nil {
+			return fmt.Errorf("error resetting summary file: %w", err)
+		}
+
+		cmd := exec.Command(executable, append([]string{"--profile", profile}, args...)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		cmd.Env = append(withoutCredentials(os.Environ()), accountSummaryEnv+"="+summaries.Name())
+		run := accountRun{profile: profile}
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return fmt.Errorf("error running profile %s: %w", profile, err)
+			}
+			run.status = exitErr.ExitCode()
+		}
+		run.summary = readAccountSummary(summaries.Name())
+		runs = append(runs, run)
+
+		if run.status == exitInterrupted {
+			break
+		}
+	}
+
+	showAccountSummaries(runs, len(profiles))
+	return accountsOutcome(runs)
+}
+
+// accountsOutcome folds the exit statuses of the accounts into the outcome
+// of the whole run: Ctrl-C wins, then any account with a problem, then
+// declined runs. Only when no account matched anything does --fail-on-empty
+// apply.
+func accountsOutcome(runs []accountRun) error {
+	noMatches := 0
+	for _, run := range runs {
+		switch run.status {
+		case exitInterrupted:
+			return errInterrupted
+		case exitSuccess:
+		case exitCancelled:
+			outcome.cancelled = true
+		case exitNoMatches:
+			noMatches++
+		default:
+			outcome.failed = true
+		}
+	}
+	if noMatches == len(runs) {
+		return errNoMatches
+	}
+	return nil
+}
+
+// withoutProfileFlags drops --profile and --profiles from args; each run
+// gets its own --profile.
+func withoutProfileFlags(args []string) []string {
+	var kept []string
+	for i := 0; i < len(args); i++ {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || args[i] == "--" || (name != "profile" && name != "profiles") {
+			kept = append(kept, args[i])
+			continue
+		}
+		if !hasValue {
+			i++
+		}
+	}
+	return kept
+}
+
+func withoutCredentials(environ []string) []string {
+	var kept []string
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		if !slices.Contains(accountCredentials, name) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// readAccountSummary returns the last stats line a run wrote, or nil when it
+// processed no items.
+func readAccountSummary(path string) *statsLine {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var summary *statsLine
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line statsLine
+		if json.Unmarshal(scanner.Bytes(), &line) == nil {
+			summary = &line
+		}
+	}
+	return summary
+}
+
+func showAccountSummaries(runs []accountRun, total int) {
+	console.summaryf(emojiStats, "Summary of %d accounts:", total)
+	width := len("PROFILE")
+	for _, run := range runs {
+		width = max(width, len([]rune(run.profile)))
+	}
+	console.linef("   %s  %-12s  %7s  %9s  %6s  %6s", pad("PROFILE", width), "RESULT", "MATCHED", "SUCCEEDED", "GONE", "FAILED")
+	for _, run := range runs {
+		if run.summary == nil {
+			console.linef("   %s  %-12s  %7s  %9s  %6s  %6s", pad(run.profile, width), exitStatusName(run.status), "-", "-", "-", "-")
+			continue
+		}
+		s := run.summary
+		console.linef("   %s  %-12s  %7d  %9d  %6d  %6d", pad(run.profile, width), exitStatusName(run.status), s.Matched, s.Succeeded, s.AlreadyGone, s.Failed)
+	}
+	if skipped := total - len(runs); skipped > 0 {
+		console.warnf("%d accounts were skipped after Ctrl-C", skipped)
+	}
+}
+
+func exitStatusName(status int) string {
+	switch status {
+	case exitSuccess:
+		return "ok"
+	case exitFailure:
+		return "failed"
+	case exitCancelled:
+		return "cancelled"
+	case exitVaultLocked:
+		return "locked"
+	case exitBWMissing:
+		return "no bw"
+	case exitNoMatches:
+		return "no matches"
+	case exitInterrupted:
+		return "interrupted"
+	}
+	return fmt.Sprintf("exit %d", status)
+}
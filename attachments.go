@@ -0,0 +1,399 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type attachmentManifestEntry struct {
+	ItemID       string `json:"itemId"`
+	ItemName     string `json:"itemName"`
+	AttachmentID string `json:"attachmentId"`
+	FileName     string `json:"fileName"`
+	Size         string `json:"size"`
+	Path         string `json:"path"`
+	Error        string `json:"error,omitempty"`
+}
+
+func runAttachmentsCommand(args []string) error {
+	if len(args) > 0 && args[0] == "delete" {
+		return runAttachmentsDeleteCommand(args[1:])
+	}
+	if len(args) == 0 || args[0] != "download" {
+		return fmt.Errorf("usage: %s attachments download --dest <dir> | delete [--min-size <size>] [--max-size <size>] [--name <pattern>] [--dry-run]", filepath.Base(os.Args[0]))
+	}
+
+	flags := flag.NewFlagSet("attachments download", flag.ExitOnError)
+	dest := flags.String("dest", "", "Directory to download attachments into (required)")
+	options, err := parseOptions(flags, args[1:], defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+
+	if *dest == "" {
+		return fmt.Errorf("--dest is required")
+	}
+
+	return downloadAttachments(*dest, options)
+}
+
+func downloadAttachments(dest string, options CommandOptions) error {
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return err
+	}
+	recordFilterHistory(options, items)
+
+	var withAttachments []BitwardenItem
+	attachmentCount := 0
+	for _, item := range items {
+		if len(item.Attachments) > 0 {
+			withAttachments = append(withAttachments, item)
+			attachmentCount += len(item.Attachments)
+		}
+	}
+
+	console.infof(emojiSearch, "Found %d attachments on %d items", attachmentCount, len(withAttachments))
+	if len(withAttachments) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(dest, 0o700); err != nil {
+		return fmt.Errorf("error creating destination directory: %w", err)
+	}
+
+	dirs := attachmentDirectories(withAttachments)
+
+	var manifest []attachmentManifestEntry
+	var mu sync.Mutex
+
+	op := itemOperation{
+		processName:  "attachment download",
+		progressVerb: "downloading attachments of",
+		doneText:     "have had their attachments downloaded",
+		idempotent:   true,
+		run: func(item BitwardenItem) error {
+			entries, err := downloadItemAttachments(item, dest, dirs[item.ID])
+			mu.Lock()
+			manifest = append(manifest, entries...)
+			mu.Unlock()
+			return err
+		},
+	}
+
+	stats := &DeleteStats{total: len(withAttachments)}
+	if err := processItems(withAttachments, stats, op, options); err != nil {
+		return err
+	}
+
+	sort.Slice(manifest, func(i, j int) bool { return manifest[i].Path < manifest[j].Path })
+	manifestPath := filepath.Join(dest, "manifest.json")
+	if err := writeJSONFile(manifestPath, manifest); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, entry := range manifest {
+		if entry.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		console.warnf("%d of %d attachments could not be downloaded", failed, attachmentCount)
+	}
+	console.infof(emojiSuccess, "Manifest written to %s", manifestPath)
+	return nil
+}
+
+// runAttachmentsDeleteCommand deletes the attachments of matched items that
+// match the size and file name filters, leaving the items themselves alone.
+func runAttachmentsDeleteCommand(args []string) error {
+	flags := flag.NewFlagSet("attachments delete", flag.ExitOnError)
+	minSize := flags.String("min-size", "", "Only delete attachments of at least this size (e.g. 5MB, 500KB)")
+	maxSize := flags.String("max-size", "", "Only delete attachments of at most this size")
+	name := flags.String("name", "", "Only delete attachments whose file name matches this glob (e.g. '*.pdf') or /regular expression/")
+	dryRun := flags.Bool("dry-run", false, "List the attachments that would be deleted without deleting them")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+
+	match, err := attachmentFilter(*minSize, *maxSize, *name)
+	if err != nil {
+		return err
+	}
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return err
+	}
+
+	var withMatches []BitwardenItem
+	matches := make(map[string][]BitwardenAttachment)
+	count := 0
+	var total int64
+	for _, item := range items {
+		for _, attachment := range item.Attachments {
+			if match(attachment) {
+				matches[item.ID] = append(matches[item.ID], attachment)
+				count++
+				total += attachment.bytes()
+			}
+		}
+		if len(matches[item.ID]) > 0 {
+			withMatches = append(withMatches, item)
+		}
+	}
+
+	console.infof(emojiSearch, "Found %d matching attachments (%s) on %d items", count, formatBytes(total), len(withMatches))
+	for _, item := range withMatches {
+		console.linef("   %s (%s)", options.redact.name(item.Name), item.ID)
+		for _, attachment := range matches[item.ID] {
+			console.linef("      - %s (%s)", attachment.FileName, formatBytes(attachment.bytes()))
+		}
+	}
+	if len(withMatches) == 0 {
+		return nil
+	}
+	if *dryRun {
+		recordFilterHistory(options, items)
+		return nil
+	}
+
+	op := itemOperation{
+		verb:         "delete attachments of",
+		modeEmoji:    emojiWarning,
+		modeText:     "Delete attachments (the items are kept; deleted attachments cannot be restored)",
+		confirmText:  fmt.Sprintf("delete %d attachments (%s) of", count, formatBytes(total)),
+		processName:  "attachment deletion",
+		progressVerb: "deleting attachments of",
+		doneText:     "have had their matching attachments deleted",
+		idempotent:   true,
+		run: func(item BitwardenItem) error {
+			for _, attachment := range matches[item.ID] {
+				if err := deleteAttachment(item.ID, attachment); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	displayOperationMode(op)
+	stats := &DeleteStats{total: len(withMatches), protected: options.protection.count()}
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	recordFilterHistory(options, items)
+	return executeItems(withMatches, stats, op, options)
+}
+
+// attachmentFilter builds the predicate for the size bounds and file name
+// pattern of 'attachments delete'; empty values match every attachment.
+func attachmentFilter(minSize, maxSize, name string) (func(attachment BitwardenAttachment) bool, error) {
+	lower, err := parseSize("min-size", minSize)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := parseSize("max-size", maxSize)
+	if err != nil {
+		return nil, err
+	}
+	var pattern *regexp.Regexp
+	if name != "" {
+		if pattern, err = compileNamePattern("name", name); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(attachment BitwardenAttachment) bool {
+		size := attachment.bytes()
+		if minSize != "" && size < lower || maxSize != "" && size > upper {
+			return false
+		}
+		return pattern == nil || pattern.MatchString(attachment.FileName)
+	}, nil
+}
+
+// parseSize reads a size such as 500KB, 5MB or 1.5GB with the binary units
+// formatBytes prints, or a plain number of bytes.
+func parseSize(flag, value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "B")
+	multiplier := int64(1)
+	if i := strings.IndexAny(number, "KMGT"); i >= 0 && i == len(number)-1 {
+		multiplier = int64(1) << (10 * (strings.IndexByte("KMGT", number[i]) + 1))
+		number = number[:i]
+	}
+	size, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid --%s %q (expected e.g. 500KB, 5MB or a number of bytes)", flag, value)
+	}
+	return int64(size * float64(multiplier)), nil
+}
+
+func (attachment BitwardenAttachment) bytes() int64 {
+	size, _ := strconv.ParseInt(attachment.Size, 10, 64)
+	return size
+}
+
+func deleteAttachment(itemID string, attachment BitwardenAttachment) error {
+	if err := vault.DeleteAttachment(itemID, attachment.ID); err != nil {
+		return fmt.Errorf("%w (%s)", err, attachment.FileName)
+	}
+	return nil
+}
+
+func downloadItemAttachments(item BitwardenItem, dest, dir string) ([]attachmentManifestEntry, error) {
+	if err := os.MkdirAll(filepath.Join(dest, dir), 0o700); err != nil {
+		return nil, fmt.Errorf("error creating item directory: %w", err)
+	}
+
+	var entries []attachmentManifestEntry
+	var firstErr error
+	used := make(map[string]bool)
+
+	for _, attachment := range item.Attachments {
+		fileName := safeFileName(attachment.FileName)
+		if used[fileName] {
+			fileName = attachment.ID + "-" + fileName
+		}
+		used[fileName] = true
+
+		path := filepath.Join(dir, fileName)
+		entry := attachmentManifestEntry{
+			ItemID:       item.ID,
+			ItemName:     item.Name,
+			AttachmentID: attachment.ID,
+			FileName:     attachment.FileName,
+			Size:         attachment.Size,
+			Path:         path,
+		}
+
+		if err := vault.DownloadAttachment(item.ID, attachment.ID, filepath.Join(dest, path)); err != nil {
+			entry.Error = err.Error()
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, firstErr
+}
+
+// reuploadAttachments re-encrypts an item's attachments by downloading each
+// one, attaching the downloaded copy again and only then deleting the
+// original, so a failed upload never loses the attachment.
+func reuploadAttachments(item BitwardenItem) error {
+	if len(item.Attachments) == 0 {
+		return nil
+	}
+
+	tempDir, err := os.MkdirTemp("", "bitwarden-attachments-")
+	if err != nil {
+		return fmt.Errorf("error creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, attachment := range item.Attachments {
+		dir := filepath.Join(tempDir, attachment.ID)
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			return fmt.Errorf("error creating temporary directory: %w", err)
+		}
+
+		path := filepath.Join(dir, safeFileName(attachment.FileName))
+		if err := vault.DownloadAttachment(item.ID, attachment.ID, path); err != nil {
+			return err
+		}
+		if err := vault.UploadAttachment(item.ID, path); err != nil {
+			return fmt.Errorf("%w (%s)", err, attachment.FileName)
+		}
+		if err := vault.DeleteAttachment(item.ID, attachment.ID); err != nil {
+			return fmt.Errorf("%w (original of %s; a re-uploaded copy exists)", err, attachment.FileName)
+		}
+	}
+
+	return nil
+}
+
+// attachmentDirectories names one directory per item after the item name,
+// adding the item ID when several items share the same name.
+func attachmentDirectories(items []BitwardenItem) map[string]string {
+	nameCount := make(map[string]int)
+	for _, item := range items {
+		nameCount[safeFileName(item.Name)]++
+	}
+
+	dirs := make(map[string]string, len(items))
+	for _, item := range items {
+		name := safeFileName(item.Name)
+		if nameCount[name] > 1 {
+			name = fmt.Sprintf("%s (%s)", name, item.ID)
+		}
+		dirs[item.ID] = name
+	}
+	return dirs
+}
+
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+
+	if name == "" || name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+func writeJSONFile(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// auditItem is a login as written to the reports of 'audit'. It never
+// carries the password itself.
+type auditItem struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Username     string `json:"username,omitempty"`
+	URI          string `json:"uri,omitempty"`
+	RevisionDate string `json:"revisionDate"`
+}
+
+// reusedCluster is a group of logins sharing one password. Clusters are
+// numbered by size, so the number identifies the password within a report
+// without revealing anything about it.
+type reusedCluster struct {
+	Number     int               `json:"number"`
+	Size       int               `json:"size"`
+	Keep       auditItem         `json:"keep"`
+	Duplicates []reusedDuplicate `json:"duplicates"`
+}
+
+// reusedDuplicate is a login sharing the password of the kept one. A copy
+// has the same username on the same registrable domain as a more recently
+// revised login of the cluster, so it is the same account saved twice; only
+// copies are deleted by --delete.
+type reusedDuplicate struct {
+	auditItem
+	Copy bool `json:"copy"`
+}
+
+type reusedReport struct {
+	CreatedAt     time.Time       `json:"createdAt"`
+	LoginsAudited int             `json:"loginsAudited"`
+	Clusters      []reusedCluster `json:"clusters"`
+	Action        string          `json:"action"`
+}
+
+func runAuditCommand(args []string) error {
+	usage := fmt.Errorf("usage: %s audit reused|pwned [--delete [--permanent] | --tag <text>] [--report <file>] | weak [--min-length <n>] [--min-entropy <bits>] [--min-classes <n>] [--delete [--permanent] [--interactive]] [--report <file>] | stale [--older-than <age>] [--check-uris dns|http] [--delete [--permanent] | --tag <text>] [--report <file>]", filepath.Base(os.Args[0]))
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "reused":
+		return runAuditReused(args[1:])
+	case "weak":
+		return runAuditWeak(args[1:])
+	case "pwned":
+		return runAuditPwned(args[1:])
+	case "stale":
+		return runAuditStale(args[1:])
+	default:
+		return usage
+	}
+}
+
+func runAuditReused(args []string) error {
+	flags := flag.NewFlagSet("audit reused", flag.ExitOnError)
+	deleteDuplicates := flags.Bool("delete", false, "Delete the logins of a cluster that are copies of a newer one: the same username on the same domain")
+	permanent := flags.Bool("permanent", false, "With --delete, permanently delete items (skip trash)")
+	tag := flags.String("tag", "", "Append this line to the notes of every login of a cluster but the most recently revised one")
+	reportPath := flags.String("report", "", "Also write the clusters to this JSON file")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	options.isPermanent = *permanent
+	if *permanent && !*deleteDuplicates {
+		return fmt.Errorf("--permanent can only be used with --delete")
+	}
+	if *deleteDuplicates && *tag != "" {
+		return fmt.Errorf("--delete and --tag cannot be used together")
+	}
+
+	logins, err := fetchAuditLogins(options)
+	if err != nil {
+		return err
+	}
+	sets := findReusedPasswords(logins)
+
+	report := reusedReport{CreatedAt: time.Now().UTC(), LoginsAudited: len(logins), Action: "report only"}
+	switch {
+	case *deleteDuplicates:
+		report.Action = "delete copies"
+	case *tag != "":
+		report.Action = fmt.Sprintf("tag duplicates with %q", *tag)
+	}
+
+	var duplicates, copies []BitwardenItem
+	if len(sets) == 0 {
+		console.infof(emojiSuccess, "No reused passwords among %d logins", len(logins))
+	} else {
+		console.infof(emojiSearch, "Found %d reused passwords among %d logins:", len(sets), len(logins))
+	}
+	for i, set := range sets {
+		cluster := reusedCluster{Number: i + 1, Size: len(set.duplicates) + 1, Keep: newAuditItem(set.keep, options.redact)}
+		console.linef("   password #%d, used by %d logins:", cluster.Number, cluster.Size)
+		console.linef("      keep   %s, revised %s", cluster.Keep.describe(), revisionDay(set.keep))
+		newer := []BitwardenItem{set.keep}
+		for _, duplicate := range set.duplicates {
+			entry := reusedDuplicate{auditItem: newAuditItem(duplicate, options.redact)}
+			for _, login := range newer {
+				entry.Copy = entry.Copy || sameAccount(login, duplicate)
+			}
+			newer = append(newer, duplicate)
+			label := "other"
+			if entry.Copy {
+				label = "copy "
+				copies = append(copies, duplicate)
+			}
+			cluster.Duplicates = append(cluster.Duplicates, entry)
+			console.linef("      %s  %s, revised %s", label, entry.describe(), revisionDay(duplicate))
+		}
+		report.Clusters = append(report.Clusters, cluster)
+		duplicates = append(duplicates, set.duplicates...)
+	}
+
+	if *reportPath != "" {
+		if err := writeJSONFile(*reportPath, report); err != nil {
+			return err
+		}
+		console.infof(emojiSuccess, "Report written to %s", *reportPath)
+	}
+
+	if *deleteDuplicates {
+		if others := len(duplicates) - len(copies); others > 0 {
+			console.infof(emojiInfo, "%d logins are the only login of their account in a cluster and are not deleted; change their passwords, or mark them with --tag", others)
+		}
+		return handleAuditedLogins(copies, options, true, "", "copies")
+	}
+	return handleAuditedLogins(duplicates, options, false, *tag, "older logins")
+}
+
+// sameAccount reports whether two logins have the same username on the
+// same registrable domain.
+func sameAccount(a, b BitwardenItem) bool {
+	if !strings.EqualFold(a.username(), b.username()) {
+		return false
+	}
+	domains := make(map[string]bool)
+	for _, uri := range a.uris() {
+		if host := uriHost(uri); host != "" {
+			domains[registrableDomain(host)] = true
+		}
+	}
+	for _, uri := range b.uris() {
+		if host := uriHost(uri); host != "" && domains[registrableDomain(host)] {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAuditedLogins deletes the logins an audit flagged, or appends the
+// tag line to their notes, after the usual confirmation. Without either it
+// only points at the flags.
+func handleAuditedLogins(logins []BitwardenItem, options CommandOptions, deleteLogins bool, tag, noun string) error {
+	if len(logins) == 0 {
+		return nil
+	}
+	if err := writeIDList(options.idsOut, logins); err != nil {
+		return err
+	}
+	matchedItems := logins
+
+	var op itemOperation
+	switch {
+	case deleteLogins:
+		op = deleteOperation(options.isPermanent)
+		logins = protectSensitiveItems(logins, options)
+	case tag != "":
+		op = stampNotesOperation(tag)
+	default:
+		recordFilterHistory(options, logins)
+		console.infof(emojiInfo, "Run with --delete or --tag <text> to handle the %d %s", len(logins), noun)
+		return nil
+	}
+
+	displayOperationMode(op)
+	stats := &DeleteStats{total: len(logins), protected: options.protection.count()}
+	if stats.total == 0 {
+		return nil
+	}
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	recordFilterHistory(options, matchedItems)
+	return executeItems(logins, stats, op, options)
+}
+
+// fetchAuditLogins returns the matched login items, whose passwords bw
+// lists decrypted.
+func fetchAuditLogins(options CommandOptions) ([]BitwardenItem, error) {
+	if err := checkBitwardenCLI(); err != nil {
+		return nil, err
+	}
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var logins []BitwardenItem
+	for _, item := range items {
+		if item.Type == itemTypeLogin {
+			logins = append(logins, item)
+		}
+	}
+	return logins, nil
+}
+
+// findReusedPasswords groups logins by their password and keeps the most
+// recently revised login of every password used more than once. The largest
+// clusters come first.
+func findReusedPasswords(logins []BitwardenItem) []duplicateSet {
+	sets := findDuplicateItems(logins, func(item BitwardenItem) string {
+		details, err := decodeItemDetails(item)
+		if err != nil || details.Login == nil {
+			return ""
+		}
+		return details.Login.Password
+	})
+	sort.SliceStable(sets, func(i, j int) bool { return len(sets[i].duplicates) > len(sets[j].duplicates) })
+	return sets
+}
+
+func newAuditItem(item BitwardenItem, redact redactor) auditItem {
+	entry := auditItem{
+		ID:           item.ID,
+		Name:         redact.name(item.Name),
+		Username:     redact.username(item.username()),
+		RevisionDate: item.RevisionDate,
+	}
+	if uris := item.uris(); len(uris) > 0 {
+		entry.URI = redact.uri(uris[0])
+	}
+	return entry
+}
+
+func (entry auditItem) describe() string {
+	description := fmt.Sprintf("%s (%s)", entry.Name, entry.ID)
+	if entry.Username != "" {
+		description += " user: " + entry.Username
+	}
+	if entry.URI != "" {
+		description += " uri: " + entry.URI
+	}
+	return description
+}
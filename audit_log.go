@@ -0,0 +1,44 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// auditLogLine is one processed item in a --log-file.
+type auditLogLine struct {
+	Time time.Time `json:"time"`
+	Mode string    `json:"mode"`
+	resultRecord
+}
+
+// auditLog serializes the appends of the workers, so that lines of
+// parallel items never interleave.
+var auditLog sync.Mutex
+
+// appendAuditLog adds the outcome of one item to --log-file. A log that
+// cannot be written is a warning rather than a reason to stop the run.
+func appendAuditLog(result itemResult, op itemOperation, options CommandOptions) {
+	if options.logFile == "" {
+		return
+	}
+	line := auditLogLine{Time: time.Now().UTC(), Mode: operationMode(op), resultRecord: result.record(options.redact)}
+
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if err := appendJSONLine(options.logFile, line); err != nil {
+		console.warnf("Warning: could not write log file: %v", err)
+	}
+}
+
+// operationMode tells deletions to the trash from permanent ones and from
+// operations that edit items.
+func operationMode(op itemOperation) string {
+	switch {
+	case op.deletesItems && op.permanent:
+		return "permanent"
+	case op.deletesItems:
+		return "trash"
+	}
+	return "edit"
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxAutoBatch = 16
+	autoBatchStart      = 2
+	// A window whose average latency stays below this multiple of the best
+	// window seen so far lets the workers grow; above twice that, the server
+	// is struggling and one worker is taken away.
+	autoBatchGrowFactor = 1.5
+)
+
+// batchFlag is the value of --batch: a number of workers, or "auto".
+type batchFlag struct {
+	size int
+	auto bool
+}
+
+func (b *batchFlag) Set(value string) error {
+	if value == "auto" {
+		b.auto = true
+		return nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 1 {
+		return fmt.Errorf("expected a number of workers or auto")
+	}
+	b.size, b.auto = size, false
+	return nil
+}
+
+func (b *batchFlag) String() string {
+	if b == nil {
+		return ""
+	}
+	if b.auto {
+		return "auto"
+	}
+	return strconv.Itoa(b.size)
+}
+
+// autoBatch sizes the worker pool of --batch auto while a run is going. All
+// workers up to the cap are started, but only limit of them may process an
+// item at a time. The limit grows by one after every window of items in
+// which latency held steady, and halves after a window with throttled or
+// failed items, so the run settles just below what the server accepts.
+type autoBatch struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	ceiling int
+	active  int
+
+	window    int
+	latency   time.Duration
+	throttled int
+	best      time.Duration
+	low, high int
+}
+
+func newAutoBatch(ceiling int) *autoBatch {
+	start := min(autoBatchStart, ceiling)
+	batch := &autoBatch{limit: start, ceiling: ceiling, low: start, high: start}
+	batch.cond = sync.NewCond(&batch.mu)
+	return batch
+}
+
+// acquire waits until fewer than limit workers are busy.
+func (b *autoBatch) acquire() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.active >= b.limit {
+		b.cond.Wait()
+	}
+	b.active++
+}
+
+// release frees the slot of a worker and takes the result of its item, if
+// it processed one, into account.
+func (b *autoBatch) release(result *itemResult) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active--
+	if result != nil {
+		b.observe(*result)
+	}
+	b.cond.Broadcast()
+}
+
+func (b *autoBatch) observe(result itemResult) {
+	b.window++
+	b.latency += result.Duration
+	if result.Retries > 0 || (result.Err != nil && isRetryable(result.Err)) {
+		b.throttled++
+	}
+	if b.window < max(b.limit, 4) {
+		return
+	}
+
+	average := b.latency / time.Duration(b.window)
+	previous := b.limit
+	switch {
+	case b.throttled > 0:
+		b.limit = max(b.limit/2, 1)
+	case b.best == 0 || average < b.best:
+		b.best = average
+		b.limit = min(b.limit+1, b.ceiling)
+	case float64(average) <= autoBatchGrowFactor*float64(b.best):
+		b.limit = min(b.limit+1, b.ceiling)
+	case float64(average) > 2*autoBatchGrowFactor*float64(b.best):
+		b.limit = max(b.limit-1, 1)
+	}
+	if b.limit != previous {
+		console.debugf("", "   --batch auto: %d -> %d workers (avg %s, %d throttled)", previous, b.limit, average.Round(time.Millisecond), b.throttled)
+	}
+	b.low, b.high = min(b.low, b.limit), max(b.high, b.limit)
+	b.window, b.latency, b.throttled = 0, 0, 0
+}
+
+func (b *autoBatch) report() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	console.infof(emojiStats, "--batch auto: ended at %d workers (between %d and %d, --max-batch %d)", b.limit, b.low, b.high, b.ceiling)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// observeWindow feeds one full window of items that each took latency.
+func observeWindow(batch *autoBatch, latency time.Duration, retries int) {
+	for size := max(batch.limit, 4); size > 0; size-- {
+		batch.observe(itemResult{Duration: latency, Retries: retries})
+	}
+}
+
+func TestAutoBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		ceiling   int
+		windows   []time.Duration
+		throttled int
+		want      int
+	}{
+		{"grows while latency is steady", 16, []time.Duration{100, 100, 120, 140}, -1, 6},
+		{"stops at the ceiling", 3, []time.Duration{100, 100, 100, 100}, -1, 3},
+		{"holds when latency rises", 16, []time.Duration{100, 200, 200}, -1, 3},
+		{"shrinks when latency doubles", 16, []time.Duration{100, 100, 400}, -1, 3},
+		{"halves when throttled", 16, []time.Duration{100, 100, 100, 100}, 3, 2},
+		{"never drops below one", 1, []time.Duration{100, 100}, 0, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			batch := newAutoBatch(test.ceiling)
+			for i, latency := range test.windows {
+				retries := 0
+				if i == test.throttled {
+					retries = 1
+				}
+				observeWindow(batch, latency*time.Millisecond, retries)
+			}
+			if batch.limit != test.want {
+				t.Errorf("limit = %d, want %d", batch.limit, test.want)
+			}
+			if batch.low > batch.limit || batch.high < batch.limit {
+				t.Errorf("range %d-%d does not include the limit %d", batch.low, batch.high, batch.limit)
+			}
+		})
+	}
+}
+
+func TestAutoBatchWaitsForAFullWindow(t *testing.T) {
+	batch := newAutoBatch(16)
+	for i := 0; i < 3; i++ {
+		batch.observe(itemResult{Duration: time.Millisecond})
+	}
+	if batch.limit != autoBatchStart || batch.window != 3 {
+		t.Errorf("limit = %d after %d items, want %d until the window is full", batch.limit, batch.window, autoBatchStart)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	backupFileVersion   = 1
+	backupKDFIterations = 600000
+	backupPassphraseEnv = "BITWARDEN_CLEANUP_BACKUP_PASSPHRASE"
+)
+
+// backupFile holds the full JSON of items as 'bw list items' returned them.
+// Encrypted backups keep the items in Data, sealed with AES-256-GCM under a
+// key derived from a passphrase.
+type backupFile struct {
+	Version    int               `json:"version"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	Items      []json.RawMessage `json:"items,omitempty"`
+	Encryption *backupEncryption `json:"encryption,omitempty"`
+	Data       []byte            `json:"data,omitempty"`
+}
+
+type backupEncryption struct {
+	Cipher     string `json:"cipher"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+}
+
+// writeBackup writes items to a new backup file next to path, with the time
+// of the run added to its name so that earlier backups are never replaced.
+// A directory gets a file named bitwarden-backup-<time>.json.
+func writeBackup(items []BitwardenItem, path string, encrypt bool) error {
+	backup := backupFile{Version: backupFileVersion, CreatedAt: time.Now().UTC()}
+	for _, item := range items {
+		raw := item.raw
+		if raw == nil {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return fmt.Errorf("error encoding item %s: %w", item.ID, err)
+			}
+			raw = data
+		}
+		backup.Items = append(backup.Items, raw)
+	}
+
+	if encrypt {
+		passphrase, err := backupPassphrase(true)
+		if err != nil {
+			return err
+		}
+		if err := backup.seal(passphrase); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding backup: %w", err)
+	}
+
+	path = timestampedBackupPath(path, backup.CreatedAt.Local())
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating backup: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing backup: %w", err)
+	}
+
+	state := "unencrypted"
+	if encrypt {
+		state = "encrypted"
+	}
+	console.summaryf(emojiSuccess, "Backed up %d items to %s (%s; restore with: %s restore-backup %s)", len(items), path, state, filepath.Base(os.Args[0]), shellJoin([]string{path}))
+	return nil
+}
+
+func timestampedBackupPath(path string, now time.Time) string {
+	stamp := now.Format("20060102-150405")
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return filepath.Join(path, "bitwarden-backup-"+stamp+".json")
+	}
+	ext := filepath.Ext(path)
+	if ext == "" {
+		ext = ".json"
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "-" + stamp + ext
+}
+
+func (backup *backupFile) seal(passphrase string) error {
+	plaintext, err := json.Marshal(backup.Items)
+	if err != nil {
+		return fmt.Errorf("error encoding backup: %w", err)
+	}
+
+	encryption := &backupEncryption{Cipher: "aes-256-gcm", KDF: "pbkdf2-sha256", Iterations: backupKDFIterations, Salt: make([]byte, 16)}
+	if _, err := rand.Read(encryption.Salt); err != nil {
+		return fmt.Errorf("error generating salt: %w", err)
+	}
+	aead, err := backupCipher(passphrase, encryption)
+	if err != nil {
+		return err
+	}
+	encryption.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(encryption.Nonce); err != nil {
+		return fmt.Errorf("error generating nonce: %w", err)
+	}
+
+	backup.Encryption = encryption
+	backup.Data = aead.Seal(nil, encryption.Nonce, plaintext, nil)
+	backup.Items = nil
+	return nil
+}
+
+func (backup *backupFile) open(passphrase string) error {
+	encryption := backup.Encryption
+	if encryption.Cipher != "aes-256-gcm" || encryption.KDF != "pbkdf2-sha256" {
+		return fmt.Errorf("unsupported backup encryption %s/%s", encryption.Cipher, encryption.KDF)
+	}
+	aead, err := backupCipher(passphrase, encryption)
+	if err != nil {
+		return err
+	}
+	plaintext, err := aead.Open(nil, encryption.Nonce, backup.Data, nil)
+	if err != nil {
+		return fmt.Errorf("cannot decrypt backup: wrong passphrase or damaged file")
+	}
+	if err := json.Unmarshal(plaintext, &backup.Items); err != nil {
+		return fmt.Errorf("error parsing decrypted backup: %w", err)
+	}
+	return nil
+}
+
+func backupCipher(passphrase string, encryption *backupEncryption) (cipher.AEAD, error) {
+	key := pbkdf2.Key([]byte(passphrase), encryption.Salt, encryption.Iterations, 32, sha256.New)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up backup encryption: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up backup encryption: %w", err)
+	}
+	return aead, nil
+}
+
+// backupPassphrase reads the passphrase from the environment, or asks for it
+// (twice when repeat is set, so that a typo does not lock the backup away).
+func backupPassphrase(repeat bool) (string, error) {
+	if passphrase := os.Getenv(backupPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	if !stdinIsTerminal() {
+		return "", fmt.Errorf("no terminal to ask for the backup passphrase; set %s", backupPassphraseEnv)
+	}
+	console.promptf(emojiInfo, "Backup passphrase: ")
+	passphrase, err := readPassword()
+	if err != nil {
+		return "", fmt.Errorf("error reading passphrase: %w", err)
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("the backup passphrase must not be empty")
+	}
+	if repeat {
+		console.promptf(emojiInfo, "Repeat the passphrase: ")
+		again, err := readPassword()
+		if err != nil {
+			return "", fmt.Errorf("error reading passphrase: %w", err)
+		}
+		if again != passphrase {
+			return "", fmt.Errorf("the passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+func readBackup(path string) (*backupFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup: %w", err)
+	}
+	var backup backupFile
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("error parsing backup %s: %w", path, err)
+	}
+	if backup.Version != backupFileVersion {
+		return nil, fmt.Errorf("backup %s has unsupported version %d", path, backup.Version)
+	}
+
+	if backup.Encryption != nil {
+		passphrase, err := backupPassphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		if err := backup.open(passphrase); err != nil {
+			return nil, err
+		}
+	}
+	return &backup, nil
+}
+
+// runRestoreBackupCommand brings back the items of a backup file: items in
+// the trash are restored and items that are gone are recreated, while items
+// still in the vault are left alone.
+func runRestoreBackupCommand(args []string) error {
+	flags := flag.NewFlagSet("restore-backup", flag.ExitOnError)
+	batchSize := flags.Int("batch", 1, "Number of items to process in parallel")
+	flags.IntVar(batchSize, "b", 1, "Number of items to process in parallel (shorthand)")
+	consoleValues := defineConsoleFlags(flags)
+	flags.Parse(args)
+	if err := consoleValues.apply(); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: %s restore-backup [--batch <n>] <backup.json>", filepath.Base(os.Args[0]))
+	}
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	backup, err := readBackup(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	var ids []string
+	backups := make(map[string]json.RawMessage, len(backup.Items))
+	for _, raw := range backup.Items {
+		var item BitwardenItem
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return fmt.Errorf("error parsing backed-up item: %w", err)
+		}
+		ids = append(ids, item.ID)
+		backups[item.ID] = raw
+	}
+	plan, err := planRestore(ids, backups)
+	if err != nil {
+		return err
+	}
+
+	console.infof(emojiSearch, "Backup from %s: %d items to restore from trash, %d to recreate", backup.CreatedAt.Local().Format("2006-01-02 15:04"), len(plan.toRestore), len(plan.toRecreate))
+	if !plan.confirm() {
+		return nil
+	}
+	op := undoOperation(plan.trashed)
+	op.processName = "backup restore"
+	if err := processItems(plan.items(), &DeleteStats{total: len(plan.items())}, op, batchOptions(*batchSize)); err != nil {
+		return err
+	}
+
+	if err := syncBitwarden(""); err != nil {
+		console.warnf("Warning: Final sync failed")
+	}
+	return nil
+}
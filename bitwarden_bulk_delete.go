@@ -1,189 +1,1042 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"os/exec"
+	"slices"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/mitas/bitwarden-cleanup/pkg/cleanup"
 )
 
 type BitwardenItem struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID             string                `json:"id"`
+	Type           int                   `json:"type"`
+	Name           string                `json:"name"`
+	FolderID       string                `json:"folderId"`
+	OrganizationID string                `json:"organizationId"`
+	CollectionIDs  []string              `json:"collectionIds"`
+	Notes          string                `json:"notes"`
+	Fields         []BitwardenField      `json:"fields"`
+	Login          *BitwardenLogin       `json:"login"`
+	SSHKey         *BitwardenSSHKey      `json:"sshKey"`
+	Attachments    []BitwardenAttachment `json:"attachments"`
+	RevisionDate   string                `json:"revisionDate"`
+	CreationDate   string                `json:"creationDate"`
+	DeletedDate    string                `json:"deletedDate"`
+
+	raw json.RawMessage
+}
+
+// ItemID lets the cleanup engine process vault items.
+func (item BitwardenItem) ItemID() string { return item.ID }
+
+// UnmarshalJSON keeps the item's original JSON next to the parsed fields so
+// the complete item can be backed up or recreated later.
+func (item *BitwardenItem) UnmarshalJSON(data []byte) error {
+	type plainItem BitwardenItem
+	if err := json.Unmarshal(data, (*plainItem)(item)); err != nil {
+		return err
+	}
+	item.raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+type BitwardenAttachment struct {
+	ID       string `json:"id"`
+	FileName string `json:"fileName"`
+	Size     string `json:"size"`
+	SizeName string `json:"sizeName"`
+}
+
+type BitwardenField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  int    `json:"type"`
+}
+
+type BitwardenLogin struct {
+	Username         string            `json:"username"`
+	Password         string            `json:"password"`
+	TOTP             string            `json:"totp"`
+	URIs             []BitwardenURI    `json:"uris"`
+	Fido2Credentials []json.RawMessage `json:"fido2Credentials"`
+}
+
+type BitwardenSSHKey struct {
+	PrivateKey     string `json:"privateKey"`
+	PublicKey      string `json:"publicKey"`
+	KeyFingerprint string `json:"keyFingerprint"`
+}
+
+type BitwardenURI struct {
+	URI   string `json:"uri"`
+	Match *int   `json:"match"`
 }
 
 type CommandOptions struct {
-	searchTerm  string
-	batchSize   int
-	isPermanent bool
+	searchTerm          string
+	searchTerms         []string
+	searchMatch         string
+	batchSize           int
+	autoBatch           bool
+	retries             int
+	retryBackoff        time.Duration
+	rate                float64
+	delay               time.Duration
+	limiter             *rateLimiter
+	batcher             *autoBatch
+	isPermanent         bool
+	trimPasswordHistory int
+	hasPasskey          bool
+	noPasskey           bool
+	onlyIncomplete      bool
+	fields              []string
+	notesContains       string
+	usernames           []string
+	emails              []string
+	tagged              string
+	stripPasskeys       bool
+	hasAppURI           bool
+	appURI              string
+	appURIOnly          bool
+	matchWords          bool
+	exactName           bool
+	startsWith          bool
+	caseSensitive       bool
+	glob                string
+	fuzzy               bool
+	fuzzyThreshold      float64
+	reveal              bool
+	session             string
+	bwTimeout           time.Duration
+	ignoreBWVersion     bool
+	server              string
+	output              string
+	assumeYes           bool
+	forceThreshold      int
+	typeToConfirm       int
+	cooldown            time.Duration
+	statsFile           string
+	metricsFile         string
+	logFile             string
+	notifyURL           string
+	notifyDesktop       bool
+	notifyFailures      int
+	notifier            *notifier
+	preHook             string
+	itemHook            string
+	postHook            string
+	hooks               *hooks
+	syncEvery           int
+	syncGate            *syncGate
+	failOnEmpty         bool
+	limit               int
+	skip                int
+	sortBy              string
+	noAutoRetry         bool
+	backend             string
+	redact              redactor
+	csvFile             string
+	retryFailed         string
+	idsFile             string
+	idsOut              string
+	fromExport          string
+	failedFile          string
+	presentIn           string
+	itemTypes           string
+	regex               string
+	regexURI            string
+	uris                []string
+	domains             []string
+	domainFile          string
+	folder              string
+	folderID            string
+	organizationID      string
+	collection          string
+	createdBefore       string
+	createdAfter        string
+	modifiedBefore      string
+	modifiedAfter       string
+	setURIMatch         string
+	reuploadAttachments bool
+	stampNotes          string
+	moveToOrg           string
+	moveToFolder        string
+	toCollections       []string
+	groupBy             string
+	selectionArgs       []string
+	excludeIDs          map[string]bool
+	excludes            []string
+	console             consoleFlags
+	protectFile         string
+	protection          *protectionRules
+	previewThreshold    int
+	preview             int
+	profiles            string
+	interactive         bool
+	confirmEach         bool
+	pageSize            int
+	dryRun              bool
+	maxItems            int
+	allowLarge          bool
+	hasDryRun           bool
+	staged              bool
+	stagedWindow        time.Duration
+	twoPhase            string
+	commit              string
+	scheduleAt          string
+	scheduleAfter       time.Duration
+	watch               bool
+	watchInterval       time.Duration
+	commandArgs         []string
+	noBackup            bool
+	backupPath          string
+	encryptBackup       bool
+	exportURIs          string
+	reportPath          string
+	exportKeePass       string
+	export1PUX          string
+	stopFile            string
+	checkpointPath      string
+	resume              string
 }
 
 type DeleteStats struct {
-	total     int
-	completed int
+	total       int
+	completed   int
+	protected   int
+	stop        *stopSignal
+	checkpoint  *checkpoint
+	interrupted bool
+
+	mu           sync.Mutex
+	failed       int
+	alreadyGone  int
+	totalLatency time.Duration
+	started      time.Time
+	recent       []time.Time
+	results      []itemResult
 }
 
 // UI emojis
 const (
-	emojiError      = "❌"
-	emojiSuccess    = "✅"
-	emojiSync       = "🔄"
-	emojiSearch     = "🔍"
-	emojiWarning    = "⚠️"
-	emojiInfo       = "ℹ️"
-	emojiStart      = "🚀"
-	emojiProgress   = "⏳"
-	emojiComplete   = "🎉"
+	emojiError    = "❌"
+	emojiSuccess  = "✅"
+	emojiSync     = "🔄"
+	emojiSearch   = "🔍"
+	emojiWarning  = "⚠️"
+	emojiInfo     = "ℹ️"
+	emojiStart    = "🚀"
+	emojiProgress = "⏳"
+	emojiComplete = "🎉"
+	emojiStats    = "📊"
 )
 
 func main() {
-	options := parseCommandLineOptions()
+	setupConsole()
 
-	if err := runBulkDelete(options); err != nil {
-		fmt.Printf("%s Error: %v\n", emojiError, err)
+	if len(os.Args) > 1 {
+		if command, ok := subcommands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				exitWithError(err)
+			}
+			exitWithStatus()
+			return
+		}
+	}
+
+	options, err := parseCommandLineOptions()
+	if err != nil {
+		console.errorf("Error: %v", err)
 		os.Exit(1)
 	}
+
+	if err := runBulkDelete(options); err != nil {
+		exitWithError(err)
+	}
+	exitWithStatus()
 }
 
-func parseCommandLineOptions() CommandOptions {
-	searchTerm := flag.String("search", "", "Search term to filter items")
-	searchShort := flag.String("s", "", "Search term to filter items (shorthand)")
-	batchSize := flag.Int("batch", 1, "Number of items to process in parallel")
-	batchShort := flag.Int("b", 1, "Number of items to process in parallel (shorthand)")
-	permanent := flag.Bool("permanent", false, "Permanently delete items (skip trash)")
-	permanentShort := flag.Bool("p", false, "Permanently delete items (skip trash) (shorthand)")
-	
-	flag.Parse()
+func exitWithError(err error) {
+	console.errorf("Error: %v", err)
+	emitError(err)
+	finishMetrics(exitCode(err))
+	os.Exit(exitCode(err))
+}
 
-	options := CommandOptions{}
-	
-	options.searchTerm = *searchTerm
-	if options.searchTerm == "" && *searchShort != "" {
-		options.searchTerm = *searchShort
+func parseCommandLineOptions() (CommandOptions, error) {
+	collectOptions := defineCommandFlags(flag.CommandLine)
+	return parseOptions(flag.CommandLine, os.Args[1:], collectOptions)
+}
+
+// parseOptions parses the command line of a command and applies the
+// options that affect the whole process: the output format and --yes.
+func parseOptions(flags *flag.FlagSet, args []string, collectOptions func() CommandOptions) (CommandOptions, error) {
+	options, err := parseSelection(flags, args, collectOptions)
+	if err != nil {
+		return CommandOptions{}, err
 	}
+	if err := setOutputMode(options.output, options.redact); err != nil {
+		return CommandOptions{}, err
+	}
+	if err := options.console.apply(); err != nil {
+		return CommandOptions{}, err
+	}
+	unattended.yes = options.assumeYes
+	unattended.typeToConfirm, unattended.cooldown = options.typeToConfirm, options.cooldown
+	if options.session != "" {
+		bwSession = options.session
+	}
+	if options.server != "" {
+		bwEnvironment.server = options.server
+	}
+	bwTimeout = options.bwTimeout
+	runMetrics.file = options.metricsFile
+	bwCompatibility.ignore = options.ignoreBWVersion
+	if options.fromExport != "" {
+		export, err := loadExportVault(options.fromExport)
+		if err != nil {
+			return CommandOptions{}, err
+		}
+		vault = export
+	}
+	return options, nil
+}
 
-	options.batchSize = *batchSize
-	if *batchSize == 1 && *batchShort != 1 {
-		options.batchSize = *batchShort
+// parseSelection parses args into flags, fills in a recalled selection from
+// the filter history when --last or --recall is given, then the flags set by
+// the environment and the config file, and collects the resulting options.
+func parseSelection(flags *flag.FlagSet, args []string, collectOptions func() CommandOptions) (CommandOptions, error) {
+	if err := flags.Parse(args); err != nil {
+		return CommandOptions{}, err
+	}
+
+	recalled, err := recallSelection(flags)
+	if err != nil {
+		return CommandOptions{}, err
+	}
+	if err := applyEnvironment(flags); err != nil {
+		return CommandOptions{}, err
+	}
+	if err := applyConfig(flags); err != nil {
+		return CommandOptions{}, err
+	}
+
+	options := collectOptions()
+	if _, err := rateInterval(options.rate, options.delay); err != nil {
+		return CommandOptions{}, err
+	}
+	if err := validatePaging(options); err != nil {
+		return CommandOptions{}, err
+	}
+	if options.searchMatch != matchAll && options.searchMatch != matchAny {
+		return CommandOptions{}, fmt.Errorf("unknown --match %q (expected all or any)", options.searchMatch)
+	}
+	if options.idsFile != "" && len(options.searchTerms) > 0 {
+		return CommandOptions{}, fmt.Errorf("--ids-file selects items by ID and cannot be combined with --search")
+	}
+	if err := validateNotifyURL(options.notifyURL); err != nil {
+		return CommandOptions{}, err
+	}
+	if options.notifyFailures < 0 {
+		return CommandOptions{}, fmt.Errorf("--notify-failures must not be negative")
+	}
+	if err := validateHooks(options); err != nil {
+		return CommandOptions{}, err
+	}
+	if options.bwTimeout < 0 {
+		return CommandOptions{}, fmt.Errorf("--bw-timeout must not be negative")
+	}
+	if options.syncEvery < 0 {
+		return CommandOptions{}, fmt.Errorf("--sync-every must not be negative")
+	}
+	if options.typeToConfirm < 0 || options.cooldown < 0 {
+		return CommandOptions{}, fmt.Errorf("--type-to-confirm and --cooldown must not be negative")
+	}
+	if options.protection, err = loadProtectionRules(options.excludes, options.protectFile); err != nil {
+		return CommandOptions{}, err
+	}
+	options.selectionArgs = selectionArgs(flags)
+	options.commandArgs = commandArgs(flags)
+	if recalled != nil && flagIsTrue(flags, "new-only") {
+		options.excludeIDs = make(map[string]bool, len(recalled.MatchedIDs))
+		for _, id := range recalled.MatchedIDs {
+			options.excludeIDs[id] = true
+		}
 	}
-	
-	options.isPermanent = *permanent || *permanentShort
 
-	return options
+	return options, nil
+}
+
+func defineCommandFlags(flags *flag.FlagSet) func() CommandOptions {
+	collectSelection := defineSelectionFlags(flags)
+	permanent := flags.Bool("permanent", false, "Permanently delete items (skip trash)")
+	permanentShort := flags.Bool("p", false, "Permanently delete items (skip trash) (shorthand)")
+	staged := flags.Bool("staged", false, "With --permanent, move items to trash first and purge them only after a confirmation window or a second invocation")
+	stagedWindow := flags.Duration("staged-window", 0, "With --staged, wait this long (e.g. 30m) and then purge the staged items; 0 leaves them for 'staged purge'")
+	twoPhase := flags.String("two-phase", "", "Move matched items to trash and list them in this manifest file, for a later --commit to delete them permanently")
+	commit := flags.String("commit", "", "Permanently delete the items listed in this --two-phase manifest that are still in the trash")
+	at := flags.String("at", "", "Record the approved run and execute it at this local time (e.g. 2024-07-01T02:00)")
+	after := flags.Duration("after", 0, "Record the approved run and execute it after this delay (e.g. 24h)")
+	watch := flags.Bool("watch", false, "Keep running and repeat the run every --interval, deleting new matches without asking (requires --yes)")
+	watchInterval := flags.Duration("interval", defaultWatchInterval, "With --watch, time between the starts of two runs (e.g. 24h, 30m)")
+	reportPath := flags.String("report", "", "After the run, write the ID, name, folder, type and outcome of every item to this file (.json for JSON, CSV otherwise)")
+	exportURIs := flags.String("export-uris", "", "Write the names and web URIs of matched items to this file before processing (.html for browser bookmarks, plain text otherwise)")
+	exportKeePass := flags.String("export-keepass", "", "Write matched items to this KeePass 2 XML file before processing (folders become groups)")
+	export1PUX := flags.String("export-1pux", "", "Write matched items to this 1Password .1pux archive before processing (folders become tags)")
+	noBackup := flags.Bool("no-backup", false, "Do not keep a local backup of permanently deleted items (they cannot be recreated by 'undo')")
+	backupPath := flags.String("backup", "", "Write the full JSON of matched items to a timestamped backup file at this path (or in this directory) before processing")
+	encryptBackup := flags.Bool("encrypt-backup", false, "Encrypt the --backup file with a passphrase (AES-256-GCM)")
+	checkpointPath := flags.String("checkpoint", "", "Record the progress of the run in this file (default: a new file in ~/.config/bitwarden-cleanup/checkpoints)")
+	resume := flags.String("resume", "", "Resume the interrupted run recorded in this checkpoint file, skipping the items it already processed")
+	dryRun := flags.Bool("dry-run", false, "Show what the run would do and estimate its impact without changing anything")
+	clearHistory := flags.Bool("clear-password-history", false, "Clear the password history of matched items instead of deleting them")
+	trimHistory := flags.Int("trim-password-history", -1, "Keep only the N most recent password history entries of matched items instead of deleting them")
+	setURIMatch := flags.String("set-uri-match", "", "Set the URI match detection of matched items instead of deleting them (base-domain, host, starts-with, exact, regex, never, default)")
+	reuploadAttachments := flags.Bool("reupload-attachments", false, "Download and re-upload the attachments of matched items (migrates them to new encryption keys after a key rotation)")
+	stampNotes := flags.String("stamp-notes", "", "Append this marker line to the notes of matched items instead of deleting them")
+	moveToFolder := flags.String("move-to-folder", "", "Move matched items to the folder with this name instead of deleting them, creating it if needed (\"No Folder\" removes them from their folder)")
+	moveToOrg := flags.String("move-to-org", "", "Transfer matched personal items to this organization (ID) instead of deleting them")
+	toCollections := flags.String("to-collections", "", "With --move-to-org, comma-separated collection IDs or names to assign the items to")
+	previewThreshold := flags.Int("preview-threshold", 50, "Page through a preview of the matched items before confirming when more than this many match (0 disables)")
+	interactive := flags.Bool("interactive", false, "Hand-pick the items to process from a checkbox list of the matched items before confirming")
+	confirmEach := flags.Bool("confirm-each", false, "Ask yes, no, all or quit for every matched item, showing its name, username, URIs and last-modified date")
+	pageSize := flags.Int("page-size", 20, "Number of items per preview page")
+	profiles := flags.String("profiles", "", "Run the same cleanup against each of these comma-separated config file profiles in turn (all for every profile)")
+	preview := flags.Int("preview", 0, "Page through a table of the matched items, N per page, before the confirmation prompt, whatever their number (0 disables)")
+	backend := flags.String("backend", backendExec, "How to talk to Bitwarden: exec runs bw once per call, serve starts 'bw serve' once and uses its local REST API")
+	noAutoRetry := flags.Bool("no-auto-retry", false, "Do not retry the items that failed in earlier runs before this run")
+	stripPasskeys := flags.Bool("strip-passkeys", false, "Remove passkeys (FIDO2 credentials) from matched items instead of deleting them")
+
+	return func() CommandOptions {
+		options := collectSelection()
+
+		options.isPermanent = *permanent || *permanentShort
+		options.dryRun = *dryRun
+		options.staged = *staged
+		options.stagedWindow = *stagedWindow
+		options.twoPhase = *twoPhase
+		options.commit = *commit
+		options.scheduleAt = *at
+		options.scheduleAfter = *after
+		options.watch = *watch
+		options.watchInterval = *watchInterval
+		options.noBackup = *noBackup
+		options.backupPath = *backupPath
+		options.encryptBackup = *encryptBackup
+		options.reportPath = *reportPath
+		options.exportURIs = *exportURIs
+		options.exportKeePass = *exportKeePass
+		options.export1PUX = *export1PUX
+		options.checkpointPath = *checkpointPath
+		options.resume = *resume
+
+		options.trimPasswordHistory = *trimHistory
+		if *clearHistory {
+			options.trimPasswordHistory = 0
+		}
+
+		options.stripPasskeys = *stripPasskeys
+		options.setURIMatch = *setURIMatch
+		options.reuploadAttachments = *reuploadAttachments
+		options.stampNotes = strings.TrimSpace(*stampNotes)
+		options.moveToOrg = *moveToOrg
+		options.moveToFolder = strings.TrimSpace(*moveToFolder)
+		for _, collection := range strings.Split(*toCollections, ",") {
+			if collection = strings.TrimSpace(collection); collection != "" {
+				options.toCollections = append(options.toCollections, collection)
+			}
+		}
+		options.noAutoRetry = *noAutoRetry
+		options.backend = *backend
+		options.previewThreshold = *previewThreshold
+		options.interactive = *interactive
+		options.confirmEach = *confirmEach
+		options.pageSize = max(*pageSize, 1)
+		options.preview = max(*preview, 0)
+		options.profiles = *profiles
+
+		return options
+	}
+}
+
+// defineSelectionFlags registers the flags that decide which items a run
+// works on, shared by the default delete command and the subcommands.
+func defineSelectionFlags(flags *flag.FlagSet) func() CommandOptions {
+	searchTerms := &listFlag{}
+	flags.Var(searchTerms, "search", "Search term to filter items; repeatable, combined with --match")
+	searchShort := &listFlag{}
+	flags.Var(searchShort, "s", "Search term to filter items (shorthand)")
+	searchMatch := flags.String("match", matchAll, "With several --search terms, match items that contain all of them or any of them (all, any)")
+	batchSize := &batchFlag{size: 1}
+	flags.Var(batchSize, "batch", "Number of items to process in parallel, or auto to adjust it to the server's latency and throttling (default 1)")
+	batchShort := &batchFlag{size: 1}
+	flags.Var(batchShort, "b", "Number of items to process in parallel, or auto (shorthand)")
+	maxBatch := flags.Int("max-batch", defaultMaxAutoBatch, "With --batch auto, never run more than this many items in parallel")
+	retries := flags.Int("retries", defaultRetries, "Retry an item this many times when it fails because of rate limiting or the network")
+	retryBackoff := flags.Duration("retry-backoff", defaultRetryBackoff, "Wait about this long before the first retry, doubling with every further retry (up to 30s)")
+	rate := flags.Float64("rate", 0, "Make at most this many requests per second, shared by all workers (e.g. 2, 0.5)")
+	delay := flags.Duration("delay", 0, "Wait at least this long between two requests, shared by all workers (e.g. 500ms)")
+	syncEvery := flags.Int("sync-every", 0, "Run bw sync after every N processed items, waiting for the items in flight (0 only syncs before and after the run)")
+	flags.Bool("last", false, "Re-run the selection flags of the most recent run")
+	flags.Int("recall", 0, "Re-run the selection flags of entry N from 'history filters'")
+	flags.Bool("new-only", false, "With --last or --recall, only match items that were not matched by the recalled run")
+	stopFile := flags.String("stop-file", "", "Stop gracefully between items once this file exists (default: ~/.config/bitwarden-cleanup/STOP)")
+	groupBy := flags.String("group-by", "", "Process items one folder or collection at a time (folder, collection)")
+	hasPasskey := flags.Bool("has-passkey", false, "Only match items that carry a passkey (FIDO2 credential)")
+	noPasskey := flags.Bool("no-passkey", false, "Only match items that do not carry a passkey (FIDO2 credential)")
+	onlyIncomplete := flags.Bool("only-incomplete", false, "Only match logins without a password, username, URI, TOTP, passkey or notes, as left behind by browser-extension misfires")
+	fields := &listFlag{}
+	flags.Var(fields, "field", "Only match items with a custom field of this name and value (name=value, or just name for any value); repeatable")
+	notesContains := flags.String("notes-contains", "", "Only match items whose notes contain this text, ignoring case")
+	usernames := &listFlag{}
+	flags.Var(usernames, "username", "Only match logins with this username, ignoring case, or one matching this glob (e.g. 'svc-*'); repeatable, any may match")
+	emails := &listFlag{}
+	flags.Var(emails, "email", "Only match logins with this email address as username and identities with it as email, ignoring case, or matching this glob (e.g. '*@old-work.example.com'); repeatable, any may match")
+	tagged := flags.String("tagged", "", "Only match items whose name starts with this tag or whose notes have it as a line, as set by 'tag'")
+	hasAppURI := flags.Bool("has-app-uri", false, "Only match items with an Android (androidapp://) or iOS (iosapp://) app URI")
+	appURI := flags.String("app-uri", "", "Only match items with an app URI containing this text (e.g. a package name)")
+	appURIOnly := flags.Bool("app-uri-only", false, "Only match items whose URIs are all app URIs (no web URIs)")
+	itemTypes := &itemTypeList{}
+	flags.Var(itemTypes, "type", "Only match items of these comma-separated types (login, note, card, identity, sshkey); repeatable")
+	regex := flags.String("regex", "", "Only match items whose name, username or a URI matches this regular expression")
+	regexURI := flags.String("regex-uri", "", "Only match items with a URI that matches this regular expression")
+	uris := &listFlag{}
+	flags.Var(uris, "uri", "Only match items with a URI on this host (ignoring scheme, port and www.), below its path if it has one; repeatable")
+	domains := &listFlag{}
+	flags.Var(domains, "domain", "Only match items with a URI on this registrable domain (login.example.co.uk matches example.co.uk); comma-separated, repeatable")
+	domainFile := flags.String("domain-file", "", "Only match items with a URI on one of the domains in this file (one per line, # starts a comment)")
+	createdBefore := flags.String("created-before", "", "Only match items created before this date (2024-01-31 or RFC 3339) or longer ago than this age (e.g. 2y, 90d)")
+	createdAfter := flags.String("created-after", "", "Only match items created after this date or more recently than this age")
+	modifiedBefore := flags.String("modified-before", "", "Only match items last modified before this date or longer ago than this age (e.g. 2y)")
+	modifiedAfter := flags.String("modified-after", "", "Only match items last modified after this date or more recently than this age")
+	folder := flags.String("folder", "", "Only match items in the folder with this name (\"No Folder\" for items without a folder)")
+	folderID := flags.String("folder-id", "", "Only match items in the folder with this ID")
+	organizationID := flags.String("organization-id", "", "Only match items that belong to the organization with this ID")
+	collection := flags.String("collection", "", "Only match items in the collection with this name or ID")
+	limit := flags.Int("limit", 0, "Only process the first N matched items, to work through a large selection in chunks (0 for all)")
+	skip := flags.Int("skip", 0, "Skip the first N matched items, e.g. to continue a non-destructive run where the last one stopped")
+	sortBy := flags.String("sort", "", "Order the matched items before --skip and --limit are applied (name, created, modified; dates oldest first)")
+	excludes := &excludePatterns{}
+	flags.Var(excludes, "exclude", "Never match items whose name matches this glob (e.g. 'prod-*') or /regular expression/; repeatable")
+	protectFile := flags.String("protect-file", "", "Never match the items named in this file (one item name or ID per line, # starts a comment)")
+	csvFile := flags.String("csv", "", "Only match the items listed in this CSV file (columns: id or name, optional username and uri), validated against the vault")
+	retryFailed := flags.String("retry-failed", "", "Only match the items whose IDs are listed in this file, as written after a run with failures")
+	idsFile := flags.String("ids-file", "", "Select the items whose IDs are listed in this file (one per line, - reads stdin) instead of searching")
+	idsOut := flags.String("ids-out", "", "Write the IDs of the items the command selects to this file, one per line, for a later --ids-file")
+	fromExport := flags.String("from-export", "", "Read the items from this unencrypted 'bw export --format json' file instead of the vault, for dry runs and reports without vault access")
+	failedFile := flags.String("failed-file", "", "Write the IDs of items that failed to this file (default: ~/.config/bitwarden-cleanup/failed-items.txt)")
+	reveal := flags.Bool("reveal", false, "Show full passwords, card numbers, private keys and notes in previews, reports and hook input instead of masking them")
+	flags.String("profile", "", "Use the settings of this profile from ~/.config/bitwarden-cleanup/config.toml on top of its defaults")
+	server := flags.String("server", "", "URL of the self-hosted Bitwarden or Vaultwarden server; bw is configured for it before logging in")
+	session := flags.String("session", "", "Bitwarden session key from 'bw unlock --raw' (default: BW_SESSION from the environment, which keeps the key out of the process list)")
+	commandTimeout := flags.Duration("bw-timeout", defaultBWTimeout, "Kill a bw command that runs longer than this and count it as failed (0 disables)")
+	ignoreBWVersion := flags.Bool("ignore-bw-version", false, "Run even when the installed bw is older than the release that added a command or flag the run needs")
+	consoleValues := defineConsoleFlags(flags)
+	output := flags.String("output", outputText, "Output format: text, or json for NDJSON events on stdout with the console output on stderr")
+	assumeYes := flags.Bool("yes", false, "Answer confirmations with yes, for cron jobs and CI (items with passkeys or SSH private keys are only included when selected explicitly)")
+	assumeYesShort := flags.Bool("y", false, "Answer confirmations with yes (shorthand)")
+	applyLimits := defineLimitFlags(flags)
+	typeToConfirm := flags.Int("type-to-confirm", defaultTypeToConfirm, "Confirm permanent deletions of more than this many items by typing their number or DELETE instead of y (0 disables)")
+	cooldown := flags.Duration("cooldown", 0, "Count down this long after a deletion is confirmed, so that Ctrl-C can still abort it (e.g. 10s)")
+	statsFile := flags.String("stats-file", "", "Append a JSON line with the counts and duration of each run to this file")
+	metricsFile := flags.String("metrics-file", "", "When the process ends, write its timings and counts to this file in the Prometheus textfile collector format")
+	logFile := flags.String("log-file", "", "Append a JSON line with the time, item, mode, result and error of every processed item to this file")
+	failOnEmpty := flags.Bool("fail-on-empty", false, "Exit with status 5 when the selection matches no items")
+	notifyURL := flags.String("notify-url", "", "POST a Slack-compatible JSON message with the counts of the run to this webhook when it ends")
+	notifyDesktop := flags.Bool("notify-desktop", false, "Show a desktop notification with the counts of the run when it ends")
+	notifyFailures := flags.Int("notify-failures", 0, "With --notify-url or --notify-desktop, only notify when this many items failed, as soon as they have (0 notifies at the end of every run)")
+	preHook := flags.String("pre-hook", "", "Run this command with the matched items as JSON on stdin before any is processed; the run stops if it fails")
+	itemHook := flags.String("on-delete-hook", "", "Run this command with an item and its result as JSON on stdin after each item is processed")
+	postHook := flags.String("post-hook", "", "Run this command with the counts and the result of every item as JSON on stdin when the run ends")
+	redact := &redactor{}
+	flags.Var(redact, "redact", "Hash or truncate these comma-separated fields in output and reports (names, usernames, uris; append :truncate to truncate instead of hash)")
+	presentIn := flags.String("present-in", "", "Only match the vault items that correspond to the entries of this Bitwarden JSON or CSV export, newest copy first (undoes a double import)")
+	matchWords := flags.Bool("match-words", false, "Only match items whose name contains the search term as whole words (\"test\" matches \"test account\" but not \"contest\")")
+	exactName := flags.Bool("exact", false, "Only match items whose name is the search term")
+	startsWith := flags.Bool("starts-with", false, "Only match items whose name starts with the search term")
+	caseSensitive := flags.Bool("case-sensitive", false, "Only match items whose name contains the search term in the same case")
+	glob := flags.String("glob", "", "Only match items whose whole name matches this glob, ignoring case (* matches any characters, ? a single one)")
+	fuzzy := flags.Bool("fuzzy", false, "Match the search term against item names by similarity, so that lightly misspelled names match too")
+	fuzzyThreshold := flags.Float64("fuzzy-threshold", defaultFuzzyThreshold, "With --fuzzy, how similar a name has to be to the search term, from 0 to 1 (1 only matches the term itself)")
+
+	return func() CommandOptions {
+		options := CommandOptions{}
+
+		for _, term := range append(slices.Clone(*searchTerms), *searchShort...) {
+			if term != "" {
+				options.searchTerms = append(options.searchTerms, term)
+			}
+		}
+		// A single term is searched by bw; several, or fuzzy ones, are
+		// matched locally against the full list.
+		options.fuzzy = *fuzzy
+		options.fuzzyThreshold = *fuzzyThreshold
+		if len(options.searchTerms) == 1 && !options.fuzzy {
+			options.searchTerm = options.searchTerms[0]
+		}
+		options.searchMatch = strings.ToLower(*searchMatch)
+
+		batch := *batchSize
+		if !batch.auto && batch.size == 1 {
+			batch = *batchShort
+		}
+		options.batchSize, options.autoBatch = batch.size, batch.auto
+		if batch.auto {
+			options.batchSize = max(*maxBatch, 1)
+		}
+		options.retries = max(*retries, 0)
+		options.retryBackoff = *retryBackoff
+		options.rate = *rate
+		options.delay = *delay
+		options.syncEvery = *syncEvery
+
+		options.groupBy = strings.ToLower(*groupBy)
+		options.stopFile = *stopFile
+		options.hasPasskey = *hasPasskey
+		options.noPasskey = *noPasskey
+		options.onlyIncomplete = *onlyIncomplete
+		options.fields = *fields
+		options.notesContains = *notesContains
+		options.usernames = *usernames
+		options.emails = *emails
+		options.tagged = strings.TrimSpace(*tagged)
+		options.hasAppURI = *hasAppURI
+		options.appURI = *appURI
+		options.appURIOnly = *appURIOnly
+		options.matchWords = *matchWords
+		options.exactName = *exactName
+		options.startsWith = *startsWith
+		options.caseSensitive = *caseSensitive
+		options.glob = *glob
+		options.reveal = *reveal
+		options.session = *session
+		options.bwTimeout = *commandTimeout
+		options.ignoreBWVersion = *ignoreBWVersion
+		options.server = strings.TrimRight(*server, "/")
+		options.output = strings.ToLower(*output)
+		options.console = *consoleValues
+		options.assumeYes = *assumeYes || *assumeYesShort
+		applyLimits(&options)
+		options.typeToConfirm = *typeToConfirm
+		options.cooldown = *cooldown
+		options.redact = *redact
+		options.statsFile = *statsFile
+		options.metricsFile = *metricsFile
+		options.logFile = *logFile
+		options.failOnEmpty = *failOnEmpty
+		options.notifyURL = strings.TrimSpace(*notifyURL)
+		options.notifyDesktop = *notifyDesktop
+		options.notifyFailures = *notifyFailures
+		options.preHook = strings.TrimSpace(*preHook)
+		options.itemHook = strings.TrimSpace(*itemHook)
+		options.postHook = strings.TrimSpace(*postHook)
+		options.csvFile = *csvFile
+		options.retryFailed = *retryFailed
+		options.idsFile = *idsFile
+		options.idsOut = *idsOut
+		options.fromExport = *fromExport
+		options.failedFile = *failedFile
+		options.presentIn = *presentIn
+		options.itemTypes = itemTypes.String()
+		options.regex = *regex
+		options.regexURI = *regexURI
+		options.uris = *uris
+		options.domains = *domains
+		options.domainFile = *domainFile
+		options.createdBefore = *createdBefore
+		options.createdAfter = *createdAfter
+		options.modifiedBefore = *modifiedBefore
+		options.modifiedAfter = *modifiedAfter
+		options.folder = *folder
+		options.folderID = *folderID
+		options.organizationID = *organizationID
+		options.collection = *collection
+		options.limit = *limit
+		options.skip = *skip
+		options.sortBy = strings.ToLower(*sortBy)
+		options.excludes = *excludes
+		options.protectFile = *protectFile
+
+		return options
+	}
 }
 
 func runBulkDelete(options CommandOptions) error {
+	if options.profiles != "" {
+		if options.session != "" {
+			return fmt.Errorf("--session belongs to one account; set session-env in each profile for --profiles")
+		}
+		profiles, err := parseProfiles(options.profiles)
+		if err != nil {
+			return err
+		}
+		return runProfiles(profiles)
+	}
+	if usingExport() && !options.dryRun {
+		return fmt.Errorf("--from-export only reads %s; add --dry-run, and --ids-out to save the IDs for a run against the vault with --ids-file", options.fromExport)
+	}
+	if usingExport() && options.backend == backendServe {
+		return fmt.Errorf("--backend serve cannot be used with --from-export")
+	}
 	if err := checkBitwardenCLI(); err != nil {
 		return err
 	}
-	
-	displayDeletionMode(options)
+
+	if options.resume != "" {
+		return resumeCheckpoint(options.resume)
+	}
+
+	if options.commit != "" {
+		if options.twoPhase != "" {
+			return fmt.Errorf("--commit and --two-phase cannot be used together")
+		}
+		return commitTwoPhaseManifest(options.commit, options)
+	}
+
+	op, err := selectOperation(options)
+	if err != nil {
+		return err
+	}
+
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+
+	if err := validateGroupBy(options.groupBy); err != nil {
+		return err
+	}
+
+	executeAt, err := parseSchedule(options.scheduleAt, options.scheduleAfter)
+	if err != nil {
+		return err
+	}
+
+	if err := validateBackend(options.backend); err != nil {
+		return err
+	}
+
+	if options.encryptBackup && options.backupPath == "" {
+		return fmt.Errorf("--encrypt-backup requires --backup")
+	}
+
+	if options.interactive && options.assumeYes {
+		return fmt.Errorf("--interactive and --yes cannot be used together")
+	}
+
+	if options.confirmEach && (options.interactive || options.assumeYes) {
+		return fmt.Errorf("--confirm-each cannot be used with --interactive or --yes")
+	}
+
+	if err := validateWatch(options); err != nil {
+		return err
+	}
+
+	displayOperationMode(op)
 
 	if err := syncBitwarden("before starting"); err != nil {
-		fmt.Printf("%s Warning: Initial sync failed but continuing\n", emojiWarning)
+		console.warnf("Warning: Initial sync failed but continuing")
 	}
 
-	items, err := fetchBitwardenItems(options.searchTerm)
+	if options.backend == backendServe {
+		api, err := startServeBackend()
+		if err != nil {
+			return err
+		}
+		defer api.close()
+		vault = api
+		defer func() { vault = bwCLI{} }()
+	}
+
+	if options.watch {
+		return watchRuns(options, func() error { return runSelection(filters, executeAt, op, options) })
+	}
+	return runSelection(filters, executeAt, op, options)
+}
+
+// runSelection retries the queued items of earlier runs, then fetches the
+// items matching filters and processes them once they are confirmed.
+func runSelection(filters []itemFilter, executeAt time.Time, op itemOperation, options CommandOptions) error {
+	if !options.dryRun && !options.noAutoRetry {
+		if err := processRetryQueue(options); errors.Is(err, errInterrupted) {
+			return err
+		} else if err != nil {
+			console.warnf("Warning: retrying queued items failed: %v", err)
+		}
+	}
+
+	items, err := fetchMatchingItems(options, filters)
 	if err != nil {
 		return err
 	}
 
-	stats := &DeleteStats{total: len(items)}
-	displayItemCount(stats)
+	if err := writeIDList(options.idsOut, items); err != nil {
+		return err
+	}
+
+	stats := &DeleteStats{total: len(items), protected: options.protection.count()}
+	displayItemCount(stats, op)
+
+	// The selection becomes --last once it is checked with --dry-run or
+	// confirmed, not when the run is declined.
+	matchedItems := items
+	if options.dryRun {
+		recordFilterHistory(options, matchedItems)
+		showImpactEstimate(items, op, options)
+		return nil
+	}
+
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+
+	if stats.total > 0 && op.deletesItems {
+		items = protectSensitiveItems(items, options)
+		stats.total = len(items)
+	}
+
+	offered := stats.total
+	// An explicit --preview is shown before the picker or --confirm-each;
+	// the paged preview of a large selection only replaces them.
+	largeSelection := options.previewThreshold > 0 && stats.total > options.previewThreshold && !options.interactive && !options.confirmEach
+	if stats.total > 0 && !options.assumeYes && (options.preview > 0 || largeSelection) {
+		var ok bool
+		if items, ok = previewItems(items, options); !ok {
+			cancelOperation()
+			return nil
+		}
+		stats.total = len(items)
+	}
+	if stats.total > 0 && options.interactive {
+		matched := len(items)
+		if items, err = pickItems(items, options); err != nil {
+			return err
+		}
+		stats.total = len(items)
+		console.infof(emojiInfo, "Selected %d of %d matched items", stats.total, matched)
+	} else if stats.total > 0 && options.confirmEach {
+		items = confirmEachItem(items, op, options)
+		stats.total = len(items)
+	}
+	// Nothing left after the picker, the excluded preview pages or
+	// --confirm-each means the user declined every item.
+	if offered > 0 && stats.total == 0 {
+		cancelOperation()
+	}
 
 	if stats.total > 0 {
-		if confirmed := confirmDeletion(stats, options); !confirmed {
-			fmt.Printf("%s Operation cancelled\n", emojiError)
+		confirm := confirmOperation
+		if options.confirmEach {
+			confirm = confirmEachOperation
+		}
+		if !confirm(stats, op) {
+			cancelOperation()
 			return nil
 		}
+		recordFilterHistory(options, matchedItems)
 
-		if err := processItems(items, stats, options); err != nil {
+		if err := exportMatchedItems(items, options); err != nil {
 			return err
 		}
 
-		if err := syncBitwarden(""); err != nil {
-			fmt.Printf("%s Warning: Final sync failed\n", emojiWarning)
+		if err := prepareUndoBackup(op, options); err != nil {
+			return err
+		}
+		// A staged run with a window purges the items itself once it passed.
+		if options.staged && options.stagedWindow > 0 {
+			if err := prepareUndoBackup(deleteOperation(true), options); err != nil {
+				return err
+			}
+		}
+		if !executeAt.IsZero() {
+			return schedulePendingRun(items, executeAt, options)
 		}
+		if stats.checkpoint, err = startCheckpoint(options.checkpointPath, items, op, options, true); err != nil {
+			return err
+		}
+		return executeItems(items, stats, op, options)
 	}
 
 	return nil
 }
 
-func checkBitwardenCLI() error {
-	if _, err := exec.LookPath("bw"); err != nil {
-		return fmt.Errorf("Bitwarden CLI (bw) not found in PATH. Please install it first: %w", err)
+func executeItems(items []BitwardenItem, stats *DeleteStats, op itemOperation, options CommandOptions) error {
+	if err := processItems(items, stats, op, options); err != nil {
+		return err
+	}
+
+	if err := syncBitwarden(""); err != nil {
+		console.warnf("Warning: Final sync failed")
+	}
+
+	if options.staged {
+		return finishStagedRun(items, options)
 	}
+
+	if options.twoPhase != "" {
+		return writeTwoPhaseManifest(stats, options)
+	}
+
 	return nil
 }
 
+func checkBitwardenCLI() error {
+	if usingExport() {
+		return nil
+	}
+	if err := lookupBW(); err != nil {
+		return fmt.Errorf("%w. Please install it first: %w", errBWMissing, err)
+	}
+	if err := requireBWCapabilities(capabilityTrash); err != nil {
+		return err
+	}
+	if err := ensureServer(); err != nil {
+		return err
+	}
+	return ensureUnlocked()
+}
+
 func syncBitwarden(context string) error {
+	if usingExport() {
+		return nil
+	}
 	contextMsg := ""
 	if context != "" {
 		contextMsg = " " + context
 	}
-	fmt.Printf("%s Syncing Bitwarden database%s...\n", emojiSync, contextMsg)
-	
-	syncCmd := exec.Command("bw", "sync")
-	syncOutput, err := syncCmd.CombinedOutput()
-	
+	console.infof(emojiSync, "Syncing Bitwarden database%s...", contextMsg)
+
+	start := time.Now()
+	syncOutput, err := vault.Sync()
+	timeSync(start)
 	if err != nil {
-		fmt.Printf("%s Failed to sync Bitwarden: %v\n", emojiError, err)
-		fmt.Printf("%s Command output: %s\n", emojiError, string(syncOutput))
+		console.errorf("Failed to sync Bitwarden: %v", err)
+		console.errorf("Command output: %s", syncOutput)
 		return err
-	} 
-	
-	fmt.Printf("%s Sync completed successfully\n", emojiSuccess)
-	fmt.Printf("%s Command output: %s\n", emojiSuccess, string(syncOutput))
+	}
+
+	console.infof(emojiSuccess, "Sync completed successfully")
+	console.debugf(emojiSuccess, "Command output: %s", strings.TrimSpace(syncOutput))
 	return nil
 }
 
 func fetchBitwardenItems(searchTerm string) ([]BitwardenItem, error) {
-	fmt.Printf("%s Fetching Bitwarden items...\n", emojiSearch)
-	
-	listCmd := "bw list items"
-	if searchTerm != "" {
-		listCmd += fmt.Sprintf(" --search '%s'", searchTerm)
-	}
-	
-	listCommand := exec.Command("sh", "-c", listCmd)
-	listOutput, err := listCommand.Output()
+	console.infof(emojiSearch, "Fetching Bitwarden items...")
+	defer timeList(time.Now())
+	return vault.ListItems(searchTerm)
+}
+
+func fetchTrashItems() ([]BitwardenItem, error) {
+	console.infof(emojiSearch, "Fetching trashed Bitwarden items...")
+	defer timeList(time.Now())
+	return vault.ListTrash()
+}
+
+// fetchMatchingItems returns the items a run works on: the items matching
+// the search term, narrowed to the validated rows of --csv, the entries of
+// --present-in and the IDs of --ids-file when given, and paged by --sort, --skip and --limit.
+func fetchMatchingItems(options CommandOptions, filters []itemFilter) ([]BitwardenItem, error) {
+	items, err := fetchBitwardenItems(options.searchTerm)
 	if err != nil {
-		return nil, fmt.Errorf("error executing list command: %w", err)
+		return nil, err
 	}
 
-	var items []BitwardenItem
-	if err := json.Unmarshal(listOutput, &items); err != nil {
-		return nil, fmt.Errorf("error parsing list output: %w", err)
+	if options.csvFile != "" {
+		items, err = resolveDeletionList(options.csvFile, items)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.presentIn != "" {
+		items, err = selectPresentIn(options.presentIn, items)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.retryFailed != "" {
+		items, err = selectFailedItems(options.retryFailed, items)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if options.idsFile != "" {
+		items, err = selectListedItems(options.idsFile, items)
+		if err != nil {
+			return nil, err
+		}
+	}
+	items = pageItems(options.protection.apply(filterItems(items, filters)), options)
+	if len(items) == 0 && options.failOnEmpty {
+		return nil, errNoMatches
 	}
-	
 	return items, nil
 }
 
-func displayDeletionMode(options CommandOptions) {
-	if options.isPermanent {
-		fmt.Printf("%s Mode: Permanent deletion (items will bypass trash)\n", emojiWarning)
-	} else {
-		fmt.Printf("%s Mode: Standard deletion (items will go to trash)\n", emojiInfo)
+func displayOperationMode(op itemOperation) {
+	console.infof(op.modeEmoji, "Mode: %s", op.modeText)
+}
+
+func displayItemCount(stats *DeleteStats, op itemOperation) {
+	console.infof(emojiSearch, "Found %d items to %s", stats.total, op.verb)
+}
+
+func confirmOperation(stats *DeleteStats, op itemOperation) bool {
+	if !operationAllowed(stats) {
+		return false
+	}
+	if typedConfirmationRequired(stats, op) {
+		if !confirmTyped(stats, op) {
+			return false
+		}
+	} else if !promptYesNo(fmt.Sprintf("Are you sure you want to %s all %d items?", op.confirmText, stats.total)) {
+		return false
 	}
+	return !op.deletesItems || waitCooldown(unattended.cooldown)
 }
 
-func displayItemCount(stats *DeleteStats) {
-	fmt.Printf("%s Found %d items to delete\n", emojiSearch, stats.total)
+// confirmEachOperation stands in for confirmOperation once --confirm-each
+// has asked about every item: it skips the question for the whole run but
+// keeps the typed confirmation and the cooldown.
+func confirmEachOperation(stats *DeleteStats, op itemOperation) bool {
+	if !operationAllowed(stats) {
+		return false
+	}
+	if typedConfirmationRequired(stats, op) && !confirmTyped(stats, op) {
+		return false
+	}
+	return !op.deletesItems || waitCooldown(unattended.cooldown)
 }
 
-func confirmDeletion(stats *DeleteStats, options CommandOptions) bool {
-	confirmMsg := "Are you sure you want to delete all"
-	if options.isPermanent {
-		confirmMsg = "Are you sure you want to PERMANENTLY delete all"
+// operationAllowed runs the checks that come before any confirmation.
+func operationAllowed(stats *DeleteStats) bool {
+	if usingExport() {
+		console.errorf("%v", errReadOnlyExport)
+		unattended.refused = true
+		return false
 	}
-	
-	fmt.Printf("%s %s %d items? (y/N) ", emojiWarning, confirmMsg, stats.total)
-	var confirm string
-	if _, err := fmt.Scanln(&confirm); err != nil {
-		fmt.Printf("%s Error reading confirmation: %v\n", emojiError, err)
+	if stats.protected > 0 {
+		console.infof(emojiInfo, "%d matched items were skipped due to protection rules (--exclude, --protect-file)", stats.protected)
+	}
+	return true
+}
+
+func promptYesNo(question string) bool {
+	if unattended.yes {
+		console.infof(emojiWarning, "%s (y/N) yes (--yes)", question)
+		return true
+	}
+	console.promptf(emojiWarning, "%s (y/N) ", question)
+	confirm, err := readLine()
+	if err != nil {
+		console.errorf("Error reading confirmation: %v", err)
 		return false
 	}
 
@@ -191,64 +1044,144 @@ func confirmDeletion(stats *DeleteStats, options CommandOptions) bool {
 	return confirm == "y" || confirm == "yes"
 }
 
-func processItems(items []BitwardenItem, stats *DeleteStats, options CommandOptions) error {
-	fmt.Printf("%s Starting deletion process...\n", emojiStart)
-
-	jobs := make(chan string, stats.total)
-	results := make(chan string, stats.total)
-	var wg sync.WaitGroup
+func processItems(items []BitwardenItem, stats *DeleteStats, op itemOperation, options CommandOptions) error {
+	if usingExport() {
+		return errReadOnlyExport
+	}
+	stop, err := newStopSignal(options.stopFile)
+	if err != nil {
+		return err
+	}
+	stats.stop = stop
+	ctx, stopInterrupt := interruptContext()
+	defer stopInterrupt()
+	interval, err := rateInterval(options.rate, options.delay)
+	if err != nil {
+		return err
+	}
+	options.limiter = newRateLimiter(interval)
+	if options.autoBatch {
+		options.batcher = newAutoBatch(options.batchSize)
+	}
+	options.notifier = newNotifier(len(items), op, options)
+	options.hooks = newHooks(op, options, len(items))
+	options.syncGate = newSyncGate(options.syncEvery, len(items))
+	if op.deletesItems {
+		if err := recoverCrashedRuns(); err != nil {
+			return err
+		}
+		if err := prepareUndoBackup(op, options); err != nil {
+			return err
+		}
+		// Runs that delete items are journaled in a checkpoint even when
+		// they cannot be resumed.
+		if stats.checkpoint == nil {
+			if stats.checkpoint, err = startCheckpoint("", items, op, options, false); err != nil {
+				return err
+			}
+		}
+	}
+	// The checkpoint is closed once the run has been recorded for undo.
+	defer stats.checkpoint.finish(stats)
+	if err := options.hooks.before(items); err != nil {
+		return err
+	}
 
-	for w := 1; w <= options.batchSize; w++ {
-		wg.Add(1)
-		go deleteWorker(w, jobs, results, &wg, options.isPermanent)
+	console.infof(emojiStart, "Starting %s process...", op.processName)
+	if interval > 0 {
+		console.infof(emojiInfo, "Throttled to one request every %s across all workers", interval.Round(time.Millisecond))
 	}
+	emitMatched(items, op.verb)
 
-	for _, item := range items {
-		jobs <- item.ID
+	started := time.Now()
+	if options.groupBy != "" {
+		if err := processItemGroups(ctx, items, stats, op, options); err != nil {
+			return err
+		}
+	} else {
+		runWorkerPool(ctx, items, stats, op, options)
+	}
+	recordRunMetrics(stats, options.batchSize, time.Since(started))
+	stats.interrupted = ctx.Err() != nil
+	if stats.failed > 0 {
+		outcome.failed = true
 	}
-	close(jobs)
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	showCompletionMessage(stats, op)
+	showThroughputSummary(stats)
+	options.batcher.report()
+	showFailureSummary(stats, options)
+	writeFailedItems(stats, op, options)
+	writeRunReport(items, stats, op, options)
+	appendStatsLine(stats, op, options)
+	emitEvent(summaryEvent{Event: "summary", statsLine: summaryLine(stats, op, options)})
+	options.notifier.finish(stats)
+	options.hooks.finish(stats)
+	updateRetryQueue(stats, op, options)
+
+	if op.deletesItems {
+		recordDeleteRun(items, stats, op, options)
+	}
 
-	processResults(results, stats)
-	showCompletionMessage(stats, options)
-	
+	if stats.interrupted {
+		return errInterrupted
+	}
 	return nil
 }
 
-func deleteWorker(id int, jobs <-chan string, results chan<- string, wg *sync.WaitGroup, isPermanent bool) {
-	defer wg.Done()
-	for itemID := range jobs {
-		var deleteCmd *exec.Cmd
-		
-		if isPermanent {
-			deleteCmd = exec.Command("bw", "delete", "item", itemID, "--permanent")
-		} else {
-			deleteCmd = exec.Command("bw", "delete", "item", itemID)
+// runWorkerPool hands the items to --batch workers of the cleanup engine
+// until all are done or ctx is cancelled; items already handed out are
+// always finished.
+func runWorkerPool(ctx context.Context, items []BitwardenItem, stats *DeleteStats, op itemOperation, options CommandOptions) {
+	if stats.started.IsZero() {
+		stats.started = time.Now()
+	}
+	progress := newProgressReporter(stats)
+	engine := engineOptions(op, options)
+	engine.Start = func(ctx context.Context, item BitwardenItem) bool {
+		options.batcher.acquire()
+		if ctx.Err() != nil || stats.stop.requested() || options.limiter.wait(ctx) != nil {
+			options.batcher.release(nil)
+			return false
 		}
-		
-		if err := deleteCmd.Run(); err != nil {
-			fmt.Printf("%s Error deleting item %s: %v\n", emojiError, itemID, err)
+		options.syncGate.enter()
+		stats.checkpoint.begin(item)
+		return true
+	}
+	engine.OnResult = func(outcome cleanup.ItemResult[BitwardenItem]) {
+		item := outcome.Item
+		result := itemResult{ItemID: item.ID, Name: item.Name, Operation: op.verb, Duration: outcome.Duration, Err: outcome.Err, Retries: outcome.Retries}
+		options.syncGate.leave(result)
+		options.batcher.release(&result)
+		stats.recordResult(result)
+		appendAuditLog(result, op, options)
+		options.notifier.observe(result)
+		options.hooks.observe(item, result)
+		if result.status() == resultFailed {
+			console.errorf("Error %s item %s: %v", op.progressVerb, item.ID, result.Err)
+		} else {
+			console.debugf("", "   %s %s (%s): %s in %s", op.progressVerb, options.redact.name(item.Name), item.ID, result.status(), result.Duration.Round(time.Millisecond))
 		}
-		results <- itemID
 	}
-}
-
-func processResults(results <-chan string, stats *DeleteStats) {
-	for range results {
+	engine.OnProgress = func(cleanup.Progress[BitwardenItem]) {
+		stats.mu.Lock()
 		stats.completed++
-		fmt.Printf("%s Progress: [%d/%d]\r", emojiProgress, stats.completed, stats.total)
+		stats.mu.Unlock()
+		progress.update()
 	}
-	fmt.Println()
+
+	cleanup.Run(ctx, nil, items, engine)
+	console.endProgress()
 }
 
-func showCompletionMessage(stats *DeleteStats, options CommandOptions) {
-	if options.isPermanent {
-		fmt.Printf("%s All %d items have been permanently deleted!\n", emojiComplete, stats.total)
-	} else {
-		fmt.Printf("%s All %d items have been moved to trash!\n", emojiComplete, stats.total)
+func showCompletionMessage(stats *DeleteStats, op itemOperation) {
+	if stats.interrupted {
+		console.warnf("Interrupted after %d of %d items; %d items were not processed", stats.completed, stats.total, stats.total-stats.completed)
+		return
+	}
+	if stats.stop.requested() {
+		console.warnf("Stopped by %s after %d of %d items; %d items were not processed", stats.stop.path, stats.completed, stats.total, stats.total-stats.completed)
+		return
 	}
-}
\ No newline at end of file
+	console.summaryf(emojiComplete, "All %d items %s!", stats.total, op.doneText)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessItems(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(backupPassphraseEnv, "correct horse")
+	t.Cleanup(func() { undoBackupPassphrase = "" })
+	items := []BitwardenItem{{ID: "id1", Name: "test item 1"}, {ID: "id2", Name: "test item 2"}, {ID: "id3", Name: "test item 3"}, {ID: "id4", Name: "test item 4"}}
+	fake := useFakeVault(t, items[:3]...)
+	fake.fail("id1", errRateLimited)
+	fake.fail("id2", errPermissionDenied)
+
+	stats := &DeleteStats{total: len(items)}
+	options := CommandOptions{batchSize: 2, retries: 1, retryBackoff: time.Millisecond}
+	if err := processItems(items, stats, deleteOperation(true), options); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.completed != len(items) || stats.failed != 1 || stats.alreadyGone != 1 {
+		t.Errorf("completed %d, failed %d, already gone %d; want %d, 1, 1", stats.completed, stats.failed, stats.alreadyGone, len(items))
+	}
+	statuses := make(map[string]string)
+	for _, result := range stats.results {
+		statuses[result.ItemID] = result.status()
+	}
+	want := map[string]string{"id1": resultDone, "id2": resultFailed, "id3": resultDone, "id4": resultGone}
+	for id, status := range want {
+		if statuses[id] != status {
+			t.Errorf("%s: status %q, want %q", id, statuses[id], status)
+		}
+	}
+	if _, left := fake.items["id1"]; left {
+		t.Error("id1 was not deleted after its retry")
+	}
+	if _, left := fake.items["id2"]; !left {
+		t.Error("id2 was deleted despite failing")
+	}
+}
+
+func TestUndoBackupIsEncrypted(t *testing.T) {
+	t.Setenv(backupPassphraseEnv, "correct horse")
+	t.Cleanup(func() { undoBackupPassphrase = "" })
+	items := []json.RawMessage{json.RawMessage(`{"id":"id1","login":{"password":"hunter2"}}`)}
+
+	sealed, err := sealUndoBackup(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := &runRecord{EncryptedBackup: sealed}
+	stored, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(stored), "hunter2") {
+		t.Fatalf("run record holds the password in plain text: %s", stored)
+	}
+
+	var loaded runRecord
+	if err := json.Unmarshal(stored, &loaded); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := runBackup(&loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backup) != 1 || !strings.Contains(string(backup[0]), "hunter2") {
+		t.Errorf("decrypted backup = %s, want the item", backup)
+	}
+	if loaded.EncryptedBackup.Items != nil {
+		t.Error("runBackup left the decrypted items in the record")
+	}
+
+	t.Setenv(backupPassphraseEnv, "wrong")
+	if _, err := runBackup(&loaded); err == nil {
+		t.Error("runBackup decrypted the backup with a wrong passphrase")
+	}
+}
+
+func TestScheduledPermanentRunAsksForPassphraseFirst(t *testing.T) {
+	if stdinIsTerminal() {
+		t.Skip("stdin is a terminal")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(backupPassphraseEnv, "")
+	t.Cleanup(func() { undoBackupPassphrase = "" })
+	fake := useFakeVault(t, BitwardenItem{ID: "id1", Name: "test item 1", raw: json.RawMessage(`{"id":"id1"}`)})
+
+	due := time.Now().Add(time.Hour)
+	record := &runRecord{ID: newRunID(), Kind: pendingRunKind, Status: pendingStatusWaiting, Args: []string{"--permanent"}, ItemIDs: []string{"id1"}, ExecuteAt: &due}
+	if err := saveRunRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	// Without a terminal or the passphrase the run fails before waiting an hour.
+	if err := executePendingRun(record); err == nil || !strings.Contains(err.Error(), "--no-backup") {
+		t.Fatalf("executePendingRun = %v, want the missing passphrase error", err)
+	}
+	if _, left := fake.items["id1"]; !left {
+		t.Fatal("id1 was deleted without a backup")
+	}
+
+	t.Setenv(backupPassphraseEnv, "correct horse")
+	past := time.Now().Add(-time.Minute)
+	record.ExecuteAt = &past
+	if err := saveRunRecord(record); err != nil {
+		t.Fatal(err)
+	}
+	if err := executePendingRun(record); err != nil {
+		t.Fatal(err)
+	}
+	if _, left := fake.items["id1"]; left {
+		t.Error("id1 was not deleted once the run was due")
+	}
+	runs, err := listRunRecords(deleteRunKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].EncryptedBackup == nil {
+		t.Errorf("got %d deletion runs, want one with an encrypted backup", len(runs))
+	}
+}
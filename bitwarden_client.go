@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// BitwardenClient is everything the tool asks of Bitwarden. Item, folder and
+// collection payloads for create and edit are plain JSON; encoding them for
+// the transport is up to the client.
+type BitwardenClient interface {
+	Status() (VaultStatus, error)
+	LoginAPIKey() error
+	Unlock(password string) (string, error)
+	ServerURL() (string, error)
+	ConfigureServer(url string) error
+	Sync() (string, error)
+
+	ListItems(searchTerm string) ([]BitwardenItem, error)
+	ListTrash() ([]BitwardenItem, error)
+	GetItem(id string) ([]byte, error)
+	CreateItem(data []byte) ([]byte, error)
+	EditItem(id string, data []byte) error
+	EditItemCollections(id string, collectionIDs []string) error
+	DeleteItem(id string, permanent bool) error
+	RestoreItem(id string) error
+
+	ListFolders() ([]BitwardenFolder, error)
+	CreateFolder(name string) (*BitwardenFolder, error)
+	DeleteFolder(id string) error
+	ListCollections() ([]BitwardenCollection, error)
+	GetOrgCollection(organizationID, id string) (*organizationCollection, error)
+	DeleteOrgCollection(organizationID, id string) error
+	ListOrgMembers(organizationID string) ([]organizationMember, error)
+
+	DownloadAttachment(itemID, attachmentID, path string) error
+	UploadAttachment(itemID, path string) error
+	DeleteAttachment(itemID, attachmentID string) error
+
+	ListSends() ([]BitwardenSend, error)
+	DeleteSend(id string) error
+}
+
+// VaultStatus is the part of 'bw status' the tool looks at.
+type VaultStatus struct {
+	Status    string `json:"status"`
+	UserEmail string `json:"userEmail"`
+}
+
+// vault is the client of the current run: the bw CLI, unless --backend
+// serve swaps in the bw serve API for the duration of a run.
+var vault BitwardenClient = bwCLI{}
+
+// bwCLI runs one bw command per call.
+type bwCLI struct{}
+
+func (bwCLI) Status() (VaultStatus, error) {
+	output, err := bwCommand("status").Output()
+	if err != nil {
+		return VaultStatus{}, fmt.Errorf("error checking vault status: %w", err)
+	}
+	var status VaultStatus
+	if err := json.Unmarshal(output, &status); err != nil {
+		return VaultStatus{}, fmt.Errorf("error parsing vault status: %w", err)
+	}
+	return status, nil
+}
+
+// LoginAPIKey logs in with the personal API key, which bw reads from
+// BW_CLIENTID and BW_CLIENTSECRET.
+func (bwCLI) LoginAPIKey() error {
+	output, err := bwCommand("login", "--apikey", "--nointeraction").CombinedOutput()
+	if err != nil {
+		return commandError("error logging in with the API key", err, output)
+	}
+	return nil
+}
+
+func (bwCLI) Unlock(password string) (string, error) {
+	unlockCmd := bwCommand("unlock", "--raw", "--passwordenv", "BW_PASSWORD")
+	unlockCmd.Env = append(unlockCmd.Env, "BW_PASSWORD="+password)
+	output, err := unlockCmd.Output()
+	session := strings.TrimSpace(string(output))
+	if err != nil || session == "" {
+		return "", fmt.Errorf("unlock failed; check the master password")
+	}
+	return session, nil
+}
+
+func (bwCLI) ServerURL() (string, error) {
+	output, err := bwCommand("config", "server").Output()
+	if err != nil {
+		return "", fmt.Errorf("error checking Bitwarden server: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (bwCLI) ConfigureServer(url string) error {
+	output, err := bwCommand("config", "server", url).CombinedOutput()
+	if err != nil {
+		return commandError("error configuring Bitwarden server", err, output)
+	}
+	return nil
+}
+
+func (bwCLI) Sync() (string, error) {
+	output, err := bwCommand("sync").CombinedOutput()
+	return string(output), err
+}
+
+func (bwCLI) ListItems(searchTerm string) ([]BitwardenItem, error) {
+	args := []string{"list", "items"}
+	if searchTerm != "" {
+		args = append(args, "--search", searchTerm)
+	}
+	return listItemsCommand(args...)
+}
+
+func (bwCLI) ListTrash() ([]BitwardenItem, error) {
+	return listItemsCommand("list", "items", "--trash")
+}
+
+func listItemsCommand(args ...string) ([]BitwardenItem, error) {
+	listOutput, err := bwCommand(args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error executing list command: %w", err)
+	}
+
+	var items []BitwardenItem
+	if err := json.Unmarshal(listOutput, &items); err != nil {
+		return nil, fmt.Errorf("error parsing list output: %w", err)
+	}
+	return items, nil
+}
+
+func (bwCLI) GetItem(id string) ([]byte, error) {
+	var stderr bytes.Buffer
+	getCmd := bwCommand("get", "item", id)
+	getCmd.Stderr = &stderr
+	output, err := getCmd.Output()
+	if err != nil {
+		return nil, commandError("error fetching item", err, stderr.Bytes())
+	}
+	return output, nil
+}
+
+func (bwCLI) CreateItem(data []byte) ([]byte, error) {
+	var stderr bytes.Buffer
+	createCmd := bwCommand("create", "item")
+	createCmd.Stdin = strings.NewReader(base64.StdEncoding.EncodeToString(data))
+	createCmd.Stderr = &stderr
+	output, err := createCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error creating item: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+func (bwCLI) EditItem(id string, data []byte) error {
+	editCmd := bwCommand("edit", "item", id)
+	editCmd.Stdin = strings.NewReader(base64.StdEncoding.EncodeToString(data))
+	if output, err := editCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error editing item: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (bwCLI) EditItemCollections(id string, collectionIDs []string) error {
+	data, err := json.Marshal(collectionIDs)
+	if err != nil {
+		return fmt.Errorf("error encoding collections: %w", err)
+	}
+
+	editCmd := bwCommand("edit", "item-collections", id)
+	editCmd.Stdin = strings.NewReader(base64.StdEncoding.EncodeToString(data))
+	if output, err := editCmd.CombinedOutput(); err != nil {
+		return commandError("error editing item collections", err, output)
+	}
+	return nil
+}
+
+func (bwCLI) DeleteItem(id string, permanent bool) error {
+	args := []string{"delete", "item", id}
+	if permanent {
+		args = append(args, "--permanent")
+	}
+	output, err := bwCommand(args...).CombinedOutput()
+	if err != nil {
+		return commandError("error deleting item", err, output)
+	}
+	return nil
+}
+
+func (bwCLI) RestoreItem(id string) error {
+	if output, err := bwCommand("restore", "item", id).CombinedOutput(); err != nil {
+		return fmt.Errorf("error restoring item: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (bwCLI) ListFolders() ([]BitwardenFolder, error) {
+	output, err := bwCommand("list", "folders").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error listing folders: %w", err)
+	}
+
+	var folders []BitwardenFolder
+	if err := json.Unmarshal(output, &folders); err != nil {
+		return nil, fmt.Errorf("error parsing folder list: %w", err)
+	}
+	return folders, nil
+}
+
+func (bwCLI) CreateFolder(name string) (*BitwardenFolder, error) {
+	data, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding folder: %w", err)
+	}
+
+	createCmd := bwCommand("create", "folder")
+	createCmd.Stdin = strings.NewReader(base64.StdEncoding.EncodeToString(data))
+	output, err := createCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error creating folder %q: %w", name, err)
+	}
+
+	var folder BitwardenFolder
+	if err := json.Unmarshal(output, &folder); err != nil {
+		return nil, fmt.Errorf("error parsing created folder: %w", err)
+	}
+	return &folder, nil
+}
+
+func (bwCLI) DeleteFolder(id string) error {
+	output, err := bwCommand("delete", "folder", id).CombinedOutput()
+	if err != nil {
+		return commandError("error deleting folder", err, output)
+	}
+	return nil
+}
+
+func (bwCLI) ListCollections() ([]BitwardenCollection, error) {
+	output, err := bwCommand("list", "collections").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error listing collections: %w", err)
+	}
+
+	var collections []BitwardenCollection
+	if err := json.Unmarshal(output, &collections); err != nil {
+		return nil, fmt.Errorf("error parsing collection list: %w", err)
+	}
+	return collections, nil
+}
+
+func (bwCLI) GetOrgCollection(organizationID, id string) (*organizationCollection, error) {
+	output, err := bwCommand("get", "org-collection", id, "--organizationid", organizationID).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching collection %s: %w", id, err)
+	}
+
+	var collection organizationCollection
+	if err := json.Unmarshal(output, &collection); err != nil {
+		return nil, fmt.Errorf("error parsing collection %s: %w", id, err)
+	}
+	return &collection, nil
+}
+
+func (bwCLI) DeleteOrgCollection(organizationID, id string) error {
+	output, err := bwCommand("delete", "org-collection", id, "--organizationid", organizationID).CombinedOutput()
+	if err != nil {
+		return commandError("error deleting collection", err, output)
+	}
+	return nil
+}
+
+func (bwCLI) ListOrgMembers(organizationID string) ([]organizationMember, error) {
+	output, err := bwCommand("list", "org-members", "--organizationid", organizationID).Output()
+	if err != nil {
+		return nil, fmt.Errorf("error listing organization members: %w", err)
+	}
+
+	var members []organizationMember
+	if err := json.Unmarshal(output, &members); err != nil {
+		return nil, fmt.Errorf("error parsing organization members: %w", err)
+	}
+	return members, nil
+}
+
+func (bwCLI) DownloadAttachment(itemID, attachmentID, path string) error {
+	getCmd := bwCommand("get", "attachment", attachmentID, "--itemid", itemID, "--output", path)
+	if output, err := getCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error downloading attachment %s: %w: %s", attachmentID, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (bwCLI) UploadAttachment(itemID, path string) error {
+	uploadCmd := bwCommand("create", "attachment", "--file", path, "--itemid", itemID)
+	if output, err := uploadCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error uploading attachment: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (bwCLI) DeleteAttachment(itemID, attachmentID string) error {
+	output, err := bwCommand("delete", "attachment", attachmentID, "--itemid", itemID).CombinedOutput()
+	if err != nil {
+		return commandError("error deleting attachment", err, output)
+	}
+	return nil
+}
+
+func (bwCLI) ListSends() ([]BitwardenSend, error) {
+	output, err := bwCommand("send", "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error listing Sends: %w", err)
+	}
+
+	var sends []BitwardenSend
+	if err := json.Unmarshal(output, &sends); err != nil {
+		return nil, fmt.Errorf("error parsing Send list: %w", err)
+	}
+	return sends, nil
+}
+
+func (bwCLI) DeleteSend(id string) error {
+	output, err := bwCommand("send", "delete", id).CombinedOutput()
+	if err != nil {
+		return commandError("error deleting Send", err, output)
+	}
+	return nil
+}
@@ -17,6 +17,67 @@ from urllib.parse import urlparse
 from typing import Dict, List, Tuple, Optional
 
 
+# Subset of Bitwarden's global equivalent domains. Logins for any domain in a
+# group are treated as belonging to the same site when grouping duplicates.
+GLOBAL_EQUIVALENT_DOMAINS = [
+    ["google.com", "youtube.com", "gmail.com"],
+    ["apple.com", "icloud.com"],
+    ["ameritrade.com", "tdameritrade.com"],
+    ["bankofamerica.com", "bofa.com", "mbna.com", "usecfo.com"],
+    ["sprint.com", "sprintpcs.com", "nextel.com"],
+    [
+        "microsoft.com",
+        "live.com",
+        "login.live.com",
+        "microsoftonline.com",
+        "msn.com",
+        "passport.net",
+        "windows.com",
+        "windowsazure.com",
+        "azure.com",
+        "office.com",
+        "office365.com",
+        "outlook.com",
+        "hotmail.com",
+        "xbox.com",
+    ],
+    [
+        "amazon.com",
+        "amazon.ca",
+        "amazon.com.au",
+        "amazon.com.br",
+        "amazon.com.mx",
+        "amazon.co.jp",
+        "amazon.co.uk",
+        "amazon.de",
+        "amazon.es",
+        "amazon.fr",
+        "amazon.in",
+        "amazon.it",
+        "amazon.nl",
+        "amazon.pl",
+        "amazon.se",
+    ],
+    ["paypal.com", "paypal-search.com"],
+    [
+        "ebay.com",
+        "ebay.ca",
+        "ebay.co.uk",
+        "ebay.com.au",
+        "ebay.de",
+        "ebay.es",
+        "ebay.fr",
+        "ebay.it",
+    ],
+    ["yahoo.com", "flickr.com"],
+    ["steampowered.com", "steamcommunity.com", "steamgames.com"],
+    ["github.com", "github.io"],
+    ["facebook.com", "messenger.com"],
+    ["atlassian.com", "atlassian.net", "bitbucket.org", "trello.com"],
+    ["dropbox.com", "getdropbox.com"],
+]
+
+
 def parse_arguments():
     """Parse command line arguments."""
     parser = argparse.ArgumentParser(
@@ -48,6 +109,16 @@ def parse_arguments():
         default="Personal",
         help="Default folder to use when folder is empty",
     )
+    parser.add_argument(
+        "-e",
+        "--equivalent-domains",
+        help="File with user-defined equivalent domains, one comma-separated group per line",
+    )
+    parser.add_argument(
+        "--no-global-equivalents",
+        action="store_true",
+        help="Do not use the built-in list of Bitwarden global equivalent domains",
+    )
     return parser.parse_args()
 
 
@@ -114,6 +185,50 @@ def is_domain_name(text: str) -> bool:
     return bool(re.match(domain_pattern, text))
 
 
+def load_equivalent_domains(
+    path: Optional[str], include_global: bool
+) -> Dict[str, str]:
+    """Build a lookup from each equivalent domain to its group's canonical domain."""
+    groups = [list(group) for group in GLOBAL_EQUIVALENT_DOMAINS] if include_global else []
+
+    if path:
+        with open(path, "r", encoding="utf-8") as domains_file:
+            for line in domains_file:
+                line = line.strip()
+                if not line or line.startswith("#"):
+                    continue
+                group = [d.strip().lower() for d in line.split(",") if d.strip()]
+                if len(group) > 1:
+                    groups.append(group)
+
+    # Groups sharing a domain are merged, so user-defined groups extend the global ones
+    lookup: Dict[str, str] = {}
+    for group in groups:
+        existing = [lookup[d] for d in group if d in lookup]
+        canonical = existing[0] if existing else group[0]
+        merged = set(group)
+        for old in set(existing):
+            merged |= {d for d, c in lookup.items() if c == old}
+        for domain in merged:
+            lookup[domain] = canonical
+
+    return lookup
+
+
+def canonical_domain(domain: Optional[str], equivalent_domains: Dict[str, str]) -> Optional[str]:
+    """Map a domain (or any of its subdomains) to its equivalent-domain group."""
+    if not domain or not equivalent_domains:
+        return domain
+
+    labels = domain.lower().split(".")
+    for i in range(len(labels) - 1):
+        candidate = ".".join(labels[i:])
+        if candidate in equivalent_domains:
+            return equivalent_domains[candidate]
+
+    return domain
+
+
 def fix_login_uri(entry: Dict[str, str], default_folder: str) -> Dict[str, str]:
     """Update login_uri if empty but name contains valid domain/IP."""
     # Clone the entry so we don't modify the original
@@ -152,7 +267,7 @@ def should_remove_entry(entry: Dict[str, str], filter_keywords: List[str]) -> bo
     return False
 
 
-def get_grouping_key(entry: Dict[str, str]) -> Tuple:
+def get_grouping_key(entry: Dict[str, str], equivalent_domains: Dict[str, str]) -> Tuple:
     """Create grouping key for entries considering domain from URL."""
     name = entry.get("name", "")
     username = entry.get("login_username", "")
@@ -165,6 +280,8 @@ def get_grouping_key(entry: Dict[str, str]) -> Tuple:
     # If we couldn't extract domain, use whole URI
     if not uri_domain and uri:
         uri_domain = uri
+    else:
+        uri_domain = canonical_domain(uri_domain, equivalent_domains)
 
     # Consider TOTP as part of the key so entries with different TOTP are not considered duplicates
     totp = bool(entry.get("login_totp", ""))
@@ -240,12 +357,22 @@ def main():
     default_folder = args.default_folder
     analysis_mode = args.analyze
 
+    try:
+        equivalent_domains = load_equivalent_domains(
+            args.equivalent_domains, not args.no_global_equivalents
+        )
+    except OSError as e:
+        print(f"Error reading equivalent domains file: {e}")
+        sys.exit(1)
+    equivalent_groups = len(set(equivalent_domains.values()))
+
     print("=== BITWARDEN DEDUPLICATION TOOL ===")
     print(f"Input file: {input_file}")
     print(f"Output file: {output_file}")
     print(f"Analysis mode: {'Enabled' if analysis_mode else 'Disabled'}")
     print(f"Filter keywords: {', '.join(filter_keywords)}")
     print(f"Default folder: {default_folder}")
+    print(f"Equivalent domain groups: {equivalent_groups}")
     print("=" * 40)
 
     # Validate input file exists
@@ -348,7 +475,7 @@ def main():
     print("\nSTEP 4: Grouping entries for deduplication...")
     grouped = defaultdict(list)
     for entry in filtered_entries:
-        key = get_grouping_key(entry)
+        key = get_grouping_key(entry, equivalent_domains)
         grouped[key].append(entry)
 
     unique_groups = {k: v for k, v in grouped.items() if len(v) == 1}
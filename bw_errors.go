@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mitas/bitwarden-cleanup/pkg/cleanup"
+)
+
+// errItemGone marks failures caused by the item no longer existing. It is
+// the error of the cleanup package, so both classify such failures alike.
+var errItemGone = cleanup.ErrItemGone
+
+// The causes of failed bw invocations are those of the cleanup package, so
+// that programs using it classify failures alike.
+var (
+	errRateLimited      = cleanup.ErrRateLimited
+	errNetwork          = cleanup.ErrNetwork
+	errPermissionDenied = cleanup.ErrPermissionDenied
+)
+
+// commandError wraps a failed bw invocation with its output, classifying
+// "not found" responses as errItemGone and other known messages by cause.
+func commandError(action string, err error, output []byte) error {
+	message := strings.TrimSpace(string(output))
+	if cleanup.IsNotFoundMessage(message) {
+		return fmt.Errorf("%s: %w", action, errItemGone)
+	}
+	if message == "" {
+		return withFailureCause(fmt.Errorf("%s: %w", action, err), err.Error())
+	}
+	return withFailureCause(fmt.Errorf("%s: %w: %s", action, err, message), message)
+}
+
+// withFailureCause attaches the cause that message points to, if any.
+func withFailureCause(err error, message string) error {
+	if cause := cleanup.CauseOf(message); cause != nil {
+		return cleanup.WithCause(err, cause)
+	}
+	return err
+}
+
+// Failure classes reported in the end-of-run summary.
+const (
+	failureRateLimited = "rate-limited"
+	failureLocked      = "locked vault"
+	failureLoggedOut   = "logged out"
+	failurePermission  = "no permission"
+	failureNetwork     = "network"
+	failureTimeout     = "timed out"
+	failureNotFound    = "not found"
+	failureUnknown     = "unknown"
+)
+
+// Items that were not found count as already gone rather than failed, so
+// failureNotFound only appears in run records.
+var failureClassOrder = []string{failureRateLimited, failureLocked, failureLoggedOut, failurePermission, failureNetwork, failureTimeout, failureUnknown}
+
+// failureClasses name the causes of failed operations.
+var failureClasses = []struct {
+	class string
+	cause error
+}{
+	{failureRateLimited, errRateLimited},
+	{failureLocked, errVaultLocked},
+	{failureLoggedOut, errNotLoggedIn},
+	{failurePermission, errPermissionDenied},
+	{failureNetwork, errNetwork},
+}
+
+// statusFailureCause is the cause an HTTP status of bw serve stands for, or
+// nil.
+func statusFailureCause(code int) error {
+	switch code {
+	case http.StatusTooManyRequests:
+		return errRateLimited
+	case http.StatusUnauthorized:
+		return errNotLoggedIn
+	case http.StatusForbidden:
+		return errPermissionDenied
+	}
+	return nil
+}
+
+// classifyFailure names the likely cause of a failed operation from its
+// typed cause or, for errors without one, the message it carries.
+func classifyFailure(err error) string {
+	if errors.Is(err, errItemGone) {
+		return failureNotFound
+	}
+	if errors.Is(err, errBWTimeout) {
+		return failureTimeout
+	}
+	cause := cleanup.CauseOf(err.Error())
+	for _, entry := range failureClasses {
+		if errors.Is(err, entry.cause) {
+			return entry.class
+		}
+	}
+	for _, entry := range failureClasses {
+		if cause == entry.cause {
+			return entry.class
+		}
+	}
+	return failureUnknown
+}
+
+// failureCounts counts failed results by class, for summaries.
+func failureCounts(failed []itemResult) map[string]int {
+	if len(failed) == 0 {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, result := range failed {
+		counts[classifyFailure(result.Err)]++
+	}
+	return counts
+}
+
+// failureHint suggests what to do about failures of a class.
+func failureHint(class string, options CommandOptions) string {
+	switch class {
+	case failureRateLimited:
+		if options.autoBatch {
+			return "the server is throttling even at the lowest --batch auto settled on; lower --max-batch or add --rate"
+		}
+		if options.batchSize > 1 {
+			return fmt.Sprintf("the server is throttling; re-run with a lower --batch (e.g. --batch %d)", max(options.batchSize/2, 1))
+		}
+		return "the server is throttling; wait a few minutes and re-run"
+	case failureLocked:
+		return "unlock the vault with 'bw unlock', export BW_SESSION and re-run; the failed items are retried first"
+	case failureLoggedOut:
+		return "the login has expired; log in again with 'bw login' (or BW_CLIENTID and BW_CLIENTSECRET), unlock and re-run"
+	case failurePermission:
+		return "your account may not change these organization items; ask an admin for Manage access to their collections, or leave them out with --organization-id or --exclude"
+	case failureNetwork:
+		return "check the connection to the Bitwarden server and re-run; the failed items are retried first"
+	case failureTimeout:
+		return fmt.Sprintf("bw hung and was killed after %s; check the connection, or raise --bw-timeout for large vaults, and re-run", bwTimeout)
+	default:
+		return "see the errors below and the run record"
+	}
+}
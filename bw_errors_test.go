@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/mitas/bitwarden-cleanup/pkg/cleanup"
+)
+
+func TestCommandError(t *testing.T) {
+	exit := errors.New("exit status 1")
+	tests := []struct {
+		output string
+		want   error
+	}{
+		{"Not found.", errItemGone},
+		{"Something went wrong\nItem not found.", errItemGone},
+		{"Error: 429 Too Many Requests", errRateLimited},
+		{"You are not logged in.", errNotLoggedIn},
+		{"Vault is locked.", errVaultLocked},
+		{"connect ECONNREFUSED 127.0.0.1:8087", errNetwork},
+		{"You do not have permission to edit this.", errPermissionDenied},
+	}
+	for _, test := range tests {
+		err := commandError("error deleting item", exit, []byte(test.output))
+		if !errors.Is(err, test.want) {
+			t.Errorf("commandError(%q) = %v, want %v", test.output, err, test.want)
+		}
+		if !errors.Is(err, exit) && test.want != errItemGone {
+			t.Errorf("commandError(%q) = %v, lost the exit error", test.output, err)
+		}
+	}
+}
+
+func TestCommandErrorNotFoundNeedsAWholeLine(t *testing.T) {
+	for _, output := range []string{"getaddrinfo ENOTFOUND vault.example.com (host not found)", "<h1>404 Not Found</h1>"} {
+		if err := commandError("error deleting item", errors.New("exit status 1"), []byte(output)); errors.Is(err, errItemGone) {
+			t.Errorf("commandError(%q) = %v, want no errItemGone", output, err)
+		}
+	}
+}
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"gone", fmt.Errorf("error deleting item: %w", errItemGone), failureNotFound},
+		{"timeout", fmt.Errorf("error deleting item: %w", errBWTimeout), failureTimeout},
+		{"typed cause", cleanup.WithCause(errors.New("error deleting item: exit status 1"), errRateLimited), failureRateLimited},
+		{"typed cause wins over the message", cleanup.WithCause(errors.New("network unreachable"), errPermissionDenied), failurePermission},
+		{"locked", fmt.Errorf("error syncing: %w", errVaultLocked), failureLocked},
+		{"logged out by status", cleanup.WithCause(errors.New("bw serve: 401"), statusFailureCause(401)), failureLoggedOut},
+		{"message only", errors.New("socket hang up"), failureNetwork},
+		{"message with a bare status code", errors.New("item 429abc failed"), failureUnknown},
+		{"unknown", errors.New("exit status 1"), failureUnknown},
+	}
+	for _, test := range tests {
+		if got := classifyFailure(test.err); got != test.want {
+			t.Errorf("%s: classifyFailure(%v) = %q, want %q", test.name, test.err, got, test.want)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errRateLimited, true},
+		{errNetwork, true},
+		{errBWTimeout, true},
+		{fmt.Errorf("%w: %w", errBWTimeout, errUncertainOutcome), false},
+		{errItemGone, false},
+		{errPermissionDenied, false},
+		{errVaultLocked, false},
+		{errors.New("exit status 1"), false},
+	}
+	for _, test := range tests {
+		if got := isRetryable(test.err); got != test.want {
+			t.Errorf("isRetryable(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mitas/bitwarden-cleanup/pkg/cleanup"
+)
+
+const (
+	backendExec  = "exec"
+	backendServe = "serve"
+
+	serveStartTimeout  = 30 * time.Second
+	serveStartAttempts = 3
+	serveOutputLimit   = 4096
+)
+
+var errServePortTaken = errors.New("another process took the port of bw serve")
+
+// bwServeClient talks to the Vault Management API of a 'bw serve' process
+// started for one run. The vault is unlocked and decrypted once instead of
+// for every bw invocation, which is what makes per-item calls slow. Calls
+// the API does not take over go to the embedded CLI client.
+type bwServeClient struct {
+	bwCLI
+
+	baseURL string
+	cmd     *exec.Cmd
+	client  *http.Client
+}
+
+type bwServeResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func validateBackend(backend string) error {
+	switch backend {
+	case backendExec, backendServe:
+		return nil
+	default:
+		return fmt.Errorf("unknown --backend %q (expected exec or serve)", backend)
+	}
+}
+
+// startServeBackend starts 'bw serve' on a free loopback port and waits
+// until it answers with an unlocked vault. The port is only free when it is
+// picked: bw binds it a moment later, and another process may take it in
+// between. bw serve then exits, and it is started again on a new port.
+func startServeBackend() (*bwServeClient, error) {
+	if err := requireBWCapabilities(capabilityServe); err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		api, err := launchServeBackend()
+		if !errors.Is(err, errServePortTaken) || attempt == serveStartAttempts {
+			return api, err
+		}
+		console.warnf("Warning: %v; trying another port", err)
+	}
+}
+
+func launchServeBackend() (*bwServeClient, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("error finding a free port for bw serve: %w", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	console.infof(emojiStart, "Starting bw serve on port %d...", port)
+	cmd := bwCommandWithTimeout(0, "serve", "--hostname", "127.0.0.1", "--port", strconv.Itoa(port)).Cmd
+	output := &serveOutput{}
+	cmd.Stdout, cmd.Stderr = output, output
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("error starting bw serve: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	api := &bwServeClient{
+		baseURL: fmt.Sprintf("http://127.0.0.1:%d", port),
+		cmd:     cmd,
+		client:  &http.Client{},
+	}
+
+	deadline := time.Now().Add(serveStartTimeout)
+	for {
+		select {
+		case err := <-exited:
+			message := output.String()
+			if lower := strings.ToLower(message); strings.Contains(lower, "eaddrinuse") || strings.Contains(lower, "address already in use") {
+				return nil, fmt.Errorf("%w: port %d", errServePortTaken, port)
+			}
+			return nil, fmt.Errorf("bw serve exited during startup: %v: %s", err, message)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		status, err := api.status()
+		if err == nil {
+			if status != "unlocked" {
+				api.close()
+				return nil, fmt.Errorf("vault is %s; unlock it with 'bw unlock' and export BW_SESSION first", status)
+			}
+			return api, nil
+		}
+		if time.Now().After(deadline) {
+			api.close()
+			return nil, fmt.Errorf("bw serve did not become ready within %s: %w", serveStartTimeout, err)
+		}
+	}
+}
+
+// serveOutput keeps the start of what bw serve prints, which holds the
+// reason when it fails to start, without growing during a long run.
+type serveOutput struct {
+	mu   sync.Mutex
+	data []byte
+}
+
+func (o *serveOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if room := serveOutputLimit - len(o.data); room > 0 {
+		o.data = append(o.data, p[:min(len(p), room)]...)
+	}
+	return len(p), nil
+}
+
+func (o *serveOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return strings.TrimSpace(string(o.data))
+}
+
+func (api *bwServeClient) close() {
+	if api.cmd.Process != nil {
+		api.cmd.Process.Kill()
+	}
+}
+
+func (api *bwServeClient) status() (string, error) {
+	data, err := api.request(http.MethodGet, "/status")
+	if err != nil {
+		return "", err
+	}
+
+	var status struct {
+		Template struct {
+			Status string `json:"status"`
+		} `json:"template"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return "", fmt.Errorf("error parsing bw serve status: %w", err)
+	}
+	return status.Template.Status, nil
+}
+
+func (api *bwServeClient) ListItems(searchTerm string) ([]BitwardenItem, error) {
+	path := "/list/object/items"
+	if searchTerm != "" {
+		path += "?search=" + url.QueryEscape(searchTerm)
+	}
+	data, err := api.request(http.MethodGet, path)
+	if err != nil {
+		return nil, fmt.Errorf("error listing items: %w", err)
+	}
+
+	var list struct {
+		Data []BitwardenItem `json:"data"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("error parsing list output: %w", err)
+	}
+	return list.Data, nil
+}
+
+// DeleteItem moves an item to the trash; the API cannot delete permanently.
+func (api *bwServeClient) DeleteItem(id string, permanent bool) error {
+	if permanent {
+		return api.bwCLI.DeleteItem(id, true)
+	}
+	if _, err := api.request(http.MethodDelete, "/object/item/"+url.PathEscape(id)); err != nil {
+		return fmt.Errorf("error deleting item: %w", err)
+	}
+	return nil
+}
+
+// request performs an API call and returns the data of a successful
+// response. Not-found responses are reported as errItemGone, like the
+// output of a failed bw invocation. Calls get the timeouts of the bw
+// commands they stand in for.
+func (api *bwServeClient) request(method, path string) (json.RawMessage, error) {
+	timeout := bwTimeout
+	if strings.HasPrefix(path, "/list/") {
+		timeout = commandTimeout([]string{"list"})
+	}
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, api.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := api.client.Do(req)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("bw serve did not answer %s %s within %s: %w", method, path, timeout, errBWTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var response bwServeResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("unexpected bw serve response (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if resp.StatusCode == http.StatusNotFound || cleanup.IsNotFoundMessage(response.Message) {
+		return nil, errItemGone
+	}
+	if resp.StatusCode >= 300 || !response.Success {
+		err := fmt.Errorf("HTTP %d: %s", resp.StatusCode, response.Message)
+		if cause := statusFailureCause(resp.StatusCode); cause != nil {
+			return nil, cleanup.WithCause(err, cause)
+		}
+		return nil, withFailureCause(err, response.Message)
+	}
+	return response.Data, nil
+}
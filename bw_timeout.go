@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+const (
+	defaultBWTimeout = 30 * time.Second
+	// bwTransferTimeout is the least time the commands that move a whole
+	// vault or a file get, whatever --bw-timeout says.
+	bwTransferTimeout = 10 * time.Minute
+)
+
+// bwTimeout is how long one bw command may run before it is considered hung
+// and killed (--bw-timeout); 0 lets commands run as long as they take.
+var bwTimeout = defaultBWTimeout
+
+// errBWTimeout marks bw commands that were killed after their timeout.
+var errBWTimeout = errors.New("bw did not respond")
+
+// errUncertainOutcome marks killed commands that may have reached the
+// server anyway. Running them again could create a second copy, so they are
+// never retried.
+var errUncertainOutcome = errors.New("the command may have completed")
+
+// bwTransferCommands are the commands whose duration grows with the vault
+// or the file they move.
+var bwTransferCommands = map[string]bool{
+	"sync":              true,
+	"list":              true,
+	"export":            true,
+	"get attachment":    true,
+	"create attachment": true,
+}
+
+// commandTimeout is the timeout of the bw command with these arguments.
+func commandTimeout(args []string) time.Duration {
+	if bwTimeout == 0 || len(args) == 0 {
+		return bwTimeout
+	}
+	if bwTransferCommands[args[0]] || len(args) > 1 && bwTransferCommands[args[0]+" "+args[1]] {
+		return max(bwTimeout, bwTransferTimeout)
+	}
+	return bwTimeout
+}
+
+// bwProcess is a bw command with a deadline. Output and CombinedOutput
+// release the deadline when the command ends and report a killed command
+// as errBWTimeout.
+type bwProcess struct {
+	*exec.Cmd
+	ctx     context.Context
+	cancel  context.CancelFunc
+	timeout time.Duration
+}
+
+// bwPath is the bw executable found by lookupBW.
+var bwPath = "bw"
+
+// lookupBW finds bw in PATH under the names it is installed as on this
+// platform.
+func lookupBW() error {
+	var err error
+	for _, name := range bwExecutables {
+		var path string
+		if path, err = exec.LookPath(name); err == nil {
+			bwPath = path
+			return nil
+		}
+	}
+	return err
+}
+
+func newBWProcess(timeout time.Duration, args ...string) *bwProcess {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	cmd := exec.CommandContext(ctx, bwPath, args...)
+	// bw can leave a helper holding its output open; do not wait for it
+	// once bw itself was killed.
+	cmd.WaitDelay = time.Second
+	escapeForShim(cmd)
+	killTreeOnCancel(cmd)
+	return &bwProcess{Cmd: cmd, ctx: ctx, cancel: cancel, timeout: timeout}
+}
+
+func (p *bwProcess) Output() ([]byte, error) {
+	defer p.cancel()
+	output, err := p.Cmd.Output()
+	return output, p.timeoutError(err)
+}
+
+func (p *bwProcess) CombinedOutput() ([]byte, error) {
+	defer p.cancel()
+	output, err := p.Cmd.CombinedOutput()
+	return output, p.timeoutError(err)
+}
+
+func (p *bwProcess) timeoutError(err error) error {
+	if err != nil && errors.Is(p.ctx.Err(), context.DeadlineExceeded) {
+		// bw create sends the new item, folder or file before it waits for
+		// the answer.
+		if p.Args[1] == "create" {
+			return fmt.Errorf("bw %s was killed after %s: %w, %w", p.Args[1], p.timeout, errBWTimeout, errUncertainOutcome)
+		}
+		return fmt.Errorf("bw %s was killed after %s: %w", p.Args[1], p.timeout, errBWTimeout)
+	}
+	return err
+}
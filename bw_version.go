@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// bwVersion is a version of the Bitwarden CLI. Older releases are numbered
+// 1.x.y, newer ones by year and month, such as 2024.6.0; both compare as
+// plain numbers.
+type bwVersion struct {
+	major, minor, patch int
+}
+
+var bwVersionPattern = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+func parseBWVersion(output string) (bwVersion, error) {
+	match := bwVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return bwVersion{}, fmt.Errorf("unrecognized bw version %q", strings.TrimSpace(output))
+	}
+	var parts [3]int
+	for i := range parts {
+		parts[i], _ = strconv.Atoi(match[i+1])
+	}
+	return bwVersion{major: parts[0], minor: parts[1], patch: parts[2]}, nil
+}
+
+func (v bwVersion) less(other bwVersion) bool {
+	if v.major != other.major {
+		return v.major < other.major
+	}
+	if v.minor != other.minor {
+		return v.minor < other.minor
+	}
+	return v.patch < other.patch
+}
+
+func (v bwVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+}
+
+// bwCapability is a part of the bw CLI the tool relies on, with the first
+// release that has it.
+type bwCapability struct {
+	name  string
+	since bwVersion
+}
+
+var (
+	capabilityTrash = bwCapability{name: "the trash ('bw delete --permanent', 'bw restore')", since: bwVersion{1, 10, 0}}
+	capabilitySend  = bwCapability{name: "Sends ('bw send')", since: bwVersion{1, 15, 0}}
+	capabilityServe = bwCapability{name: "'bw serve'", since: bwVersion{2022, 9, 0}}
+)
+
+// bwCompatibility holds the version of the installed bw, asked for once per
+// run, and --ignore-bw-version.
+var bwCompatibility struct {
+	once    sync.Once
+	version bwVersion
+	err     error
+	ignore  bool
+}
+
+func installedBWVersion() (bwVersion, error) {
+	bwCompatibility.once.Do(func() {
+		output, err := bwCommand("--version").Output()
+		if err != nil {
+			bwCompatibility.err = fmt.Errorf("error running 'bw --version': %w", err)
+			return
+		}
+		bwCompatibility.version, bwCompatibility.err = parseBWVersion(string(output))
+		if bwCompatibility.err == nil {
+			console.debugf(emojiInfo, "Bitwarden CLI %s", bwCompatibility.version)
+		}
+	})
+	return bwCompatibility.version, bwCompatibility.err
+}
+
+// requireBWCapabilities refuses to run with a bw that is too old for the
+// given capabilities, unless --ignore-bw-version is set. A version that
+// cannot be determined is only a warning, since bw builds from source or
+// wrappers may print something else.
+func requireBWCapabilities(capabilities ...bwCapability) error {
+	version, err := installedBWVersion()
+	if err != nil {
+		console.warnf("Warning: could not check the bw version: %v", err)
+		return nil
+	}
+	var missing []string
+	for _, capability := range capabilities {
+		if version.less(capability.since) {
+			missing = append(missing, fmt.Sprintf("%s needs %s or later", capability.name, capability.since))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if bwCompatibility.ignore {
+		console.warnf("Warning: bw %s is too old: %s; continuing because of --ignore-bw-version", version, strings.Join(missing, ", "))
+		return nil
+	}
+	return fmt.Errorf("bw %s is too old: %s; update the Bitwarden CLI, or pass --ignore-bw-version to try anyway", version, strings.Join(missing, ", "))
+}
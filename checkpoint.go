@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	checkpointVersion = 2
+	checkpointsDir    = "checkpoints"
+)
+
+// checkpointHeader is the first line of a checkpoint file: the flags of the
+// run and the items it was confirmed for. Every following line is a
+// checkpointEntry.
+type checkpointHeader struct {
+	Version      int       `json:"version"`
+	PID          int       `json:"pid"`
+	CreatedAt    time.Time `json:"createdAt"`
+	Operation    string    `json:"operation,omitempty"`
+	DeletesItems bool      `json:"deletesItems,omitempty"`
+	Permanent    bool      `json:"permanent,omitempty"`
+	// Resumable checkpoints were started by the delete command and can be
+	// continued with --resume; the others only journal the run.
+	Resumable bool     `json:"resumable,omitempty"`
+	Args      []string `json:"args"`
+	ItemIDs   []string `json:"itemIds"`
+}
+
+// checkpointEntry records that the bw call for an item was started, or its
+// result. A run that is resumed or that ends without processing every item
+// also leaves a line, with the PID of the resuming process or the ended
+// status.
+type checkpointEntry struct {
+	Time   time.Time `json:"time"`
+	ItemID string    `json:"itemId,omitempty"`
+	Name   string    `json:"name,omitempty"`
+	Status string    `json:"status"`
+	Error  string    `json:"error,omitempty"`
+	PID    int       `json:"pid,omitempty"`
+}
+
+const (
+	checkpointStarted = "started"
+	checkpointResumed = "resumed"
+	checkpointEnded   = "ended"
+)
+
+// checkpoint appends every item it is about to process and the outcome of
+// every processed item to the checkpoint file, flushed to disk as it
+// happens. A run that is interrupted or stopped can be resumed with
+// --resume, and one that was killed or crashed leaves behind which items
+// it was in the middle of, for recoverCrashedRuns. Failed items are tried
+// again on resume.
+type checkpoint struct {
+	path      string
+	resumable bool
+	redact    redactor
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// startCheckpoint writes the header of a new checkpoint file at path, or in
+// the checkpoints directory when path is empty. Only the delete command
+// starts resumable checkpoints; every other operation that deletes items
+// journals its run in one that is removed when the run ends.
+func startCheckpoint(path string, items []BitwardenItem, op itemOperation, options CommandOptions, resumable bool) (*checkpoint, error) {
+	if path == "" {
+		dir, err := configFile(checkpointsDir)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("error creating checkpoints directory: %w", err)
+		}
+		path = filepath.Join(dir, newRunID()+".jsonl")
+	}
+
+	header := checkpointHeader{
+		Version:      checkpointVersion,
+		PID:          os.Getpid(),
+		CreatedAt:    time.Now().UTC(),
+		Operation:    op.verb,
+		DeletesItems: op.deletesItems,
+		Permanent:    op.permanent,
+		Resumable:    resumable,
+		Args:         options.commandArgs,
+	}
+	for _, item := range items {
+		header.ItemIDs = append(header.ItemIDs, item.ID)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error creating checkpoint: %w", err)
+	}
+	cp := &checkpoint{path: path, resumable: resumable, redact: options.redact, file: file}
+	if err := cp.write(header); err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	return cp, nil
+}
+
+// openCheckpoint reads the checkpoint file at path and reopens it for
+// appending, returning the header and the IDs of the items already processed.
+func openCheckpoint(path string) (*checkpoint, *checkpointHeader, map[string]bool, error) {
+	found, err := readCheckpoint(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !found.header.Resumable {
+		return nil, nil, nil, fmt.Errorf("checkpoint %s journals a %s run and cannot be resumed", path, found.header.Operation)
+	}
+	processed := make(map[string]bool)
+	for id, entry := range found.last {
+		if entry.Status == resultDone || entry.Status == resultGone {
+			processed[id] = true
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error opening checkpoint: %w", err)
+	}
+	cp := &checkpoint{path: path, resumable: true, file: file}
+	if err := cp.write(checkpointEntry{Time: time.Now().UTC(), Status: checkpointResumed, PID: os.Getpid()}); err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+	return cp, &found.header, processed, nil
+}
+
+func (cp *checkpoint) write(line any) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Errorf("error encoding checkpoint: %w", err)
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if _, err := cp.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	if err := cp.file.Sync(); err != nil {
+		return fmt.Errorf("error writing checkpoint: %w", err)
+	}
+	return nil
+}
+
+// begin records that the bw call for item is about to be made.
+func (cp *checkpoint) begin(item BitwardenItem) {
+	if cp == nil {
+		return
+	}
+	entry := checkpointEntry{Time: time.Now().UTC(), ItemID: item.ID, Name: cp.redact.name(item.Name), Status: checkpointStarted}
+	if err := cp.write(entry); err != nil {
+		console.warnf("Warning: %v", err)
+	}
+}
+
+// record records the result of the bw call for an item.
+func (cp *checkpoint) record(result itemResult) {
+	if cp == nil {
+		return
+	}
+	entry := checkpointEntry{Time: time.Now().UTC(), ItemID: result.ItemID, Status: result.status()}
+	if result.Err != nil {
+		entry.Error = result.Err.Error()
+	}
+	if err := cp.write(entry); err != nil {
+		console.warnf("Warning: %v", err)
+	}
+}
+
+// finish closes the checkpoint of a run that ended. It is removed once every
+// item has been processed, and always when it only journaled the run;
+// otherwise it tells how to resume the run.
+func (cp *checkpoint) finish(stats *DeleteStats) {
+	if cp == nil {
+		return
+	}
+
+	if cp.resumable && stats.completed < stats.total {
+		if err := cp.write(checkpointEntry{Time: time.Now().UTC(), Status: checkpointEnded}); err != nil {
+			console.warnf("Warning: %v", err)
+		}
+		cp.file.Close()
+		console.infof(emojiInfo, "Progress saved; resume the remaining items with: %s --resume %s", filepath.Base(os.Args[0]), shellJoin([]string{cp.path}))
+		return
+	}
+	cp.file.Close()
+	if err := os.Remove(cp.path); err != nil {
+		console.warnf("Warning: error removing checkpoint: %v", err)
+	}
+}
+
+// resumeCheckpoint continues the run recorded in the checkpoint file at path
+// with the operation and flags it was started with. The items were confirmed
+// when the run started, so it does not ask again; items already processed
+// are skipped.
+func resumeCheckpoint(path string) error {
+	cp, header, processed, err := openCheckpoint(path)
+	if err != nil {
+		return err
+	}
+	op, options, err := replayOperation(header.Args)
+	if err != nil {
+		cp.file.Close()
+		return fmt.Errorf("cannot resume run %s: %w", shellJoin(header.Args), err)
+	}
+
+	var remaining []string
+	for _, id := range header.ItemIDs {
+		if !processed[id] {
+			remaining = append(remaining, id)
+		}
+	}
+	console.infof(emojiStart, "Resuming the run %s from %s: %d of %d items left", shellJoin(header.Args), header.CreatedAt.Local().Format("2006-01-02 15:04"), len(remaining), len(header.ItemIDs))
+	displayOperationMode(op)
+
+	if err := syncBitwarden("before resuming"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+	items, err := fetchItemsByID(remaining, options.protection)
+	if err != nil {
+		cp.file.Close()
+		return err
+	}
+	if skipped := options.protection.count(); skipped > 0 {
+		console.infof(emojiInfo, "%d remaining items were skipped due to protection rules (--exclude, --protect-file)", skipped)
+	}
+
+	stats := &DeleteStats{total: len(items), checkpoint: cp}
+	if stats.total == 0 {
+		cp.finish(stats)
+		console.infof(emojiSuccess, "Nothing left to do")
+		return nil
+	}
+	return executeItems(items, stats, op, options)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCheckpointFile(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadCheckpoint(t *testing.T) {
+	path := writeCheckpointFile(t,
+		`{"version":2,"pid":100,"operation":"delete","deletesItems":true,"resumable":true,"itemIds":["a","b","c","d"]}`,
+		`{"itemId":"a","name":"first","status":"started"}`,
+		`{"itemId":"a","status":"done"}`,
+		`{"itemId":"b","name":"second","status":"started"}`,
+		`{"itemId":"b","status":"failed","error":"rate limited"}`,
+		`{"status":"ended"}`,
+		`{"status":"resumed","pid":200}`,
+		`{"itemId":"c","name":"third","status":"started"}`,
+		`{"itemId":"d","na`,
+	)
+	found, err := readCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.pid != 200 || found.ended {
+		t.Errorf("pid %d, ended %v; want the resuming process 200, not ended", found.pid, found.ended)
+	}
+	if got := strings.Join(found.order, ","); got != "a,b,c" {
+		t.Errorf("order = %s, want a,b,c", got)
+	}
+	if found.last["a"].Status != resultDone || found.last["a"].Name != "first" {
+		t.Errorf("last entry of a = %+v, want done with its name", found.last["a"])
+	}
+	inFlight := found.inFlight()
+	if len(inFlight) != 1 || inFlight[0].ItemID != "c" {
+		t.Errorf("in flight = %+v, want c", inFlight)
+	}
+}
+
+func TestOpenCheckpoint(t *testing.T) {
+	path := writeCheckpointFile(t,
+		`{"version":2,"pid":100,"operation":"delete","deletesItems":true,"resumable":true,"itemIds":["a","b","c"]}`,
+		`{"itemId":"a","status":"done"}`,
+		`{"itemId":"b","status":"gone"}`,
+		`{"itemId":"c","status":"failed"}`,
+	)
+	cp, header, processed, err := openCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.file.Close()
+	if len(header.ItemIDs) != 3 || !processed["a"] || !processed["b"] || processed["c"] {
+		t.Errorf("processed = %v, want a and b of %v", processed, header.ItemIDs)
+	}
+
+	found, err := readCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.pid != os.Getpid() {
+		t.Errorf("pid after resuming = %d, want %d", found.pid, os.Getpid())
+	}
+}
+
+func TestOpenCheckpointRefusesJournals(t *testing.T) {
+	path := writeCheckpointFile(t, `{"version":2,"pid":100,"operation":"delete","deletesItems":true,"itemIds":["a"]}`)
+	if _, _, _, err := openCheckpoint(path); err == nil {
+		t.Error("openCheckpoint resumed a checkpoint that only journals its run")
+	}
+}
+
+func TestReadCheckpointVersion1(t *testing.T) {
+	path := writeCheckpointFile(t, `{"version":1,"args":["--search=x"],"itemIds":["a"]}`, `{"itemId":"a","status":"done"}`)
+	found, err := readCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found.header.Resumable || found.pid != 0 {
+		t.Errorf("version 1 checkpoint: resumable %v, pid %d; want resumable without a process", found.header.Resumable, found.pid)
+	}
+}
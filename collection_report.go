@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func runCollectionsCommand(args []string) error {
+	if len(args) == 0 || args[0] != "report" {
+		return fmt.Errorf("usage: %s collections report [--max-collections <n>] [--out <file.csv>] [--search <term>]", filepath.Base(os.Args[0]))
+	}
+
+	flags := flag.NewFlagSet("collections report", flag.ExitOnError)
+	maxCollections := flags.Int("max-collections", 3, "Highlight items that belong to more than this many collections")
+	csvPath := flags.String("out", "", "Also write the report to this CSV file")
+	options, err := parseOptions(flags, args[1:], defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	return reportCollectionMembership(options, *maxCollections, *csvPath)
+}
+
+// reportCollectionMembership lists, for every organization item, the
+// collections it belongs to, flagging items in no collection (reachable only
+// by organization admins) or in more collections than expected.
+func reportCollectionMembership(options CommandOptions, maxCollections int, csvPath string) error {
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	collections, err := vault.ListCollections()
+	if err != nil {
+		return err
+	}
+	names := make(map[string]string, len(collections))
+	for _, collection := range collections {
+		names[collection.ID] = collection.Name
+	}
+
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return err
+	}
+
+	var orgItems []BitwardenItem
+	for _, item := range items {
+		if item.OrganizationID != "" {
+			orgItems = append(orgItems, item)
+		}
+	}
+	sort.Slice(orgItems, func(i, j int) bool { return strings.ToLower(orgItems[i].Name) < strings.ToLower(orgItems[j].Name) })
+
+	console.infof(emojiSearch, "Collection membership of %d organization items:", len(orgItems))
+	var rows [][]string
+	unassigned, overAssigned := 0, 0
+	for _, item := range orgItems {
+		var memberOf []string
+		for _, id := range item.CollectionIDs {
+			if name, ok := names[id]; ok {
+				memberOf = append(memberOf, name)
+			} else {
+				memberOf = append(memberOf, id)
+			}
+		}
+		sort.Strings(memberOf)
+
+		finding := ""
+		switch {
+		case len(memberOf) == 0:
+			finding = "none"
+			unassigned++
+		case maxCollections > 0 && len(memberOf) > maxCollections:
+			finding = "too many"
+			overAssigned++
+		}
+
+		name := options.redact.name(item.Name)
+		line := fmt.Sprintf("%s (%s): %d collections", name, item.ID, len(memberOf))
+		if len(memberOf) > 0 {
+			line += " - " + strings.Join(memberOf, ", ")
+		}
+		if finding != "" {
+			console.infof(emojiWarning, "%s", line)
+		} else {
+			console.linef("   %s", line)
+		}
+
+		rows = append(rows, []string{item.ID, name, item.OrganizationID, strconv.Itoa(len(memberOf)), strings.Join(memberOf, "; "), finding})
+	}
+
+	console.infof(emojiInfo, "\n%d items in no collection, %d items in more than %d collections", unassigned, overAssigned, maxCollections)
+
+	if csvPath != "" {
+		if err := writeCollectionReport(csvPath, rows); err != nil {
+			return err
+		}
+		console.infof(emojiSuccess, "Report written to %s", csvPath)
+	}
+	return nil
+}
+
+func writeCollectionReport(path string, rows [][]string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating report: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"id", "name", "organizationId", "collectionCount", "collections", "flag"})
+	writer.WriteAll(rows)
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing report: %w", err)
+	}
+	return nil
+}
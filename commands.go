@@ -0,0 +1,27 @@
+package main
+
+var subcommands = map[string]func(args []string) error{
+	"apply":          runApplyCommand,
+	"attachments":    runAttachmentsCommand,
+	"audit":          runAuditCommand,
+	"collections":    runCollectionsCommand,
+	"dedupe":         runDedupeCommand,
+	"delete":         runDeleteCommand,
+	"folders":        runFoldersCommand,
+	"history":        runHistoryCommand,
+	"list":           runListCommand,
+	"org":            runOrgCommand,
+	"pending":        runPendingCommand,
+	"plan":           runPlanCommand,
+	"purge-trash":    runPurgeTrashCommand,
+	"quarantine":     runQuarantineCommand,
+	"restore":        runRestoreCommand,
+	"restore-backup": runRestoreBackupCommand,
+	"review":         runReviewCommand,
+	"sends":          runSendsCommand,
+	"serve":          runServeCommand,
+	"staged":         runStagedCommand,
+	"stats":          runStatsCommand,
+	"tag":            runTagCommand,
+	"undo":           runUndoCommand,
+}
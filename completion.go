@@ -0,0 +1,358 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// completion lists every subcommand, so it is registered here rather than in
+// the subcommands table it reads.
+func init() {
+	subcommands["completion"] = runCompletionCommand
+}
+
+// completionWords are the second words of the subcommands that have them.
+var completionWords = map[string][]string{
+	"attachments": {"download", "delete"},
+	"audit":       {"reused", "weak", "pwned", "stale"},
+	"collections": {"report"},
+	"completion":  {"bash", "zsh", "fish"},
+	"folders":     {"duplicates", "empty"},
+	"history":     {"filters", "clear"},
+	"org":         {"departed"},
+	"pending":     {"list", "run", "cancel"},
+	"staged":      {"list", "purge"},
+}
+
+// completionNoFlags are the commands that take no flags. They are not run
+// with -h to list flags, since some of them act on their arguments right
+// away.
+var completionNoFlags = []string{"completion bash", "completion zsh", "completion fish", "history filters", "history clear", "pending list", "pending run", "pending cancel", "staged list"}
+
+// folderFlags take a folder name, which is completed from 'bw list folders'.
+var folderFlags = []string{"folder", "move-to-folder", "to-folder"}
+
+// completionCommand is a command and the flags it accepts.
+type completionCommand struct {
+	path   string
+	words  []string
+	flags  []string
+	valued []string
+}
+
+func runCompletionCommand(args []string) error {
+	usage := fmt.Errorf("usage: %s completion bash|zsh|fish", filepath.Base(os.Args[0]))
+	if len(args) != 1 {
+		return usage
+	}
+
+	var generate func(program string, commands []completionCommand) string
+	switch args[0] {
+	case "bash":
+		generate = bashCompletion
+	case "zsh":
+		generate = zshCompletion
+	case "fish":
+		generate = fishCompletion
+	case "folders":
+		// Called by the scripts to complete folder names; stays quiet when
+		// the vault is locked.
+		completeFolders()
+		return nil
+	default:
+		return usage
+	}
+
+	commands, err := collectCompletionCommands()
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stdout, generate(filepath.Base(os.Args[0]), commands))
+	return nil
+}
+
+func completeFolders() {
+	folders, err := vault.ListFolders()
+	if err != nil {
+		return
+	}
+	names := make([]string, 0, len(folders))
+	for _, folder := range folders {
+		names = append(names, folder.Name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(os.Stdout, name)
+	}
+}
+
+// collectCompletionCommands lists the commands, first the default one, with
+// the flags each of them prints for -h, so that the scripts always match the
+// flags of this build.
+func collectCompletionCommands() ([]completionCommand, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("error locating %s: %w", filepath.Base(os.Args[0]), err)
+	}
+
+	var names []string
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	paths := []string{""}
+	for _, name := range names {
+		paths = append(paths, name)
+		for _, word := range completionWords[name] {
+			paths = append(paths, name+" "+word)
+		}
+	}
+
+	var commands []completionCommand
+	for _, path := range paths {
+		command := completionCommand{path: path, words: completionWords[path]}
+		probe := command.words == nil && !slices.Contains(completionNoFlags, path)
+		if path == "" {
+			command.words, probe = names, true
+		}
+		if probe {
+			if command.flags, command.valued, err = probeFlags(executable, path); err != nil {
+				return nil, err
+			}
+		}
+		commands = append(commands, command)
+	}
+	return commands, nil
+}
+
+// flagUsageLine matches the first line of a flag in the -h output of the
+// flag package: the name, then the type of its value unless it is a bool.
+var flagUsageLine = regexp.MustCompile(`^  -(\S+?)(?: (\w+))?(?:\t|$)`)
+
+func probeFlags(executable, path string) (flags, valued []string, err error) {
+	cmd := exec.Command(executable, append(strings.Fields(path), "-h")...)
+	// -h makes the command print its flags and exit, before it reads
+	// anything or talks to bw.
+	output, _ := cmd.CombinedOutput()
+	for _, line := range strings.Split(string(output), "\n") {
+		match := flagUsageLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		flags = append(flags, flagSpelling(match[1]))
+		if match[2] != "" {
+			valued = append(valued, flagSpelling(match[1]))
+		}
+	}
+	if len(flags) == 0 {
+		return nil, nil, fmt.Errorf("error listing the flags of %q", strings.TrimSpace(filepath.Base(os.Args[0])+" "+path))
+	}
+	return flags, valued, nil
+}
+
+// flagSpelling is how the scripts offer a flag: -x for shorthands, --name
+// otherwise.
+func flagSpelling(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+func completionFunction(program string) string {
+	return "_" + regexp.MustCompile(`[^A-Za-z0-9_]`).ReplaceAllString(program, "_")
+}
+
+// nestedPaths lists the two-word commands, as recognized from the first two
+// arguments.
+func nestedPaths(commands []completionCommand) []string {
+	var paths []string
+	for _, command := range commands {
+		if strings.Contains(command.path, " ") {
+			paths = append(paths, command.path)
+		}
+	}
+	return paths
+}
+
+func folderFlagSpellings() []string {
+	var spellings []string
+	for _, name := range folderFlags {
+		spellings = append(spellings, flagSpelling(name))
+	}
+	return spellings
+}
+
+func bashCompletion(program string, commands []completionCommand) string {
+	function := completionFunction(program)
+	var script strings.Builder
+	fmt.Fprintf(&script, "# bash completion for %s, generated by '%s completion bash'\n", program, program)
+	fmt.Fprintf(&script, "%s() {\n", function)
+	script.WriteString(`    local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+    local cmdpath="" words="" flags="" valued=""
+    if (( COMP_CWORD > 1 )); then
+        cmdpath=${COMP_WORDS[1]}
+    fi
+    if (( COMP_CWORD > 2 )); then
+        case "$cmdpath ${COMP_WORDS[2]}" in
+`)
+	fmt.Fprintf(&script, "        %s) cmdpath=\"$cmdpath ${COMP_WORDS[2]}\" ;;\n", bashPatterns(nestedPaths(commands)))
+	script.WriteString("        esac\n    fi\n    case \"$cmdpath\" in\n")
+	for _, command := range commands[1:] {
+		fmt.Fprintf(&script, "    %q) words=%q flags=%q valued=%q ;;\n", command.path, strings.Join(command.words, " "), strings.Join(command.flags, " "), strings.Join(command.valued, " "))
+	}
+	root := commands[0]
+	fmt.Fprintf(&script, "    *) (( COMP_CWORD == 1 )) && words=%q; flags=%q valued=%q ;;\n", strings.Join(root.words, " "), strings.Join(root.flags, " "), strings.Join(root.valued, " "))
+	script.WriteString(`    esac
+
+    if [[ " $valued " == *" $prev "* ]]; then
+        case "$prev" in
+`)
+	fmt.Fprintf(&script, "        %s)\n", strings.Join(folderFlagSpellings(), "|"))
+	script.WriteString(`            local IFS=$'\n' i
+            COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" completion folders 2>/dev/null)" -- "$cur"))
+            for i in "${!COMPREPLY[@]}"; do
+                COMPREPLY[i]=$(printf '%q' "${COMPREPLY[i]}")
+            done
+            ;;
+        *)
+            compopt -o default
+            COMPREPLY=()
+            ;;
+        esac
+        return
+    fi
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+    elif [[ -n $words ]]; then
+        COMPREPLY=($(compgen -W "$words" -- "$cur"))
+    else
+        compopt -o default
+        COMPREPLY=()
+    fi
+}
+`)
+	fmt.Fprintf(&script, "complete -F %s %s\n", function, program)
+	return script.String()
+}
+
+func bashPatterns(paths []string) string {
+	quoted := make([]string, 0, len(paths))
+	for _, path := range paths {
+		quoted = append(quoted, fmt.Sprintf("%q", path))
+	}
+	return strings.Join(quoted, "|")
+}
+
+func zshCompletion(program string, commands []completionCommand) string {
+	function := completionFunction(program)
+	var script strings.Builder
+	fmt.Fprintf(&script, "#compdef %s\n# zsh completion for %s, generated by '%s completion zsh'\n", program, program, program)
+	fmt.Fprintf(&script, "%s() {\n", function)
+	script.WriteString(`    local cmdpath="" cmds="" flags="" valued="" prev=${words[CURRENT-1]}
+    if (( CURRENT > 2 )); then
+        cmdpath=${words[2]}
+    fi
+    if (( CURRENT > 3 )); then
+        case "$cmdpath ${words[3]}" in
+`)
+	fmt.Fprintf(&script, "        (%s) cmdpath=\"$cmdpath ${words[3]}\" ;;\n", bashPatterns(nestedPaths(commands)))
+	script.WriteString("        esac\n    fi\n    case \"$cmdpath\" in\n")
+	for _, command := range commands[1:] {
+		fmt.Fprintf(&script, "    (%q) cmds=%q flags=%q valued=%q ;;\n", command.path, strings.Join(command.words, " "), strings.Join(command.flags, " "), strings.Join(command.valued, " "))
+	}
+	root := commands[0]
+	fmt.Fprintf(&script, "    (*) (( CURRENT == 2 )) && cmds=%q; flags=%q valued=%q ;;\n", strings.Join(root.words, " "), strings.Join(root.flags, " "), strings.Join(root.valued, " "))
+	script.WriteString(`    esac
+
+    if [[ " $valued " == *" $prev "* ]]; then
+        case "$prev" in
+`)
+	fmt.Fprintf(&script, "        (%s)\n", strings.Join(folderFlagSpellings(), "|"))
+	script.WriteString(`            local -a folders
+            folders=(${(f)"$(${words[1]} completion folders 2>/dev/null)"})
+            compadd -a folders
+            ;;
+        (*)
+            _files
+            ;;
+        esac
+        return
+    fi
+    if [[ $PREFIX == -* ]]; then
+        compadd -- ${=flags}
+    elif [[ -n $cmds ]]; then
+        compadd -- ${=cmds}
+    else
+        _files
+    fi
+}
+`)
+	fmt.Fprintf(&script, "compdef %s %s\n", function, program)
+	return script.String()
+}
+
+func fishCompletion(program string, commands []completionCommand) string {
+	function := completionFunction(program)
+	var script strings.Builder
+	fmt.Fprintf(&script, "# fish completion for %s, generated by '%s completion fish'\n", program, program)
+	fmt.Fprintf(&script, "function %s_path\n", function)
+	script.WriteString(`    set -l args (commandline -opc)
+    set -e args[1]
+    if test (count $args) -ge 2
+        switch "$args[1] $args[2]"
+`)
+	fmt.Fprintf(&script, "            case %s\n", fishQuote(nestedPaths(commands)))
+	script.WriteString(`                echo "$args[1] $args[2]"
+                return
+        end
+    end
+`)
+	fmt.Fprintf(&script, "    if test (count $args) -ge 1; and contains -- $args[1] %s\n", fishQuote(commands[0].words))
+	script.WriteString(`        echo $args[1]
+    else
+        echo ''
+    end
+end
+
+`)
+	fmt.Fprintf(&script, "function %s_folders\n    set -l program (commandline -opc)[1]\n    $program completion folders 2>/dev/null\nend\n\n", function)
+	fmt.Fprintf(&script, "complete -c %s -f\n", program)
+	fmt.Fprintf(&script, "complete -c %s -n 'test (count (commandline -opc)) -eq 1' -a %s\n", program, fishQuote([]string{strings.Join(commands[0].words, " ")}))
+	for _, command := range commands {
+		condition := fmt.Sprintf("test (%s_path) = %s", function, fishQuote([]string{command.path}))
+		if command.path != "" && len(command.words) > 0 {
+			fmt.Fprintf(&script, "complete -c %s -n %s -a %s\n", program, fishQuote([]string{"test (count (commandline -opc)) -eq 2; and " + condition}), fishQuote([]string{strings.Join(command.words, " ")}))
+		}
+		for _, flag := range command.flags {
+			option := "-l " + strings.TrimPrefix(flag, "--")
+			if !strings.HasPrefix(flag, "--") {
+				option = "-s " + strings.TrimPrefix(flag, "-")
+			}
+			switch {
+			case slices.Contains(folderFlagSpellings(), flag) && slices.Contains(command.valued, flag):
+				option += fmt.Sprintf(" -x -a '(%s_folders)'", function)
+			case slices.Contains(command.valued, flag):
+				option += " -r -F"
+			}
+			fmt.Fprintf(&script, "complete -c %s -n %s %s\n", program, fishQuote([]string{condition}), option)
+		}
+	}
+	return script.String()
+}
+
+// fishQuote quotes each value for fish and joins them with spaces.
+func fishQuote(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, "'"+strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)+"'")
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const configFileName = "config.toml"
+
+// configDataDir is the one config key that is not a flag: it points bw at
+// its own data directory, so that a profile can stay logged in to a
+// different account or server.
+const configDataDir = "data-dir"
+
+// configCredentials are the config keys that name the environment variable
+// holding a credential of a profile, so that the secrets of several accounts
+// can be kept apart without writing them into the file.
+var configCredentials = map[string]string{
+	"session-env":       "BW_SESSION",
+	"password-env":      "BW_PASSWORD",
+	"client-id-env":     "BW_CLIENTID",
+	"client-secret-env": "BW_CLIENTSECRET",
+}
+
+// configDeniedFlags cannot be set in the config file. The safety flags
+// would apply to every run, including interactive ones that were meant to
+// ask first, and a session key does not belong in a file; session-env names
+// the variable that holds it instead.
+var configDeniedFlags = map[string]string{
+	"yes":         "pass --yes on the command line of the runs that need it",
+	"y":           "pass --yes on the command line of the runs that need it",
+	"force":       "pass --force on the command line of the runs that need it",
+	"permanent":   "pass --permanent on the command line of the runs that need it",
+	"p":           "pass --permanent on the command line of the runs that need it",
+	"allow-large": "pass --allow-large on the command line of the runs that need it",
+	"no-backup":   "pass --no-backup on the command line of the runs that need it",
+	"reveal":      "pass --reveal on the command line of the runs that need it",
+	"session":     "set session-env to the name of the variable holding the key",
+}
+
+// configShorthands maps the flags with a shorthand to it, so that a default
+// from the config file does not override the shorthand given on the command
+// line.
+var configShorthands = map[string]string{
+	"batch":     "b",
+	"search":    "s",
+	"yes":       "y",
+	"permanent": "p",
+	"verbose":   "v",
+}
+
+// bwEnvironment holds the server (--server) and the data directory (config
+// file) of this run.
+var bwEnvironment struct {
+	server  string
+	dataDir string
+}
+
+// cleanupConfig is the parsed config file: defaults for every run, and named
+// profiles whose values replace the defaults. Each key holds every value of
+// the flag, so that repeatable flags like exclude can be preset as a list.
+type cleanupConfig struct {
+	defaults map[string][]string
+	profiles map[string]map[string][]string
+}
+
+// applyConfig fills the flags that were not given on the command line from
+// the defaults of the config file and the profile selected with --profile.
+func applyConfig(flags *flag.FlagSet) error {
+	profile := ""
+	if f := flags.Lookup("profile"); f != nil {
+		profile = f.Value.String()
+	}
+
+	config, path, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		if profile != "" {
+			return fmt.Errorf("--profile %s needs a config file at %s", profile, path)
+		}
+		return nil
+	}
+
+	known := flag.NewFlagSet("probe", flag.ContinueOnError)
+	defineCommandFlags(known)
+	sections := []map[string][]string{config.defaults}
+	for _, settings := range config.profiles {
+		sections = append(sections, settings)
+	}
+	for _, settings := range sections {
+		for key := range settings {
+			if hint, denied := configDeniedFlags[key]; denied {
+				return fmt.Errorf("%s cannot be set in %s; %s", key, path, hint)
+			}
+			_, credential := configCredentials[key]
+			if key != configDataDir && !credential && (known.Lookup(key) == nil || key == "profile" || key == "profiles") {
+				return fmt.Errorf("unknown setting %q in %s", key, path)
+			}
+		}
+	}
+
+	values := make(map[string][]string)
+	for key, value := range config.defaults {
+		values[key] = value
+	}
+	if profile != "" {
+		settings, ok := config.profiles[profile]
+		if !ok {
+			return fmt.Errorf("no profile %q in %s", profile, path)
+		}
+		for key, value := range settings {
+			values[key] = value
+		}
+	}
+
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	for key, value := range values {
+		if key == configDataDir {
+			bwEnvironment.dataDir = expandHome(value[0])
+			continue
+		}
+		if variable, ok := configCredentials[key]; ok {
+			credential, set := os.LookupEnv(value[0])
+			if !set {
+				console.warnf("Warning: %s names %s, which is not set", key, value[0])
+			}
+			os.Setenv(variable, credential)
+			continue
+		}
+		if flags.Lookup(key) == nil || explicit[key] || explicit[configShorthands[key]] {
+			continue
+		}
+		for _, v := range value {
+			if err := flags.Set(key, v); err != nil {
+				return fmt.Errorf("invalid setting %s in %s: %w", key, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+const envPrefix = "BITWARDEN_CLEANUP_"
+
+// envExcludedFlags cannot be set from the environment: the runs of
+// --profiles inherit it, and a recalled selection is read before it.
+var envExcludedFlags = []string{"profiles", "last", "recall", "new-only"}
+
+// envVariable names the variable that sets a flag: --max-items is set by
+// BITWARDEN_CLEANUP_MAX_ITEMS.
+func envVariable(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnvironment fills the flags that were not given on the command line
+// from BITWARDEN_CLEANUP_* variables. It runs before applyConfig, which only
+// fills the flags that are still unset, so the environment wins over the
+// config file. Empty variables count as unset.
+func applyEnvironment(flags *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || len(f.Name) == 1 {
+			return
+		}
+		variable := envVariable(f.Name)
+		value := os.Getenv(variable)
+		if value == "" {
+			return
+		}
+		if slices.Contains(envExcludedFlags, f.Name) {
+			err = fmt.Errorf("%s cannot be set from the environment", variable)
+			return
+		}
+		if explicit[f.Name] || explicit[configShorthands[f.Name]] {
+			return
+		}
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid %s: %w", variable, setErr)
+		}
+	})
+	return err
+}
+
+// loadConfig reads the config file, returning nil when there is none.
+func loadConfig() (*cleanupConfig, string, error) {
+	path, err := configFile(configFileName)
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, path, nil
+	}
+	if err != nil {
+		return nil, path, fmt.Errorf("error opening config file: %w", err)
+	}
+	defer file.Close()
+
+	config, err := parseConfig(bufio.NewScanner(file))
+	if err != nil {
+		return nil, path, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	return config, path, nil
+}
+
+// parseConfig reads the subset of TOML the config file needs: key = value
+// lines with strings, numbers, booleans or single-line arrays of them, and
+// [profiles.<name>] tables.
+func parseConfig(scanner *bufio.Scanner) (*cleanupConfig, error) {
+	config := &cleanupConfig{defaults: make(map[string][]string), profiles: make(map[string]map[string][]string)}
+	section := config.defaults
+
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			name, ok := strings.CutPrefix(strings.TrimSuffix(line, "]"), "[profiles.")
+			if !ok || !strings.HasSuffix(line, "]") || name == "" {
+				return nil, fmt.Errorf("line %d: expected a [profiles.<name>] table", number)
+			}
+			name = strings.Trim(name, `"`)
+			if _, exists := config.profiles[name]; !exists {
+				config.profiles[name] = make(map[string][]string)
+			}
+			section = config.profiles[name]
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", number)
+		}
+		key = strings.TrimSpace(key)
+		values, err := parseConfigValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+		section[key] = values
+	}
+	return config, scanner.Err()
+}
+
+func parseConfigValue(raw string) ([]string, error) {
+	if !strings.HasPrefix(raw, "[") {
+		value, err := parseConfigScalar(raw)
+		if err != nil {
+			return nil, err
+		}
+		return []string{value}, nil
+	}
+
+	inner, ok := strings.CutSuffix(strings.TrimPrefix(raw, "["), "]")
+	if !ok {
+		return nil, fmt.Errorf("arrays must be closed on the same line")
+	}
+	var values []string
+	for _, element := range splitConfigArray(inner) {
+		if element = strings.TrimSpace(element); element == "" {
+			continue
+		}
+		value, err := parseConfigScalar(element)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func parseConfigScalar(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case raw == "":
+		return "", fmt.Errorf("missing value")
+	}
+	return raw, nil
+}
+
+// splitConfigArray splits the elements of an array at the commas outside
+// quotes.
+func splitConfigArray(inner string) []string {
+	var elements []string
+	var quote rune
+	start := 0
+	for i, r := range inner {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || inner[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			elements = append(elements, inner[start:i])
+			start = i + 1
+		}
+	}
+	return append(elements, inner[start:])
+}
+
+// stripComment removes a # comment that is not inside a quoted string.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote && (quote == '\'' || line[i-1] != '\\') {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
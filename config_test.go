@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	input := `# defaults for every command
+batch = 4
+exclude = ["Bank *", 'single', "a,b"]  # trailing comment
+tag-in-name = true
+note = "say \"hi\" # not a comment"
+
+[profiles.weekly]
+search = 'old # items'
+retries = 5
+
+[profiles."team"]
+exclude = []
+`
+	config, err := parseConfig(bufio.NewScanner(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantDefaults := map[string][]string{
+		"batch":       {"4"},
+		"exclude":     {"Bank *", "single", "a,b"},
+		"tag-in-name": {"true"},
+		"note":        {`say "hi" # not a comment`},
+	}
+	if !reflect.DeepEqual(config.defaults, wantDefaults) {
+		t.Errorf("defaults = %q, want %q", config.defaults, wantDefaults)
+	}
+	wantProfiles := map[string]map[string][]string{
+		"weekly": {"search": {"old # items"}, "retries": {"5"}},
+		"team":   {"exclude": nil},
+	}
+	if !reflect.DeepEqual(config.profiles, wantProfiles) {
+		t.Errorf("profiles = %q, want %q", config.profiles, wantProfiles)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"batch 4", "line 1: expected key = value"},
+		{"\n[defaults]", "line 2: expected a [profiles.<name>] table"},
+		{"[profiles.]", "line 1: expected a [profiles.<name>] table"},
+		{"[profiles.weekly", "line 1: expected a [profiles.<name>] table"},
+		{"batch =", "line 1: missing value"},
+		{`search = "open`, `line 1: invalid string "open`},
+		{"search = 'open", "line 1: invalid string 'open"},
+		{`exclude = ["a",`, "line 1: arrays must be closed on the same line"},
+	}
+	for _, test := range tests {
+		_, err := parseConfig(bufio.NewScanner(strings.NewReader(test.input)))
+		if err == nil || err.Error() != test.want {
+			t.Errorf("parseConfig(%q) error = %v, want %q", test.input, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import "strings"
+
+// confirmEachItem asks about the matched items one at a time and returns
+// the ones the user agreed to. "a" agrees to the current item and all that
+// follow; "q" declines it and all that follow, keeping the earlier answers.
+func confirmEachItem(items []BitwardenItem, op itemOperation, options CommandOptions) []BitwardenItem {
+	var confirmed []BitwardenItem
+	for i, item := range items {
+		showConfirmItem(item, i, len(items), options)
+		for {
+			console.promptf("", "Do you want to %s this item? [y]es, [n]o, [a]ll remaining, [q]uit: ", op.confirmText)
+			answer, err := readLine()
+			if err != nil {
+				console.errorf("\nError reading input: %v", err)
+				return finishConfirmEach(confirmed, len(items))
+			}
+
+			switch strings.ToLower(answer) {
+			case "y", "yes":
+				confirmed = append(confirmed, item)
+			case "n", "no", "":
+			case "a", "all":
+				return finishConfirmEach(append(confirmed, items[i:]...), len(items))
+			case "q", "quit":
+				return finishConfirmEach(confirmed, len(items))
+			default:
+				console.warnf("Answer y, n, a or q")
+				continue
+			}
+			break
+		}
+	}
+	return finishConfirmEach(confirmed, len(items))
+}
+
+func showConfirmItem(item BitwardenItem, index, total int, options CommandOptions) {
+	console.linef("\n[%d/%d] %s (%s) [%s]", index+1, total, options.redact.name(item.Name), item.ID, item.typeName())
+	if username := item.username(); username != "" {
+		console.linef("   Username: %s", options.redact.username(username))
+	}
+	for _, uri := range item.uris() {
+		console.linef("   URI:      %s", options.redact.uri(uri))
+	}
+	console.linef("   Modified: %s", revisionDay(item))
+}
+
+func finishConfirmEach(confirmed []BitwardenItem, total int) []BitwardenItem {
+	console.infof(emojiInfo, "\nConfirmed %d of %d matched items", len(confirmed), total)
+	return confirmed
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+)
+
+// defaultTypeToConfirm is the number of items from which a permanent
+// deletion has to be confirmed by typing instead of with y.
+const defaultTypeToConfirm = 20
+
+// confirmTyped asks for the number of items, or the word DELETE, before a
+// large permanent deletion, so that a y typed out of habit does not start it.
+func confirmTyped(stats *DeleteStats, op itemOperation) bool {
+	console.warnf("This will %s %d items. They cannot be restored from the trash.", op.confirmText, stats.total)
+	console.promptf(emojiWarning, "Type %d or DELETE to continue: ", stats.total)
+	answer, err := readLine()
+	if err != nil {
+		console.errorf("Error reading confirmation: %v", err)
+		return false
+	}
+	if answer == strconv.Itoa(stats.total) || answer == "DELETE" {
+		return true
+	}
+	if answer != "" {
+		console.errorf("%q is neither %d nor DELETE", answer, stats.total)
+	}
+	return false
+}
+
+// waitCooldown counts down --cooldown before a confirmed deletion starts and
+// reports false when Ctrl-C aborts it.
+func waitCooldown(cooldown time.Duration) bool {
+	if cooldown <= 0 {
+		return true
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	console.infof(emojiWarning, "Starting in %s, press Ctrl-C to abort", cooldown)
+	deadline := time.Now().Add(cooldown)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			console.endProgress()
+			return true
+		}
+		console.progressf(emojiProgress, "Starting in %s...", remaining.Round(time.Second))
+		select {
+		case <-interrupt:
+			console.endProgress()
+			console.warnf("Aborted during the cooldown; nothing was changed")
+			return false
+		case <-time.After(min(remaining, time.Second)):
+		}
+	}
+}
+
+// typedConfirmationRequired reports whether --type-to-confirm applies. --yes
+// answers for the user, so it is left to --force-threshold.
+func typedConfirmationRequired(stats *DeleteStats, op itemOperation) bool {
+	return op.deletesItems && op.permanent && !unattended.yes && unattended.typeToConfirm > 0 && stats.total > unattended.typeToConfirm
+}
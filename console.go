@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// logLevel orders the console messages. --quiet shows the summary level
+// only, --verbose adds the debug level.
+type logLevel int
+
+const (
+	levelSummary logLevel = iota
+	levelInfo
+	levelDebug
+)
+
+// plainTags replace the emoji of errors and warnings with --no-emoji; the
+// other emoji are dropped.
+var plainTags = map[string]string{
+	emojiError:   "error:",
+	emojiWarning: "warning:",
+}
+
+// consoleLogger writes the human-readable messages of a run to ui. Errors,
+// warnings, prompts and the results of a run are on the summary level;
+// progress through a run is info, and the bw commands and per-item results
+// are debug.
+type consoleLogger struct {
+	mu          sync.Mutex
+	level       logLevel
+	plain       bool
+	progressing bool
+}
+
+var console = &consoleLogger{level: levelInfo, plain: os.Getenv("NO_COLOR") != ""}
+
+// consoleFlags are the values of --quiet, --verbose and --no-emoji.
+type consoleFlags struct {
+	quiet   bool
+	verbose bool
+	plain   bool
+}
+
+func defineConsoleFlags(flags *flag.FlagSet) *consoleFlags {
+	values := &consoleFlags{}
+	flags.BoolVar(&values.quiet, "quiet", false, "Only print errors, warnings, prompts and the summary of a run")
+	flags.BoolVar(&values.verbose, "verbose", false, "Also print every bw command and the result and duration of every item")
+	flags.BoolVar(&values.verbose, "v", false, "Also print every bw command and per-item results (shorthand)")
+	flags.BoolVar(&values.plain, "no-emoji", false, "Print plain text without emoji (also set by NO_COLOR)")
+	flags.BoolVar(&values.plain, "no-color", false, "Same as --no-emoji")
+	return values
+}
+
+func (values consoleFlags) apply() error {
+	if values.quiet && values.verbose {
+		return fmt.Errorf("--quiet and --verbose cannot be used together")
+	}
+	switch {
+	case values.quiet:
+		console.level = levelSummary
+	case values.verbose:
+		console.level = levelDebug
+	}
+	console.plain = console.plain || values.plain
+	return nil
+}
+
+func (l *consoleLogger) summaryf(emoji, format string, args ...any) {
+	l.logf(levelSummary, emoji, format, args...)
+}
+
+func (l *consoleLogger) infof(emoji, format string, args ...any) {
+	l.logf(levelInfo, emoji, format, args...)
+}
+
+func (l *consoleLogger) debugf(emoji, format string, args ...any) {
+	l.logf(levelDebug, emoji, format, args...)
+}
+
+func (l *consoleLogger) warnf(format string, args ...any) {
+	l.logf(levelSummary, emojiWarning, format, args...)
+}
+
+func (l *consoleLogger) errorf(format string, args ...any) {
+	l.logf(levelSummary, emojiError, format, args...)
+}
+
+// linef prints an info line without emoji, such as the entries of a list.
+func (l *consoleLogger) linef(format string, args ...any) {
+	l.logf(levelInfo, "", format, args...)
+}
+
+// logf prints one message followed by a newline. Newlines at the start of
+// format are printed before the emoji, so a message can be set apart from
+// the previous one.
+func (l *consoleLogger) logf(level logLevel, emoji, format string, args ...any) {
+	if level > l.level {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	body := strings.TrimLeft(message, "\n")
+	lead := message[:len(message)-len(body)]
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.progressing {
+		fmt.Fprintln(ui)
+		l.progressing = false
+	}
+	fmt.Fprintln(ui, lead+l.prefix(emoji, body)+body)
+}
+
+// promptf prints a question and leaves the cursor on its line; prompts are
+// always shown.
+func (l *consoleLogger) promptf(emoji, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprint(ui, l.prefix(emoji, message)+message)
+}
+
+// progressf redraws the progress line in place, clearing what is left of a
+// longer previous line. It is only drawn on a terminal, where the carriage
+// return works, and not with --quiet.
+func (l *consoleLogger) progressf(emoji, format string, args ...any) {
+	if l.level < levelInfo || !uiIsTerminal() {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprint(ui, l.prefix(emoji, message)+message+termClearLine+"\r")
+	l.progressing = true
+}
+
+// endProgress moves past the progress line, if one was drawn.
+func (l *consoleLogger) endProgress() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.progressing {
+		fmt.Fprintln(ui)
+		l.progressing = false
+	}
+}
+
+func (l *consoleLogger) prefix(emoji, message string) string {
+	switch {
+	case emoji == "":
+		return ""
+	case !l.plain:
+		return emoji + " "
+	}
+	tag, ok := plainTags[emoji]
+	if !ok || strings.HasPrefix(strings.ToLower(message), tag[:len(tag)-1]) {
+		return ""
+	}
+	return tag + " "
+}
+
+func uiIsTerminal() bool {
+	file, ok := ui.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
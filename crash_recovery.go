@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+var errRunInterrupted = errors.New("the run ended before this item was deleted")
+
+// crashChecked makes the process look for the checkpoints of crashed runs
+// only once.
+var crashChecked bool
+
+// checkpointFile is a checkpoint file as read back: its header, the items
+// in the order they were started with the last entry of each, and whether
+// the run that wrote it last ended.
+type checkpointFile struct {
+	path   string
+	header checkpointHeader
+	pid    int
+	ended  bool
+	order  []string
+	last   map[string]checkpointEntry
+}
+
+func (f checkpointFile) inFlight() []checkpointEntry {
+	var entries []checkpointEntry
+	for _, id := range f.order {
+		if f.last[id].Status == checkpointStarted {
+			entries = append(entries, f.last[id])
+		}
+	}
+	return entries
+}
+
+func readCheckpoint(path string) (checkpointFile, error) {
+	found := checkpointFile{path: path, last: make(map[string]checkpointEntry)}
+	file, err := os.Open(path)
+	if err != nil {
+		return found, fmt.Errorf("error opening checkpoint: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20)
+	if !scanner.Scan() {
+		return found, fmt.Errorf("checkpoint %s is empty", path)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &found.header); err != nil {
+		return found, fmt.Errorf("error parsing checkpoint %s: %w", path, err)
+	}
+	switch found.header.Version {
+	case checkpointVersion:
+	case 1:
+		// Checkpoints of version 1 were all written by the delete command
+		// and did not record the items in flight.
+		found.header.Resumable = true
+	default:
+		return found, fmt.Errorf("checkpoint %s has unsupported version %d", path, found.header.Version)
+	}
+	found.pid = found.header.PID
+
+	for scanner.Scan() {
+		var entry checkpointEntry
+		// A line cut short by a crash is ignored; its item counts as in
+		// flight, or is processed again on resume.
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		switch {
+		case entry.Status == checkpointResumed:
+			found.pid, found.ended = entry.PID, false
+		case entry.Status == checkpointEnded:
+			found.ended = true
+		case entry.ItemID != "":
+			previous, seen := found.last[entry.ItemID]
+			if !seen {
+				found.order = append(found.order, entry.ItemID)
+			}
+			if entry.Name == "" {
+				entry.Name = previous.Name
+			}
+			found.last[entry.ItemID] = entry
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return found, fmt.Errorf("error reading checkpoint %s: %w", path, err)
+	}
+	return found, nil
+}
+
+// findCrashedRuns returns the checkpoints in the checkpoints directory of
+// runs that deleted items and neither ended nor are still running, oldest
+// first.
+func findCrashedRuns() ([]checkpointFile, error) {
+	dir, err := configFile(checkpointsDir)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var crashed []checkpointFile
+	for _, path := range paths {
+		found, err := readCheckpoint(path)
+		if err != nil {
+			console.warnf("Warning: %v", err)
+			continue
+		}
+		if !found.header.DeletesItems || found.ended || len(found.order) == 0 || found.pid == 0 || found.pid == os.Getpid() || processRunning(found.pid) {
+			continue
+		}
+		crashed = append(crashed, found)
+	}
+	return crashed, nil
+}
+
+// recoverCrashedRuns looks for the checkpoints of runs that were killed or
+// crashed before a run that deletes items starts, shows the items they were
+// in the middle of and offers to check them against the vault. The checked
+// run is recorded as if it had ended, so that 'undo' and 'runs' know about
+// it. --yes does not answer this: the check is left for a run with a
+// terminal.
+func recoverCrashedRuns() error {
+	if crashChecked {
+		return nil
+	}
+	crashChecked = true
+
+	crashed, err := findCrashedRuns()
+	if err != nil || len(crashed) == 0 {
+		return err
+	}
+	for _, found := range crashed {
+		inFlight := found.inFlight()
+		run := found.header.Operation + " run"
+		if len(found.header.Args) > 0 {
+			run += " " + shellJoin(found.header.Args)
+		}
+		console.warnf("The %s started %s did not finish: %d items were processed, %d were in flight when it stopped",
+			run, found.header.CreatedAt.Local().Format("2006-01-02 15:04"), len(found.order)-len(inFlight), len(inFlight))
+		for _, entry := range inFlight {
+			console.linef("   - %s (%s)", entry.Name, entry.ItemID)
+		}
+		if unattended.yes {
+			console.infof(emojiInfo, "Not checking %s with --yes; run a command that deletes items without --yes to check it", found.path)
+			continue
+		}
+		question := "Record the run for undo before continuing?"
+		if len(inFlight) > 0 {
+			question = "Check these items against the vault and record the run for undo before continuing?"
+		}
+		if !promptYesNo(question) {
+			console.infof(emojiInfo, "Keeping the checkpoint %s; it is offered again on the next run", found.path)
+			continue
+		}
+		if err := reconcileCrashedRun(found); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileCrashedRun finds out what became of the items a crashed run left
+// in flight and records the run. The checkpoint keeps what was found, so
+// that --resume skips the items that were deleted after all, and is marked
+// as ended; a checkpoint that cannot be resumed is removed.
+func reconcileCrashedRun(found checkpointFile) error {
+	if err := syncBitwarden("before checking the crashed run"); err != nil {
+		console.warnf("Warning: Sync failed but continuing")
+	}
+
+	permanent := found.header.Permanent
+	var items []BitwardenItem
+	var results []itemResult
+	var checked []checkpointEntry
+	for _, id := range found.order {
+		entry := found.last[id]
+		result := itemResult{ItemID: id, Name: entry.Name, Operation: found.header.Operation}
+		switch entry.Status {
+		case checkpointStarted:
+			state, err := vaultItemState(id)
+			if err != nil {
+				return fmt.Errorf("error checking item %s: %w", id, err)
+			}
+			console.linef("   %s (%s): %s", entry.Name, id, state)
+			if !permanent && state == itemStateTrash || permanent && state == itemStateGone {
+				checked = append(checked, checkpointEntry{Time: time.Now().UTC(), ItemID: id, Status: resultDone})
+				break
+			}
+			result.Err = errRunInterrupted
+			if state == itemStateGone {
+				result.Err = errItemGone
+				checked = append(checked, checkpointEntry{Time: time.Now().UTC(), ItemID: id, Status: resultGone})
+			}
+		case resultGone:
+			result.Err = errItemGone
+		case resultFailed:
+			result.Err = errors.New(entry.Error)
+		}
+		items = append(items, BitwardenItem{ID: id, Name: entry.Name})
+		results = append(results, result)
+	}
+
+	stats := &DeleteStats{total: len(items), completed: len(results), results: results}
+	recordDeleteRun(items, stats, itemOperation{verb: found.header.Operation, permanent: permanent}, CommandOptions{})
+	if err := closeCrashedRun(found, checked); err != nil {
+		return err
+	}
+	if remaining := countResults(results, resultFailed); remaining > 0 {
+		console.infof(emojiInfo, "%d items were not deleted; running the same selection again matches them", remaining)
+	}
+	return nil
+}
+
+// closeCrashedRun appends the checked entries and marks the run as ended, or
+// removes a checkpoint that cannot be resumed or has nothing left to do.
+func closeCrashedRun(found checkpointFile, checked []checkpointEntry) error {
+	processed := 0
+	for _, entry := range found.last {
+		if entry.Status == resultDone || entry.Status == resultGone {
+			processed++
+		}
+	}
+	if !found.header.Resumable || processed+len(checked) >= len(found.header.ItemIDs) {
+		if err := os.Remove(found.path); err != nil {
+			return fmt.Errorf("error removing checkpoint: %w", err)
+		}
+		return nil
+	}
+
+	file, err := os.OpenFile(found.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening checkpoint: %w", err)
+	}
+	cp := &checkpoint{path: found.path, resumable: true, file: file}
+	defer file.Close()
+	for _, entry := range append(checked, checkpointEntry{Time: time.Now().UTC(), Status: checkpointEnded}) {
+		if err := cp.write(entry); err != nil {
+			return err
+		}
+	}
+	console.infof(emojiInfo, "Resume the remaining items with: %s --resume %s", filepath.Base(os.Args[0]), shellJoin([]string{found.path}))
+	return nil
+}
+
+const (
+	itemStateVault = "still in the vault"
+	itemStateTrash = "in the trash"
+	itemStateGone  = "deleted permanently"
+)
+
+func vaultItemState(id string) (string, error) {
+	data, err := vault.GetItem(id)
+	if errors.Is(err, errItemGone) {
+		return itemStateGone, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var item BitwardenItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		return "", fmt.Errorf("error parsing item: %w", err)
+	}
+	if item.inTrash() {
+		return itemStateTrash, nil
+	}
+	return itemStateVault, nil
+}
+
+func countResults(results []itemResult, status string) int {
+	count := 0
+	for _, result := range results {
+		if result.status() == status {
+			count++
+		}
+	}
+	return count
+}
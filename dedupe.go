@@ -0,0 +1,277 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var dedupeKeyFields = map[string]func(item BitwardenItem) string{
+	"name": func(item BitwardenItem) string {
+		return strings.ToLower(strings.Join(strings.Fields(item.Name), " "))
+	},
+	"username": func(item BitwardenItem) string {
+		return strings.ToLower(strings.TrimSpace(item.username()))
+	},
+	"uri": func(item BitwardenItem) string {
+		var uris []string
+		for _, uri := range item.uris() {
+			uris = append(uris, normalizeDedupeURI(uri))
+		}
+		sort.Strings(uris)
+		return strings.Join(uris, " ")
+	},
+	"type": func(item BitwardenItem) string {
+		return item.typeName()
+	},
+}
+
+// duplicateSet is a group of items with the same key. The most recently
+// revised copy is kept, and a copy in the trash only when all of them are.
+type duplicateSet struct {
+	keep       BitwardenItem
+	duplicates []BitwardenItem
+}
+
+func runDedupeCommand(args []string) error {
+	flags := flag.NewFlagSet("dedupe", flag.ExitOnError)
+	keyFields := flags.String("key", "name,username,uri", "Comma-separated fields that make items duplicates of each other (name, username, uri, type)")
+	deleteDuplicates := flags.Bool("delete", false, "Delete every copy but the most recently revised one")
+	merge := flags.Bool("merge", false, "Add the URIs, custom fields, notes and passwords of the older copies to the one kept, then delete the older copies")
+	permanent := flags.Bool("permanent", false, "With --delete or --merge, permanently delete items (skip trash)")
+	includeTrash := flags.Bool("include-trash", false, "Also compare with the items in the trash; trashed copies of kept items are purged permanently, so that restoring them cannot bring a duplicate back")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	options.isPermanent = *permanent
+	if *permanent && !*deleteDuplicates && !*merge {
+		return fmt.Errorf("--permanent can only be used with --delete or --merge")
+	}
+
+	key, err := dedupeKey(*keyFields)
+	if err != nil {
+		return err
+	}
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return err
+	}
+	if *includeTrash {
+		trashOptions := options
+		trashOptions.failOnEmpty = false
+		trashed, err := fetchMatchingTrashItems(trashOptions, filters, 0)
+		if err != nil {
+			return err
+		}
+		items = append(items, trashed...)
+	}
+
+	sets := findDuplicateItems(items, key)
+	if len(sets) == 0 {
+		console.infof(emojiSuccess, "No duplicate items found")
+		return nil
+	}
+
+	var duplicates, trashed []BitwardenItem
+	console.infof(emojiSearch, "Found %d sets of duplicate items:", len(sets))
+	for _, set := range sets {
+		console.linef("   keep   %s, revised %s%s", describeItem(set.keep, options), revisionDay(set.keep), trashNote(set.keep))
+		for _, duplicate := range set.duplicates {
+			if duplicate.inTrash() {
+				console.linef("   purge  %s, revised %s (in trash)", describeItem(duplicate, options), revisionDay(duplicate))
+				trashed = append(trashed, duplicate)
+			} else {
+				console.linef("   delete %s, revised %s", describeItem(duplicate, options), revisionDay(duplicate))
+				duplicates = append(duplicates, duplicate)
+			}
+		}
+	}
+	selection := append(append([]BitwardenItem(nil), duplicates...), trashed...)
+	if err := writeIDList(options.idsOut, selection); err != nil {
+		return err
+	}
+
+	if !*deleteDuplicates && !*merge {
+		recordFilterHistory(options, selection)
+		console.infof(emojiInfo, "Run with --delete to remove the %d older copies, or --merge to keep their data first", len(selection))
+		return nil
+	}
+
+	op := deleteOperation(options.isPermanent)
+	displayOperationMode(op)
+	duplicates = protectSensitiveItems(duplicates, options)
+	stats := &DeleteStats{total: len(duplicates), protected: options.protection.count()}
+	if stats.total == 0 {
+		return purgeTrashedDuplicates(trashed, selection, options)
+	}
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	var plans []mergePlan
+	if *merge {
+		plans = planMerges(sets, duplicates)
+		showMergePlans(plans, options)
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	recordFilterHistory(options, selection)
+	if *merge {
+		duplicates = mergeDuplicates(plans, options)
+		stats.total = len(duplicates)
+		if stats.total == 0 {
+			return fmt.Errorf("no copies were deleted because merging their data failed")
+		}
+	}
+	if err := executeItems(duplicates, stats, op, options); err != nil {
+		return err
+	}
+	return purgeTrashedDuplicates(trashed, nil, options)
+}
+
+// purgeTrashedDuplicates permanently deletes the copies that --include-trash
+// found in the trash. Their data is not merged: they were deleted before.
+// A non-nil selection is recorded for --last once the purge is confirmed.
+func purgeTrashedDuplicates(trashed, selection []BitwardenItem, options CommandOptions) error {
+	if len(trashed) == 0 {
+		return nil
+	}
+	op := deleteOperation(true)
+	op.modeText = "Purge of duplicate copies in the trash (they will be permanently deleted)"
+	displayOperationMode(op)
+	trashed = protectSensitiveItems(trashed, options)
+	stats := &DeleteStats{total: len(trashed)}
+	if stats.total == 0 {
+		return nil
+	}
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	if selection != nil {
+		recordFilterHistory(options, selection)
+	}
+	return executeItems(trashed, stats, op, options)
+}
+
+func (item BitwardenItem) inTrash() bool {
+	return item.DeletedDate != ""
+}
+
+func trashNote(item BitwardenItem) string {
+	if item.inTrash() {
+		return " (in trash)"
+	}
+	return ""
+}
+
+// dedupeKey builds the function that computes the duplicate key of an item
+// from the comma-separated field names.
+func dedupeKey(value string) (func(item BitwardenItem) string, error) {
+	var fields []func(item BitwardenItem) string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		field, ok := dedupeKeyFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown --key field %q (expected name, username, uri or type)", name)
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("usage: %s dedupe [--key name,username,uri] [--delete | --merge] [--permanent]", filepath.Base(os.Args[0]))
+	}
+
+	return func(item BitwardenItem) string {
+		parts := make([]string, len(fields))
+		empty := true
+		for i, field := range fields {
+			parts[i] = field(item)
+			empty = empty && parts[i] == ""
+		}
+		if empty {
+			return ""
+		}
+		return strings.Join(parts, "\x00")
+	}, nil
+}
+
+// findDuplicateItems groups items by key and keeps the most recently revised
+// item of every group with more than one member, preferring items outside
+// the trash. Items whose key fields are all empty are never duplicates.
+func findDuplicateItems(items []BitwardenItem, key func(item BitwardenItem) string) []duplicateSet {
+	byKey := make(map[string][]BitwardenItem)
+	var keys []string
+	for _, item := range items {
+		k := key(item)
+		if k == "" {
+			continue
+		}
+		if _, seen := byKey[k]; !seen {
+			keys = append(keys, k)
+		}
+		byKey[k] = append(byKey[k], item)
+	}
+
+	var sets []duplicateSet
+	for _, k := range keys {
+		members := byKey[k]
+		if len(members) < 2 {
+			continue
+		}
+		sort.SliceStable(members, func(i, j int) bool {
+			if members[i].inTrash() != members[j].inTrash() {
+				return !members[i].inTrash()
+			}
+			return revisionTime(members[i]).After(revisionTime(members[j]))
+		})
+		sets = append(sets, duplicateSet{keep: members[0], duplicates: members[1:]})
+	}
+	sort.SliceStable(sets, func(i, j int) bool { return strings.ToLower(sets[i].keep.Name) < strings.ToLower(sets[j].keep.Name) })
+	return sets
+}
+
+// normalizeDedupeURI ignores the scheme, a leading "www." and a trailing
+// slash, which often differ between copies of an imported login.
+func normalizeDedupeURI(uri string) string {
+	uri = strings.ToLower(strings.TrimSpace(uri))
+	for _, scheme := range []string{"https://", "http://"} {
+		uri = strings.TrimPrefix(uri, scheme)
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(uri, "www."), "/")
+}
+
+func revisionTime(item BitwardenItem) time.Time {
+	revised, _ := time.Parse(time.RFC3339, item.RevisionDate)
+	return revised
+}
+
+func revisionDay(item BitwardenItem) string {
+	if revised := revisionTime(item); !revised.IsZero() {
+		return revised.Local().Format(reviewDateLayout)
+	}
+	return "unknown"
+}
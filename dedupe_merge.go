@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// mergePlan is the data that the copies of a duplicate set have and the kept
+// item lacks. Fields are copied verbatim from the copies' JSON, so hidden and
+// linked fields keep their type.
+type mergePlan struct {
+	keep       BitwardenItem
+	duplicates []BitwardenItem
+	uris       []string
+	fields     []map[string]any
+	notes      []string
+	passwords  []string
+	totp       string
+}
+
+// planMerges works out what each set's copies add to its kept item. Only the
+// copies listed in removing take part; protected copies are neither merged
+// nor deleted.
+func planMerges(sets []duplicateSet, removing []BitwardenItem) []mergePlan {
+	removed := make(map[string]bool, len(removing))
+	for _, item := range removing {
+		removed[item.ID] = true
+	}
+
+	var plans []mergePlan
+	for _, set := range sets {
+		plan := mergePlan{keep: set.keep}
+		for _, duplicate := range set.duplicates {
+			if removed[duplicate.ID] {
+				plan.duplicates = append(plan.duplicates, duplicate)
+				plan.add(duplicate)
+			}
+		}
+		if len(plan.duplicates) > 0 {
+			plans = append(plans, plan)
+		}
+	}
+	return plans
+}
+
+func (plan *mergePlan) add(duplicate BitwardenItem) {
+	keep := plan.keep
+	if keep.Login != nil && duplicate.Login != nil {
+		for _, uri := range duplicate.uris() {
+			if !slices.ContainsFunc(append(keep.uris(), plan.uris...), func(existing string) bool {
+				return normalizeDedupeURI(existing) == normalizeDedupeURI(uri)
+			}) {
+				plan.uris = append(plan.uris, uri)
+			}
+		}
+		password := duplicate.Login.Password
+		if password != "" && password != keep.Login.Password && !slices.Contains(plan.passwords, password) {
+			plan.passwords = append(plan.passwords, password)
+		}
+		if keep.Login.TOTP == "" && plan.totp == "" {
+			plan.totp = duplicate.Login.TOTP
+		}
+	}
+
+	for _, field := range rawFields(duplicate) {
+		name, _ := field["name"].(string)
+		value, _ := field["value"].(string)
+		known := slices.ContainsFunc(keep.Fields, func(existing BitwardenField) bool {
+			return existing.Name == name && existing.Value == value
+		}) || slices.ContainsFunc(plan.fields, func(existing map[string]any) bool {
+			return existing["name"] == name && existing["value"] == value
+		})
+		if !known {
+			plan.fields = append(plan.fields, field)
+		}
+	}
+
+	notes := strings.TrimSpace(duplicate.Notes)
+	if notes != "" && !strings.Contains(keep.Notes, notes) && !slices.Contains(plan.notes, notes) {
+		plan.notes = append(plan.notes, notes)
+	}
+}
+
+// rawFields returns the custom fields of an item as bw listed them.
+func rawFields(item BitwardenItem) []map[string]any {
+	var parsed struct {
+		Fields []map[string]any `json:"fields"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(item.raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parsed); err != nil {
+		return nil
+	}
+	return parsed.Fields
+}
+
+func (plan mergePlan) empty() bool {
+	return len(plan.uris) == 0 && len(plan.fields) == 0 && len(plan.notes) == 0 && len(plan.passwords) == 0 && plan.totp == ""
+}
+
+// summary lists what is merged without showing any values, since fields and
+// passwords may be secret.
+func (plan mergePlan) summary() string {
+	var parts []string
+	count := func(n int, singular, plural string) {
+		if n == 1 {
+			parts = append(parts, "1 "+singular)
+		} else if n > 1 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, plural))
+		}
+	}
+	count(len(plan.uris), "URI", "URIs")
+	count(len(plan.fields), "custom field", "custom fields")
+	count(len(plan.notes), "note", "notes")
+	count(len(plan.passwords), "password into the history", "passwords into the history")
+	if plan.totp != "" {
+		parts = append(parts, "the TOTP secret")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// apply adds the planned data to the kept item's current JSON. Old passwords
+// of the copies go into the password history, so they can still be looked up
+// in the web vault.
+func (plan mergePlan) apply(item map[string]any) bool {
+	if login, ok := item["login"].(map[string]any); ok {
+		uris, _ := login["uris"].([]any)
+		for _, uri := range plan.uris {
+			uris = append(uris, map[string]any{"uri": uri, "match": nil})
+		}
+		login["uris"] = uris
+		if totp, _ := login["totp"].(string); totp == "" && plan.totp != "" {
+			login["totp"] = plan.totp
+		}
+
+		history, _ := item["passwordHistory"].([]any)
+		now := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+		for _, password := range plan.passwords {
+			if slices.ContainsFunc(history, func(entry any) bool {
+				old, _ := entry.(map[string]any)
+				return old["password"] == password
+			}) {
+				continue
+			}
+			history = append(history, map[string]any{"lastUsedDate": now, "password": password})
+		}
+		item["passwordHistory"] = history
+	}
+
+	fields, _ := item["fields"].([]any)
+	for _, field := range plan.fields {
+		fields = append(fields, field)
+	}
+	item["fields"] = fields
+
+	if len(plan.notes) > 0 {
+		notes, _ := item["notes"].(string)
+		item["notes"] = strings.TrimSpace(strings.Join(append([]string{notes}, plan.notes...), "\n\n"))
+	}
+	return true
+}
+
+func showMergePlans(plans []mergePlan, options CommandOptions) {
+	header := false
+	for _, plan := range plans {
+		if plan.empty() {
+			continue
+		}
+		if !header {
+			console.infof(emojiInfo, "Before the copies are deleted, their data is merged into the items that are kept:")
+			header = true
+		}
+		console.linef("   %s <- %s", describeItem(plan.keep, options), plan.summary())
+	}
+	if !header {
+		console.infof(emojiInfo, "The copies hold no data that the kept items lack")
+	}
+}
+
+// mergeDuplicates edits every kept item that gains data and returns the
+// copies that may now be deleted. When an edit fails, the copies of that set
+// are kept so that nothing is lost.
+func mergeDuplicates(plans []mergePlan, options CommandOptions) []BitwardenItem {
+	var removable []BitwardenItem
+	merged := 0
+	for _, plan := range plans {
+		if plan.empty() {
+			removable = append(removable, plan.duplicates...)
+			continue
+		}
+		if err := updateItem(plan.keep.ID, plan.apply); err != nil {
+			console.errorf("Error merging into %s, keeping its %d copies: %v", describeItem(plan.keep, options), len(plan.duplicates), err)
+			outcome.failed = true
+			continue
+		}
+		merged++
+		console.infof(emojiSuccess, "Merged %s into %q", plan.summary(), options.redact.name(plan.keep.Name))
+		removable = append(removable, plan.duplicates...)
+	}
+	if merged > 0 {
+		console.summaryf(emojiSuccess, "Merged the data of their copies into %d items", merged)
+	}
+	return removable
+}
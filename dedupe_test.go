@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// parseItem reads an item the way bw lists it, keeping its raw JSON.
+func parseItem(t *testing.T, data string) BitwardenItem {
+	t.Helper()
+	var item BitwardenItem
+	if err := json.Unmarshal([]byte(data), &item); err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+func TestDedupeKey(t *testing.T) {
+	first := parseItem(t, `{"id":"1","type":1,"name":"My  Bank","login":{"username":"Alice ","uris":[{"uri":"https://www.bank.com/"},{"uri":"http://b.example"}]}}`)
+	second := parseItem(t, `{"id":"2","type":1,"name":"my bank","login":{"username":"alice","uris":[{"uri":"b.example"},{"uri":"bank.com"}]}}`)
+	other := parseItem(t, `{"id":"3","type":1,"name":"my bank","login":{"username":"bob","uris":[{"uri":"https://bank.org"}]}}`)
+	note := parseItem(t, `{"id":"4","type":2,"name":"my bank"}`)
+	tests := []struct {
+		fields string
+		a, b   BitwardenItem
+		same   bool
+	}{
+		{"name", first, second, true},
+		{"name,username", first, second, true},
+		{" URI , username ", first, second, true},
+		{"name,username", first, other, false},
+		{"uri", second, other, false},
+		{"name", first, note, true},
+		{"name,type", first, note, false},
+	}
+	for _, test := range tests {
+		key, err := dedupeKey(test.fields)
+		if err != nil {
+			t.Errorf("dedupeKey(%q): %v", test.fields, err)
+			continue
+		}
+		if same := key(test.a) == key(test.b); same != test.same {
+			t.Errorf("dedupeKey(%q): items %s and %s share a key = %v, want %v", test.fields, test.a.ID, test.b.ID, same, test.same)
+		}
+	}
+
+	// An item without any of the key fields is never a duplicate.
+	key, err := dedupeKey("username,uri")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := key(note); got != "" {
+		t.Errorf("key of an item without the fields = %q, want empty", got)
+	}
+}
+
+func TestDedupeKeyErrors(t *testing.T) {
+	for _, fields := range []string{"", " , ", "name,password", "folder"} {
+		if _, err := dedupeKey(fields); err == nil {
+			t.Errorf("dedupeKey(%q) accepted the fields", fields)
+		}
+	}
+}
+
+func TestFindDuplicateItems(t *testing.T) {
+	items := []BitwardenItem{
+		{ID: "old", Name: "Bank", RevisionDate: "2023-01-01T00:00:00Z"},
+		{ID: "new", Name: "bank", RevisionDate: "2024-01-01T00:00:00Z"},
+		{ID: "trashed", Name: "bank", RevisionDate: "2025-01-01T00:00:00Z", DeletedDate: "2025-02-01T00:00:00Z"},
+		{ID: "single", Name: "mail", RevisionDate: "2024-01-01T00:00:00Z"},
+		{ID: "unnamed1"},
+		{ID: "unnamed2"},
+		{ID: "a2", Name: "Alpha", RevisionDate: "2022-01-01T00:00:00Z", DeletedDate: "2022-02-01T00:00:00Z"},
+		{ID: "a1", Name: "alpha", RevisionDate: "2021-01-01T00:00:00Z", DeletedDate: "2022-02-01T00:00:00Z"},
+	}
+	key, err := dedupeKey("name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got [][]string
+	for _, set := range findDuplicateItems(items, key) {
+		ids := []string{set.keep.ID}
+		for _, duplicate := range set.duplicates {
+			ids = append(ids, duplicate.ID)
+		}
+		got = append(got, ids)
+	}
+	want := [][]string{{"a2", "a1"}, {"new", "old", "trashed"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findDuplicateItems = %q, want %q", got, want)
+	}
+}
+
+func TestPlanMerges(t *testing.T) {
+	keep := parseItem(t, `{"id":"keep","type":1,"name":"bank","notes":"main account",
+		"login":{"username":"alice","password":"current","uris":[{"uri":"https://bank.com"}]},
+		"fields":[{"name":"pin","value":"1234","type":1}]}`)
+	copy1 := parseItem(t, `{"id":"copy1","type":1,"name":"bank","notes":"main account",
+		"login":{"username":"alice","password":"older","totp":"otpauth://totp/bank","uris":[{"uri":"http://www.bank.com/"},{"uri":"https://app.bank.com"}]},
+		"fields":[{"name":"pin","value":"1234","type":1},{"name":"recovery","value":"xyz","type":1}]}`)
+	copy2 := parseItem(t, `{"id":"copy2","type":1,"name":"bank","notes":"old card",
+		"login":{"username":"alice","password":"older","uris":[{"uri":"https://app.bank.com/"}]}}`)
+	protected := parseItem(t, `{"id":"protected","type":1,"name":"bank","notes":"do not merge",
+		"login":{"username":"alice","password":"secret"}}`)
+	sets := []duplicateSet{
+		{keep: keep, duplicates: []BitwardenItem{copy1, copy2, protected}},
+		{keep: BitwardenItem{ID: "other"}, duplicates: []BitwardenItem{{ID: "untouched"}}},
+	}
+
+	plans := planMerges(sets, []BitwardenItem{copy1, copy2})
+	if len(plans) != 1 {
+		t.Fatalf("planMerges returned %d plans, want 1", len(plans))
+	}
+	plan := plans[0]
+	var duplicates []string
+	for _, duplicate := range plan.duplicates {
+		duplicates = append(duplicates, duplicate.ID)
+	}
+	if want := []string{"copy1", "copy2"}; !reflect.DeepEqual(duplicates, want) {
+		t.Errorf("duplicates = %q, want %q", duplicates, want)
+	}
+	if want := []string{"https://app.bank.com"}; !reflect.DeepEqual(plan.uris, want) {
+		t.Errorf("uris = %q, want %q", plan.uris, want)
+	}
+	if want := []string{"older"}; !reflect.DeepEqual(plan.passwords, want) {
+		t.Errorf("passwords = %q, want %q", plan.passwords, want)
+	}
+	if want := "otpauth://totp/bank"; plan.totp != want {
+		t.Errorf("totp = %q, want %q", plan.totp, want)
+	}
+	if want := []string{"old card"}; !reflect.DeepEqual(plan.notes, want) {
+		t.Errorf("notes = %q, want %q", plan.notes, want)
+	}
+	if len(plan.fields) != 1 || plan.fields[0]["name"] != "recovery" {
+		t.Errorf("fields = %v, want only the recovery field", plan.fields)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// deletionListRow is one approved entry of a --csv list. Either the ID or
+// the name identifies the item; username and URI, when filled in, must
+// match the live item as well.
+type deletionListRow struct {
+	line     int
+	id       string
+	name     string
+	username string
+	uri      string
+}
+
+type deletionListMismatch struct {
+	row    deletionListRow
+	reason string
+}
+
+func readDeletionList(path string) ([]deletionListRow, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening CSV list: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV header of %s: %w", path, err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	_, hasID := columns["id"]
+	_, hasName := columns["name"]
+	if !hasID && !hasName {
+		return nil, fmt.Errorf("CSV list %s needs an id or name column", path)
+	}
+
+	var rows []deletionListRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV list %s: %w", path, err)
+		}
+
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		row := deletionListRow{line: line, id: field("id"), name: field("name"), username: field("username"), uri: field("uri")}
+		if row.id == "" && row.name == "" {
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// resolveDeletionList returns the vault items approved by the CSV list at
+// path. Rows that do not identify exactly one item matching all of their
+// columns are reported and left out, so a stale or mistyped spreadsheet
+// never deletes the wrong item.
+func resolveDeletionList(path string, vault []BitwardenItem) ([]BitwardenItem, error) {
+	rows, err := readDeletionList(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []BitwardenItem
+	var mismatches []deletionListMismatch
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		item, reason := resolveDeletionListRow(row, vault)
+		if reason != "" {
+			mismatches = append(mismatches, deletionListMismatch{row: row, reason: reason})
+			continue
+		}
+		if !seen[item.ID] {
+			seen[item.ID] = true
+			matched = append(matched, item)
+		}
+	}
+
+	console.infof(emojiInfo, "CSV list %s: %d of %d rows validated", path, len(rows)-len(mismatches), len(rows))
+	if len(mismatches) > 0 {
+		console.warnf("%d rows do not match the vault and will be skipped:", len(mismatches))
+		for _, mismatch := range mismatches {
+			console.linef("   line %d (%s): %s", mismatch.row.line, mismatch.row.label(), mismatch.reason)
+		}
+	}
+	return matched, nil
+}
+
+func resolveDeletionListRow(row deletionListRow, vault []BitwardenItem) (BitwardenItem, string) {
+	var candidates []BitwardenItem
+	for _, item := range vault {
+		if row.id != "" && item.ID != row.id {
+			continue
+		}
+		if row.id == "" && !strings.EqualFold(strings.TrimSpace(item.Name), row.name) {
+			continue
+		}
+		candidates = append(candidates, item)
+	}
+	if len(candidates) == 0 {
+		if row.id != "" {
+			return BitwardenItem{}, "no item with this ID"
+		}
+		return BitwardenItem{}, "no item with this name"
+	}
+
+	var matching []BitwardenItem
+	var reason string
+	for _, item := range candidates {
+		switch {
+		case row.id != "" && row.name != "" && !strings.EqualFold(strings.TrimSpace(item.Name), row.name):
+			reason = fmt.Sprintf("name is %q", item.Name)
+		case row.username != "" && !strings.EqualFold(item.username(), row.username):
+			reason = fmt.Sprintf("username is %q", item.username())
+		case row.uri != "" && !matchesExpectedURI(row.uri, item.uris()):
+			reason = fmt.Sprintf("no URI matches %q", row.uri)
+		default:
+			matching = append(matching, item)
+		}
+	}
+
+	switch len(matching) {
+	case 1:
+		return matching[0], ""
+	case 0:
+		if len(candidates) > 1 {
+			return BitwardenItem{}, fmt.Sprintf("none of the %d items with this name match the expected username/URI", len(candidates))
+		}
+		return BitwardenItem{}, reason
+	default:
+		return BitwardenItem{}, fmt.Sprintf("ambiguous: %d items match; add an id, username or uri column", len(matching))
+	}
+}
+
+func (row deletionListRow) label() string {
+	if row.id != "" {
+		return row.id
+	}
+	return row.name
+}
+
+func (item BitwardenItem) username() string {
+	if item.Login == nil {
+		return ""
+	}
+	return item.Login.Username
+}
+
+// matchesExpectedURI compares full URIs loosely (case, trailing slash) and a
+// bare host such as "example.com" against the host of each URI.
+func matchesExpectedURI(expected string, uris []string) bool {
+	normalize := func(uri string) string { return strings.TrimSuffix(strings.ToLower(uri), "/") }
+
+	bareHost := !strings.Contains(expected, "://")
+	for _, uri := range uris {
+		if normalize(uri) == normalize(expected) {
+			return true
+		}
+		if bareHost {
+			if parsed, err := url.Parse(uri); err == nil && strings.EqualFold(parsed.Hostname(), expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Organization member status of a revoked (deactivated) member.
+const memberStatusRevoked = -1
+
+type organizationMember struct {
+	ID     string `json:"id"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	Status int    `json:"status"`
+}
+
+type collectionAssignment struct {
+	ID string `json:"id"`
+}
+
+// organizationCollection is a collection with its access assignments, as
+// returned by 'bw get org-collection'.
+type organizationCollection struct {
+	ID             string                 `json:"id"`
+	OrganizationID string                 `json:"organizationId"`
+	Name           string                 `json:"name"`
+	Groups         []collectionAssignment `json:"groups"`
+	Users          []collectionAssignment `json:"users"`
+}
+
+type departedCollection struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	DepartedMembers []string `json:"departedMembers"`
+}
+
+type departedItem struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Collections []string `json:"collections"`
+}
+
+type departedReport struct {
+	OrganizationID string               `json:"organizationId"`
+	CreatedAt      time.Time            `json:"createdAt"`
+	Collections    []departedCollection `json:"collections"`
+	Items          []departedItem       `json:"items"`
+	Action         string               `json:"action"`
+}
+
+func runOrgCommand(args []string) error {
+	usage := fmt.Errorf("usage: %s org departed --organization <id> [--reassign-to <collection> | --delete-items [--permanent]] [--delete-collections] [--out report.json]", filepath.Base(os.Args[0]))
+	if len(args) == 0 || args[0] != "departed" {
+		return usage
+	}
+
+	flags := flag.NewFlagSet("org departed", flag.ExitOnError)
+	organizationID := flags.String("organization", "", "ID of the organization to clean up (required)")
+	reassignTo := flags.String("reassign-to", "", "Move affected items into this collection (ID or name)")
+	deleteItems := flags.Bool("delete-items", false, "Delete affected items")
+	permanent := flags.Bool("permanent", false, "With --delete-items, permanently delete items (skip trash)")
+	deleteCollections := flags.Bool("delete-collections", false, "Delete the orphaned collections once their items are handled")
+	out := flags.String("out", "", "Write the full report to this JSON file")
+	batchSize := flags.Int("batch", 1, "Number of items to process in parallel")
+	flags.IntVar(batchSize, "b", 1, "Number of items to process in parallel (shorthand)")
+	redact := &redactor{}
+	flags.Var(redact, "redact", "Hash or truncate these comma-separated fields in output and the report (names, usernames; append :truncate to truncate instead of hash)")
+	excludes := &excludePatterns{}
+	flags.Var(excludes, "exclude", "Never reassign or delete items whose name matches this glob or /regular expression/; repeatable")
+	protectFile := flags.String("protect-file", "", "Never reassign or delete the items named in this file (one item name or ID per line)")
+	applyLimits := defineLimitFlags(flags)
+	consoleValues := defineConsoleFlags(flags)
+	flags.Parse(args[1:])
+	if err := consoleValues.apply(); err != nil {
+		return err
+	}
+
+	if *organizationID == "" {
+		return usage
+	}
+	if *reassignTo != "" && *deleteItems {
+		return fmt.Errorf("--reassign-to and --delete-items cannot be used together")
+	}
+	if *permanent && !*deleteItems {
+		return fmt.Errorf("--permanent can only be used with --delete-items")
+	}
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	report, affected, err := findDepartedMemberDebris(*organizationID, *redact)
+	if err != nil {
+		return err
+	}
+	showDepartedReport(report)
+
+	options := batchOptions(*batchSize)
+	options.isPermanent, options.redact = *permanent, *redact
+	options.excludes, options.protectFile = *excludes, *protectFile
+	applyLimits(&options)
+	if options, err = withProtection(options); err != nil {
+		return err
+	}
+	switch {
+	case *reassignTo != "":
+		report.Action = "reassign to " + *reassignTo
+	case *deleteItems:
+		report.Action = "delete items"
+	default:
+		report.Action = "report only"
+	}
+	if *out != "" {
+		if err := writeJSONFile(*out, report); err != nil {
+			return err
+		}
+		console.infof(emojiSuccess, "Report written to %s", *out)
+	}
+
+	if len(report.Collections) == 0 {
+		return nil
+	}
+
+	orphaned := make(map[string]bool, len(report.Collections))
+	for _, collection := range report.Collections {
+		orphaned[collection.ID] = true
+	}
+
+	if len(affected) > 0 {
+		var op itemOperation
+		switch {
+		case *reassignTo != "":
+			target, err := resolveCollection(*organizationID, *reassignTo)
+			if err != nil {
+				return err
+			}
+			if orphaned[target.ID] {
+				return fmt.Errorf("collection %q is itself orphaned", target.Name)
+			}
+			op = reassignCollectionOperation(orphaned, target)
+		case *deleteItems:
+			op = deleteOperation(*permanent)
+		default:
+			console.infof(emojiInfo, "Run with --reassign-to <collection> or --delete-items to handle the %d items", len(affected))
+			return nil
+		}
+
+		affected = options.protection.apply(affected)
+		stats := &DeleteStats{total: len(affected), protected: options.protection.count()}
+		displayOperationMode(op)
+		if op.deletesItems {
+			affected = protectSensitiveItems(affected, options)
+			stats.total = len(affected)
+		}
+		if stats.total > 0 {
+			if err := checkLargeSelection(stats.total, options); err != nil {
+				return err
+			}
+			if !confirmOperation(stats, op) {
+				cancelOperation()
+				return nil
+			}
+			if err := processItems(affected, stats, op, options); err != nil {
+				return err
+			}
+			if stats.failed > 0 {
+				return fmt.Errorf("%d items could not be processed; orphaned collections were kept", stats.failed)
+			}
+		}
+	}
+
+	if *deleteCollections && options.protection.count() > 0 {
+		console.infof(emojiInfo, "Orphaned collections kept, since protected items are still only reachable through them")
+	} else if *deleteCollections {
+		if !promptYesNo(fmt.Sprintf("Delete the %d orphaned collections?", len(report.Collections))) {
+			console.infof(emojiInfo, "Collections kept")
+		} else {
+			for _, collection := range report.Collections {
+				if err := vault.DeleteOrgCollection(*organizationID, collection.ID); err != nil {
+					console.errorf("Error deleting collection %q: %v", collection.Name, err)
+					continue
+				}
+				console.infof(emojiSuccess, "Deleted collection %q", collection.Name)
+			}
+		}
+	}
+
+	if err := syncBitwarden(""); err != nil {
+		console.warnf("Warning: Final sync failed")
+	}
+	return nil
+}
+
+// findDepartedMemberDebris returns the collections of the organization that
+// are assigned to no group and only to members who were removed or revoked,
+// and the items that are reachable through such collections only. Item names
+// and member emails in the report are passed through redact.
+func findDepartedMemberDebris(organizationID string, redact redactor) (*departedReport, []BitwardenItem, error) {
+	members, err := vault.ListOrgMembers(organizationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	active := make(map[string]bool, len(members))
+	labels := make(map[string]string, len(members))
+	for _, member := range members {
+		labels[member.ID] = redact.username(member.Email)
+		if member.Status != memberStatusRevoked {
+			active[member.ID] = true
+		} else {
+			labels[member.ID] += " (revoked)"
+		}
+	}
+
+	collections, err := vault.ListCollections()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &departedReport{OrganizationID: organizationID, CreatedAt: time.Now().UTC()}
+	names := make(map[string]string)
+	orphaned := make(map[string]bool)
+	for _, summary := range collections {
+		if summary.OrganizationID != organizationID {
+			continue
+		}
+		names[summary.ID] = summary.Name
+
+		collection, err := vault.GetOrgCollection(organizationID, summary.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(collection.Groups) > 0 || len(collection.Users) == 0 {
+			continue
+		}
+
+		var departed []string
+		for _, user := range collection.Users {
+			if active[user.ID] {
+				departed = nil
+				break
+			}
+			label, known := labels[user.ID]
+			if !known {
+				label = user.ID + " (removed)"
+			}
+			departed = append(departed, label)
+		}
+		if len(departed) > 0 {
+			orphaned[collection.ID] = true
+			report.Collections = append(report.Collections, departedCollection{ID: collection.ID, Name: summary.Name, DepartedMembers: departed})
+		}
+	}
+
+	items, err := fetchBitwardenItems("")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var affected []BitwardenItem
+	for _, item := range items {
+		if item.OrganizationID != organizationID || len(item.CollectionIDs) == 0 {
+			continue
+		}
+		onlyOrphaned := true
+		var collectionNames []string
+		for _, id := range item.CollectionIDs {
+			if !orphaned[id] {
+				onlyOrphaned = false
+				break
+			}
+			collectionNames = append(collectionNames, names[id])
+		}
+		if onlyOrphaned {
+			affected = append(affected, item)
+			report.Items = append(report.Items, departedItem{ID: item.ID, Name: redact.name(item.Name), Collections: collectionNames})
+		}
+	}
+	return report, affected, nil
+}
+
+func showDepartedReport(report *departedReport) {
+	if len(report.Collections) == 0 {
+		console.infof(emojiSuccess, "No collections are left assigned only to departed members")
+		return
+	}
+
+	console.warnf("%d collections are assigned only to departed members:", len(report.Collections))
+	for _, collection := range report.Collections {
+		console.linef("   %s (%s): %s", collection.Name, collection.ID, strings.Join(collection.DepartedMembers, ", "))
+	}
+	console.warnf("%d items are reachable only through these collections:", len(report.Items))
+	for _, item := range report.Items {
+		console.linef("   - %s (%s) in %s", item.Name, item.ID, strings.Join(item.Collections, ", "))
+	}
+}
+
+func reassignCollectionOperation(orphaned map[string]bool, target BitwardenCollection) itemOperation {
+	return itemOperation{
+		verb:         "reassign",
+		modeEmoji:    emojiInfo,
+		modeText:     fmt.Sprintf("Reassign items to the collection %q", target.Name),
+		confirmText:  "reassign",
+		processName:  "reassignment",
+		progressVerb: "reassigning",
+		doneText:     fmt.Sprintf("have been reassigned to %q", target.Name),
+		cost:         singleCallCost,
+		idempotent:   true,
+		run: func(item BitwardenItem) error {
+			collectionIDs := []string{target.ID}
+			for _, id := range item.CollectionIDs {
+				if !orphaned[id] && id != target.ID {
+					collectionIDs = append(collectionIDs, id)
+				}
+			}
+			return vault.EditItemCollections(item.ID, collectionIDs)
+		},
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// listFlag collects the values of a repeatable flag.
+type listFlag []string
+
+func (list *listFlag) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+func (list *listFlag) String() string {
+	if list == nil {
+		return ""
+	}
+	return strings.Join(*list, ",")
+}
+
+func (list *listFlag) values() []string {
+	return *list
+}
+
+// uriHost returns the lowercased host of a web URI. bw stores URIs with or
+// without a scheme; app URIs have no host.
+func uriHost(uri string) string {
+	parsed := parseWebURI(uri)
+	if parsed == nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+}
+
+func parseWebURI(uri string) *url.URL {
+	uri = strings.TrimSpace(uri)
+	if uri == "" || isAppURI(uri) {
+		return nil
+	}
+	if !strings.Contains(uri, "://") {
+		uri = "https://" + uri
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Hostname() == "" {
+		return nil
+	}
+	return parsed
+}
+
+// registrableDomain reduces a host to the domain a user registers with a
+// registrar (its eTLD+1 by the Public Suffix List): login.example.co.uk
+// becomes example.co.uk. IP addresses, single-label hosts and public
+// suffixes themselves are kept as they are.
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// domainFilter matches items with a URI on one of the registrable domains
+// of the given domains, which may be listed one per line in file.
+func domainFilter(domains []string, file string) (itemFilter, error) {
+	given := len(domains) > 0
+	if file != "" {
+		listed, err := readDomainFile(file)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(slices.Clone(domains), listed...)
+	}
+
+	wanted := make(map[string]bool)
+	for _, value := range domains {
+		for _, domain := range strings.Split(value, ",") {
+			if strings.TrimSpace(domain) == "" {
+				continue
+			}
+			host := uriHost(domain)
+			if host == "" {
+				return nil, fmt.Errorf("invalid --domain %q", domain)
+			}
+			wanted[registrableDomain(host)] = true
+		}
+	}
+	switch {
+	case len(wanted) > 0:
+	case file == "":
+		return nil, fmt.Errorf("--domain lists no domains")
+	case given:
+		return nil, fmt.Errorf("neither --domain nor --domain-file %s lists any domains", file)
+	default:
+		return nil, fmt.Errorf("--domain-file %s lists no domains", file)
+	}
+
+	return func(item BitwardenItem) bool {
+		for _, uri := range item.uris() {
+			if host := uriHost(uri); host != "" && wanted[registrableDomain(host)] {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// uriFilter matches items with a URI on the same host as one of the given
+// URIs, ignoring the scheme, port and a leading "www.", and below its path
+// if it has one.
+func uriFilter(uris []string) (itemFilter, error) {
+	type target struct{ host, path string }
+	var targets []target
+	for _, value := range uris {
+		parsed := parseWebURI(value)
+		if parsed == nil {
+			return nil, fmt.Errorf("invalid --uri %q", value)
+		}
+		targets = append(targets, target{host: matchHost(parsed), path: strings.TrimSuffix(parsed.Path, "/")})
+	}
+
+	return func(item BitwardenItem) bool {
+		for _, uri := range item.uris() {
+			parsed := parseWebURI(uri)
+			if parsed == nil {
+				continue
+			}
+			host, path := matchHost(parsed), strings.TrimSuffix(parsed.Path, "/")
+			for _, t := range targets {
+				if host == t.host && (t.path == "" || path == t.path || strings.HasPrefix(path, t.path+"/")) {
+					return true
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+func matchHost(parsed *url.URL) string {
+	return strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(parsed.Hostname()), "."), "www.")
+}
+
+func readDomainFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening domain file: %w", err)
+	}
+	defer file.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading domain file: %w", err)
+	}
+	return domains, nil
+}
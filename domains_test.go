@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		host, want string
+	}{
+		{"example.com", "example.com"},
+		{"login.example.com", "example.com"},
+		{"login.example.co.uk", "example.co.uk"},
+		{"accounts.google.com.au", "google.com.au"},
+		{"user.github.io", "user.github.io"},
+		{"co.uk", "co.uk"},
+		{"localhost", "localhost"},
+		{"192.168.1.10", "192.168.1.10"},
+		{"::1", "::1"},
+	}
+	for _, test := range tests {
+		if got := registrableDomain(test.host); got != test.want {
+			t.Errorf("registrableDomain(%q) = %q, want %q", test.host, got, test.want)
+		}
+	}
+}
+
+func TestDomainFilter(t *testing.T) {
+	login := func(uri string) BitwardenItem {
+		return BitwardenItem{Login: &BitwardenLogin{URIs: []BitwardenURI{{URI: uri}}}}
+	}
+	tests := []struct {
+		name    string
+		domains []string
+		item    BitwardenItem
+		want    bool
+	}{
+		{"same host", []string{"example.com"}, login("https://example.com/login"), true},
+		{"subdomain", []string{"example.com"}, login("https://accounts.example.com"), true},
+		{"given as subdomain", []string{"www.example.co.uk"}, login("https://shop.example.co.uk"), true},
+		{"comma-separated", []string{"other.org,example.com"}, login("example.com"), true},
+		{"other domain", []string{"example.com"}, login("https://example.org"), false},
+		{"suffix only", []string{"example.com"}, login("https://notexample.com"), false},
+		{"no login", []string{"example.com"}, BitwardenItem{Name: "example.com"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := domainFilter(test.domains, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filter(test.item); got != test.want {
+				t.Errorf("filter = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDomainFilterRejectsEmptyLists(t *testing.T) {
+	for _, domains := range [][]string{{","}, {" "}, {"", ","}} {
+		if _, err := domainFilter(domains, ""); err == nil {
+			t.Errorf("domainFilter(%q) accepted a list without domains", domains)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const latencySamples = 3
+
+// showImpactEstimate prints how much work a run would be and what it would
+// destroy, without calling any operation. The duration is extrapolated from
+// the measured latency of a few bw calls.
+func showImpactEstimate(items []BitwardenItem, op itemOperation, options CommandOptions) {
+	console.infof(emojiInfo, "\nDry run: nothing will be changed")
+	if len(items) == 0 {
+		return
+	}
+
+	listDryRunItems(items, op, options)
+
+	invocations, apiCalls := 0, 0
+	organizationItems, passkeyItems, sshKeyItems := 0, 0, 0
+	attachments := 0
+	var attachmentBytes int64
+
+	for _, item := range items {
+		calls, api := op.cost(item)
+		invocations += calls
+		apiCalls += api
+
+		if item.OrganizationID != "" {
+			organizationItems++
+		}
+		if item.hasPasskey() {
+			passkeyItems++
+		}
+		if item.hasPrivateKey() {
+			sshKeyItems++
+		}
+		for _, attachment := range item.Attachments {
+			attachments++
+			size, _ := strconv.ParseInt(attachment.Size, 10, 64)
+			attachmentBytes += size
+		}
+	}
+
+	console.infof(emojiSearch, "Impact estimate:")
+	console.linef("   Items: %d (%d personal, %d organization)", len(items), len(items)-organizationItems, organizationItems)
+	console.linef("   bw invocations: %d (about %d server API calls)", invocations, apiCalls)
+
+	if latency, err := measureLatency(items); err == nil {
+		workers := max(options.batchSize, 1)
+		estimate := time.Duration(invocations) * latency / time.Duration(workers)
+		if options.autoBatch {
+			console.linef("   Estimated duration: at least %s (%s per bw call, up to %d parallel workers with --batch auto)", estimate.Round(100*time.Millisecond), latency.Round(time.Millisecond), workers)
+		} else {
+			console.linef("   Estimated duration: %s (%s per bw call, %d parallel workers)", estimate.Round(100*time.Millisecond), latency.Round(time.Millisecond), workers)
+		}
+		if interval, _ := rateInterval(options.rate, options.delay); interval > 0 && time.Duration(len(items))*interval > estimate {
+			console.linef("   Throttled by --rate/--delay to at least %s", (time.Duration(len(items)) * interval).Round(100*time.Millisecond))
+		}
+	} else {
+		console.linef("   Estimated duration: unknown (%v)", err)
+	}
+
+	if op.deletesItems {
+		console.linef("   Items with passkeys: %d", passkeyItems)
+		console.linef("   Items with SSH private keys: %d", sshKeyItems)
+		if options.isPermanent {
+			console.linef("   Attachments destroyed: %d (%s)", attachments, formatBytes(attachmentBytes))
+		} else {
+			console.linef("   Attachments moved to trash with their items: %d (%s)", attachments, formatBytes(attachmentBytes))
+		}
+	}
+}
+
+// listDryRunItems prints every item the run would process, straight from
+// the selection the real run would use.
+func listDryRunItems(items []BitwardenItem, op itemOperation, options CommandOptions) {
+	console.infof(emojiSearch, "Items this run would %s:", op.verb)
+	listItems(items, options)
+	emitMatched(items, op.verb)
+}
+
+// listItems prints items one per line with their ID, type and folder, and
+// for trashed items how long they have been in the trash.
+func listItems(items []BitwardenItem, options CommandOptions) {
+	folderName := folderNamer()
+	for i, item := range items {
+		folder := folderName(item)
+		age := ""
+		if deleted, err := time.Parse(time.RFC3339, item.DeletedDate); err == nil {
+			age = fmt.Sprintf(", in trash for %s", trashAge(time.Since(deleted)))
+		}
+		console.linef("%5d. %s (%s) [%s] in %s%s", i+1, options.redact.name(item.Name), item.ID, item.typeName(), folder, age)
+	}
+}
+
+// folderNamer returns a function that names the folder of an item. Folders
+// are listed once; if that fails, folders are shown by their ID.
+func folderNamer() func(item BitwardenItem) string {
+	names := map[string]string{"": noFolderGroup}
+	if folders, err := vault.ListFolders(); err == nil {
+		for _, folder := range folders {
+			names[folder.ID] = folder.Name
+		}
+	}
+	return func(item BitwardenItem) string {
+		if name, ok := names[item.FolderID]; ok {
+			return name
+		}
+		return item.FolderID
+	}
+}
+
+// measureLatency times read-only `bw get item` calls on a few matched items
+// as a stand-in for the per-call overhead of the real operation.
+func measureLatency(items []BitwardenItem) (time.Duration, error) {
+	samples := min(latencySamples, len(items))
+	var total time.Duration
+
+	for i := 0; i < samples; i++ {
+		start := time.Now()
+		if _, err := vault.GetItem(items[i].ID); err != nil {
+			return 0, fmt.Errorf("could not measure bw latency: %w", err)
+		}
+		total += time.Since(start)
+	}
+
+	return total / time.Duration(samples), nil
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
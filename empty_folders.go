@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"sort"
+	"strings"
+)
+
+// runEmptyFoldersCommand deletes the folders that hold no items, after
+// listing them and asking for confirmation.
+func runEmptyFoldersCommand(args []string) error {
+	flags := flag.NewFlagSet("folders empty", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "List the empty folders without deleting them")
+	batchSize := flags.Int("batch", 1, "Number of folders to delete in parallel")
+	flags.IntVar(batchSize, "b", 1, "Number of folders to delete in parallel (shorthand)")
+	applyLimits := defineLimitFlags(flags)
+	consoleValues := defineConsoleFlags(flags)
+	flags.Parse(args)
+	if err := consoleValues.apply(); err != nil {
+		return err
+	}
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	folders, err := vault.ListFolders()
+	if err != nil {
+		return err
+	}
+	items, err := fetchBitwardenItems("")
+	if err != nil {
+		return err
+	}
+
+	empty := findEmptyFolders(folders, items)
+	console.infof(emojiSearch, "%d of %d folders are empty", len(empty), len(folders))
+	for _, folder := range empty {
+		console.linef("   %s (%s)", folder.Name, folder.ID)
+	}
+	if len(empty) == 0 || *dryRun {
+		return nil
+	}
+
+	// The worker pool runs on items, so each folder travels as an item
+	// carrying the folder's ID and name.
+	var targets []BitwardenItem
+	for _, folder := range empty {
+		targets = append(targets, BitwardenItem{ID: folder.ID, Name: folder.Name})
+	}
+	op := itemOperation{
+		verb:         "delete folder",
+		modeEmoji:    emojiInfo,
+		modeText:     "Delete empty folders",
+		confirmText:  "delete",
+		processName:  "folder deletion",
+		progressVerb: "deleting folder",
+		doneText:     "have been deleted",
+		idempotent:   true,
+		run: func(folder BitwardenItem) error {
+			return vault.DeleteFolder(folder.ID)
+		},
+	}
+
+	displayOperationMode(op)
+	stats := &DeleteStats{total: len(targets)}
+	options := batchOptions(*batchSize)
+	applyLimits(&options)
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	return executeItems(targets, stats, op, options)
+}
+
+// findEmptyFolders returns the folders that no item is filed in, sorted by
+// name. Items in the trash do not count. A folder whose subfolders
+// ("Parent/Child") hold items is kept, so that the nesting stays intact.
+func findEmptyFolders(folders []BitwardenFolder, items []BitwardenItem) []BitwardenFolder {
+	used := make(map[string]bool)
+	for _, item := range items {
+		used[item.FolderID] = true
+	}
+
+	var empty []BitwardenFolder
+	for _, folder := range folders {
+		if used[folder.ID] {
+			continue
+		}
+		parentOfUsed := false
+		for _, other := range folders {
+			if used[other.ID] && strings.HasPrefix(other.Name, folder.Name+"/") {
+				parentOfUsed = true
+				break
+			}
+		}
+		if !parentOfUsed {
+			empty = append(empty, folder)
+		}
+	}
+	sort.Slice(empty, func(i, j int) bool { return strings.ToLower(empty[i].Name) < strings.ToLower(empty[j].Name) })
+	return empty
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// excludePatterns collects the values of a repeatable --exclude flag. A value
+// wrapped in slashes (/^prod-/) is a regular expression, anything else a glob
+// where * and ? match any characters; both are matched against item names,
+// ignoring case.
+type excludePatterns []string
+
+func (list *excludePatterns) Set(value string) error {
+	if _, err := compileNamePattern("exclude", value); err != nil {
+		return err
+	}
+	*list = append(*list, value)
+	return nil
+}
+
+func (list *excludePatterns) String() string {
+	if list == nil {
+		return ""
+	}
+	return strings.Join(*list, ",")
+}
+
+func (list *excludePatterns) values() []string {
+	return *list
+}
+
+// compileNamePattern compiles the glob or /regular expression/ given to the
+// flag into a case-insensitive expression that matches whole names.
+func compileNamePattern(flag, value string) (*regexp.Regexp, error) {
+	if len(value) > 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		pattern, err := regexp.Compile("(?i)" + value[1:len(value)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s %s: %w", flag, value, err)
+		}
+		return pattern, nil
+	}
+
+	pattern, err := compileGlob(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s %s: %w", flag, value, err)
+	}
+	return pattern, nil
+}
+
+// compileGlob turns a glob, where * matches any characters and ? a single
+// one, into a case-insensitive expression that matches whole names.
+func compileGlob(value string) (*regexp.Regexp, error) {
+	var glob strings.Builder
+	glob.WriteString("(?i)^")
+	for _, r := range value {
+		switch r {
+		case '*':
+			glob.WriteString(".*")
+		case '?':
+			glob.WriteString(".")
+		default:
+			glob.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	glob.WriteString("$")
+	return regexp.Compile(glob.String())
+}
+
+// protectionRules keep items out of every run no matter what the selection
+// matched. They are applied after the search and filters, and count the
+// matched items they skipped for the confirmation.
+type protectionRules struct {
+	excludes []*regexp.Regexp
+	ids      map[string]bool
+	names    map[string]bool
+	skipped  int
+}
+
+func loadProtectionRules(excludes []string, protectFile string) (*protectionRules, error) {
+	if len(excludes) == 0 && protectFile == "" {
+		return nil, nil
+	}
+
+	rules := &protectionRules{ids: make(map[string]bool), names: make(map[string]bool)}
+	for _, value := range excludes {
+		pattern, err := compileNamePattern("exclude", value)
+		if err != nil {
+			return nil, err
+		}
+		rules.excludes = append(rules.excludes, pattern)
+	}
+
+	if protectFile != "" {
+		file, err := os.Open(protectFile)
+		if err != nil {
+			return nil, fmt.Errorf("error opening protect file: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			rules.ids[line] = true
+			rules.names[strings.ToLower(line)] = true
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading protect file: %w", err)
+		}
+	}
+	return rules, nil
+}
+
+func (rules *protectionRules) protects(item BitwardenItem) bool {
+	if rules.ids[item.ID] || rules.names[strings.ToLower(item.Name)] {
+		return true
+	}
+	for _, pattern := range rules.excludes {
+		if pattern.MatchString(item.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply removes the protected items from the matched items.
+func (rules *protectionRules) apply(items []BitwardenItem) []BitwardenItem {
+	if rules == nil {
+		return items
+	}
+
+	var kept []BitwardenItem
+	rules.skipped = 0
+	for _, item := range items {
+		if rules.protects(item) {
+			rules.skipped++
+		} else {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// withProtection loads the protection rules of options that were parsed
+// again from the flags of an earlier run.
+func withProtection(options CommandOptions) (CommandOptions, error) {
+	var err error
+	options.protection, err = loadProtectionRules(options.excludes, options.protectFile)
+	return options, err
+}
+
+func (rules *protectionRules) count() int {
+	if rules == nil {
+		return 0
+	}
+	return rules.skipped
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestCompileNamePattern(t *testing.T) {
+	tests := []struct {
+		value string
+		name  string
+		want  bool
+	}{
+		{"test*", "test item 1", true},
+		{"test*", "TEST ITEM", true},
+		{"test*", "my test", false},
+		{"item ?", "item 7", true},
+		{"item ?", "item 10", false},
+		{"a.b", "a.b", true},
+		{"a.b", "axb", false},
+		{"(old)", "(old)", true},
+		{"/^test item [0-9]+$/", "Test Item 12", true},
+		{"/^test item [0-9]+$/", "test item x", false},
+		{"/bank/", "my bank login", true},
+		{"//", "//", true},
+		{"//", "x", false},
+	}
+	for _, test := range tests {
+		pattern, err := compileNamePattern("exclude", test.value)
+		if err != nil {
+			t.Errorf("compileNamePattern(%q): %v", test.value, err)
+			continue
+		}
+		if got := pattern.MatchString(test.name); got != test.want {
+			t.Errorf("%q matches %q = %v, want %v", test.value, test.name, got, test.want)
+		}
+	}
+}
+
+func TestCompileNamePatternRejectsInvalidRegex(t *testing.T) {
+	if _, err := compileNamePattern("exclude", "/(unclosed/"); err == nil {
+		t.Error("compileNamePattern accepted an invalid regular expression")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/mitas/bitwarden-cleanup/pkg/cleanup"
+)
+
+// Exit statuses of the tool, so that wrapper scripts can tell the outcomes
+// apart. A run stopped with Ctrl-C exits with exitInterrupted.
+const (
+	exitSuccess     = 0
+	exitFailure     = 1
+	exitCancelled   = 2
+	exitVaultLocked = 3
+	exitBWMissing   = 4
+	exitNoMatches   = 5
+)
+
+var (
+	errVaultLocked = cleanup.ErrVaultLocked
+	errNotLoggedIn = cleanup.ErrNotLoggedIn
+	errBWMissing   = errors.New("Bitwarden CLI (bw) not found in PATH")
+	errNoMatches   = errors.New("no items matched the selection (--fail-on-empty)")
+)
+
+// outcome collects what a command that returned without error still has to
+// report through its exit status.
+var outcome struct {
+	cancelled bool
+	failed    bool
+}
+
+// cancelOperation reports that the user declined a run.
+func cancelOperation() {
+	console.errorf("Operation cancelled")
+	outcome.cancelled = true
+}
+
+// exitCode maps the error of a command to its exit status.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, errInterrupted):
+		return exitInterrupted
+	case errors.Is(err, errVaultLocked), errors.Is(err, errNotLoggedIn):
+		return exitVaultLocked
+	case errors.Is(err, errBWMissing):
+		return exitBWMissing
+	case errors.Is(err, errNoMatches):
+		return exitNoMatches
+	}
+	return exitFailure
+}
+
+// exitWithStatus ends a command that returned without error: items that
+// failed and runs refused for --from-export exit with exitFailure,
+// declined runs with exitCancelled.
+func exitWithStatus() {
+	code := exitSuccess
+	switch {
+	case outcome.failed || unattended.refused:
+		code = exitFailure
+	case outcome.cancelled:
+		code = exitCancelled
+	}
+	finishMetrics(code)
+	if code != exitSuccess {
+		os.Exit(code)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var errReadOnlyExport = errors.New("--from-export only reads the export; write the IDs with --ids-out and process them against the vault with --ids-file")
+
+// exportVault serves the items, folders and collections of an unencrypted
+// 'bw export --format json' file in place of the vault, so that selections
+// can be matched and reported without bw, a login or a session. Everything
+// that would change the vault fails with errReadOnlyExport.
+type exportVault struct {
+	path        string
+	items       []BitwardenItem
+	folders     []BitwardenFolder
+	collections []BitwardenCollection
+}
+
+func loadExportVault(path string) (*exportVault, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading export: %w", err)
+	}
+	var export struct {
+		Encrypted   bool                  `json:"encrypted"`
+		Folders     []BitwardenFolder     `json:"folders"`
+		Collections []BitwardenCollection `json:"collections"`
+		Items       []BitwardenItem       `json:"items"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("error parsing export %s: %w", path, err)
+	}
+	if export.Encrypted {
+		return nil, fmt.Errorf("export %s is encrypted; use 'bw export --format json'", path)
+	}
+	if export.Items == nil {
+		return nil, fmt.Errorf("%s is not a Bitwarden JSON export: it has no items", path)
+	}
+	return &exportVault{path: path, items: export.Items, folders: export.Folders, collections: export.Collections}, nil
+}
+
+// usingExport reports whether the run reads an export given with
+// --from-export instead of the vault.
+func usingExport() bool {
+	_, ok := vault.(*exportVault)
+	return ok
+}
+
+func (v *exportVault) Status() (VaultStatus, error) {
+	return VaultStatus{Status: "unlocked"}, nil
+}
+
+func (v *exportVault) ServerURL() (string, error) {
+	return "", nil
+}
+
+func (v *exportVault) Sync() (string, error) {
+	return "", nil
+}
+
+// ListItems searches the way bw does: a term matches items whose name,
+// username or one of whose URIs contains it, ignoring case, and the item
+// with that ID.
+func (v *exportVault) ListItems(searchTerm string) ([]BitwardenItem, error) {
+	if searchTerm == "" {
+		return v.items, nil
+	}
+	term := strings.ToLower(searchTerm)
+	var matched []BitwardenItem
+	for _, item := range v.items {
+		found := item.ID == searchTerm || strings.Contains(strings.ToLower(item.Name), term) || strings.Contains(strings.ToLower(item.username()), term)
+		for _, uri := range item.uris() {
+			found = found || strings.Contains(strings.ToLower(uri), term)
+		}
+		if found {
+			matched = append(matched, item)
+		}
+	}
+	return matched, nil
+}
+
+func (v *exportVault) ListTrash() ([]BitwardenItem, error) {
+	return nil, fmt.Errorf("%s holds no trash: bw does not export trashed items", v.path)
+}
+
+func (v *exportVault) GetItem(id string) ([]byte, error) {
+	for _, item := range v.items {
+		if item.ID == id {
+			return rawItem(item), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", errItemGone, id)
+}
+
+func rawItem(item BitwardenItem) json.RawMessage {
+	if len(item.raw) > 0 {
+		return item.raw
+	}
+	data, _ := json.Marshal(item)
+	return data
+}
+
+func (v *exportVault) ListFolders() ([]BitwardenFolder, error) {
+	return v.folders, nil
+}
+
+func (v *exportVault) ListCollections() ([]BitwardenCollection, error) {
+	return v.collections, nil
+}
+
+func (v *exportVault) GetOrgCollection(string, string) (*organizationCollection, error) {
+	return nil, fmt.Errorf("%s holds no collection permissions", v.path)
+}
+
+func (v *exportVault) ListOrgMembers(string) ([]organizationMember, error) {
+	return nil, fmt.Errorf("%s holds no organization members", v.path)
+}
+
+func (v *exportVault) DownloadAttachment(string, string, string) error {
+	return fmt.Errorf("%s holds no attachment contents", v.path)
+}
+
+func (v *exportVault) ListSends() ([]BitwardenSend, error) {
+	return nil, fmt.Errorf("%s holds no Sends", v.path)
+}
+
+// Nothing can be changed through an export.
+func (v *exportVault) LoginAPIKey() error                            { return errReadOnlyExport }
+func (v *exportVault) Unlock(string) (string, error)                 { return "", errReadOnlyExport }
+func (v *exportVault) ConfigureServer(string) error                  { return errReadOnlyExport }
+func (v *exportVault) CreateItem([]byte) ([]byte, error)             { return nil, errReadOnlyExport }
+func (v *exportVault) EditItem(string, []byte) error                 { return errReadOnlyExport }
+func (v *exportVault) DeleteItem(string, bool) error                 { return errReadOnlyExport }
+func (v *exportVault) RestoreItem(string) error                      { return errReadOnlyExport }
+func (v *exportVault) EditItemCollections(string, []string) error    { return errReadOnlyExport }
+func (v *exportVault) CreateFolder(string) (*BitwardenFolder, error) { return nil, errReadOnlyExport }
+func (v *exportVault) DeleteFolder(string) error                     { return errReadOnlyExport }
+func (v *exportVault) DeleteOrgCollection(string, string) error      { return errReadOnlyExport }
+func (v *exportVault) UploadAttachment(string, string) error         { return errReadOnlyExport }
+func (v *exportVault) DeleteAttachment(string, string) error         { return errReadOnlyExport }
+func (v *exportVault) DeleteSend(string) error                       { return errReadOnlyExport }
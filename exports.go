@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// exportMatchedItems writes every export requested on the command line
+// before anything is processed.
+func exportMatchedItems(items []BitwardenItem, options CommandOptions) error {
+	if options.backupPath != "" {
+		if err := writeBackup(items, options.backupPath, options.encryptBackup); err != nil {
+			return err
+		}
+	}
+	if options.exportURIs != "" {
+		if err := exportURIs(items, options.exportURIs); err != nil {
+			return err
+		}
+	}
+	if options.exportKeePass != "" {
+		if err := exportKeePass(items, options.exportKeePass); err != nil {
+			return err
+		}
+	}
+	if options.export1PUX != "" {
+		if err := export1PUX(items, options.export1PUX); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportURIs writes the web URIs of items to path so the sites stay
+// findable after their credentials are deleted. A .html/.htm path produces a
+// Netscape bookmarks file that browsers can import; anything else produces
+// tab-separated "name<TAB>uri" lines. App URIs are left out.
+func exportURIs(items []BitwardenItem, path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating URI export: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	ext := strings.ToLower(filepath.Ext(path))
+	asHTML := ext == ".html" || ext == ".htm"
+
+	if asHTML {
+		fmt.Fprintln(writer, "<!DOCTYPE NETSCAPE-Bookmark-file-1>")
+		fmt.Fprintln(writer, `<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">`)
+		fmt.Fprintln(writer, "<TITLE>Bookmarks</TITLE>")
+		fmt.Fprintln(writer, "<H1>Bookmarks</H1>")
+		fmt.Fprintln(writer, "<DL><p>")
+		fmt.Fprintf(writer, "    <DT><H3>Bitwarden cleanup %s</H3>\n", time.Now().Format("2006-01-02"))
+		fmt.Fprintln(writer, "    <DL><p>")
+	}
+
+	count := 0
+	for _, item := range items {
+		for _, uri := range item.uris() {
+			if isAppURI(uri) {
+				continue
+			}
+			if asHTML {
+				fmt.Fprintf(writer, "        <DT><A HREF=\"%s\">%s</A>\n", html.EscapeString(bookmarkURL(uri)), html.EscapeString(item.Name))
+			} else {
+				fmt.Fprintf(writer, "%s\t%s\n", item.Name, uri)
+			}
+			count++
+		}
+	}
+
+	if asHTML {
+		fmt.Fprintln(writer, "    </DL><p>")
+		fmt.Fprintln(writer, "</DL><p>")
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("error writing URI export: %w", err)
+	}
+
+	console.infof(emojiSuccess, "Exported %d URIs to %s", count, path)
+	return nil
+}
+
+// bookmarkURL adds a scheme to bare domains, which Bitwarden allows in URIs
+// but browsers do not accept as bookmarks.
+func bookmarkURL(uri string) string {
+	if strings.Contains(uri, "://") {
+		return uri
+	}
+	return "https://" + uri
+}
+
+// itemDetails is the full content of an item as needed by the password
+// manager exports, decoded from the item's original JSON.
+type itemDetails struct {
+	Type     int               `json:"type"`
+	Name     string            `json:"name"`
+	Notes    string            `json:"notes"`
+	FolderID string            `json:"folderId"`
+	Favorite bool              `json:"favorite"`
+	Login    *loginDetails     `json:"login"`
+	Card     map[string]string `json:"card"`
+	Identity map[string]string `json:"identity"`
+	SSHKey   *BitwardenSSHKey  `json:"sshKey"`
+	Fields   []BitwardenField  `json:"fields"`
+
+	CreationDate string `json:"creationDate"`
+	RevisionDate string `json:"revisionDate"`
+}
+
+type loginDetails struct {
+	Username string         `json:"username"`
+	Password string         `json:"password"`
+	TOTP     string         `json:"totp"`
+	URIs     []BitwardenURI `json:"uris"`
+}
+
+// Custom field type of hidden (password-like) values.
+const hiddenFieldType = 1
+
+func decodeItemDetails(item BitwardenItem) (itemDetails, error) {
+	var details itemDetails
+	if item.raw == nil {
+		return itemDetails{Name: item.Name, FolderID: item.FolderID}, nil
+	}
+
+	var generic map[string]any
+	if err := json.Unmarshal(item.raw, &generic); err != nil {
+		return details, fmt.Errorf("error decoding item %s: %w", item.ID, err)
+	}
+	// Card and identity values are strings or null; drop the nulls so they
+	// decode into plain string maps.
+	for _, section := range []string{"card", "identity"} {
+		if values, ok := generic[section].(map[string]any); ok {
+			for key, value := range values {
+				if _, isString := value.(string); !isString {
+					delete(values, key)
+				}
+			}
+		}
+	}
+	cleaned, err := json.Marshal(generic)
+	if err != nil {
+		return details, fmt.Errorf("error decoding item %s: %w", item.ID, err)
+	}
+	if err := json.Unmarshal(cleaned, &details); err != nil {
+		return details, fmt.Errorf("error decoding item %s: %w", item.ID, err)
+	}
+	return details, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const failedItemsFileName = "failed-items.txt"
+
+// writeFailedItems saves the IDs of the items that failed in this run to
+// --failed-file, or to failed-items.txt in the config directory, for a later
+// run with --retry-failed. Runs without failures leave the file alone.
+func writeFailedItems(stats *DeleteStats, op itemOperation, options CommandOptions) {
+	failed := stats.failures()
+	if len(failed) == 0 {
+		return
+	}
+
+	path := options.failedFile
+	if path == "" {
+		defaultPath, err := configFile(failedItemsFileName)
+		if err != nil {
+			console.warnf("Warning: %v", err)
+			return
+		}
+		path = defaultPath
+	}
+
+	var lines strings.Builder
+	fmt.Fprintf(&lines, "# %d items failed to %s on %s\n", len(failed), op.verb, time.Now().Format("2006-01-02 15:04"))
+	for _, result := range failed {
+		fmt.Fprintln(&lines, result.ItemID)
+	}
+	if err := os.WriteFile(path, []byte(lines.String()), 0o600); err != nil {
+		console.warnf("Warning: error writing failed items: %v", err)
+		return
+	}
+	console.infof(emojiInfo, "Failed item IDs written to %s (re-run just these with --retry-failed %s)", path, shellJoin([]string{path}))
+}
+
+// readFailedItems reads a file of item IDs, one per line, as written by
+// writeFailedItems. Blank lines and lines starting with # are skipped.
+func readFailedItems(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening failed items file: %w", err)
+	}
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		ids = append(ids, fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading failed items file: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no item IDs in %s", path)
+	}
+	return ids, nil
+}
+
+// selectFailedItems narrows items down to those listed in the failed items
+// file at path.
+func selectFailedItems(path string, items []BitwardenItem) ([]BitwardenItem, error) {
+	ids, err := readFailedItems(path)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var selected []BitwardenItem
+	for _, item := range items {
+		if wanted[item.ID] {
+			selected = append(selected, item)
+		}
+	}
+	console.infof(emojiInfo, "%d of the %d items in %s are still in the selection", len(selected), len(wanted), path)
+	return selected, nil
+}
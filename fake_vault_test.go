@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+// fakeVault is a BitwardenClient that keeps its items in memory. Failures
+// queued for an item are returned by its next calls, one per call, before
+// the call succeeds. Methods it does not implement panic through the nil
+// embedded client.
+type fakeVault struct {
+	BitwardenClient
+
+	mu       sync.Mutex
+	items    map[string]BitwardenItem
+	failures map[string][]error
+	calls    map[string]int
+}
+
+// useFakeVault swaps the vault of the tests for a fake holding items until
+// the test ends.
+func useFakeVault(t *testing.T, items ...BitwardenItem) *fakeVault {
+	t.Helper()
+	fake := &fakeVault{items: make(map[string]BitwardenItem), failures: make(map[string][]error), calls: make(map[string]int)}
+	for _, item := range items {
+		fake.items[item.ID] = item
+	}
+	previous := vault
+	vault = fake
+	t.Cleanup(func() { vault = previous })
+	return fake
+}
+
+// fail queues errs for the next calls that touch the item with id.
+func (f *fakeVault) fail(id string, errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[id] = append(f.failures[id], errs...)
+}
+
+func (f *fakeVault) callCount(id string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[id]
+}
+
+func (f *fakeVault) call(id string) error {
+	f.calls[id]++
+	if queued := f.failures[id]; len(queued) > 0 {
+		f.failures[id] = queued[1:]
+		return queued[0]
+	}
+	if _, ok := f.items[id]; !ok {
+		return errItemGone
+	}
+	return nil
+}
+
+func (f *fakeVault) Sync() (string, error) { return "", nil }
+
+func (f *fakeVault) ListItems(searchTerm string) ([]BitwardenItem, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var items []BitwardenItem
+	for _, item := range f.items {
+		if !item.inTrash() {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (f *fakeVault) GetItem(id string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(id); err != nil {
+		return nil, err
+	}
+	return json.Marshal(f.items[id])
+}
+
+func (f *fakeVault) DeleteItem(id string, permanent bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(id); err != nil {
+		return err
+	}
+	if permanent {
+		delete(f.items, id)
+		return nil
+	}
+	item := f.items[id]
+	item.DeletedDate = "2024-06-01T00:00:00.000Z"
+	f.items[id] = item
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// folderDuplicates is a set of folders whose names differ only in case or
+// whitespace. The canonical folder keeps its name and receives the items.
+type folderDuplicates struct {
+	canonical  BitwardenFolder
+	duplicates []BitwardenFolder
+	counts     map[string]int
+}
+
+func runFoldersCommand(args []string) error {
+	usage := fmt.Errorf("usage: %s folders duplicates [--merge] [--batch <n>] | empty [--dry-run] [--batch <n>]", filepath.Base(os.Args[0]))
+	if len(args) > 0 && args[0] == "empty" {
+		return runEmptyFoldersCommand(args[1:])
+	}
+	if len(args) == 0 || args[0] != "duplicates" {
+		return usage
+	}
+
+	flags := flag.NewFlagSet("folders duplicates", flag.ExitOnError)
+	merge := flags.Bool("merge", false, "Move the items of duplicate folders into one canonical folder and delete the duplicates")
+	batchSize := flags.Int("batch", 1, "Number of items to process in parallel")
+	flags.IntVar(batchSize, "b", 1, "Number of items to process in parallel (shorthand)")
+	consoleValues := defineConsoleFlags(flags)
+	flags.Parse(args[1:])
+	if err := consoleValues.apply(); err != nil {
+		return err
+	}
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	folders, err := vault.ListFolders()
+	if err != nil {
+		return err
+	}
+	items, err := fetchBitwardenItems("")
+	if err != nil {
+		return err
+	}
+
+	groups := findDuplicateFolders(folders, items)
+	if len(groups) == 0 {
+		console.infof(emojiSuccess, "No duplicate folders found")
+		return nil
+	}
+
+	console.infof(emojiSearch, "Found %d sets of duplicate folders:", len(groups))
+	for _, group := range groups {
+		var names []string
+		for _, duplicate := range group.duplicates {
+			names = append(names, fmt.Sprintf("%q (%d items)", duplicate.Name, group.counts[duplicate.ID]))
+		}
+		console.linef("   %q (%d items) <- %s", group.canonical.Name, group.counts[group.canonical.ID], strings.Join(names, ", "))
+	}
+
+	if !*merge {
+		console.infof(emojiInfo, "Run with --merge to consolidate them")
+		return nil
+	}
+	return mergeDuplicateFolders(groups, items, batchOptions(*batchSize))
+}
+
+// findDuplicateFolders groups folders by their normalized name. The folder
+// holding the most items becomes canonical, so the fewest items move.
+func findDuplicateFolders(folders []BitwardenFolder, items []BitwardenItem) []folderDuplicates {
+	counts := make(map[string]int)
+	for _, item := range items {
+		counts[item.FolderID]++
+	}
+
+	byName := make(map[string][]BitwardenFolder)
+	var keys []string
+	for _, folder := range folders {
+		if folder.ID == "" {
+			continue
+		}
+		key := normalizeFolderName(folder.Name)
+		if _, seen := byName[key]; !seen {
+			keys = append(keys, key)
+		}
+		byName[key] = append(byName[key], folder)
+	}
+	sort.Strings(keys)
+
+	var groups []folderDuplicates
+	for _, key := range keys {
+		members := byName[key]
+		if len(members) < 2 {
+			continue
+		}
+		sort.SliceStable(members, func(i, j int) bool { return counts[members[i].ID] > counts[members[j].ID] })
+		groups = append(groups, folderDuplicates{canonical: members[0], duplicates: members[1:], counts: counts})
+	}
+	return groups
+}
+
+func normalizeFolderName(name string) string {
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		parts[i] = strings.ToLower(strings.Join(strings.Fields(part), " "))
+	}
+	return strings.Join(parts, "/")
+}
+
+func mergeDuplicateFolders(groups []folderDuplicates, items []BitwardenItem, options CommandOptions) error {
+	target := make(map[string]string)
+	for _, group := range groups {
+		for _, duplicate := range group.duplicates {
+			target[duplicate.ID] = group.canonical.ID
+		}
+	}
+
+	var moving []BitwardenItem
+	for _, item := range items {
+		if _, ok := target[item.FolderID]; ok {
+			moving = append(moving, item)
+		}
+	}
+
+	if !promptYesNo(fmt.Sprintf("Move %d items and delete %d duplicate folders?", len(moving), len(target))) {
+		cancelOperation()
+		return nil
+	}
+
+	if len(moving) > 0 {
+		op := itemOperation{
+			verb:         "move",
+			processName:  "folder merge",
+			progressVerb: "moving",
+			doneText:     "have been moved to their canonical folder",
+			cost:         editCost,
+			idempotent:   true,
+			run: func(item BitwardenItem) error {
+				return updateItem(item.ID, func(fields map[string]any) bool {
+					fields["folderId"] = target[item.FolderID]
+					return true
+				})
+			},
+		}
+		stats := &DeleteStats{total: len(moving)}
+		if err := processItems(moving, stats, op, options); err != nil {
+			return err
+		}
+		if stats.failed > 0 {
+			return fmt.Errorf("%d items could not be moved; duplicate folders were kept", stats.failed)
+		}
+	}
+
+	for _, group := range groups {
+		for _, duplicate := range group.duplicates {
+			if err := vault.DeleteFolder(duplicate.ID); err != nil {
+				console.errorf("Error deleting folder %q: %v", duplicate.Name, err)
+				continue
+			}
+			console.infof(emojiSuccess, "Merged %q into %q", duplicate.Name, group.canonical.Name)
+		}
+	}
+
+	if err := syncBitwarden(""); err != nil {
+		console.warnf("Warning: Final sync failed")
+	}
+	return nil
+}
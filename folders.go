@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type BitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type BitwardenCollection struct {
+	ID             string `json:"id"`
+	OrganizationID string `json:"organizationId"`
+	Name           string `json:"name"`
+}
+
+var errFolderNotFound = errors.New("no folder named")
+
+// resolveFolderID returns the ID of the folder named name, compared without
+// regard to case. "No Folder" stands for items without a folder.
+func resolveFolderID(name string) (string, error) {
+	if strings.EqualFold(name, noFolderGroup) {
+		return "", nil
+	}
+	folders, err := vault.ListFolders()
+	if err != nil {
+		return "", err
+	}
+	for _, folder := range folders {
+		if strings.EqualFold(folder.Name, name) {
+			return folder.ID, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", errFolderNotFound, name)
+}
+
+// ensureFolder returns the ID of the folder named name like
+// resolveFolderID, creating the folder when there is none.
+func ensureFolder(name string) (string, error) {
+	id, err := resolveFolderID(name)
+	if !errors.Is(err, errFolderNotFound) {
+		return id, err
+	}
+	folder, err := vault.CreateFolder(name)
+	if err != nil {
+		return "", err
+	}
+	console.infof(emojiSuccess, "Created folder %q", folder.Name)
+	return folder.ID, nil
+}
+
+// resolveCollection finds a collection by ID or by name within the
+// organization when one is given; see findCollection.
+func resolveCollection(organizationID, nameOrID string) (BitwardenCollection, error) {
+	collections, err := vault.ListCollections()
+	if err != nil {
+		return BitwardenCollection{}, err
+	}
+	return findCollection(collections, organizationID, nameOrID)
+}
+
+// findCollection picks a collection by ID or by name. A name matches
+// exactly, or without regard to case when no name matches exactly. A name
+// used by several collections, within one organization or across several,
+// is an error.
+func findCollection(collections []BitwardenCollection, organizationID, nameOrID string) (BitwardenCollection, error) {
+	var exact, folded []BitwardenCollection
+	for _, collection := range collections {
+		if organizationID != "" && collection.OrganizationID != organizationID {
+			continue
+		}
+		switch {
+		case collection.ID == nameOrID:
+			return collection, nil
+		case collection.Name == nameOrID:
+			exact = append(exact, collection)
+		case strings.EqualFold(collection.Name, nameOrID):
+			folded = append(folded, collection)
+		}
+	}
+
+	matches := exact
+	if len(matches) == 0 {
+		matches = folded
+	}
+	switch len(matches) {
+	case 0:
+		if organizationID != "" {
+			return BitwardenCollection{}, fmt.Errorf("no collection %q in organization %s", nameOrID, organizationID)
+		}
+		return BitwardenCollection{}, fmt.Errorf("no collection named %q", nameOrID)
+	case 1:
+		return matches[0], nil
+	default:
+		return BitwardenCollection{}, fmt.Errorf("%d collections are named %q; select one by ID or with --organization-id", len(matches), nameOrID)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestFindCollection(t *testing.T) {
+	collections := []BitwardenCollection{
+		{ID: "c1", OrganizationID: "org1", Name: "Servers"},
+		{ID: "c2", OrganizationID: "org1", Name: "servers"},
+		{ID: "c3", OrganizationID: "org1", Name: "Finance"},
+		{ID: "c4", OrganizationID: "org2", Name: "Finance"},
+		{ID: "c5", OrganizationID: "org2", Name: "Shared"},
+	}
+	tests := []struct {
+		organizationID, nameOrID string
+		want                     string
+	}{
+		{"", "c3", "c3"},
+		{"", "Servers", "c1"},
+		{"", "servers", "c2"},
+		{"", "SERVERS", ""},
+		{"", "shared", "c5"},
+		{"", "Finance", ""},
+		{"org2", "finance", "c4"},
+		{"org1", "Shared", ""},
+		{"", "Missing", ""},
+	}
+	for _, test := range tests {
+		collection, err := findCollection(collections, test.organizationID, test.nameOrID)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("findCollection(%q, %q) = %s, want an error", test.organizationID, test.nameOrID, collection.ID)
+			}
+			continue
+		}
+		if err != nil || collection.ID != test.want {
+			t.Errorf("findCollection(%q, %q) = %s, %v, want %s", test.organizationID, test.nameOrID, collection.ID, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import "strings"
+
+const defaultFuzzyThreshold = 0.8
+
+// fuzzyNameFilter matches items whose name, or a run of as many of its
+// words as the term has, is at least threshold similar to the term.
+func fuzzyNameFilter(term string, threshold float64) itemFilter {
+	termWords := nameWords(term)
+	return func(item BitwardenItem) bool { return nameSimilarity(item.Name, termWords) >= threshold }
+}
+
+// nameSimilarity is the best similarity of the term to the whole name or to
+// any run of consecutive words of it, so that "gogle" matches
+// "Google Drive" as well as "Gogle".
+func nameSimilarity(name string, termWords []string) float64 {
+	if len(termWords) == 0 {
+		return 0
+	}
+	words := nameWords(name)
+	term := strings.Join(termWords, " ")
+	best := similarity(strings.Join(words, " "), term)
+	for start := 0; start+len(termWords) <= len(words); start++ {
+		best = max(best, similarity(strings.Join(words[start:start+len(termWords)], " "), term))
+	}
+	return best
+}
+
+// nameWords lowercases a name and splits it at everything but letters and
+// digits, so that "AWS-Staging (old)" and "aws staging old" compare equal.
+func nameWords(name string) []string {
+	return strings.FieldsFunc(strings.ToLower(name), func(r rune) bool { return !isWordRune(r) })
+}
+
+// similarity is 1 minus the edit distance of a and b relative to the longer
+// of them: 1 for equal strings, 0 for entirely different ones.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(ra, rb))/float64(longest)
+}
+
+// editDistance is the number of inserted, removed or replaced characters,
+// or swapped neighbours, that turn a into b (optimal string alignment
+// distance), so that a typo like "tets" is one edit from "test".
+func editDistance(a, b []rune) int {
+	before := make([]int, len(b)+1)
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				current[j] = min(current[j], before[j-2]+1)
+			}
+		}
+		before, previous, current = previous, current, before
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"test", "", 4},
+		{"", "test", 4},
+		{"test", "test", 0},
+		{"test", "tets", 1},
+		{"test", "tast", 1},
+		{"test", "tests", 1},
+		{"test", "est", 1},
+		{"kitten", "sitting", 3},
+		{"ca", "abc", 3},
+		{"grüße", "gruße", 1},
+	}
+	for _, test := range tests {
+		if got := editDistance([]rune(test.a), []rune(test.b)); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+		if got := editDistance([]rune(test.b), []rune(test.a)); got != test.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", test.b, test.a, got, test.want)
+		}
+	}
+}
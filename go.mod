@@ -0,0 +1,8 @@
+module github.com/mitas/bitwarden-cleanup
+
+go 1.22
+
+require (
+	golang.org/x/crypto v0.33.0
+	golang.org/x/net v0.35.0
+)
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+const (
+	noFolderGroup     = "No Folder"
+	noCollectionGroup = "No Collection"
+)
+
+type itemGroup struct {
+	name  string
+	items []BitwardenItem
+}
+
+func validateGroupBy(groupBy string) error {
+	switch groupBy {
+	case "", "folder", "collection":
+		return nil
+	default:
+		return fmt.Errorf("unknown --group-by value %q (expected folder or collection)", groupBy)
+	}
+}
+
+// processItemGroups runs the worker pool once per group, finishing a group
+// before the next one starts, and prints a subtotal after each group.
+func processItemGroups(ctx context.Context, items []BitwardenItem, stats *DeleteStats, op itemOperation, options CommandOptions) error {
+	groups, err := groupItems(items, options.groupBy)
+	if err != nil {
+		return err
+	}
+
+	for i, group := range groups {
+		console.infof(emojiStart, "\nGroup %d/%d: %s (%d items)", i+1, len(groups), group.name, len(group.items))
+
+		groupStats := &DeleteStats{total: len(group.items), stop: stats.stop, checkpoint: stats.checkpoint}
+		runWorkerPool(ctx, group.items, groupStats, op, options)
+		stats.absorb(groupStats)
+
+		console.infof(emojiSuccess, "Group subtotal: %d items processed in %s (%d/%d overall)", groupStats.completed, group.name, stats.completed, stats.total)
+
+		if ctx.Err() != nil || stats.stop.requested() {
+			break
+		}
+	}
+	console.linef("")
+
+	return nil
+}
+
+// groupItems splits items by folder or collection name. Groups are ordered by
+// name with the "no folder"/"no collection" group last. An item that belongs
+// to several collections is processed with the first of them by name.
+func groupItems(items []BitwardenItem, groupBy string) ([]itemGroup, error) {
+	names, err := groupNames(groupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*itemGroup)
+	var ordered []*itemGroup
+	for _, item := range items {
+		name := itemGroupName(item, groupBy, names)
+		group, ok := byName[name]
+		if !ok {
+			group = &itemGroup{name: name}
+			byName[name] = group
+			ordered = append(ordered, group)
+		}
+		group.items = append(group.items, item)
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		iEmpty := ordered[i].name == noFolderGroup || ordered[i].name == noCollectionGroup
+		jEmpty := ordered[j].name == noFolderGroup || ordered[j].name == noCollectionGroup
+		if iEmpty != jEmpty {
+			return jEmpty
+		}
+		return ordered[i].name < ordered[j].name
+	})
+
+	groups := make([]itemGroup, len(ordered))
+	for i, group := range ordered {
+		groups[i] = *group
+	}
+	return groups, nil
+}
+
+func groupNames(groupBy string) (map[string]string, error) {
+	names := make(map[string]string)
+
+	if groupBy == "folder" {
+		folders, err := vault.ListFolders()
+		if err != nil {
+			return nil, err
+		}
+		for _, folder := range folders {
+			names[folder.ID] = folder.Name
+		}
+		return names, nil
+	}
+
+	collections, err := vault.ListCollections()
+	if err != nil {
+		return nil, err
+	}
+	for _, collection := range collections {
+		names[collection.ID] = collection.Name
+	}
+	return names, nil
+}
+
+func itemGroupName(item BitwardenItem, groupBy string, names map[string]string) string {
+	if groupBy == "folder" {
+		if name, ok := names[item.FolderID]; ok && item.FolderID != "" {
+			return name
+		}
+		return noFolderGroup
+	}
+
+	var collectionNames []string
+	for _, id := range item.CollectionIDs {
+		if name, ok := names[id]; ok {
+			collectionNames = append(collectionNames, name)
+		}
+	}
+	if len(collectionNames) == 0 {
+		return noCollectionGroup
+	}
+
+	sort.Strings(collectionNames)
+	return collectionNames[0]
+}
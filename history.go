@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	historyFileName   = "history.json"
+	maxHistoryEntries = 20
+)
+
+type filterHistoryEntry struct {
+	Time       time.Time `json:"time"`
+	Args       []string  `json:"args"`
+	MatchedIDs []string  `json:"matchedIds"`
+}
+
+// Flags registered by defineSelectionFlags that control how a run is
+// processed or recalled rather than which items it matches.
+var nonFilterFlags = map[string]bool{
+	"batch":             true,
+	"b":                 true,
+	"max-batch":         true,
+	"retries":           true,
+	"retry-backoff":     true,
+	"rate":              true,
+	"delay":             true,
+	"sync-every":        true,
+	"bw-timeout":        true,
+	"ignore-bw-version": true,
+	"group-by":          true,
+	"stop-file":         true,
+	"stats-file":        true,
+	"metrics-file":      true,
+	"log-file":          true,
+	"fail-on-empty":     true,
+	"notify-url":        true,
+	"notify-desktop":    true,
+	"notify-failures":   true,
+	"pre-hook":          true,
+	"on-delete-hook":    true,
+	"post-hook":         true,
+	"last":              true,
+	"recall":            true,
+	"new-only":          true,
+	"reveal":            true,
+	"redact":            true,
+	"output":            true,
+	"failed-file":       true,
+	"ids-out":           true,
+	"from-export":       true,
+	"session":           true,
+	"server":            true,
+	"yes":               true,
+	"y":                 true,
+	"force":             true,
+	"force-threshold":   true,
+	"allow-large":       true,
+	"max-items":         true,
+	"type-to-confirm":   true,
+	"cooldown":          true,
+	"profile":           true,
+	"quiet":             true,
+	"verbose":           true,
+	"v":                 true,
+	"no-emoji":          true,
+	"no-color":          true,
+}
+
+// Flags that are not replayed when a recorded run is executed later: the
+// item IDs are already fixed, the schedule has been consumed and the plan
+// file has been written.
+var nonReplayFlags = map[string]bool{
+	"last":          true,
+	"recall":        true,
+	"new-only":      true,
+	"at":            true,
+	"after":         true,
+	"watch":         true,
+	"interval":      true,
+	"dry-run":       true,
+	"no-auto-retry": true,
+	"out":           true,
+	"session":       true,
+	"server":        true,
+	"checkpoint":    true,
+	"resume":        true,
+	"interactive":   true,
+	"profile":       true,
+	"profiles":      true,
+}
+
+func runHistoryCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s history filters|clear", filepath.Base(os.Args[0]))
+	}
+
+	switch args[0] {
+	case "filters":
+		return showFilterHistory()
+	case "clear":
+		path, err := configFile(historyFileName)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error clearing history: %w", err)
+		}
+		console.infof(emojiSuccess, "Filter history cleared")
+		return nil
+	default:
+		return fmt.Errorf("unknown history command %q (expected filters or clear)", args[0])
+	}
+}
+
+func showFilterHistory() error {
+	history, err := loadFilterHistory()
+	if err != nil {
+		return err
+	}
+
+	if len(history) == 0 {
+		console.infof(emojiInfo, "No filters recorded yet")
+		return nil
+	}
+
+	for i, entry := range history {
+		args := strings.Join(entry.Args, " ")
+		if args == "" {
+			args = "(all items)"
+		}
+		console.linef("%3d. %s  %s  (%d matched)", i+1, entry.Time.Local().Format("2006-01-02 15:04"), args, len(entry.MatchedIDs))
+	}
+	return nil
+}
+
+// recallSelection applies the filter flags of a history entry to flags when
+// --last or --recall is set. Flags given explicitly on the command line keep
+// their values.
+func recallSelection(flags *flag.FlagSet) (*filterHistoryEntry, error) {
+	index := 0
+	if flagIsTrue(flags, "last") {
+		index = 1
+	}
+	if recall := flags.Lookup("recall"); recall != nil && recall.Value.String() != "0" {
+		fmt.Sscan(recall.Value.String(), &index)
+	}
+	if index == 0 {
+		if flagIsTrue(flags, "new-only") {
+			return nil, fmt.Errorf("--new-only requires --last or --recall")
+		}
+		return nil, nil
+	}
+
+	history, err := loadFilterHistory()
+	if err != nil {
+		return nil, err
+	}
+	if index < 1 || index > len(history) {
+		return nil, fmt.Errorf("no filter history entry %d (see 'history filters')", index)
+	}
+	entry := history[index-1]
+
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	for _, arg := range entry.Args {
+		name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if explicit[name] {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return nil, fmt.Errorf("error recalling %s: %w", arg, err)
+		}
+	}
+
+	console.infof(emojiInfo, "Recalled filters: %s", strings.Join(entry.Args, " "))
+	return &entry, nil
+}
+
+// selectionArgs returns the filter flags that were set, in --name=value form.
+func selectionArgs(flags *flag.FlagSet) []string {
+	probe := flag.NewFlagSet("probe", flag.ContinueOnError)
+	defineSelectionFlags(probe)
+
+	var args []string
+	flags.Visit(func(f *flag.Flag) {
+		if probe.Lookup(f.Name) != nil && !nonFilterFlags[f.Name] {
+			args = append(args, flagArgs(f)...)
+		}
+	})
+	return args
+}
+
+// commandArgs returns every flag that was set, in --name=value form, except
+// those that only matter while selecting items or scheduling the run.
+func commandArgs(flags *flag.FlagSet) []string {
+	var args []string
+	flags.Visit(func(f *flag.Flag) {
+		if !nonReplayFlags[f.Name] {
+			args = append(args, flagArgs(f)...)
+		}
+	})
+	return args
+}
+
+// repeatedFlag is implemented by flag values that keep each occurrence of a
+// repeatable flag, so that replaying them does not merge the occurrences.
+type repeatedFlag interface {
+	values() []string
+}
+
+func flagArgs(f *flag.Flag) []string {
+	repeated, ok := f.Value.(repeatedFlag)
+	if !ok {
+		return []string{fmt.Sprintf("--%s=%s", f.Name, f.Value.String())}
+	}
+	var args []string
+	for _, value := range repeated.values() {
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, value))
+	}
+	return args
+}
+
+func flagIsTrue(flags *flag.FlagSet, name string) bool {
+	f := flags.Lookup(name)
+	return f != nil && f.Value.String() == "true"
+}
+
+// recordFilterHistory stores the selection of this run at the top of the
+// history, replacing an older entry with the same filters. Failures only
+// produce a warning because history is a convenience.
+func recordFilterHistory(options CommandOptions, items []BitwardenItem) {
+	history, err := loadFilterHistory()
+	if err != nil {
+		console.warnf("Warning: %v", err)
+		return
+	}
+
+	entry := filterHistoryEntry{Time: time.Now().UTC(), Args: options.selectionArgs}
+	for _, item := range items {
+		entry.MatchedIDs = append(entry.MatchedIDs, item.ID)
+	}
+	// A --new-only run extends the recalled selection instead of replacing it
+	for id := range options.excludeIDs {
+		entry.MatchedIDs = append(entry.MatchedIDs, id)
+	}
+
+	updated := []filterHistoryEntry{entry}
+	key := strings.Join(entry.Args, "\x00")
+	for _, old := range history {
+		if strings.Join(old.Args, "\x00") != key && len(updated) < maxHistoryEntries {
+			updated = append(updated, old)
+		}
+	}
+
+	path, err := configFile(historyFileName)
+	if err == nil {
+		err = writeJSONFile(path, updated)
+	}
+	if err != nil {
+		console.warnf("Warning: could not save filter history: %v", err)
+	}
+}
+
+func loadFilterHistory() ([]filterHistoryEntry, error) {
+	path, err := configFile(historyFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading filter history: %w", err)
+	}
+
+	var history []filterHistoryEntry
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("error parsing filter history: %w", err)
+	}
+	return history, nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"flag"
+	"slices"
+	"testing"
+)
+
+func TestSelectionArgsSkipsLimitFlags(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	defineSelectionFlags(flags)
+	if err := flags.Parse([]string{"--search", "test", "--allow-large", "--max-items", "5", "--force", "--force-threshold", "3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := selectionArgs(flags), []string{"--search=test"}; !slices.Equal(got, want) {
+		t.Errorf("selectionArgs = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	hookTimeout = 5 * time.Minute
+	hookEnv     = "BITWARDEN_CLEANUP_HOOK"
+	hookOpEnv   = "BITWARDEN_CLEANUP_OPERATION"
+)
+
+// hookItems is the input of --pre-hook: the items as bw listed them, with
+// their secrets masked unless --reveal is set.
+type hookItems struct {
+	Hook      string            `json:"hook"`
+	Operation string            `json:"operation"`
+	Items     []json.RawMessage `json:"items"`
+}
+
+// hookItem is the input of --on-delete-hook.
+type hookItem struct {
+	Hook      string          `json:"hook"`
+	Operation string          `json:"operation"`
+	Item      json.RawMessage `json:"item"`
+	Result    resultRecord    `json:"result"`
+}
+
+// hookSummary is the input of --post-hook.
+type hookSummary struct {
+	Hook      string         `json:"hook"`
+	Operation string         `json:"operation"`
+	Summary   statsLine      `json:"summary"`
+	Results   []resultRecord `json:"results"`
+}
+
+// hooks runs the commands given with --pre-hook, --on-delete-hook and
+// --post-hook around an operation.
+type hooks struct {
+	pre, item, post []string
+	op              itemOperation
+	options         CommandOptions
+
+	// queue holds the items waiting for --on-delete-hook, which runs in
+	// its own goroutine so that a slow hook never holds up a worker.
+	queue chan hookItem
+	done  chan struct{}
+}
+
+// newHooks prepares the hooks of a run over count items.
+func newHooks(op itemOperation, options CommandOptions, count int) *hooks {
+	if options.preHook == "" && options.itemHook == "" && options.postHook == "" {
+		return nil
+	}
+	// The commands were checked by validateHooks.
+	h := &hooks{op: op, options: options}
+	h.pre, _ = splitCommand(options.preHook)
+	h.item, _ = splitCommand(options.itemHook)
+	h.post, _ = splitCommand(options.postHook)
+	if h.item != nil {
+		h.queue = make(chan hookItem, count)
+		h.done = make(chan struct{})
+		go h.runItemHooks()
+	}
+	return h
+}
+
+func validateHooks(options CommandOptions) error {
+	for flag, command := range map[string]string{"pre-hook": options.preHook, "on-delete-hook": options.itemHook, "post-hook": options.postHook} {
+		if command == "" {
+			continue
+		}
+		if _, err := splitCommand(command); err != nil {
+			return fmt.Errorf("invalid --%s: %w", flag, err)
+		}
+	}
+	return nil
+}
+
+// before runs --pre-hook with the items about to be processed. A hook that
+// fails stops the run before any item is touched.
+func (h *hooks) before(items []BitwardenItem) error {
+	if h == nil || h.pre == nil {
+		return nil
+	}
+	input := hookItems{Hook: "pre", Operation: h.op.verb, Items: make([]json.RawMessage, 0, len(items))}
+	for _, item := range items {
+		input.Items = append(input.Items, maskedItem(item, h.options))
+	}
+	console.infof(emojiStart, "Running pre-hook for %d items...", len(items))
+	if err := runHook(h.pre, "pre", h.op, input); err != nil {
+		return fmt.Errorf("error running --pre-hook, no items were processed: %w", err)
+	}
+	return nil
+}
+
+// observe queues --on-delete-hook for an item the operation succeeded on.
+// The queue holds every item of the run, so the worker never waits.
+func (h *hooks) observe(item BitwardenItem, result itemResult) {
+	if h == nil || h.item == nil || result.status() != resultDone {
+		return
+	}
+	h.queue <- hookItem{Hook: "item", Operation: h.op.verb, Item: maskedItem(item, h.options), Result: result.record(h.options.redact)}
+}
+
+// runItemHooks runs --on-delete-hook for the queued items one at a time, in
+// the order the items were done.
+func (h *hooks) runItemHooks() {
+	defer close(h.done)
+	for input := range h.queue {
+		if err := runHook(h.item, "item", h.op, input); err != nil {
+			console.warnf("Warning: --on-delete-hook failed for %s (%s): %v", input.Result.Name, input.Result.ItemID, err)
+		}
+	}
+}
+
+// finish waits for the queued --on-delete-hook runs, then runs --post-hook
+// with the counts and the result of every item.
+func (h *hooks) finish(stats *DeleteStats) {
+	if h == nil {
+		return
+	}
+	if h.queue != nil {
+		close(h.queue)
+		<-h.done
+	}
+	if h.post == nil {
+		return
+	}
+	input := hookSummary{Hook: "post", Operation: h.op.verb, Summary: summaryLine(stats, h.op, h.options)}
+	stats.mu.Lock()
+	input.Results = resultRecords(stats.results, h.options.redact)
+	stats.mu.Unlock()
+	if err := runHook(h.post, "post", h.op, input); err != nil {
+		console.warnf("Warning: --post-hook failed: %v", err)
+	}
+}
+
+// runHook starts command directly, without a shell, with input as JSON on
+// its stdin. Its output goes where the console output goes, so that it
+// stays out of the events of --output json.
+func runHook(command []string, hook string, op itemOperation, input any) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("error encoding hook input: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.WaitDelay = time.Second
+	killTreeOnCancel(cmd)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout, cmd.Stderr = ui, os.Stderr
+	cmd.Env = append(os.Environ(), hookEnv+"="+hook, hookOpEnv+"="+op.verb)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s was killed after %s", command[0], hookTimeout)
+		}
+		return err
+	}
+	return nil
+}
+
+// splitCommand splits a hook command into its arguments the way a POSIX
+// shell would, honoring single and double quotes and backslashes, but
+// without expanding anything. Pipes and redirections need an explicit
+// sh -c '...'.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			// Within double quotes, a backslash only escapes the
+			// characters that are special there.
+			if quote == '"' && !strings.ContainsRune("$`\"\\\n", r) {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\' && quote == '"':
+			escaped = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, command)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in %q", command)
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return args, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"notify-send done", []string{"notify-send", "done"}},
+		{"  spaced \t out\n", []string{"spaced", "out"}},
+		{`echo 'single $HOME "quoted"'`, []string{"echo", `single $HOME "quoted"`}},
+		{`echo "double \"quoted\" \$x \n"`, []string{"echo", `double "quoted" $x \n`}},
+		{`echo escaped\ space`, []string{"echo", "escaped space"}},
+		{`echo ''`, []string{"echo", ""}},
+		{`echo a""b`, []string{"echo", "ab"}},
+		{`/usr/bin/logger -t "bitwarden cleanup"`, []string{"/usr/bin/logger", "-t", "bitwarden cleanup"}},
+	}
+	for _, test := range tests {
+		got, err := splitCommand(test.command)
+		if err != nil {
+			t.Errorf("splitCommand(%q): %v", test.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", test.command, got, test.want)
+		}
+	}
+}
+
+func TestSplitCommandErrors(t *testing.T) {
+	tests := []struct {
+		command, want string
+	}{
+		{`echo 'open`, `unterminated ' quote in "echo 'open"`},
+		{`echo "open`, `unterminated " quote in "echo \"open"`},
+		{`echo \`, `trailing backslash in "echo \\"`},
+		{"", "empty command"},
+		{"   ", "empty command"},
+	}
+	for _, test := range tests {
+		_, err := splitCommand(test.command)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("splitCommand(%q) error = %v, want %q", test.command, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// readItemIDs reads a list of item IDs, one per line, from path, or from
+// stdin when path is "-". Only the first field of a line is used, so files
+// written by --failed-file and 'list' output cut down to its IDs both work.
+// Blank lines and lines starting with # are skipped.
+func readItemIDs(path string) ([]string, error) {
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error opening ID list: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+		ids = append(ids, fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading ID list: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no item IDs in %s", idListName(path))
+	}
+	return ids, nil
+}
+
+// selectListedItems returns the items whose IDs are listed at path, in the
+// order of the list. Listed IDs that are not in the vault are reported and
+// left out.
+func selectListedItems(path string, items []BitwardenItem) ([]BitwardenItem, error) {
+	ids, err := readItemIDs(path)
+	if err != nil {
+		return nil, err
+	}
+	if path == "-" {
+		if err := reconnectTerminal(); err != nil {
+			return nil, err
+		}
+	}
+
+	byID := make(map[string]BitwardenItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+	var selected []BitwardenItem
+	var missing []string
+	for _, id := range ids {
+		if item, ok := byID[id]; ok {
+			selected = append(selected, item)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	console.infof(emojiInfo, "%d of the %d IDs in %s are in the vault", len(selected), len(ids), idListName(path))
+	if len(missing) > 0 {
+		console.warnf("%d listed IDs were not found and are skipped", len(missing))
+		for _, id := range missing {
+			console.debugf("", "   not found: %s", id)
+		}
+	}
+	return selected, nil
+}
+
+// reconnectTerminal points stdin at the terminal once an ID list was read
+// from a pipe, so that the run can still be confirmed. Without a terminal
+// the run has to be confirmed up front with --yes.
+func reconnectTerminal() error {
+	if unattended.yes {
+		return nil
+	}
+	tty, err := os.Open(terminalDevice)
+	if err != nil {
+		return fmt.Errorf("the IDs were read from stdin and there is no terminal to confirm on; add --yes")
+	}
+	os.Stdin = tty
+	stdinReader = bufio.NewReader(tty)
+	return nil
+}
+
+func idListName(path string) string {
+	if path == "-" {
+		return "stdin"
+	}
+	return path
+}
+
+// writeIDList writes the IDs of items to path, one per line, in the form
+// readItemIDs reads back for --ids-file. Nothing is written when path is
+// empty.
+func writeIDList(path string, items []BitwardenItem) error {
+	if path == "" {
+		return nil
+	}
+	var list strings.Builder
+	fmt.Fprintf(&list, "# %d items selected by %s %s on %s\n", len(items), filepath.Base(os.Args[0]), shellJoin(os.Args[1:]), time.Now().Format("2006-01-02 15:04"))
+	for _, item := range items {
+		list.WriteString(item.ID + "\n")
+	}
+	if err := os.WriteFile(path, []byte(list.String()), 0o600); err != nil {
+		return fmt.Errorf("error writing ID list: %w", err)
+	}
+	console.infof(emojiSuccess, "Wrote the IDs of %d items to %s", len(items), path)
+	return nil
+}
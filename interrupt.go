@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+)
+
+// exitInterrupted is the exit status of a run stopped with Ctrl-C, the same
+// status a shell reports for a process killed by SIGINT.
+const exitInterrupted = 130
+
+var errInterrupted = errors.New("interrupted by Ctrl-C")
+
+// interruptContext returns a context that is cancelled by the first Ctrl-C,
+// after which the workers start no new items and wait for the ones in
+// flight. A second Ctrl-C exits at once. Call stop once the run is over.
+func interruptContext() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupt := make(chan os.Signal, 1)
+	finished := make(chan struct{})
+	signal.Notify(interrupt, os.Interrupt)
+
+	go func() {
+		select {
+		case <-interrupt:
+		case <-finished:
+			return
+		}
+		console.warnf("\nInterrupted, finishing in-flight items (press Ctrl-C again to quit immediately)...")
+		cancel()
+
+		select {
+		case <-interrupt:
+			if api, ok := vault.(*bwServeClient); ok {
+				api.close()
+			}
+			os.Exit(exitInterrupted)
+		case <-finished:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(interrupt)
+		close(finished)
+		cancel()
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// updateItem performs a full get/modify/edit round-trip on a single item.
+// The item is decoded into a generic map so fields this tool does not know
+// about survive the edit unchanged. The edit is skipped when mutate reports
+// that nothing changed.
+func updateItem(itemID string, mutate func(item map[string]any) bool) error {
+	item, err := getItemJSON(itemID)
+	if err != nil {
+		return err
+	}
+
+	if !mutate(item) {
+		return nil
+	}
+
+	return editItemJSON(itemID, item)
+}
+
+func getItemJSON(itemID string) (map[string]any, error) {
+	output, err := vault.GetItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	decoder.UseNumber()
+
+	var item map[string]any
+	if err := decoder.Decode(&item); err != nil {
+		return nil, fmt.Errorf("error parsing item: %w", err)
+	}
+
+	return item, nil
+}
+
+func editItemJSON(itemID string, item map[string]any) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("error encoding item: %w", err)
+	}
+
+	return vault.EditItem(itemID, data)
+}
+
+// createItemJSON creates a new item from item and returns its ID.
+func createItemJSON(item map[string]any) (string, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return "", fmt.Errorf("error encoding item: %w", err)
+	}
+
+	output, err := vault.CreateItem(data)
+	if err != nil {
+		return "", err
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(output, &created); err != nil || created.ID == "" {
+		return "", fmt.Errorf("error parsing created item: %s", strings.TrimSpace(string(output)))
+	}
+	return created.ID, nil
+}
+
+func trimPasswordHistory(item map[string]any, keep int) bool {
+	history, _ := item["passwordHistory"].([]any)
+	if len(history) <= keep {
+		return false
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return historyDate(history[i]) > historyDate(history[j])
+	})
+
+	item["passwordHistory"] = append([]any{}, history[:keep]...)
+	return true
+}
+
+func historyDate(entry any) string {
+	fields, _ := entry.(map[string]any)
+	date, _ := fields["lastUsedDate"].(string)
+	return date
+}
+
+func stripPasskeys(item map[string]any) bool {
+	login, _ := item["login"].(map[string]any)
+	credentials, _ := login["fido2Credentials"].([]any)
+	if len(credentials) == 0 {
+		return false
+	}
+
+	login["fido2Credentials"] = []any{}
+	return true
+}
+
+var uriMatchTypes = map[string]any{
+	"base-domain": json.Number("0"),
+	"host":        json.Number("1"),
+	"starts-with": json.Number("2"),
+	"exact":       json.Number("3"),
+	"regex":       json.Number("4"),
+	"never":       json.Number("5"),
+	"default":     nil,
+}
+
+func parseURIMatch(name string) (any, error) {
+	match, ok := uriMatchTypes[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown URI match type %q (expected base-domain, host, starts-with, exact, regex, never or default)", name)
+	}
+	return match, nil
+}
+
+// setURIMatch sets the match detection of every URI on a login item. A nil
+// match resets the URIs to the account's default match detection.
+func setURIMatch(item map[string]any, match any) bool {
+	login, _ := item["login"].(map[string]any)
+	uris, _ := login["uris"].([]any)
+
+	changed := false
+	for _, entry := range uris {
+		uri, ok := entry.(map[string]any)
+		if !ok || uri["match"] == match {
+			continue
+		}
+		uri["match"] = match
+		changed = true
+	}
+	return changed
+}
+
+// appendNotesLine adds line to the end of the item notes unless the notes
+// already contain it, so repeated runs do not stack identical stamps.
+func appendNotesLine(item map[string]any, line string) bool {
+	notes, _ := item["notes"].(string)
+	for _, existing := range strings.Split(notes, "\n") {
+		if strings.TrimSpace(existing) == line {
+			return false
+		}
+	}
+
+	if notes != "" && !strings.HasSuffix(notes, "\n") {
+		notes += "\n"
+	}
+	item["notes"] = notes + line
+	return true
+}
+
+// removeNotesLine removes every line of the item notes that is line.
+func removeNotesLine(item map[string]any, line string) bool {
+	notes, _ := item["notes"].(string)
+	var kept []string
+	for _, existing := range strings.Split(notes, "\n") {
+		if strings.TrimSpace(existing) != line {
+			kept = append(kept, existing)
+		}
+	}
+	remaining := strings.TrimRight(strings.Join(kept, "\n"), "\n")
+	if remaining == strings.TrimRight(notes, "\n") {
+		return false
+	}
+	if remaining == "" {
+		item["notes"] = nil
+	} else {
+		item["notes"] = remaining
+	}
+	return true
+}
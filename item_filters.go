@@ -0,0 +1,456 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mitas/bitwarden-cleanup/pkg/cleanup"
+)
+
+// itemFilter is a filter of the cleanup engine over vault items, so the
+// engine's combinators apply to the filters of the selection flags.
+type itemFilter = cleanup.Filter[BitwardenItem]
+
+// Values of --match.
+const (
+	matchAll = "all"
+	matchAny = "any"
+)
+
+func buildItemFilters(options CommandOptions) ([]itemFilter, error) {
+	var filters []itemFilter
+
+	nameRules := options.exactName || options.startsWith || options.caseSensitive
+	if options.matchWords && len(options.searchTerms) == 0 {
+		return nil, fmt.Errorf("--match-words requires a search term")
+	}
+	if nameRules && len(options.searchTerms) == 0 {
+		return nil, fmt.Errorf("--exact, --starts-with and --case-sensitive require a search term")
+	}
+	if options.exactName && options.startsWith {
+		return nil, fmt.Errorf("--exact and --starts-with cannot be used together")
+	}
+	if options.fuzzy {
+		if len(options.searchTerms) == 0 {
+			return nil, fmt.Errorf("--fuzzy requires a search term")
+		}
+		if options.matchWords || nameRules {
+			return nil, fmt.Errorf("--fuzzy cannot be combined with --match-words, --exact, --starts-with or --case-sensitive")
+		}
+		if options.fuzzyThreshold <= 0 || options.fuzzyThreshold > 1 {
+			return nil, fmt.Errorf("--fuzzy-threshold must be greater than 0 and at most 1")
+		}
+	}
+	if options.fuzzy && len(options.searchTerms) == 1 {
+		filters = append(filters, fuzzyNameFilter(options.searchTerms[0], options.fuzzyThreshold))
+	} else if len(options.searchTerms) > 1 {
+		filters = append(filters, searchTermsFilter(options))
+	} else {
+		if options.matchWords {
+			filters = append(filters, func(item BitwardenItem) bool { return containsWords(item.Name, options.searchTerm) })
+		}
+		if nameRules {
+			filters = append(filters, searchNameFilter(options, options.searchTerm))
+		}
+	}
+
+	if options.regex != "" {
+		pattern, err := regexp.Compile(options.regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --regex: %w", err)
+		}
+		filters = append(filters, func(item BitwardenItem) bool {
+			return pattern.MatchString(item.Name) || pattern.MatchString(item.username()) || slices.ContainsFunc(item.uris(), pattern.MatchString)
+		})
+	}
+	if options.regexURI != "" {
+		pattern, err := regexp.Compile(options.regexURI)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --regex-uri: %w", err)
+		}
+		filters = append(filters, func(item BitwardenItem) bool { return slices.ContainsFunc(item.uris(), pattern.MatchString) })
+	}
+	if options.glob != "" {
+		pattern, err := compileGlob(options.glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --glob: %w", err)
+		}
+		filters = append(filters, func(item BitwardenItem) bool { return pattern.MatchString(item.Name) })
+	}
+	if len(options.uris) > 0 {
+		filter, err := uriFilter(options.uris)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	if len(options.domains) > 0 || options.domainFile != "" {
+		filter, err := domainFilter(options.domains, options.domainFile)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	if options.itemTypes != "" {
+		types, err := parseItemTypes(options.itemTypes)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(item BitwardenItem) bool { return types[item.Type] })
+	}
+
+	if options.folder != "" && options.folderID != "" {
+		return nil, fmt.Errorf("--folder and --folder-id cannot be used together")
+	}
+	if options.folder != "" || options.folderID != "" {
+		folderID := options.folderID
+		if options.folder != "" {
+			var err error
+			if folderID, err = resolveFolderID(options.folder); err != nil {
+				return nil, err
+			}
+		}
+		filters = append(filters, func(item BitwardenItem) bool { return item.FolderID == folderID })
+	}
+
+	if options.organizationID != "" {
+		filters = append(filters, func(item BitwardenItem) bool { return item.OrganizationID == options.organizationID })
+	}
+	if options.collection != "" {
+		collection, err := resolveCollection(options.organizationID, options.collection)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(item BitwardenItem) bool { return slices.Contains(item.CollectionIDs, collection.ID) })
+	}
+
+	dateBounds := []struct {
+		flag   string
+		value  string
+		date   func(item BitwardenItem) string
+		before bool
+	}{
+		{"created-before", options.createdBefore, func(item BitwardenItem) string { return item.CreationDate }, true},
+		{"created-after", options.createdAfter, func(item BitwardenItem) string { return item.CreationDate }, false},
+		{"modified-before", options.modifiedBefore, func(item BitwardenItem) string { return item.RevisionDate }, true},
+		{"modified-after", options.modifiedAfter, func(item BitwardenItem) string { return item.RevisionDate }, false},
+	}
+	for _, bound := range dateBounds {
+		if bound.value == "" {
+			continue
+		}
+		cutoff, err := parseDateBound(bound.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", bound.flag, err)
+		}
+		date, before := bound.date, bound.before
+		filters = append(filters, func(item BitwardenItem) bool {
+			value, err := time.Parse(time.RFC3339, date(item))
+			return err == nil && value.Before(cutoff) == before
+		})
+	}
+
+	if options.hasPasskey && options.noPasskey {
+		return nil, fmt.Errorf("--has-passkey and --no-passkey cannot be used together")
+	}
+	if options.hasPasskey {
+		filters = append(filters, func(item BitwardenItem) bool { return item.hasPasskey() })
+	}
+	if options.noPasskey {
+		filters = append(filters, func(item BitwardenItem) bool { return !item.hasPasskey() })
+	}
+
+	if options.onlyIncomplete {
+		filters = append(filters, func(item BitwardenItem) bool { return item.incompleteLogin() })
+	}
+	for _, rule := range options.fields {
+		name, value, hasValue := strings.Cut(rule, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid --field %q: expected name=value or name", rule)
+		}
+		filters = append(filters, func(item BitwardenItem) bool {
+			return slices.ContainsFunc(item.Fields, func(field BitwardenField) bool {
+				return strings.EqualFold(field.Name, name) && (!hasValue || field.Value == value)
+			})
+		})
+	}
+	if options.notesContains != "" {
+		needle := strings.ToLower(options.notesContains)
+		filters = append(filters, func(item BitwardenItem) bool { return strings.Contains(strings.ToLower(item.Notes), needle) })
+	}
+	if len(options.usernames) > 0 {
+		matches, err := accountMatcher("username", options.usernames)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(item BitwardenItem) bool { return item.Login != nil && matches(item.username()) })
+	}
+	if len(options.emails) > 0 {
+		matches, err := accountMatcher("email", options.emails)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(item BitwardenItem) bool {
+			if item.Login != nil {
+				return matches(item.username())
+			}
+			return item.Type == itemTypeIdentity && matches(identityEmail(item))
+		})
+	}
+	if options.tagged != "" {
+		filters = append(filters, func(item BitwardenItem) bool {
+			return hasTag(item, options.tagged, true) || hasTag(item, options.tagged, false)
+		})
+	}
+
+	if options.hasAppURI {
+		filters = append(filters, func(item BitwardenItem) bool { return len(item.appURIs()) > 0 })
+	}
+	if options.appURI != "" {
+		needle := strings.ToLower(options.appURI)
+		filters = append(filters, func(item BitwardenItem) bool {
+			for _, uri := range item.appURIs() {
+				if strings.Contains(strings.ToLower(uri), needle) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if options.appURIOnly {
+		filters = append(filters, func(item BitwardenItem) bool {
+			apps := item.appURIs()
+			return len(apps) > 0 && len(apps) == len(item.uris())
+		})
+	}
+
+	if len(options.excludeIDs) > 0 {
+		filters = append(filters, func(item BitwardenItem) bool { return !options.excludeIDs[item.ID] })
+	}
+
+	return filters, nil
+}
+
+// parseDateBound reads a date (2024-01-31 in local time, or RFC 3339) or an
+// age such as 2y, which stands for that long before now.
+func parseDateBound(value string) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	if date, err := time.ParseInLocation(reviewDateLayout, value, time.Local); err == nil {
+		return date, nil
+	}
+	age, err := parseAge(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither a date (2024-01-31 or RFC 3339) nor an age (e.g. 2y, 90d)", value)
+	}
+	return time.Now().Add(-age), nil
+}
+
+func filterItems(items []BitwardenItem, filters []itemFilter) []BitwardenItem {
+	if len(filters) == 0 {
+		return items
+	}
+	return cleanup.Matching(items, cleanup.All(filters...))
+}
+
+func (item BitwardenItem) hasPasskey() bool {
+	return item.Login != nil && len(item.Login.Fido2Credentials) > 0
+}
+
+// incompleteLogin reports whether the item is a login that holds nothing
+// worth keeping: no password, username, URI, TOTP secret, passkey or notes.
+func (item BitwardenItem) incompleteLogin() bool {
+	if item.Type != itemTypeLogin || strings.TrimSpace(item.Notes) != "" {
+		return false
+	}
+	if item.Login == nil {
+		return true
+	}
+	login := item.Login
+	return login.Password == "" && strings.TrimSpace(login.Username) == "" && login.TOTP == "" &&
+		len(item.uris()) == 0 && !item.hasPasskey()
+}
+
+func (item BitwardenItem) uris() []string {
+	if item.Login == nil {
+		return nil
+	}
+
+	var uris []string
+	for _, uri := range item.Login.URIs {
+		if uri.URI != "" {
+			uris = append(uris, uri.URI)
+		}
+	}
+	return uris
+}
+
+func (item BitwardenItem) appURIs() []string {
+	var apps []string
+	for _, uri := range item.uris() {
+		if isAppURI(uri) {
+			apps = append(apps, uri)
+		}
+	}
+	return apps
+}
+
+func isAppURI(uri string) bool {
+	lower := strings.ToLower(uri)
+	return strings.HasPrefix(lower, "androidapp://") || strings.HasPrefix(lower, "iosapp://")
+}
+
+// accountMatcher matches a username or email address against the values of
+// --username or --email: exactly but ignoring case, or as a glob when a value
+// has a * or ?. Empty usernames never match.
+func accountMatcher(flag string, values []string) (func(account string) bool, error) {
+	var exact []string
+	var globs []*regexp.Regexp
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return nil, fmt.Errorf("--%s must not be empty", flag)
+		}
+		if !strings.ContainsAny(value, "*?") {
+			exact = append(exact, value)
+			continue
+		}
+		pattern, err := compileGlob(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s %s: %w", flag, value, err)
+		}
+		globs = append(globs, pattern)
+	}
+	return func(account string) bool {
+		account = strings.TrimSpace(account)
+		if account == "" {
+			return false
+		}
+		return slices.ContainsFunc(exact, func(value string) bool { return strings.EqualFold(value, account) }) ||
+			slices.ContainsFunc(globs, func(pattern *regexp.Regexp) bool { return pattern.MatchString(account) })
+	}, nil
+}
+
+func identityEmail(item BitwardenItem) string {
+	details, err := decodeItemDetails(item)
+	if err != nil {
+		return ""
+	}
+	return details.Identity["email"]
+}
+
+// searchNameFilter matches the item name against a search term the way
+// bw --search cannot: as the whole name, as its start, or in the same case.
+// bw also matches usernames and URIs; these filters only look at the name.
+func searchNameFilter(options CommandOptions, term string) itemFilter {
+	fold := strings.ToLower
+	if options.caseSensitive {
+		fold = func(s string) string { return s }
+	}
+	term = fold(term)
+
+	return func(item BitwardenItem) bool {
+		name := fold(item.Name)
+		switch {
+		case options.exactName:
+			return name == term
+		case options.startsWith:
+			return strings.HasPrefix(name, term)
+		}
+		return strings.Contains(name, term)
+	}
+}
+
+// searchTermsFilter matches several search terms locally, since bw searches
+// for one term only. Each term matches like bw --search, or like --fuzzy,
+// --match-words, --exact, --starts-with and --case-sensitive when given,
+// and --match decides whether all terms or any of them must match.
+func searchTermsFilter(options CommandOptions) itemFilter {
+	var matchers []itemFilter
+	for _, term := range options.searchTerms {
+		var rules []itemFilter
+		if options.fuzzy {
+			rules = append(rules, fuzzyNameFilter(term, options.fuzzyThreshold))
+		}
+		if options.matchWords {
+			rules = append(rules, func(item BitwardenItem) bool { return containsWords(item.Name, term) })
+		}
+		if options.exactName || options.startsWith || options.caseSensitive {
+			rules = append(rules, searchNameFilter(options, term))
+		}
+		if len(rules) == 0 {
+			rules = append(rules, bwSearchFilter(term))
+		}
+		matchers = append(matchers, cleanup.All(rules...))
+	}
+
+	return func(item BitwardenItem) bool {
+		return matchSearchTerms(options, func(i int) bool { return matchers[i](item) })
+	}
+}
+
+// matchSearchTerms reports whether the terms of --search match with --match,
+// given whether the term at each index matches.
+func matchSearchTerms(options CommandOptions, matches func(i int) bool) bool {
+	for i := range options.searchTerms {
+		switch matched := matches(i); {
+		case matched && options.searchMatch == matchAny:
+			return true
+		case !matched && options.searchMatch == matchAll:
+			return false
+		}
+	}
+	return options.searchMatch == matchAll
+}
+
+// bwSearchFilter matches a term the way bw --search does: in the name, the
+// username or a URI, ignoring case, or as the start of the ID for terms of
+// eight characters or more.
+func bwSearchFilter(term string) itemFilter {
+	term = strings.ToLower(term)
+	return func(item BitwardenItem) bool {
+		if len(term) >= 8 && strings.HasPrefix(strings.ToLower(item.ID), term) {
+			return true
+		}
+		if strings.Contains(strings.ToLower(item.Name), term) || strings.Contains(strings.ToLower(item.username()), term) {
+			return true
+		}
+		return slices.ContainsFunc(item.uris(), func(uri string) bool { return strings.Contains(strings.ToLower(uri), term) })
+	}
+}
+
+// containsWords reports whether term occurs in text, ignoring case, with no
+// letter or digit directly before or after it.
+func containsWords(text, term string) bool {
+	haystack := []rune(strings.ToLower(text))
+	needle := []rune(strings.ToLower(strings.TrimSpace(term)))
+	if len(needle) == 0 {
+		return false
+	}
+
+	for start := 0; start+len(needle) <= len(haystack); start++ {
+		if string(haystack[start:start+len(needle)]) != string(needle) {
+			continue
+		}
+		end := start + len(needle)
+		if start > 0 && isWordRune(haystack[start-1]) {
+			continue
+		}
+		if end < len(haystack) && isWordRune(haystack[end]) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateBound(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-01-31T12:30:00Z", time.Date(2024, 1, 31, 12, 30, 0, 0, time.UTC)},
+		{"2024-01-31T12:30:00+02:00", time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)},
+		{"2024-01-31", time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local)},
+	}
+	for _, test := range tests {
+		got, err := parseDateBound(test.value)
+		if err != nil || !got.Equal(test.want) {
+			t.Errorf("parseDateBound(%q) = %v, %v; want %v", test.value, got, err, test.want)
+		}
+	}
+}
+
+func TestParseDateBoundAges(t *testing.T) {
+	tests := []struct {
+		value string
+		age   time.Duration
+	}{
+		{"90d", 90 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"36h", 36 * time.Hour},
+		{"0d", 0},
+	}
+	for _, test := range tests {
+		before := time.Now()
+		got, err := parseDateBound(test.value)
+		if err != nil {
+			t.Errorf("parseDateBound(%q): %v", test.value, err)
+			continue
+		}
+		if earliest, latest := before.Add(-test.age), time.Now().Add(-test.age); got.Before(earliest) || got.After(latest) {
+			t.Errorf("parseDateBound(%q) = %v, want %s ago", test.value, got, test.age)
+		}
+	}
+}
+
+func TestParseDateBoundErrors(t *testing.T) {
+	for _, value := range []string{"", "yesterday", "2024-13-01", "31.01.2024", "-5d", "5x"} {
+		if got, err := parseDateBound(value); err == nil {
+			t.Errorf("parseDateBound(%q) = %v, want an error", value, got)
+		}
+	}
+}
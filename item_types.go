@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Bitwarden item types.
+const (
+	itemTypeLogin    = 1
+	itemTypeNote     = 2
+	itemTypeCard     = 3
+	itemTypeIdentity = 4
+	itemTypeSSHKey   = 5
+)
+
+var itemTypeNames = map[int]string{
+	itemTypeLogin:    "login",
+	itemTypeNote:     "note",
+	itemTypeCard:     "card",
+	itemTypeIdentity: "identity",
+	itemTypeSSHKey:   "sshkey",
+}
+
+var itemTypeAliases = map[string]int{
+	"login":       itemTypeLogin,
+	"note":        itemTypeNote,
+	"securenote":  itemTypeNote,
+	"secure-note": itemTypeNote,
+	"card":        itemTypeCard,
+	"identity":    itemTypeIdentity,
+	"sshkey":      itemTypeSSHKey,
+	"ssh-key":     itemTypeSSHKey,
+	"ssh":         itemTypeSSHKey,
+}
+
+// parseItemTypes parses a comma-separated list of item type names.
+func parseItemTypes(value string) (map[int]bool, error) {
+	types := make(map[int]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		itemType, ok := itemTypeAliases[name]
+		if !ok {
+			var valid []string
+			for _, known := range itemTypeNames {
+				valid = append(valid, known)
+			}
+			sort.Strings(valid)
+			return nil, fmt.Errorf("unknown item type %q (expected %s)", name, strings.Join(valid, ", "))
+		}
+		types[itemType] = true
+	}
+	return types, nil
+}
+
+// itemTypeList collects the values of a repeatable --type flag, each of
+// which may itself list several types separated by commas.
+type itemTypeList []string
+
+func (list *itemTypeList) Set(value string) error {
+	if _, err := parseItemTypes(value); err != nil {
+		return err
+	}
+	*list = append(*list, value)
+	return nil
+}
+
+func (list *itemTypeList) String() string {
+	if list == nil {
+		return ""
+	}
+	return strings.Join(*list, ",")
+}
+
+// typeName names the item's type, including types this tool has no special
+// handling for, so they are never mistaken for a known type.
+func (item BitwardenItem) typeName() string {
+	if name, ok := itemTypeNames[item.Type]; ok {
+		return name
+	}
+	return fmt.Sprintf("type %d", item.Type)
+}
+
+func (item BitwardenItem) hasPrivateKey() bool {
+	return item.Type == itemTypeSSHKey && item.SSHKey != nil && item.SSHKey.PrivateKey != ""
+}
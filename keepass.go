@@ -0,0 +1,232 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type keePassFile struct {
+	XMLName xml.Name    `xml:"KeePassFile"`
+	Meta    keePassMeta `xml:"Meta"`
+	Root    struct {
+		Group *keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassMeta struct {
+	Generator    string `xml:"Generator"`
+	DatabaseName string `xml:"DatabaseName"`
+}
+
+type keePassGroup struct {
+	UUID    string          `xml:"UUID"`
+	Name    string          `xml:"Name"`
+	Entries []keePassEntry  `xml:"Entry"`
+	Groups  []*keePassGroup `xml:"Group"`
+}
+
+type keePassEntry struct {
+	UUID    string          `xml:"UUID"`
+	Strings []keePassString `xml:"String"`
+}
+
+type keePassString struct {
+	Key   string       `xml:"Key"`
+	Value keePassValue `xml:"Value"`
+}
+
+type keePassValue struct {
+	Protected string `xml:"ProtectInMemory,attr,omitempty"`
+	Text      string `xml:",chardata"`
+}
+
+var keePassCardFields = []struct{ key, label string }{
+	{"cardholderName", "Cardholder"},
+	{"brand", "Brand"},
+	{"number", "Number"},
+	{"expMonth", "Expiry month"},
+	{"expYear", "Expiry year"},
+	{"code", "Security code"},
+}
+
+var keePassIdentityFields = []struct{ key, label string }{
+	{"title", "Honorific"},
+	{"firstName", "First name"},
+	{"middleName", "Middle name"},
+	{"lastName", "Last name"},
+	{"company", "Company"},
+	{"email", "Email"},
+	{"phone", "Phone"},
+	{"address1", "Address 1"},
+	{"address2", "Address 2"},
+	{"address3", "Address 3"},
+	{"city", "City"},
+	{"state", "State"},
+	{"postalCode", "Postal code"},
+	{"country", "Country"},
+	{"username", "Username"},
+	{"ssn", "SSN"},
+	{"passportNumber", "Passport number"},
+	{"licenseNumber", "License number"},
+}
+
+// exportKeePass writes items to path as a KeePass 2 XML file that KeePass
+// and KeePassXC can import. Folders become groups below a root group, with
+// "/" in folder names creating nested groups as in Bitwarden.
+func exportKeePass(items []BitwardenItem, path string) error {
+	folders, err := vault.ListFolders()
+	if err != nil {
+		return err
+	}
+	folderNames := make(map[string]string, len(folders))
+	for _, folder := range folders {
+		folderNames[folder.ID] = folder.Name
+	}
+
+	rootName := "Bitwarden cleanup " + time.Now().Format("2006-01-02")
+	root := &keePassGroup{UUID: keePassUUID("root:" + rootName), Name: rootName}
+	for _, item := range items {
+		details, err := decodeItemDetails(item)
+		if err != nil {
+			return err
+		}
+		group := keePassGroupFor(root, folderNames[item.FolderID])
+		group.Entries = append(group.Entries, keePassEntryFor(item, details))
+	}
+
+	document := keePassFile{Meta: keePassMeta{Generator: "bitwarden-cleanup", DatabaseName: rootName}}
+	document.Root.Group = root
+
+	data, err := xml.MarshalIndent(document, "", "\t")
+	if err != nil {
+		return fmt.Errorf("error encoding KeePass export: %w", err)
+	}
+	data = append([]byte(`<?xml version="1.0" encoding="utf-8" standalone="yes"?>`+"\n"), data...)
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("error writing KeePass export: %w", err)
+	}
+
+	console.infof(emojiSuccess, "Exported %d items to KeePass XML %s", len(items), path)
+	return nil
+}
+
+func keePassGroupFor(root *keePassGroup, folderName string) *keePassGroup {
+	group := root
+	path := ""
+	for _, part := range strings.Split(folderName, "/") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		path += "/" + part
+
+		var child *keePassGroup
+		for _, existing := range group.Groups {
+			if existing.Name == part {
+				child = existing
+				break
+			}
+		}
+		if child == nil {
+			child = &keePassGroup{UUID: keePassUUID("folder:" + path), Name: part}
+			group.Groups = append(group.Groups, child)
+		}
+		group = child
+	}
+	return group
+}
+
+func keePassEntryFor(item BitwardenItem, details itemDetails) keePassEntry {
+	entry := keePassEntry{
+		UUID:    keePassUUID(item.ID),
+		Strings: []keePassString{{Key: "Title", Value: keePassValue{Text: item.Name}}},
+	}
+	add := func(key, value string, protected bool) {
+		if value == "" {
+			return
+		}
+		field := keePassString{Key: key, Value: keePassValue{Text: value}}
+		if protected {
+			field.Value.Protected = "True"
+		}
+		entry.Strings = append(entry.Strings, field)
+	}
+
+	if login := details.Login; login != nil {
+		add("UserName", login.Username, false)
+		add("Password", login.Password, true)
+		for i, uri := range login.URIs {
+			if i == 0 {
+				add("URL", uri.URI, false)
+			} else {
+				add(fmt.Sprintf("URL %d", i+1), uri.URI, false)
+			}
+		}
+		if login.TOTP != "" {
+			add("otp", keePassOTP(login.TOTP, item.Name), true)
+		}
+	}
+	add("Notes", details.Notes, false)
+
+	for _, field := range keePassCardFields {
+		add(field.label, details.Card[field.key], field.key == "number" || field.key == "code")
+	}
+	for _, field := range keePassIdentityFields {
+		add(field.label, details.Identity[field.key], field.key == "ssn")
+	}
+
+	if key := details.SSHKey; key != nil {
+		add("Private key", key.PrivateKey, true)
+		add("Public key", key.PublicKey, false)
+		add("Fingerprint", key.KeyFingerprint, false)
+	}
+
+	for _, field := range details.Fields {
+		name := field.Name
+		if name == "" {
+			name = "Field"
+		}
+		for keePassHasKey(entry, name) {
+			name += " (Bitwarden)"
+		}
+		add(name, field.Value, field.Type == hiddenFieldType)
+	}
+
+	return entry
+}
+
+func keePassHasKey(entry keePassEntry, key string) bool {
+	for _, field := range entry.Strings {
+		if field.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// keePassOTP turns a bare TOTP secret into the otpauth:// URI that
+// KeePassXC reads from the "otp" field; URIs are kept as they are.
+func keePassOTP(totp, label string) string {
+	if strings.Contains(totp, "://") {
+		return totp
+	}
+	secret := strings.ToUpper(strings.ReplaceAll(totp, " ", ""))
+	return fmt.Sprintf("otpauth://totp/%s?secret=%s", url.PathEscape(label), secret)
+}
+
+// keePassUUID returns the base64 16-byte UUID KeePass expects. Bitwarden IDs
+// are already UUIDs; other names are hashed so the export is deterministic.
+func keePassUUID(id string) string {
+	if raw, err := hex.DecodeString(strings.ReplaceAll(id, "-", "")); err == nil && len(raw) == 16 {
+		return base64.StdEncoding.EncodeToString(raw)
+	}
+	sum := sha256.Sum256([]byte(id))
+	return base64.StdEncoding.EncodeToString(sum[:16])
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	maskedValue        = "••••••••"
+	notesPreviewLength = 40
+)
+
+// itemSummary describes the secrets an item holds for listings. Passwords,
+// card numbers and private keys are masked and notes shortened unless
+// --reveal is set, so a listing can be shown on a shared screen or kept in a
+// log.
+func itemSummary(item BitwardenItem, options CommandOptions) string {
+	summary := secretSummary(item, options)
+	if username := item.username(); username != "" {
+		summary = strings.TrimSuffix("user: "+options.redact.username(username)+", "+summary, ", ")
+	}
+	return summary
+}
+
+// secretSummary is itemSummary without the username, for tables that show
+// the username in a column of its own.
+func secretSummary(item BitwardenItem, options CommandOptions) string {
+	details, err := decodeItemDetails(item)
+	if err != nil {
+		return ""
+	}
+
+	reveal := options.reveal
+	var parts []string
+	add := func(label, value string) {
+		if value != "" {
+			parts = append(parts, label+": "+value)
+		}
+	}
+
+	if login := details.Login; login != nil {
+		add("password", maskSecret(login.Password, reveal))
+		if len(login.URIs) > 0 {
+			add("uri", options.redact.uri(login.URIs[0].URI))
+		}
+	}
+	if card := details.Card; card != nil {
+		add("card", strings.TrimSpace(card["brand"]+" "+maskCardNumber(card["number"], reveal)))
+	}
+	if key := details.SSHKey; key != nil {
+		add("fingerprint", key.KeyFingerprint)
+		add("private key", maskSecret(key.PrivateKey, reveal))
+	}
+	if details.Notes != "" {
+		add("notes", fmt.Sprintf("%q", previewNotes(details.Notes, reveal)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// maskSecret hides value entirely; the mask has a fixed length so it does
+// not give away how long the secret is.
+func maskSecret(value string, reveal bool) string {
+	if reveal || value == "" {
+		return value
+	}
+	return maskedValue
+}
+
+func maskCardNumber(number string, reveal bool) string {
+	if reveal || number == "" {
+		return number
+	}
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+	if len(digits) < 8 {
+		return maskedValue
+	}
+	return "•••• " + digits[len(digits)-4:]
+}
+
+// previewNotes returns the start of the first line of notes.
+func previewNotes(notes string, reveal bool) string {
+	if reveal {
+		return notes
+	}
+	line, rest, multiline := strings.Cut(strings.TrimSpace(notes), "\n")
+	runes := []rune(strings.TrimSpace(line))
+	if len(runes) > notesPreviewLength {
+		return string(runes[:notesPreviewLength]) + "…"
+	}
+	if multiline && strings.TrimSpace(rest) != "" {
+		return string(runes) + " …"
+	}
+	return string(runes)
+}
+
+// describeItem formats an item for listings as "name (id) [type] secrets".
+func describeItem(item BitwardenItem, options CommandOptions) string {
+	description := fmt.Sprintf("%s (%s) [%s]", options.redact.name(item.Name), item.ID, item.typeName())
+	if summary := itemSummary(item, options); summary != "" {
+		description += " " + summary
+	}
+	return description
+}
+
+// maskedItemSecrets are the secrets of an item's JSON, by section, that
+// maskedItem hides.
+var maskedItemSecrets = map[string][]string{
+	"login":    {"password", "totp"},
+	"card":     {"code"},
+	"identity": {"ssn", "passportNumber", "licenseNumber"},
+	"sshKey":   {"privateKey"},
+}
+
+// maskedItem returns the JSON of item as bw listed it, for programs outside
+// the tool. Passwords, TOTP secrets, card numbers, private keys, notes and
+// custom field values are masked unless --reveal is set, and names,
+// usernames and URIs follow --redact.
+func maskedItem(item BitwardenItem, options CommandOptions) json.RawMessage {
+	data := item.raw
+	if len(data) == 0 {
+		data, _ = json.Marshal(item)
+	}
+	var generic map[string]any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return data
+	}
+
+	reveal := options.reveal
+	mask := func(values map[string]any, key string, hide func(string) string) {
+		if value, ok := values[key].(string); ok {
+			values[key] = hide(value)
+		}
+	}
+	secret := func(value string) string { return maskSecret(value, reveal) }
+
+	mask(generic, "name", options.redact.name)
+	mask(generic, "notes", secret)
+	for section, keys := range maskedItemSecrets {
+		if values, ok := generic[section].(map[string]any); ok {
+			for _, key := range keys {
+				mask(values, key, secret)
+			}
+		}
+	}
+	if card, ok := generic["card"].(map[string]any); ok {
+		mask(card, "number", func(number string) string { return maskCardNumber(number, reveal) })
+	}
+	if login, ok := generic["login"].(map[string]any); ok {
+		mask(login, "username", options.redact.username)
+		for _, entry := range anySlice(login["uris"]) {
+			mask(entry, "uri", options.redact.uri)
+		}
+		for _, entry := range anySlice(login["fido2Credentials"]) {
+			mask(entry, "keyValue", secret)
+		}
+	}
+	for _, entry := range anySlice(generic["passwordHistory"]) {
+		mask(entry, "password", secret)
+	}
+	for _, entry := range anySlice(generic["fields"]) {
+		mask(entry, "value", secret)
+	}
+
+	masked, err := json.Marshal(generic)
+	if err != nil {
+		return data
+	}
+	return masked
+}
+
+// anySlice returns the objects of a decoded JSON array.
+func anySlice(value any) []map[string]any {
+	list, _ := value.([]any)
+	var objects []map[string]any
+	for _, element := range list {
+		if object, ok := element.(map[string]any); ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
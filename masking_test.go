@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMaskedItem(t *testing.T) {
+	item := parseItem(t, `{"id":"id1","type":1,"name":"bank","notes":"pin 1234",
+		"login":{"username":"alice","password":"hunter2","totp":"JBSWY3DP","uris":[{"uri":"https://bank.com"}],"fido2Credentials":[{"keyValue":"secretkey"}]},
+		"card":{"number":"4111111111111111","code":"123"},
+		"fields":[{"name":"answer","value":"blue","type":1}],
+		"passwordHistory":[{"password":"hunter1"}]}`)
+	secrets := []string{"hunter2", "JBSWY3DP", "secretkey", "4111111111111111", `"123"`, "blue", "hunter1", "pin 1234"}
+
+	masked := string(maskedItem(item, CommandOptions{}))
+	for _, secret := range secrets {
+		if strings.Contains(masked, secret) {
+			t.Errorf("masked item contains %s: %s", secret, masked)
+		}
+	}
+	if !strings.Contains(masked, "alice") || !strings.Contains(masked, "•••• 1111") {
+		t.Errorf("masked item lost the username or the card's last digits: %s", masked)
+	}
+
+	revealed := string(maskedItem(item, CommandOptions{reveal: true}))
+	for _, secret := range secrets {
+		if !strings.Contains(revealed, secret) {
+			t.Errorf("--reveal item lacks %s: %s", secret, revealed)
+		}
+	}
+
+	var redact redactor
+	if err := redact.Set("names,usernames,uris"); err != nil {
+		t.Fatal(err)
+	}
+	var redacted BitwardenItem
+	if err := json.Unmarshal(maskedItem(item, CommandOptions{reveal: true, redact: redact}), &redacted); err != nil {
+		t.Fatal(err)
+	}
+	if redacted.Name == "bank" || redacted.Login.Username == "alice" || redacted.Login.URIs[0].URI == "https://bank.com" {
+		t.Errorf("--redact did not apply: %+v", redacted)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const metricsPrefix = "bitwarden_cleanup_"
+
+// runMetrics times the whole process for the performance summary and
+// --metrics-file: the bw syncs and listings, and every processed item.
+var runMetrics = struct {
+	mu      sync.Mutex
+	file    string
+	started time.Time
+
+	syncTime, listTime time.Duration
+	syncs, lists       int
+
+	items                   []time.Duration
+	succeeded, gone, failed int
+	// busy is the time the workers spent on items, capacity the time
+	// they could have: workers times the length of each run.
+	busy, capacity time.Duration
+}{started: time.Now()}
+
+func timeSync(start time.Time) {
+	runMetrics.mu.Lock()
+	defer runMetrics.mu.Unlock()
+	runMetrics.syncTime += time.Since(start)
+	runMetrics.syncs++
+}
+
+func timeList(start time.Time) {
+	runMetrics.mu.Lock()
+	defer runMetrics.mu.Unlock()
+	runMetrics.listTime += time.Since(start)
+	runMetrics.lists++
+}
+
+// recordRunMetrics takes the results of a finished processItems run, whose
+// workers processed items for elapsed.
+func recordRunMetrics(stats *DeleteStats, workers int, elapsed time.Duration) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+	runMetrics.mu.Lock()
+	defer runMetrics.mu.Unlock()
+
+	for _, result := range stats.results {
+		runMetrics.items = append(runMetrics.items, result.Duration)
+		runMetrics.busy += result.Duration
+		switch result.status() {
+		case resultDone:
+			runMetrics.succeeded++
+		case resultGone:
+			runMetrics.gone++
+		case resultFailed:
+			runMetrics.failed++
+		}
+	}
+	runMetrics.capacity += time.Duration(workers) * elapsed
+}
+
+// finishMetrics prints the performance summary of the process and writes
+// --metrics-file, just before it exits with code.
+func finishMetrics(code int) {
+	runMetrics.mu.Lock()
+	defer runMetrics.mu.Unlock()
+
+	total := time.Since(runMetrics.started)
+	durations := append([]time.Duration(nil), runMetrics.items...)
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	if len(durations) > 0 {
+		console.summaryf(emojiStats, "Timing: %s in total, sync %s (%d calls), list %s (%d calls), %s of worker time on items",
+			total.Round(100*time.Millisecond), runMetrics.syncTime.Round(100*time.Millisecond), runMetrics.syncs,
+			runMetrics.listTime.Round(100*time.Millisecond), runMetrics.lists, runMetrics.busy.Round(100*time.Millisecond))
+		console.summaryf(emojiStats, "Per item: mean %s, median %s, p95 %s, worker utilization %.0f%%",
+			meanDuration(durations).Round(time.Millisecond), percentile(durations, 0.5).Round(time.Millisecond),
+			percentile(durations, 0.95).Round(time.Millisecond), 100*utilization())
+	}
+
+	if runMetrics.file == "" {
+		return
+	}
+	if err := writeMetricsFile(runMetrics.file, total, durations, code); err != nil {
+		console.warnf("Warning: could not write metrics file: %v", err)
+	}
+}
+
+// writeMetricsFile writes the metrics in the text format of the Prometheus
+// node exporter's textfile collector. The file is replaced by a rename, so
+// that the collector never reads it half-written.
+func writeMetricsFile(path string, total time.Duration, durations []time.Duration, code int) error {
+	var b strings.Builder
+	metric := func(name, kind, help string, samples ...string) {
+		fmt.Fprintf(&b, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, name, help, metricsPrefix, name, kind)
+		for _, sample := range samples {
+			fmt.Fprintf(&b, "%s%s\n", metricsPrefix, sample)
+		}
+	}
+	seconds := func(d time.Duration) string { return formatMetric(d.Seconds()) }
+
+	metric("last_run_timestamp_seconds", "gauge", "When the last run ended, in seconds since the epoch.",
+		fmt.Sprintf("last_run_timestamp_seconds %d", time.Now().Unix()))
+	metric("last_run_duration_seconds", "gauge", "How long the last run took.",
+		"last_run_duration_seconds "+seconds(total))
+	metric("last_run_exit_code", "gauge", "The exit status of the last run.",
+		fmt.Sprintf("last_run_exit_code %d", code))
+	metric("phase_duration_seconds", "gauge", "Time the last run spent syncing, listing and processing items.",
+		`phase_duration_seconds{phase="sync"} `+seconds(runMetrics.syncTime),
+		`phase_duration_seconds{phase="list"} `+seconds(runMetrics.listTime),
+		`phase_duration_seconds{phase="items"} `+seconds(runMetrics.busy))
+	metric("items", "gauge", "Items processed by the last run, by result.",
+		fmt.Sprintf(`items{result="succeeded"} %d`, runMetrics.succeeded),
+		fmt.Sprintf(`items{result="already_gone"} %d`, runMetrics.gone),
+		fmt.Sprintf(`items{result="failed"} %d`, runMetrics.failed))
+	metric("item_duration_seconds", "summary", "Time the bw calls for one item took in the last run, retries included.",
+		`item_duration_seconds{quantile="0.5"} `+seconds(percentile(durations, 0.5)),
+		`item_duration_seconds{quantile="0.95"} `+seconds(percentile(durations, 0.95)),
+		"item_duration_seconds_sum "+seconds(runMetrics.busy),
+		fmt.Sprintf("item_duration_seconds_count %d", len(durations)))
+	metric("worker_utilization_ratio", "gauge", "Share of the time the workers of the last run spent on items.",
+		"worker_utilization_ratio "+formatMetric(utilization()))
+
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, []byte(b.String()), 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %w", temp, err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("error replacing %s: %w", path, err)
+	}
+	return nil
+}
+
+func utilization() float64 {
+	if runMetrics.capacity <= 0 {
+		return 0
+	}
+	return math.Min(float64(runMetrics.busy)/float64(runMetrics.capacity), 1)
+}
+
+func meanDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range durations {
+		sum += d
+	}
+	return sum / time.Duration(len(durations))
+}
+
+// percentile returns the nearest-rank percentile p of sorted durations.
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(durations)))) - 1
+	return durations[max(rank, 0)]
+}
+
+func formatMetric(value float64) string {
+	return fmt.Sprintf("%g", math.Round(value*1e6)/1e6)
+}
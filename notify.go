@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	notifyTimeout = 10 * time.Second
+	notifyTitle   = "bitwarden-cleanup"
+)
+
+// notification is the JSON body posted to --notify-url. Slack and
+// compatible webhooks show text and ignore the other fields, which carry
+// the counts for other receivers.
+type notification struct {
+	Text  string `json:"text"`
+	Event string `json:"event"`
+	Host  string `json:"host"`
+	statsLine
+}
+
+// notifier alerts the owner of a run at its end or, with --notify-failures,
+// as soon as that many items have failed. It counts the results itself, so
+// the alert also works when --group-by splits the run.
+type notifier struct {
+	url       string
+	desktop   bool
+	threshold int
+	total     int
+	op        itemOperation
+	options   CommandOptions
+	started   time.Time
+
+	mu        sync.Mutex
+	processed int
+	gone      int
+	failed    int
+	alerted   bool
+}
+
+func newNotifier(total int, op itemOperation, options CommandOptions) *notifier {
+	if options.notifyURL == "" && !options.notifyDesktop {
+		return nil
+	}
+	return &notifier{
+		url:       options.notifyURL,
+		desktop:   options.notifyDesktop,
+		threshold: options.notifyFailures,
+		total:     total,
+		op:        op,
+		options:   options,
+		started:   time.Now(),
+	}
+}
+
+// validateNotifyURL accepts http and https webhooks only.
+func validateNotifyURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid --notify-url %q (expected an http or https URL)", raw)
+	}
+	return nil
+}
+
+// observe counts one result and sends the failure alert once the failures
+// reach --notify-failures.
+func (n *notifier) observe(result itemResult) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	n.processed++
+	switch result.status() {
+	case resultGone:
+		n.gone++
+	case resultFailed:
+		n.failed++
+	}
+	alert := n.threshold > 0 && n.failed >= n.threshold && !n.alerted
+	if alert {
+		n.alerted = true
+	}
+	line := statsLine{
+		Time:        time.Now().UTC(),
+		Operation:   n.op.verb,
+		FiltersHash: filtersHash(n.options.selectionArgs),
+		Matched:     n.total,
+		Succeeded:   n.processed - n.failed - n.gone,
+		AlreadyGone: n.gone,
+		Failed:      n.failed,
+		DurationMS:  time.Since(n.started).Milliseconds(),
+	}
+	n.mu.Unlock()
+
+	if alert {
+		n.send("run.failures", fmt.Sprintf("%d items failed so far (%s, %d of %d processed)", line.Failed, n.op.verb, n.processed, n.total), line)
+	}
+}
+
+// finish sends the notification for the end of the run. With
+// --notify-failures, runs with fewer failures end quietly.
+func (n *notifier) finish(stats *DeleteStats) {
+	if n == nil {
+		return
+	}
+	line := summaryLine(stats, n.op, n.options)
+	if n.threshold > 0 && line.Failed < n.threshold {
+		return
+	}
+	text := fmt.Sprintf("%s finished: %d matched, %d succeeded, %d already gone, %d failed in %s",
+		n.op.verb, line.Matched, line.Succeeded, line.AlreadyGone, line.Failed, (time.Duration(line.DurationMS) * time.Millisecond).Round(time.Second))
+	if line.Stopped {
+		text = fmt.Sprintf("%s stopped early: %d of %d matched items processed, %d failed", n.op.verb, line.Succeeded+line.AlreadyGone+line.Failed, line.Matched, line.Failed)
+	}
+	n.send("run.finished", text, line)
+}
+
+// send delivers a notification to the webhook and the desktop. Failures are
+// warnings; a broken webhook must not fail the cleanup it reports on.
+func (n *notifier) send(event, text string, line statsLine) {
+	host, _ := os.Hostname()
+	if host != "" {
+		text = host + ": " + text
+	}
+	if n.url != "" {
+		if err := postNotification(n.url, notification{Text: notifyTitle + " on " + text, Event: event, Host: host, statsLine: line}); err != nil {
+			console.warnf("Warning: could not send notification: %v", err)
+		} else {
+			console.debugf(emojiInfo, "Notification sent to --notify-url")
+		}
+	}
+	if n.desktop {
+		if err := showDesktopNotification(text); err != nil {
+			console.warnf("Warning: could not show desktop notification: %v", err)
+		}
+	}
+}
+
+func postNotification(webhook string, body notification) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error posting to --notify-url: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("--notify-url answered HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// showDesktopNotification uses notify-send on Linux and the BSDs and
+// osascript on macOS.
+func showDesktopNotification(text string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace
+		cmd = exec.Command("osascript", "-e", fmt.Sprintf(`display notification "%s" with title "%s"`, quote(text), notifyTitle))
+	case "windows":
+		return fmt.Errorf("--notify-desktop is not supported on Windows")
+	default:
+		cmd = exec.Command("notify-send", notifyTitle, text)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("%s: %w: %s", cmd.Args[0], err, message)
+		}
+		return fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 1Password item categories used by the export.
+const (
+	onePUXLogin      = "001"
+	onePUXCreditCard = "002"
+	onePUXNote       = "003"
+	onePUXIdentity   = "004"
+)
+
+var onePUXBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type onePUXData struct {
+	Accounts []onePUXAccount `json:"accounts"`
+}
+
+type onePUXAccount struct {
+	Attrs  map[string]string `json:"attrs"`
+	Vaults []onePUXVault     `json:"vaults"`
+}
+
+type onePUXVault struct {
+	Attrs map[string]string `json:"attrs"`
+	Items []onePUXItem      `json:"items"`
+}
+
+type onePUXItem struct {
+	UUID         string         `json:"uuid"`
+	FavIndex     int            `json:"favIndex"`
+	CreatedAt    int64          `json:"createdAt"`
+	UpdatedAt    int64          `json:"updatedAt"`
+	State        string         `json:"state"`
+	CategoryUUID string         `json:"categoryUuid"`
+	Details      onePUXDetails  `json:"details"`
+	Overview     onePUXOverview `json:"overview"`
+}
+
+type onePUXDetails struct {
+	LoginFields     []onePUXLoginField `json:"loginFields"`
+	NotesPlain      string             `json:"notesPlain"`
+	Sections        []onePUXSection    `json:"sections"`
+	PasswordHistory []any              `json:"passwordHistory"`
+}
+
+type onePUXLoginField struct {
+	Value       string `json:"value"`
+	Name        string `json:"name"`
+	FieldType   string `json:"fieldType"`
+	Designation string `json:"designation"`
+}
+
+type onePUXSection struct {
+	Title  string        `json:"title"`
+	Name   string        `json:"name"`
+	Fields []onePUXField `json:"fields"`
+}
+
+type onePUXField struct {
+	Title string         `json:"title"`
+	ID    string         `json:"id"`
+	Value map[string]any `json:"value"`
+}
+
+type onePUXOverview struct {
+	Title    string      `json:"title"`
+	Subtitle string      `json:"subtitle"`
+	URL      string      `json:"url,omitempty"`
+	URLs     []onePUXURL `json:"urls,omitempty"`
+	Tags     []string    `json:"tags,omitempty"`
+}
+
+type onePUXURL struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// export1PUX writes items to path as an unencrypted 1Password export
+// (.1pux) that 1Password can import into a vault. 1Password has no folders,
+// so folder names become tags.
+func export1PUX(items []BitwardenItem, path string) error {
+	folders, err := vault.ListFolders()
+	if err != nil {
+		return err
+	}
+	folderNames := make(map[string]string, len(folders))
+	for _, folder := range folders {
+		folderNames[folder.ID] = folder.Name
+	}
+
+	vault := onePUXVault{Attrs: map[string]string{
+		"uuid": onePUXUUID("vault:" + path),
+		"name": "Bitwarden cleanup " + time.Now().Format("2006-01-02"),
+		"desc": "Items exported by bitwarden-cleanup",
+		"type": "P",
+	}}
+	for _, item := range items {
+		details, err := decodeItemDetails(item)
+		if err != nil {
+			return err
+		}
+		vault.Items = append(vault.Items, onePUXItemFor(item, details, folderNames[item.FolderID]))
+	}
+
+	data := onePUXData{Accounts: []onePUXAccount{{
+		Attrs:  map[string]string{"accountName": "Bitwarden", "name": "Bitwarden", "uuid": onePUXUUID("account:" + path)},
+		Vaults: []onePUXVault{vault},
+	}}}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating 1PUX export: %w", err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	attributes := map[string]any{"version": 3, "description": "1Password Unencrypted Export", "createdAt": time.Now().Unix()}
+	for name, value := range map[string]any{"export.attributes": attributes, "export.data": data} {
+		writer, err := archive.Create(name)
+		if err != nil {
+			return fmt.Errorf("error writing 1PUX export: %w", err)
+		}
+		if err := json.NewEncoder(writer).Encode(value); err != nil {
+			return fmt.Errorf("error writing 1PUX export: %w", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("error writing 1PUX export: %w", err)
+	}
+
+	console.infof(emojiSuccess, "Exported %d items to 1Password archive %s", len(items), path)
+	return nil
+}
+
+func onePUXItemFor(item BitwardenItem, details itemDetails, folderName string) onePUXItem {
+	converted := onePUXItem{
+		UUID:      onePUXUUID(item.ID),
+		CreatedAt: onePUXTime(details.CreationDate),
+		UpdatedAt: onePUXTime(details.RevisionDate),
+		State:     "active",
+		Details:   onePUXDetails{NotesPlain: details.Notes, LoginFields: []onePUXLoginField{}, PasswordHistory: []any{}},
+		Overview:  onePUXOverview{Title: item.Name},
+	}
+	if details.Favorite {
+		converted.FavIndex = 1
+	}
+	if folderName != "" {
+		converted.Overview.Tags = []string{folderName}
+	}
+
+	var fields []onePUXField
+	switch {
+	case details.Login != nil:
+		converted.CategoryUUID = onePUXLogin
+		login := details.Login
+		converted.Overview.Subtitle = login.Username
+		converted.Details.LoginFields = []onePUXLoginField{
+			{Value: login.Username, Name: "username", FieldType: "T", Designation: "username"},
+			{Value: login.Password, Name: "password", FieldType: "P", Designation: "password"},
+		}
+		for _, uri := range login.URIs {
+			if uri.URI == "" {
+				continue
+			}
+			if converted.Overview.URL == "" {
+				converted.Overview.URL = uri.URI
+			}
+			converted.Overview.URLs = append(converted.Overview.URLs, onePUXURL{URL: uri.URI})
+		}
+		if login.TOTP != "" {
+			fields = append(fields, onePUXField{Title: "one-time password", ID: "totp", Value: map[string]any{"totp": login.TOTP}})
+		}
+	case details.Card != nil:
+		converted.CategoryUUID = onePUXCreditCard
+		card := details.Card
+		converted.Overview.Subtitle = card["brand"]
+		fields = append(fields,
+			onePUXStringField("cardholder", "cardholder name", card["cardholderName"]),
+			onePUXField{Title: "type", ID: "type", Value: map[string]any{"creditCardType": strings.ToLower(card["brand"])}},
+			onePUXField{Title: "number", ID: "ccnum", Value: map[string]any{"creditCardNumber": card["number"]}},
+			onePUXField{Title: "verification number", ID: "cvv", Value: map[string]any{"concealed": card["code"]}},
+		)
+		if expiry := onePUXMonthYear(card["expMonth"], card["expYear"]); expiry != 0 {
+			fields = append(fields, onePUXField{Title: "expiry date", ID: "expiry", Value: map[string]any{"monthYear": expiry}})
+		}
+	case details.Identity != nil:
+		converted.CategoryUUID = onePUXIdentity
+		identity := details.Identity
+		converted.Overview.Subtitle = strings.TrimSpace(identity["firstName"] + " " + identity["lastName"])
+		for _, field := range keePassIdentityFields {
+			if value := identity[field.key]; value != "" {
+				fields = append(fields, onePUXStringField(field.key, strings.ToLower(field.label), value))
+			}
+		}
+	case details.SSHKey != nil:
+		// Imported as a note so that the key survives imports into 1Password
+		// versions without SSH key items.
+		converted.CategoryUUID = onePUXNote
+		key := details.SSHKey
+		converted.Overview.Subtitle = key.KeyFingerprint
+		fields = append(fields,
+			onePUXField{Title: "private key", ID: "private_key", Value: map[string]any{"concealed": key.PrivateKey}},
+			onePUXStringField("public_key", "public key", key.PublicKey),
+			onePUXStringField("fingerprint", "fingerprint", key.KeyFingerprint),
+		)
+	default:
+		converted.CategoryUUID = onePUXNote
+	}
+
+	for i, field := range details.Fields {
+		value := map[string]any{"string": field.Value}
+		if field.Type == hiddenFieldType {
+			value = map[string]any{"concealed": field.Value}
+		}
+		fields = append(fields, onePUXField{Title: field.Name, ID: "bitwarden-field-" + strconv.Itoa(i), Value: value})
+	}
+
+	if len(fields) > 0 {
+		converted.Details.Sections = []onePUXSection{{Title: "", Name: "bitwarden", Fields: fields}}
+	} else {
+		converted.Details.Sections = []onePUXSection{}
+	}
+	return converted
+}
+
+func onePUXStringField(id, title, value string) onePUXField {
+	return onePUXField{Title: title, ID: id, Value: map[string]any{"string": value}}
+}
+
+// onePUXMonthYear encodes a card expiry as 1Password's YYYYMM number.
+func onePUXMonthYear(month, year string) int {
+	m, errMonth := strconv.Atoi(month)
+	y, errYear := strconv.Atoi(year)
+	if errMonth != nil || errYear != nil || m < 1 || m > 12 {
+		return 0
+	}
+	if y < 100 {
+		y += 2000
+	}
+	return y*100 + m
+}
+
+func onePUXTime(value string) int64 {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Now().Unix()
+	}
+	return parsed.Unix()
+}
+
+// onePUXUUID derives a 26-character 1Password-style UUID from id so repeated
+// exports of the same item agree.
+func onePUXUUID(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return strings.ToLower(onePUXBase32.EncodeToString(sum[:16]))
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+type itemOperation struct {
+	verb         string
+	modeEmoji    string
+	modeText     string
+	confirmText  string
+	processName  string
+	progressVerb string
+	doneText     string
+	deletesItems bool
+	permanent    bool
+	run          func(item BitwardenItem) error
+	// cost returns the bw invocations and server API calls needed for one item.
+	cost func(item BitwardenItem) (invocations, apiCalls int)
+	// idempotent operations are retried after an error. The others create
+	// items or attachments, or change items relative to how they are, and
+	// may already have taken effect, so they are tried only once.
+	idempotent bool
+}
+
+// singleCallCost fits operations that make one bw call per item.
+func singleCallCost(BitwardenItem) (int, int) { return 1, 1 }
+
+// editCost fits full get/edit round-trips. The get is served from the local
+// vault cache, so only the edit reaches the server.
+func editCost(BitwardenItem) (int, int) { return 2, 1 }
+
+func selectOperation(options CommandOptions) (itemOperation, error) {
+	var selected []itemOperation
+	if options.trimPasswordHistory >= 0 {
+		selected = append(selected, trimPasswordHistoryOperation(options.trimPasswordHistory))
+	}
+	if options.stripPasskeys {
+		selected = append(selected, stripPasskeysOperation())
+	}
+	if options.reuploadAttachments {
+		selected = append(selected, reuploadAttachmentsOperation())
+	}
+	if options.stampNotes != "" {
+		selected = append(selected, stampNotesOperation(options.stampNotes))
+	}
+	if options.moveToFolder != "" {
+		selected = append(selected, moveToFolderOperation(options.moveToFolder))
+	}
+	if options.setURIMatch != "" {
+		op, err := setURIMatchOperation(options.setURIMatch)
+		if err != nil {
+			return itemOperation{}, err
+		}
+		selected = append(selected, op)
+	}
+	if options.moveToOrg != "" {
+		op, err := moveToOrganizationOperation(options.moveToOrg, options.toCollections)
+		if err != nil {
+			return itemOperation{}, err
+		}
+		selected = append(selected, op)
+	} else if len(options.toCollections) > 0 {
+		return itemOperation{}, fmt.Errorf("--to-collections can only be used with --move-to-org")
+	}
+
+	if options.twoPhase != "" {
+		if options.staged {
+			return itemOperation{}, fmt.Errorf("--staged and --two-phase cannot be used together")
+		}
+		if len(selected) > 0 {
+			return itemOperation{}, fmt.Errorf("--two-phase can only be used when deleting items")
+		}
+		return stagedDeleteOperation(), nil
+	}
+
+	if options.staged {
+		if !options.isPermanent || len(selected) > 0 {
+			return itemOperation{}, fmt.Errorf("--staged can only be used with --permanent deletion")
+		}
+		return stagedDeleteOperation(), nil
+	}
+
+	switch len(selected) {
+	case 0:
+		return deleteOperation(options.isPermanent), nil
+	case 1:
+		if options.isPermanent {
+			return itemOperation{}, fmt.Errorf("--permanent can only be used when deleting items")
+		}
+		return selected[0], nil
+	default:
+		return itemOperation{}, fmt.Errorf("only one item operation can be selected per run")
+	}
+}
+
+func deleteOperation(isPermanent bool) itemOperation {
+	op := itemOperation{
+		verb:         "delete",
+		modeEmoji:    emojiInfo,
+		modeText:     "Standard deletion (items will go to trash)",
+		confirmText:  "delete",
+		processName:  "deletion",
+		progressVerb: "deleting",
+		doneText:     "have been moved to trash",
+		deletesItems: true,
+		cost:         singleCallCost,
+		idempotent:   true,
+	}
+
+	if isPermanent {
+		op.modeEmoji = emojiWarning
+		op.modeText = "Permanent deletion (items will bypass trash)"
+		op.confirmText = "PERMANENTLY delete"
+		op.doneText = "have been permanently deleted"
+		op.permanent = true
+	}
+
+	op.run = func(item BitwardenItem) error {
+		return vault.DeleteItem(item.ID, isPermanent)
+	}
+
+	return op
+}
+
+func stagedDeleteOperation() itemOperation {
+	op := deleteOperation(false)
+	op.modeEmoji = emojiWarning
+	op.modeText = "Staged permanent deletion (items go to trash first and are purged in a second step)"
+	op.confirmText = "PERMANENTLY delete (in two steps)"
+	op.doneText = "have been moved to trash and staged for permanent deletion"
+	return op
+}
+
+func trimPasswordHistoryOperation(keep int) itemOperation {
+	op := itemOperation{
+		verb:         "update",
+		modeEmoji:    emojiWarning,
+		modeText:     "Password history clearing (old passwords will be removed from items)",
+		confirmText:  "clear the password history of",
+		processName:  "password history clearing",
+		progressVerb: "updating",
+		doneText:     "have had their password history cleared",
+		cost:         editCost,
+		idempotent:   true,
+	}
+
+	if keep > 0 {
+		op.modeText = fmt.Sprintf("Password history trimming (keeping the %d most recent entries)", keep)
+		op.confirmText = fmt.Sprintf("trim the password history to %d entries on", keep)
+		op.processName = "password history trimming"
+		op.doneText = "have had their password history trimmed"
+	}
+
+	op.run = func(item BitwardenItem) error {
+		return updateItem(item.ID, func(item map[string]any) bool {
+			return trimPasswordHistory(item, keep)
+		})
+	}
+
+	return op
+}
+
+func stripPasskeysOperation() itemOperation {
+	return itemOperation{
+		verb:         "update",
+		modeEmoji:    emojiWarning,
+		modeText:     "Passkey removal (passkeys will be removed, passwords are kept)",
+		confirmText:  "remove the passkeys from",
+		processName:  "passkey removal",
+		progressVerb: "updating",
+		doneText:     "have had their passkeys removed",
+		cost:         editCost,
+		idempotent:   true,
+		run: func(item BitwardenItem) error {
+			return updateItem(item.ID, stripPasskeys)
+		},
+	}
+}
+
+func setURIMatchOperation(matchName string) (itemOperation, error) {
+	match, err := parseURIMatch(matchName)
+	if err != nil {
+		return itemOperation{}, err
+	}
+
+	return itemOperation{
+		verb:         "update",
+		modeEmoji:    emojiInfo,
+		modeText:     fmt.Sprintf("URI match detection update (all URIs will use %q)", matchName),
+		confirmText:  fmt.Sprintf("set URI match detection to %q on", matchName),
+		processName:  "URI match update",
+		progressVerb: "updating",
+		doneText:     fmt.Sprintf("now use %q URI match detection", matchName),
+		cost:         editCost,
+		idempotent:   true,
+		run: func(item BitwardenItem) error {
+			return updateItem(item.ID, func(item map[string]any) bool {
+				return setURIMatch(item, match)
+			})
+		},
+	}, nil
+}
+
+func reuploadAttachmentsOperation() itemOperation {
+	return itemOperation{
+		verb:         "update",
+		modeEmoji:    emojiInfo,
+		modeText:     "Attachment re-upload (attachments will be downloaded, attached again and the originals removed)",
+		confirmText:  "re-upload the attachments of",
+		processName:  "attachment re-upload",
+		progressVerb: "re-uploading attachments of",
+		doneText:     "have had their attachments re-uploaded",
+		run:          reuploadAttachments,
+		cost: func(item BitwardenItem) (int, int) {
+			// download, upload and delete per attachment
+			return 3 * len(item.Attachments), 3 * len(item.Attachments)
+		},
+	}
+}
+
+func stampNotesOperation(stamp string) itemOperation {
+	return itemOperation{
+		verb:         "stamp",
+		modeEmoji:    emojiInfo,
+		modeText:     fmt.Sprintf("Notes stamping (items will be kept, %q is appended to their notes)", stamp),
+		confirmText:  fmt.Sprintf("append %q to the notes of", stamp),
+		processName:  "notes stamping",
+		progressVerb: "stamping",
+		doneText:     "have been stamped",
+		cost:         editCost,
+		run: func(item BitwardenItem) error {
+			return updateItem(item.ID, func(item map[string]any) bool {
+				return appendNotesLine(item, stamp)
+			})
+		},
+	}
+}
+
+// moveToFolderOperation moves items into the folder named name, which is
+// looked up, or created, when the first item is processed, so that a
+// cancelled or dry run leaves the folders alone.
+func moveToFolderOperation(name string) itemOperation {
+	var resolve sync.Once
+	var folderID string
+	var resolveErr error
+
+	return itemOperation{
+		verb:         "move",
+		modeEmoji:    emojiInfo,
+		modeText:     fmt.Sprintf("Move to folder (items will be kept and moved to %q)", name),
+		confirmText:  fmt.Sprintf("move (to %q)", name),
+		processName:  "folder move",
+		progressVerb: "moving",
+		doneText:     fmt.Sprintf("have been moved to %q", name),
+		cost:         editCost,
+		idempotent:   true,
+		run: func(item BitwardenItem) error {
+			resolve.Do(func() { folderID, resolveErr = ensureFolder(name) })
+			if resolveErr != nil {
+				return resolveErr
+			}
+			return updateItem(item.ID, func(fields map[string]any) bool {
+				current, _ := fields["folderId"].(string)
+				if current == folderID {
+					return false
+				}
+				if folderID == "" {
+					fields["folderId"] = nil
+				} else {
+					fields["folderId"] = folderID
+				}
+				return true
+			})
+		},
+	}
+}
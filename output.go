@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// ui receives the human-readable console output. With --output json it is
+// stderr, so that stdout carries nothing but the NDJSON events.
+var ui io.Writer = os.Stdout
+
+// events writes the NDJSON records of --output json; it is unused in text
+// mode.
+var events struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	redact  redactor
+}
+
+type matchedEvent struct {
+	Event     string    `json:"event"`
+	Time      time.Time `json:"time"`
+	ItemID    string    `json:"itemId"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Operation string    `json:"operation"`
+}
+
+// itemEvent reports a processed item with the fields of its result record.
+type itemEvent struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	resultRecord
+}
+
+type summaryEvent struct {
+	Event string `json:"event"`
+	statsLine
+}
+
+type errorEvent struct {
+	Event string    `json:"event"`
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+func setOutputMode(mode string, redact redactor) error {
+	switch mode {
+	case outputText:
+		return nil
+	case outputJSON:
+		ui = os.Stderr
+		events.encoder = json.NewEncoder(os.Stdout)
+		events.redact = redact
+		return nil
+	default:
+		return fmt.Errorf("unknown --output %q (expected text or json)", mode)
+	}
+}
+
+// quietForPipe keeps stdout free for the output of a command that is piped
+// into another tool: messages go to stderr, and only errors, warnings and
+// prompts are printed unless --verbose is given.
+func quietForPipe() {
+	ui = os.Stderr
+	if console.level < levelDebug {
+		console.level = levelSummary
+	}
+}
+
+func emitEvent(event any) {
+	if events.encoder == nil {
+		return
+	}
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	events.encoder.Encode(event)
+}
+
+// emitMatched reports the items an operation is about to process, or would
+// process in a dry run.
+func emitMatched(items []BitwardenItem, verb string) {
+	if events.encoder == nil {
+		return
+	}
+	now := time.Now().UTC()
+	for _, item := range items {
+		emitEvent(matchedEvent{Event: "item.matched", Time: now, ItemID: item.ID, Name: events.redact.name(item.Name), Type: item.typeName(), Operation: verb})
+	}
+}
+
+// emitResult reports a processed item as item.done, item.gone or item.failed.
+func emitResult(result itemResult) {
+	if events.encoder == nil {
+		return
+	}
+	record := result.record(events.redact)
+	emitEvent(itemEvent{Event: "item." + record.Status, Time: time.Now().UTC(), resultRecord: record})
+}
+
+func emitError(err error) {
+	emitEvent(errorEvent{Event: "error", Time: time.Now().UTC(), Error: err.Error()})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// transferCompareFields are the parts of an item that must survive the copy
+// into the organization unchanged before the original is deleted.
+var transferCompareFields = []string{"type", "name", "notes", "favorite", "fields", "card", "identity", "secureNote", "sshKey"}
+
+var transferCompareLoginFields = []string{"username", "password", "totp"}
+
+// moveToOrganizationOperation transfers personal items to an organization
+// by cloning each one into it, verifying the clone and only then moving the
+// original to the trash. A clone that fails verification is removed again,
+// leaving the original untouched.
+func moveToOrganizationOperation(organizationID string, collections []string) (itemOperation, error) {
+	if len(collections) == 0 {
+		return itemOperation{}, fmt.Errorf("--move-to-org requires --to-collections")
+	}
+	collectionIDs, err := resolveCollectionIDs(organizationID, collections)
+	if err != nil {
+		return itemOperation{}, err
+	}
+
+	op := itemOperation{
+		verb:         "transfer",
+		modeEmoji:    emojiWarning,
+		modeText:     fmt.Sprintf("Ownership transfer (items are copied to organization %s, verified, and the originals moved to trash)", organizationID),
+		confirmText:  "transfer",
+		processName:  "ownership transfer",
+		progressVerb: "transferring",
+		doneText:     "have been transferred to the organization",
+		cost:         func(BitwardenItem) (int, int) { return 4, 2 },
+	}
+	op.run = func(item BitwardenItem) error {
+		return transferItem(item, organizationID, collectionIDs)
+	}
+	return op, nil
+}
+
+func transferItem(item BitwardenItem, organizationID string, collectionIDs []string) error {
+	if item.OrganizationID != "" {
+		return fmt.Errorf("item already belongs to organization %s", item.OrganizationID)
+	}
+	if len(item.Attachments) > 0 {
+		return fmt.Errorf("item has attachments, which are not copied; move it with 'bw move' instead")
+	}
+
+	original, err := getItemJSON(item.ID)
+	if err != nil {
+		return err
+	}
+
+	clone := make(map[string]any, len(original))
+	for key, value := range original {
+		clone[key] = value
+	}
+	for _, field := range recreateDropFields {
+		delete(clone, field)
+	}
+	delete(clone, "folderId")
+	clone["organizationId"] = organizationID
+	clone["collectionIds"] = collectionIDs
+
+	cloneID, err := createItemJSON(clone)
+	if err != nil {
+		return err
+	}
+
+	copied, err := getItemJSON(cloneID)
+	if err == nil {
+		err = verifyTransferredItem(original, copied, organizationID)
+	}
+	if err != nil {
+		return rollbackTransfer(cloneID, fmt.Errorf("verification of the organization copy failed: %w", err))
+	}
+
+	if err := vault.DeleteItem(item.ID, false); err != nil {
+		return rollbackTransfer(cloneID, fmt.Errorf("%w (original)", err))
+	}
+	return nil
+}
+
+// rollbackTransfer removes a clone that must not stay next to its original.
+func rollbackTransfer(cloneID string, cause error) error {
+	if err := vault.DeleteItem(cloneID, true); err != nil {
+		return fmt.Errorf("%w; removing the copy %s also failed: %v", cause, cloneID, err)
+	}
+	return fmt.Errorf("%w; the copy was removed and the original kept", cause)
+}
+
+func verifyTransferredItem(original, copied map[string]any, organizationID string) error {
+	if copied["organizationId"] != organizationID {
+		return fmt.Errorf("copy is not owned by organization %s", organizationID)
+	}
+	for _, field := range transferCompareFields {
+		if !sameJSON(original[field], copied[field]) {
+			return fmt.Errorf("%s differs", field)
+		}
+	}
+
+	originalLogin, _ := original["login"].(map[string]any)
+	copiedLogin, _ := copied["login"].(map[string]any)
+	if (originalLogin == nil) != (copiedLogin == nil) {
+		return fmt.Errorf("login differs")
+	}
+	if originalLogin == nil {
+		return nil
+	}
+	for _, field := range transferCompareLoginFields {
+		if !sameJSON(originalLogin[field], copiedLogin[field]) {
+			return fmt.Errorf("login %s differs", field)
+		}
+	}
+	if !sameJSON(loginURIs(originalLogin), loginURIs(copiedLogin)) {
+		return fmt.Errorf("login URIs differ")
+	}
+	return nil
+}
+
+func loginURIs(login map[string]any) []any {
+	var uris []any
+	entries, _ := login["uris"].([]any)
+	for _, entry := range entries {
+		if fields, ok := entry.(map[string]any); ok {
+			uris = append(uris, fields["uri"])
+		}
+	}
+	return uris
+}
+
+func sameJSON(a, b any) bool {
+	left, errLeft := json.Marshal(a)
+	right, errRight := json.Marshal(b)
+	return errLeft == nil && errRight == nil && string(left) == string(right)
+}
+
+func resolveCollectionIDs(organizationID string, namesOrIDs []string) ([]string, error) {
+	collections, err := vault.ListCollections()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, wanted := range namesOrIDs {
+		collection, err := findCollection(collections, organizationID, wanted)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, collection.ID)
+	}
+	return ids, nil
+}
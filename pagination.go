@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Orders of --sort. Dates sort oldest first; ties are broken by ID so that
+// the pages of --skip and --limit are the same in every run.
+const (
+	sortByName     = "name"
+	sortByCreated  = "created"
+	sortByModified = "modified"
+)
+
+func validatePaging(options CommandOptions) error {
+	if options.limit < 0 || options.skip < 0 {
+		return fmt.Errorf("--limit and --skip must not be negative")
+	}
+	switch options.sortBy {
+	case "", sortByName, sortByCreated, sortByModified:
+		return nil
+	}
+	return fmt.Errorf("invalid --sort %q (expected name, created or modified)", options.sortBy)
+}
+
+// pageItems orders the matched items by --sort and keeps the --limit items
+// after the first --skip.
+func pageItems(items []BitwardenItem, options CommandOptions) []BitwardenItem {
+	sortItems(items, options.sortBy)
+	if options.skip == 0 && options.limit == 0 {
+		return items
+	}
+
+	matched := len(items)
+	start := min(options.skip, matched)
+	end := matched
+	if options.limit > 0 {
+		end = min(start+options.limit, matched)
+	}
+	items = items[start:end]
+	if len(items) == 0 {
+		console.infof(emojiInfo, "No items left after skipping %d of %d matched items", options.skip, matched)
+	} else {
+		console.infof(emojiInfo, "Taking items %d to %d of %d matched items (--skip %d, --limit %d)", start+1, end, matched, options.skip, options.limit)
+	}
+	return items
+}
+
+func sortItems(items []BitwardenItem, sortBy string) {
+	var key func(item BitwardenItem) string
+	switch sortBy {
+	case sortByName:
+		key = func(item BitwardenItem) string { return strings.ToLower(item.Name) }
+	case sortByCreated:
+		key = func(item BitwardenItem) string { return item.CreationDate }
+	case sortByModified:
+		key = func(item BitwardenItem) string { return item.RevisionDate }
+	default:
+		return
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if a, b := key(items[i]), key(items[j]); a != b {
+			return a < b
+		}
+		return items[i].ID < items[j].ID
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPageItems(t *testing.T) {
+	items := []BitwardenItem{
+		{ID: "c", Name: "beta", CreationDate: "2023-03-01T00:00:00Z", RevisionDate: "2024-01-01T00:00:00Z"},
+		{ID: "a", Name: "Alpha", CreationDate: "2023-02-01T00:00:00Z", RevisionDate: "2024-03-01T00:00:00Z"},
+		{ID: "b", Name: "gamma", CreationDate: "2023-02-01T00:00:00Z", RevisionDate: "2024-02-01T00:00:00Z"},
+		{ID: "d", Name: "alpha", CreationDate: "2023-01-01T00:00:00Z", RevisionDate: "2024-04-01T00:00:00Z"},
+	}
+	tests := []struct {
+		name    string
+		options CommandOptions
+		want    []string
+	}{
+		{"unsorted", CommandOptions{}, []string{"c", "a", "b", "d"}},
+		{"by name, ties by ID", CommandOptions{sortBy: sortByName}, []string{"a", "d", "c", "b"}},
+		{"by created, ties by ID", CommandOptions{sortBy: sortByCreated}, []string{"d", "a", "b", "c"}},
+		{"by modified", CommandOptions{sortBy: sortByModified}, []string{"c", "b", "a", "d"}},
+		{"limit", CommandOptions{sortBy: sortByName, limit: 2}, []string{"a", "d"}},
+		{"skip", CommandOptions{sortBy: sortByName, skip: 3}, []string{"b"}},
+		{"skip and limit", CommandOptions{sortBy: sortByName, skip: 1, limit: 2}, []string{"d", "c"}},
+		{"limit past the end", CommandOptions{sortBy: sortByName, skip: 2, limit: 10}, []string{"c", "b"}},
+		{"skip past the end", CommandOptions{sortBy: sortByName, skip: 10}, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ids := []string{}
+			for _, item := range pageItems(append([]BitwardenItem(nil), items...), test.options) {
+				ids = append(ids, item.ID)
+			}
+			if !reflect.DeepEqual(ids, test.want) {
+				t.Errorf("pageItems = %q, want %q", ids, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// configDir returns the directory holding the tool's persistent state,
+// usually ~/.config/bitwarden-cleanup, creating it when needed.
+func configDir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating config directory: %w", err)
+	}
+
+	dir := filepath.Join(base, "bitwarden-cleanup")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("error creating config directory: %w", err)
+	}
+	return dir, nil
+}
+
+func configFile(name string) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	pendingRunKind = "pending"
+
+	pendingStatusWaiting   = "waiting"
+	pendingStatusExecuted  = "executed"
+	pendingStatusCancelled = "cancelled"
+)
+
+var scheduleLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+func parseSchedule(at string, after time.Duration) (time.Time, error) {
+	if at != "" && after != 0 {
+		return time.Time{}, fmt.Errorf("--at and --after cannot be used together")
+	}
+	if after < 0 {
+		return time.Time{}, fmt.Errorf("--after must be positive")
+	}
+	if after > 0 {
+		return time.Now().Add(after), nil
+	}
+	if at == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range scheduleLayouts {
+		if executeAt, err := time.ParseInLocation(layout, at, time.Local); err == nil {
+			if executeAt.Before(time.Now()) {
+				return time.Time{}, fmt.Errorf("--at %s is in the past", at)
+			}
+			return executeAt, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --at time %q (expected e.g. 2024-07-01T02:00)", at)
+}
+
+// schedulePendingRun records an approved run in a pending-plan file and waits
+// for its execution time. If the process does not survive until then, the
+// plan can be resumed with 'pending run <id>'.
+func schedulePendingRun(items []BitwardenItem, executeAt time.Time, options CommandOptions) error {
+	executeAt = executeAt.UTC()
+	record := &runRecord{
+		ID:        newRunID(),
+		Kind:      pendingRunKind,
+		Status:    pendingStatusWaiting,
+		CreatedAt: time.Now().UTC(),
+		Args:      options.commandArgs,
+		ExecuteAt: &executeAt,
+	}
+	for _, item := range items {
+		record.ItemIDs = append(record.ItemIDs, item.ID)
+	}
+
+	if err := saveRunRecord(record); err != nil {
+		return err
+	}
+
+	console.infof(emojiInfo, "\nRun %s scheduled for %s (%d items)", record.ID, executeAt.Local().Format("2006-01-02 15:04:05"), len(record.ItemIDs))
+	console.infof(emojiInfo, "Keep this process running, or resume later with: %s pending run %s", filepath.Base(os.Args[0]), record.ID)
+	console.infof(emojiInfo, "Cancel with: %s pending cancel %s", filepath.Base(os.Args[0]), record.ID)
+
+	return executePendingRun(record)
+}
+
+func runPendingCommand(args []string) error {
+	usage := fmt.Errorf("usage: %s pending list | pending run <run-id> | pending cancel <run-id>", filepath.Base(os.Args[0]))
+	if len(args) == 0 {
+		return usage
+	}
+
+	if args[0] == "list" {
+		return listPendingRuns()
+	}
+	if len(args) != 2 || (args[0] != "run" && args[0] != "cancel") {
+		return usage
+	}
+
+	record, err := loadRunRecord(args[1])
+	if err != nil {
+		return err
+	}
+	if record.Kind != pendingRunKind || record.ExecuteAt == nil {
+		return fmt.Errorf("run %s is not a scheduled run", record.ID)
+	}
+	if record.Status != pendingStatusWaiting {
+		return fmt.Errorf("run %s is already %s", record.ID, record.Status)
+	}
+
+	if args[0] == "cancel" {
+		record.Status = pendingStatusCancelled
+		if err := saveRunRecord(record); err != nil {
+			return err
+		}
+		console.infof(emojiSuccess, "Run %s cancelled", record.ID)
+		return nil
+	}
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	return executePendingRun(record)
+}
+
+func listPendingRuns() error {
+	records, err := listRunRecords(pendingRunKind)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		console.infof(emojiInfo, "No scheduled runs")
+		return nil
+	}
+
+	for _, record := range records {
+		if record.ExecuteAt == nil {
+			continue
+		}
+		console.linef("%s  due %s  %-9s  %d items", record.ID, record.ExecuteAt.Local().Format("2006-01-02 15:04"), record.Status, len(record.ItemIDs))
+	}
+	return nil
+}
+
+// executePendingRun waits until the run is due, re-checks that it was not
+// cancelled in the meantime and applies the recorded operation to the
+// recorded items that still exist. The undo backup passphrase of a
+// permanent run is asked for before the wait, so that the run does not
+// stop at a prompt, or fail, once it is due.
+func executePendingRun(record *runRecord) error {
+	flags := flag.NewFlagSet("pending run", flag.ContinueOnError)
+	collectOptions := defineCommandFlags(flags)
+	if err := flags.Parse(record.Args); err != nil {
+		return fmt.Errorf("error replaying run %s: %w", record.ID, err)
+	}
+	options, err := withProtection(collectOptions())
+	if err != nil {
+		return err
+	}
+	options.commandArgs = record.Args
+
+	op, err := selectOperation(options)
+	if err != nil {
+		return err
+	}
+	if err := prepareUndoBackup(op, options); err != nil {
+		return err
+	}
+
+	if wait := time.Until(*record.ExecuteAt); wait > 0 {
+		console.infof(emojiProgress, "Waiting %s until the scheduled time...", wait.Round(time.Second))
+		time.Sleep(wait)
+	}
+
+	current, err := loadRunRecord(record.ID)
+	if err != nil {
+		return err
+	}
+	if current.Status != pendingStatusWaiting {
+		console.infof(emojiInfo, "Run %s was %s, nothing to do", current.ID, current.Status)
+		return nil
+	}
+	displayOperationMode(op)
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	items, err := fetchItemsByID(current.ItemIDs, options.protection)
+	if err != nil {
+		return err
+	}
+
+	stats := &DeleteStats{total: len(items), protected: options.protection.count()}
+	displayItemCount(stats, op)
+
+	current.Status = pendingStatusExecuted
+	if err := saveRunRecord(current); err != nil {
+		return err
+	}
+
+	if stats.total == 0 {
+		return nil
+	}
+	return executeItems(items, stats, op, options)
+}
+
+// fetchItemsByID returns the vault items with the given IDs, skipping IDs
+// that no longer exist and the items protection keeps out of every run.
+func fetchItemsByID(ids []string, protection *protectionRules) ([]BitwardenItem, error) {
+	items, err := fetchBitwardenItems("")
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var found []BitwardenItem
+	for _, item := range items {
+		if wanted[item.ID] {
+			found = append(found, item)
+		}
+	}
+
+	if missing := len(ids) - len(found); missing > 0 {
+		console.infof(emojiInfo, "%d recorded items no longer exist and will be skipped", missing)
+	}
+	return protection.apply(found), nil
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Terminal control sequences used by the picker and the progress line.
+const (
+	termAltScreen  = "\x1b[?1049h\x1b[?25l"
+	termMainScreen = "\x1b[?25h\x1b[?1049l"
+	termClear      = "\x1b[H\x1b[2J"
+	termReverse    = "\x1b[7m"
+	termReset      = "\x1b[0m"
+	termClearLine  = "\x1b[K"
+)
+
+// Keys the picker reacts to, as decoded by readPickerKey. Other keys are
+// returned as their character.
+const (
+	keyUp = -1 - iota
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyEscape
+	keyEnter
+	keyBackspace
+	keyCtrlC
+)
+
+// itemPicker is the state of the --interactive checkbox list. Selections are
+// kept by index into items, so filtering the list never loses them.
+type itemPicker struct {
+	items     []BitwardenItem
+	labels    []string
+	selected  map[int]bool
+	query     string
+	searching bool
+	visible   []int
+	cursor    int
+	offset    int
+}
+
+// pickItems lets the user hand-pick the items of the run from a full-screen
+// checkbox list. It returns nil when the user cancels.
+func pickItems(items []BitwardenItem, options CommandOptions) ([]BitwardenItem, error) {
+	if !stdinIsTerminal() {
+		return nil, fmt.Errorf("--interactive needs a terminal")
+	}
+	restore, err := rawInput()
+	if err != nil {
+		return nil, fmt.Errorf("cannot switch the terminal to raw mode: %w", err)
+	}
+	fmt.Fprint(ui, termAltScreen)
+	defer func() {
+		fmt.Fprint(ui, termMainScreen)
+		restore()
+	}()
+
+	picker := &itemPicker{items: items, selected: make(map[int]bool)}
+	for _, item := range items {
+		picker.labels = append(picker.labels, describeItem(item, options))
+	}
+	picker.filter()
+
+	for {
+		picker.render()
+		key, err := readPickerKey()
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %w", err)
+		}
+		done, cancelled := picker.handle(key)
+		if cancelled {
+			return nil, nil
+		}
+		if done {
+			return picker.chosen(), nil
+		}
+	}
+}
+
+// handle applies a key press and reports whether the user confirmed or
+// cancelled the selection.
+func (p *itemPicker) handle(key int) (done, cancelled bool) {
+	switch key {
+	case keyCtrlC:
+		return false, true
+	case keyUp:
+		p.move(-1)
+		return false, false
+	case keyDown:
+		p.move(1)
+		return false, false
+	case keyPageUp:
+		p.move(-p.pageSize())
+		return false, false
+	case keyPageDown:
+		p.move(p.pageSize())
+		return false, false
+	}
+
+	if p.searching {
+		switch key {
+		case keyEnter, keyEscape:
+			p.searching = false
+		case keyBackspace:
+			if p.query != "" {
+				runes := []rune(p.query)
+				p.query = string(runes[:len(runes)-1])
+				p.filter()
+			}
+		default:
+			if key >= ' ' {
+				p.query += string(rune(key))
+				p.filter()
+			}
+		}
+		return false, false
+	}
+
+	switch key {
+	case keyEnter:
+		return true, false
+	case 'q', keyEscape:
+		return false, true
+	case '/':
+		p.searching = true
+	case ' ':
+		if len(p.visible) > 0 {
+			index := p.visible[p.cursor]
+			p.selected[index] = !p.selected[index]
+			p.move(1)
+		}
+	case 'a':
+		// Select all shown items, or clear them when they are all selected.
+		all := true
+		for _, index := range p.visible {
+			all = all && p.selected[index]
+		}
+		for _, index := range p.visible {
+			p.selected[index] = !all
+		}
+	}
+	return false, false
+}
+
+// filter shows the items whose description contains the search text,
+// ignoring case.
+func (p *itemPicker) filter() {
+	needle := strings.ToLower(p.query)
+	p.visible = p.visible[:0]
+	for i, label := range p.labels {
+		if strings.Contains(strings.ToLower(label), needle) {
+			p.visible = append(p.visible, i)
+		}
+	}
+	p.cursor, p.offset = 0, 0
+}
+
+func (p *itemPicker) move(delta int) {
+	p.cursor = max(0, min(p.cursor+delta, len(p.visible)-1))
+}
+
+func (p *itemPicker) pageSize() int {
+	rows, _ := terminalSize()
+	return max(rows-4, 1)
+}
+
+func (p *itemPicker) render() {
+	rows, cols := terminalSize()
+	page := max(rows-4, 1)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+page {
+		p.offset = p.cursor - page + 1
+	}
+
+	var screen strings.Builder
+	screen.WriteString(termClear)
+	search := "/ to search"
+	if p.searching || p.query != "" {
+		search = "Search: " + p.query
+		if p.searching {
+			search += "_"
+		}
+	}
+	fmt.Fprintf(&screen, "%s Select the items to process (%d of %d shown)  %s\r\n\r\n", emojiSearch, len(p.visible), len(p.items), search)
+
+	for row := p.offset; row < min(p.offset+page, len(p.visible)); row++ {
+		index := p.visible[row]
+		box := "[ ]"
+		if p.selected[index] {
+			box = "[x]"
+		}
+		line := truncate(box+" "+p.labels[index], cols-2)
+		if row == p.cursor {
+			fmt.Fprintf(&screen, "> %s%s%s\r\n", termReverse, line, termReset)
+		} else {
+			fmt.Fprintf(&screen, "  %s\r\n", line)
+		}
+	}
+	if len(p.visible) == 0 {
+		screen.WriteString("  (no items match the search)\r\n")
+	}
+
+	help := "↑/↓ move, space toggle, a all, / search, enter confirm, q cancel"
+	if p.searching {
+		help = "type to filter, enter or esc to stop searching"
+	}
+	fmt.Fprintf(&screen, "\x1b[%d;1H%d selected - %s", rows, p.count(), truncate(help, cols-16))
+	fmt.Fprint(ui, screen.String())
+}
+
+func (p *itemPicker) count() int {
+	count := 0
+	for _, selected := range p.selected {
+		if selected {
+			count++
+		}
+	}
+	return count
+}
+
+func (p *itemPicker) chosen() []BitwardenItem {
+	var chosen []BitwardenItem
+	for i, item := range p.items {
+		if p.selected[i] {
+			chosen = append(chosen, item)
+		}
+	}
+	return chosen
+}
+
+// readPickerKey reads one key press from the raw terminal. An escape byte
+// followed by more input starts an arrow or paging key; on its own it is the
+// Esc key.
+func readPickerKey() (int, error) {
+	b, err := stdinReader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch b {
+	case 3:
+		return keyCtrlC, nil
+	case '\r', '\n':
+		return keyEnter, nil
+	case 127, 8:
+		return keyBackspace, nil
+	case 27:
+	default:
+		if b < 0x80 {
+			return int(b), nil
+		}
+		stdinReader.UnreadByte()
+		r, _, err := stdinReader.ReadRune()
+		return int(r), err
+	}
+
+	if stdinReader.Buffered() == 0 {
+		return keyEscape, nil
+	}
+	sequence := make([]byte, 0, 3)
+	for stdinReader.Buffered() > 0 && len(sequence) < 3 {
+		next, _ := stdinReader.ReadByte()
+		sequence = append(sequence, next)
+		if next >= 'A' && next <= 'Z' || next == '~' {
+			break
+		}
+	}
+	switch string(sequence) {
+	case "[A", "OA":
+		return keyUp, nil
+	case "[B", "OB":
+		return keyDown, nil
+	case "[5~":
+		return keyPageUp, nil
+	case "[6~":
+		return keyPageDown, nil
+	}
+	return 0, nil
+}
+
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if width < 1 || len(runes) <= width {
+		return text
+	}
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,31 @@
+package cleanup
+
+import "context"
+
+// Client is what Select and Run ask of Bitwarden. Implementations usually
+// run bw or talk to 'bw serve', and classify its failures with CauseOf,
+// WithCause and IsNotFoundMessage.
+type Client interface {
+	// Sync pulls the latest state of the vault from the server.
+	Sync(ctx context.Context) error
+	// ListItems returns the items matching search as bw --search does, or
+	// every item for an empty search.
+	ListItems(ctx context.Context, search string) ([]Item, error)
+	// DeleteItem moves an item to the trash, or deletes it for good when
+	// permanent is set. An item that no longer exists fails with
+	// ErrItemGone.
+	DeleteItem(ctx context.Context, id string, permanent bool) error
+}
+
+// Select syncs the vault and returns the items matching search that filter
+// matches.
+func Select(ctx context.Context, client Client, search string, filter Filter[Item]) ([]Item, error) {
+	if err := client.Sync(ctx); err != nil {
+		return nil, err
+	}
+	items, err := client.ListItems(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	return Matching(items, filter), nil
+}
@@ -0,0 +1,27 @@
+// Package cleanup is the bulk-delete engine of bitwarden-cleanup, for Go
+// programs that embed it. Select lists and filters the items of a vault
+// through a Client, and Run confirms them and deletes them, or applies
+// another operation to them, with parallel workers, retries and progress
+// callbacks. The tool runs its operations on vault items through Run.
+//
+//	items, err := cleanup.Select(ctx, client, "staging", cleanup.All(
+//		cleanup.OfType(cleanup.TypeLogin),
+//		cleanup.ModifiedBefore(time.Now().AddDate(-1, 0, 0)),
+//	))
+//	if err != nil {
+//		return err
+//	}
+//	result, err := cleanup.Run(ctx, client, items, cleanup.DeleteOptions[cleanup.Item]{
+//		Batch:   4,
+//		Retries: cleanup.DefaultRetries,
+//		Confirm: func(items []cleanup.Item) bool { return len(items) < 100 },
+//		OnProgress: func(p cleanup.Progress[cleanup.Item]) {
+//			fmt.Printf("%d/%d\n", p.Done, p.Total)
+//		},
+//	})
+//
+// A Client runs bw or talks to 'bw serve'. CauseOf, WithCause and
+// IsNotFoundMessage classify the failures of bw the way the tool does, so
+// that Run retries throttling and network trouble and counts items that no
+// longer exist as already gone.
+package cleanup
@@ -0,0 +1,84 @@
+package cleanup
+
+import (
+	"errors"
+	"strings"
+)
+
+// Causes of failed bw commands.
+var (
+	ErrItemGone         = errors.New("item no longer exists")
+	ErrRateLimited      = errors.New("rate limited by the server")
+	ErrVaultLocked      = errors.New("vault is locked")
+	ErrNotLoggedIn      = errors.New("not logged in to Bitwarden")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrNetwork          = errors.New("network error")
+)
+
+// causePatterns recognize the causes in the lowercased output of bw, first
+// match wins. Bare status codes are not matched, since item IDs, sizes and
+// URLs in the output can contain them too.
+var causePatterns = []struct {
+	cause    error
+	patterns []string
+}{
+	{ErrRateLimited, []string{"rate limit", "too many requests"}},
+	{ErrVaultLocked, []string{"vault is locked", "session key"}},
+	{ErrNotLoggedIn, []string{"not logged in", "unauthorized", "invalid_grant", "log in again"}},
+	{ErrPermissionDenied, []string{"permission", "forbidden", "not have access", "cannot edit", "cannot delete"}},
+	{ErrNetwork, []string{"econnrefused", "econnreset", "etimedout", "enotfound", "getaddrinfo", "socket hang up", "timeout", "network"}},
+}
+
+// notFoundMessages are the answers of bw and the server for an item, folder,
+// Send or attachment that does not exist. Only a line that is one of them
+// counts: a proxy's 404 page or a DNS error mentioning "not found" says
+// nothing about the item.
+var notFoundMessages = map[string]bool{
+	"not found":            true,
+	"resource not found":   true,
+	"item not found":       true,
+	"cipher not found":     true,
+	"folder not found":     true,
+	"send not found":       true,
+	"attachment not found": true,
+}
+
+// IsNotFoundMessage reports whether the output of a failed bw command says
+// that the object it was about does not exist.
+func IsNotFoundMessage(message string) bool {
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(line)), ".")
+		if notFoundMessages[line] || strings.HasPrefix(line, "attachment ") && strings.HasSuffix(line, " was not found") {
+			return true
+		}
+	}
+	return false
+}
+
+// CauseOf returns the cause that the output of a failed bw command points
+// to, or nil. Not-found answers are left to IsNotFoundMessage.
+func CauseOf(message string) error {
+	message = strings.ToLower(message)
+	for _, entry := range causePatterns {
+		for _, pattern := range entry.patterns {
+			if strings.Contains(message, pattern) {
+				return entry.cause
+			}
+		}
+	}
+	return nil
+}
+
+// WithCause keeps the message of err and adds cause, so that errors.Is
+// finds both.
+func WithCause(err, cause error) error {
+	return &causedError{err: err, cause: cause}
+}
+
+type causedError struct {
+	err   error
+	cause error
+}
+
+func (e *causedError) Error() string   { return e.err.Error() }
+func (e *causedError) Unwrap() []error { return []error{e.err, e.cause} }
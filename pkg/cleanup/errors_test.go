@@ -0,0 +1,67 @@
+package cleanup
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsNotFoundMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    bool
+	}{
+		{"Not found.", true},
+		{"not found", true},
+		{"  Item not found.  ", true},
+		{"Something went wrong\nCipher not found.", true},
+		{"Attachment `a1` was not found.", true},
+		{"Resource not found.", true},
+		{"getaddrinfo ENOTFOUND vault.example.com", false},
+		{"<h1>404 Not Found</h1>", false},
+		{"Folder name not found in list, creating", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if got := IsNotFoundMessage(test.message); got != test.want {
+			t.Errorf("IsNotFoundMessage(%q) = %v, want %v", test.message, got, test.want)
+		}
+	}
+}
+
+func TestCauseOf(t *testing.T) {
+	tests := []struct {
+		message string
+		want    error
+	}{
+		{"Rate limit exceeded. Try again later.", ErrRateLimited},
+		{"429 Too Many Requests", ErrRateLimited},
+		{"Vault is locked.", ErrVaultLocked},
+		{"You are not logged in.", ErrNotLoggedIn},
+		{"invalid_grant", ErrNotLoggedIn},
+		{"You do not have access to this item.", ErrPermissionDenied},
+		{"request to https://api.bitwarden.com failed, reason: socket hang up", ErrNetwork},
+		{"connect ETIMEDOUT 10.0.0.1:443", ErrNetwork},
+		{"item 401f5e08 attachment size 403 kB", nil},
+		{"Not found.", nil},
+		{"", nil},
+	}
+	for _, test := range tests {
+		if got := CauseOf(test.message); got != test.want {
+			t.Errorf("CauseOf(%q) = %v, want %v", test.message, got, test.want)
+		}
+	}
+}
+
+func TestWithCause(t *testing.T) {
+	base := errors.New("error deleting item: exit status 1")
+	err := WithCause(base, ErrRateLimited)
+	if err.Error() != base.Error() {
+		t.Errorf("Error() = %q, want %q", err.Error(), base.Error())
+	}
+	if !errors.Is(err, base) || !errors.Is(err, ErrRateLimited) {
+		t.Errorf("errors.Is does not find both the error and its cause in %v", err)
+	}
+	if errors.Is(err, ErrNetwork) {
+		t.Errorf("errors.Is finds a cause that was not added")
+	}
+}
@@ -0,0 +1,98 @@
+package cleanup
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Filter decides whether an item is part of a run. A nil Filter matches
+// every item.
+type Filter[T any] func(item T) bool
+
+// Match reports whether f matches item.
+func (f Filter[T]) Match(item T) bool {
+	return f == nil || f(item)
+}
+
+// Matching returns the items that filter matches, in their order.
+func Matching[T any](items []T, filter Filter[T]) []T {
+	if filter == nil {
+		return items
+	}
+	var matched []T
+	for _, item := range items {
+		if filter(item) {
+			matched = append(matched, item)
+		}
+	}
+	return matched
+}
+
+// All matches the items that every filter matches.
+func All[T any](filters ...Filter[T]) Filter[T] {
+	return func(item T) bool {
+		for _, f := range filters {
+			if !f.Match(item) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any matches the items that at least one of the filters matches.
+func Any[T any](filters ...Filter[T]) Filter[T] {
+	return func(item T) bool {
+		return slices.ContainsFunc(filters, func(f Filter[T]) bool { return f.Match(item) })
+	}
+}
+
+// Not matches the items that f does not match.
+func Not[T any](f Filter[T]) Filter[T] {
+	return func(item T) bool { return !f.Match(item) }
+}
+
+// NameContains matches items whose name contains term, ignoring case.
+func NameContains(term string) Filter[Item] {
+	term = strings.ToLower(term)
+	return func(item Item) bool { return strings.Contains(strings.ToLower(item.Name), term) }
+}
+
+// NameMatches matches items whose name matches pattern.
+func NameMatches(pattern *regexp.Regexp) Filter[Item] {
+	return func(item Item) bool { return pattern.MatchString(item.Name) }
+}
+
+// URIMatches matches logins with a URI that matches pattern.
+func URIMatches(pattern *regexp.Regexp) Filter[Item] {
+	return func(item Item) bool { return slices.ContainsFunc(item.URIs(), pattern.MatchString) }
+}
+
+// OfType matches items of one of the given types.
+func OfType(types ...ItemType) Filter[Item] {
+	return func(item Item) bool { return slices.Contains(types, item.Type) }
+}
+
+// InFolder matches items in the folder with this ID; an empty ID matches
+// items without a folder.
+func InFolder(folderID string) Filter[Item] {
+	return func(item Item) bool { return item.FolderID == folderID }
+}
+
+// InOrganization matches items owned by the organization with this ID; an
+// empty ID matches personal items.
+func InOrganization(organizationID string) Filter[Item] {
+	return func(item Item) bool { return item.OrganizationID == organizationID }
+}
+
+// CreatedBefore matches items created before t.
+func CreatedBefore(t time.Time) Filter[Item] {
+	return func(item Item) bool { return item.CreationDate.Before(t) }
+}
+
+// ModifiedBefore matches items last changed before t.
+func ModifiedBefore(t time.Time) Filter[Item] {
+	return func(item Item) bool { return item.RevisionDate.Before(t) }
+}
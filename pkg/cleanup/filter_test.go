@@ -0,0 +1,35 @@
+package cleanup
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFilterCombinators(t *testing.T) {
+	items := []Item{
+		{ID: "id1", Name: "Staging DB", Type: TypeLogin, FolderID: "f1"},
+		{ID: "id2", Name: "staging notes", Type: TypeNote},
+		{ID: "id3", Name: "production db", Type: TypeLogin, FolderID: "f1"},
+	}
+	tests := []struct {
+		name   string
+		filter Filter[Item]
+		want   []string
+	}{
+		{"nil", nil, []string{"id1", "id2", "id3"}},
+		{"all", All(NameContains("staging"), OfType(TypeLogin)), []string{"id1"}},
+		{"any", Any(NameContains("notes"), InFolder("f1")), []string{"id1", "id2", "id3"}},
+		{"not", Not(InFolder("f1")), []string{"id2"}},
+		{"all of none", All[Item](), []string{"id1", "id2", "id3"}},
+		{"any of none", Any[Item](), nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, item := range Matching(items, test.filter) {
+			got = append(got, item.ID)
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("%s: matched %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package cleanup
+
+import "time"
+
+// ItemType is the type of a vault item, numbered as by bw.
+type ItemType int
+
+const (
+	TypeLogin    ItemType = 1
+	TypeNote     ItemType = 2
+	TypeCard     ItemType = 3
+	TypeIdentity ItemType = 4
+	TypeSSHKey   ItemType = 5
+)
+
+// Keyed is what Run needs to know of the items it processes. Programs can
+// run their own item types through Run by giving them an ItemID method.
+type Keyed interface {
+	ItemID() string
+}
+
+// Item is a vault item as listed by 'bw list items', with the fields the
+// filters of this package look at.
+type Item struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organizationId"`
+	FolderID       string    `json:"folderId"`
+	CollectionIDs  []string  `json:"collectionIds"`
+	Type           ItemType  `json:"type"`
+	Name           string    `json:"name"`
+	Notes          string    `json:"notes"`
+	Login          *Login    `json:"login"`
+	CreationDate   time.Time `json:"creationDate"`
+	RevisionDate   time.Time `json:"revisionDate"`
+}
+
+// Login holds the login fields of an item of TypeLogin.
+type Login struct {
+	Username string `json:"username"`
+	URIs     []URI  `json:"uris"`
+}
+
+// URI is one URI of a login.
+type URI struct {
+	URI string `json:"uri"`
+}
+
+func (item Item) ItemID() string { return item.ID }
+
+// Username returns the username of a login, or "" for other items.
+func (item Item) Username() string {
+	if item.Login == nil {
+		return ""
+	}
+	return item.Login.Username
+}
+
+// URIs returns the URIs of a login.
+func (item Item) URIs() []string {
+	if item.Login == nil {
+		return nil
+	}
+	var uris []string
+	for _, uri := range item.Login.URIs {
+		uris = append(uris, uri.URI)
+	}
+	return uris
+}
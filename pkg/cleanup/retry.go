@@ -0,0 +1,25 @@
+package cleanup
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// The retry policy of the tool: how often a command that failed because of
+// throttling or the network is tried again, and how long to wait first.
+const (
+	DefaultRetries      = 3
+	DefaultRetryBackoff = time.Second
+	MaxRetryBackoff     = 30 * time.Second
+)
+
+// RetryDelay doubles backoff with every attempt, up to MaxRetryBackoff, and
+// picks a random point in its upper half so that parallel workers that were
+// throttled together do not retry in lockstep.
+func RetryDelay(backoff time.Duration, attempt int) time.Duration {
+	delay := min(backoff<<attempt, MaxRetryBackoff)
+	if delay <= 0 {
+		delay = MaxRetryBackoff
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
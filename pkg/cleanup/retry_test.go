@@ -0,0 +1,29 @@
+package cleanup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		backoff time.Duration
+		attempt int
+		max     time.Duration
+	}{
+		{time.Second, 0, time.Second},
+		{time.Second, 1, 2 * time.Second},
+		{time.Second, 3, 8 * time.Second},
+		{time.Second, 10, MaxRetryBackoff},
+		{time.Second, 70, MaxRetryBackoff},
+		{0, 0, MaxRetryBackoff},
+	}
+	for _, test := range tests {
+		for i := 0; i < 100; i++ {
+			delay := RetryDelay(test.backoff, test.attempt)
+			if delay < test.max/2 || delay > test.max {
+				t.Fatalf("RetryDelay(%s, %d) = %s, want between %s and %s", test.backoff, test.attempt, delay, test.max/2, test.max)
+			}
+		}
+	}
+}
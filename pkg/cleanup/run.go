@@ -0,0 +1,185 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCancelled is returned by Run when Confirm declines the run.
+var ErrCancelled = errors.New("cancelled")
+
+// DeleteOptions describe how Run processes the items of one run.
+type DeleteOptions[T Keyed] struct {
+	// Permanent deletes items for good instead of moving them to the trash.
+	Permanent bool
+	// Batch is the number of items processed in parallel; at least 1.
+	Batch int
+	// Retries is how often an item whose failure Retryable accepts is tried
+	// again, waiting RetryDelay(RetryBackoff, retry) first.
+	Retries      int
+	RetryBackoff time.Duration
+	// Retryable decides which failures are tried again; IsRetryable when
+	// nil.
+	Retryable func(err error) bool
+
+	// Apply replaces the deletion with another operation on each item, such
+	// as an edit. The client passed to Run may be nil then.
+	Apply func(ctx context.Context, item T) error
+	// Confirm is shown the items before any is processed; returning false
+	// cancels the run. A nil Confirm confirms every run.
+	Confirm func(items []T) bool
+	// Start is called by a worker before it processes an item, e.g. to wait
+	// for a rate limiter; returning false skips the item.
+	Start func(ctx context.Context, item T) bool
+	// Throttle is waited for before every retry; an error ends the retries.
+	Throttle func(ctx context.Context) error
+	// OnRetry is called before a failed item is tried again after delay.
+	OnRetry func(item T, err error, retry int, delay time.Duration)
+	// OnResult is called by the worker that processed an item, as soon as
+	// it is done.
+	OnResult func(result ItemResult[T])
+	// OnProgress is called after each processed item, from one goroutine at
+	// a time.
+	OnProgress func(progress Progress[T])
+}
+
+// ItemResult is the outcome of one item.
+type ItemResult[T Keyed] struct {
+	Item     T
+	Err      error
+	Retries  int
+	Duration time.Duration
+}
+
+// Gone reports whether the item no longer existed, which counts as done.
+func (r ItemResult[T]) Gone() bool { return errors.Is(r.Err, ErrItemGone) }
+
+// Failed reports whether the item could not be processed.
+func (r ItemResult[T]) Failed() bool { return r.Err != nil && !r.Gone() }
+
+// Progress reports an item that was processed.
+type Progress[T Keyed] struct {
+	Result ItemResult[T]
+	Done   int
+	Total  int
+	// Failed counts the failed items so far, not those already gone.
+	Failed int
+}
+
+// Result is the outcome of a run.
+type Result[T Keyed] struct {
+	Succeeded   int
+	AlreadyGone int
+	Failed      []ItemResult[T]
+}
+
+// Run asks Confirm about items and deletes the confirmed ones through
+// client, or applies Apply to them, with Batch workers. Cancelling ctx
+// starts no new items; items already handed to a worker are finished, and
+// Run returns the result so far with the context's error.
+func Run[T Keyed](ctx context.Context, client Client, items []T, options DeleteOptions[T]) (Result[T], error) {
+	var result Result[T]
+	if len(items) == 0 {
+		return result, nil
+	}
+	if options.Confirm != nil && !options.Confirm(items) {
+		return result, ErrCancelled
+	}
+	apply := options.Apply
+	if apply == nil {
+		apply = func(ctx context.Context, item T) error {
+			return client.DeleteItem(ctx, item.ItemID(), options.Permanent)
+		}
+	}
+
+	jobs := make(chan T)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+	for range max(options.Batch, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				if options.Start != nil && !options.Start(ctx, item) {
+					continue
+				}
+				started := time.Now()
+				retries, err := applyWithRetries(ctx, apply, item, options)
+				itemResult := ItemResult[T]{Item: item, Err: err, Retries: retries, Duration: time.Since(started)}
+				if options.OnResult != nil {
+					options.OnResult(itemResult)
+				}
+
+				mu.Lock()
+				done++
+				switch {
+				case itemResult.Gone():
+					result.AlreadyGone++
+				case itemResult.Failed():
+					result.Failed = append(result.Failed, itemResult)
+				default:
+					result.Succeeded++
+				}
+				if options.OnProgress != nil {
+					options.OnProgress(Progress[T]{Result: itemResult, Done: done, Total: len(items), Failed: len(result.Failed)})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, item := range items {
+		select {
+		case jobs <- item:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return result, ctx.Err()
+}
+
+// applyWithRetries runs apply on item and retries the failures that
+// options.Retryable accepts. Waiting ends early when ctx is cancelled,
+// leaving the last failure as the result.
+func applyWithRetries[T Keyed](ctx context.Context, apply func(context.Context, T) error, item T, options DeleteOptions[T]) (int, error) {
+	retryable := options.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	backoff := options.RetryBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	for retries := 0; ; retries++ {
+		err := apply(ctx, item)
+		if err == nil || retries >= options.Retries || !retryable(err) {
+			return retries, err
+		}
+
+		delay := RetryDelay(backoff, retries)
+		if options.OnRetry != nil {
+			options.OnRetry(item, err, retries+1, delay)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return retries, err
+		}
+		if options.Throttle != nil && options.Throttle(ctx) != nil {
+			return retries, err
+		}
+	}
+}
+
+// IsRetryable reports whether an operation that failed with err may succeed
+// when tried again: throttling, network trouble and timeouts pass, a missing
+// item or a locked vault does not.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrNetwork) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,123 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClient struct {
+	mu       sync.Mutex
+	items    map[string]Item
+	failures map[string][]error
+	deleted  []string
+}
+
+func newFakeClient(items ...Item) *fakeClient {
+	client := &fakeClient{items: make(map[string]Item), failures: make(map[string][]error)}
+	for _, item := range items {
+		client.items[item.ID] = item
+	}
+	return client
+}
+
+func (c *fakeClient) Sync(ctx context.Context) error { return nil }
+
+func (c *fakeClient) ListItems(ctx context.Context, search string) ([]Item, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var items []Item
+	for _, item := range c.items {
+		items = append(items, item)
+	}
+	return Matching(items, NameContains(search)), nil
+}
+
+func (c *fakeClient) DeleteItem(ctx context.Context, id string, permanent bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if queued := c.failures[id]; len(queued) > 0 {
+		c.failures[id] = queued[1:]
+		return queued[0]
+	}
+	if _, ok := c.items[id]; !ok {
+		return ErrItemGone
+	}
+	delete(c.items, id)
+	c.deleted = append(c.deleted, id)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	client := newFakeClient(Item{ID: "id1", Name: "test item 1"}, Item{ID: "id2", Name: "test item 2"}, Item{ID: "id3", Name: "test item 3"})
+	client.failures["id1"] = []error{ErrRateLimited}
+	client.failures["id2"] = []error{ErrPermissionDenied}
+	items := []Item{{ID: "id1"}, {ID: "id2"}, {ID: "id3"}, {ID: "id4"}}
+
+	var progress []Progress[Item]
+	result, err := Run(context.Background(), client, items, DeleteOptions[Item]{
+		Batch:        2,
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+		OnProgress:   func(p Progress[Item]) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Succeeded != 2 || result.AlreadyGone != 1 || len(result.Failed) != 1 {
+		t.Fatalf("succeeded %d, already gone %d, failed %d; want 2, 1, 1", result.Succeeded, result.AlreadyGone, len(result.Failed))
+	}
+	if failed := result.Failed[0]; failed.Item.ID != "id2" || !errors.Is(failed.Err, ErrPermissionDenied) || failed.Retries != 0 {
+		t.Errorf("failed item = %+v, want id2 without retries", failed)
+	}
+	if len(progress) != len(items) || progress[len(progress)-1].Done != len(items) || progress[len(progress)-1].Failed != 1 {
+		t.Errorf("got %d progress reports, last %+v", len(progress), progress[len(progress)-1])
+	}
+}
+
+func TestRunCancelledByConfirm(t *testing.T) {
+	client := newFakeClient(Item{ID: "id1"})
+	_, err := Run(context.Background(), client, []Item{{ID: "id1"}}, DeleteOptions[Item]{
+		Confirm: func(items []Item) bool { return false },
+	})
+	if !errors.Is(err, ErrCancelled) || len(client.deleted) != 0 {
+		t.Errorf("Run = %v with %d deleted, want ErrCancelled and none", err, len(client.deleted))
+	}
+}
+
+func TestRunApplyAndStart(t *testing.T) {
+	var mu sync.Mutex
+	var applied []string
+	_, err := Run(context.Background(), nil, []Item{{ID: "id1"}, {ID: "skip"}, {ID: "id2"}}, DeleteOptions[Item]{
+		Start: func(ctx context.Context, item Item) bool { return item.ID != "skip" },
+		Apply: func(ctx context.Context, item Item) error {
+			mu.Lock()
+			defer mu.Unlock()
+			applied = append(applied, item.ID)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("applied to %q, want id1 and id2", applied)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	client := newFakeClient(
+		Item{ID: "id1", Name: "staging db", Type: TypeLogin},
+		Item{ID: "id2", Name: "staging notes", Type: TypeNote},
+		Item{ID: "id3", Name: "production db", Type: TypeLogin},
+	)
+	items, err := Select(context.Background(), client, "staging", OfType(TypeLogin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != "id1" {
+		t.Errorf("Select = %+v, want id1", items)
+	}
+}
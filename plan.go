@@ -0,0 +1,283 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	planSchema  = "https://github.com/mitas/bitwarden-cleanup/schemas/plan-v1.json"
+	planVersion = 1
+)
+
+// planDocument is a self-contained record of an approved selection that can
+// be archived or reviewed by someone else before it is applied.
+type planDocument struct {
+	Schema    string        `json:"$schema"`
+	Version   int           `json:"version"`
+	ID        string        `json:"id"`
+	CreatedAt time.Time     `json:"createdAt"`
+	Operation planOperation `json:"operation"`
+	Filter    planFilter    `json:"filter"`
+	Items     []planItem    `json:"items"`
+	// Snapshot is the SHA-256 of the planned items as they were fetched,
+	// in item ID order.
+	Snapshot string `json:"snapshotChecksum"`
+}
+
+type planOperation struct {
+	Verb      string   `json:"verb"`
+	Permanent bool     `json:"permanent"`
+	Args      []string `json:"args"`
+}
+
+type planFilter struct {
+	Description string   `json:"description"`
+	Args        []string `json:"args"`
+}
+
+type planItem struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	OrganizationID string `json:"organizationId,omitempty"`
+	RevisionDate   string `json:"revisionDate,omitempty"`
+	Checksum       string `json:"checksum"`
+}
+
+func runPlanCommand(args []string) error {
+	flags := flag.NewFlagSet("plan", flag.ExitOnError)
+	collectOptions := defineCommandFlags(flags)
+	out := flags.String("out", "", "File to write the plan to (default: plan-<id>.json in the current directory)")
+	options, err := parseOptions(flags, args, collectOptions)
+	if err != nil {
+		return err
+	}
+
+	if options.dryRun || options.scheduleAt != "" || options.scheduleAfter != 0 {
+		return fmt.Errorf("--dry-run, --at and --after cannot be used with plan")
+	}
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+
+	op, err := selectOperation(options)
+	if err != nil {
+		return err
+	}
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+	if err := validateGroupBy(options.groupBy); err != nil {
+		return err
+	}
+
+	displayOperationMode(op)
+	if err := syncBitwarden("before planning"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return err
+	}
+	recordFilterHistory(options, items)
+	displayItemCount(&DeleteStats{total: len(items)}, op)
+
+	plan := newPlanDocument(items, op, options)
+	path := *out
+	if path == "" {
+		path = "plan-" + plan.ID + ".json"
+	}
+	if err := writeJSONFile(path, plan); err != nil {
+		return err
+	}
+
+	console.infof(emojiSuccess, "Plan %s written to %s (%d items, snapshot %s)", plan.ID, path, len(plan.Items), plan.Snapshot[:12])
+	return nil
+}
+
+func newPlanDocument(items []BitwardenItem, op itemOperation, options CommandOptions) *planDocument {
+	plan := &planDocument{
+		Schema:    planSchema,
+		Version:   planVersion,
+		ID:        newRunID(),
+		CreatedAt: time.Now().UTC(),
+		Operation: planOperation{Verb: op.verb, Permanent: op.permanent, Args: options.commandArgs},
+		Filter:    planFilter{Description: describeSelection(options.selectionArgs), Args: options.selectionArgs},
+	}
+
+	sorted := append([]BitwardenItem(nil), items...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	for _, item := range sorted {
+		plan.Items = append(plan.Items, planItem{
+			ID:             item.ID,
+			Name:           item.Name,
+			OrganizationID: item.OrganizationID,
+			RevisionDate:   item.RevisionDate,
+			Checksum:       itemChecksum(item),
+		})
+	}
+	plan.Snapshot = snapshotChecksum(sorted)
+	return plan
+}
+
+func describeSelection(args []string) string {
+	if len(args) == 0 {
+		return "all items"
+	}
+	return shellJoin(args)
+}
+
+func itemChecksum(item BitwardenItem) string {
+	sum := sha256.Sum256(item.raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// snapshotChecksum hashes the items in the order given.
+func snapshotChecksum(items []BitwardenItem) string {
+	hash := sha256.New()
+	for _, item := range items {
+		hash.Write([]byte(item.ID))
+		hash.Write([]byte{0})
+		hash.Write(item.raw)
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func runApplyCommand(args []string) error {
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	allowDrift := flags.Bool("allow-drift", false, "Apply the plan even if planned items changed since it was created")
+	consoleValues := defineConsoleFlags(flags)
+	flags.Parse(args)
+	if err := consoleValues.apply(); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: %s apply [--allow-drift] <planfile>", filepath.Base(os.Args[0]))
+	}
+
+	plan, err := loadPlanDocument(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+
+	replay := flag.NewFlagSet("apply", flag.ContinueOnError)
+	collectOptions := defineCommandFlags(replay)
+	if err := replay.Parse(plan.Operation.Args); err != nil {
+		return fmt.Errorf("error replaying plan %s: %w", plan.ID, err)
+	}
+	options, err := withProtection(collectOptions())
+	if err != nil {
+		return err
+	}
+	options.commandArgs = plan.Operation.Args
+
+	op, err := selectOperation(options)
+	if err != nil {
+		return err
+	}
+
+	console.infof(emojiInfo, "Plan %s from %s: %s %d items (%s)", plan.ID, plan.CreatedAt.Local().Format("2006-01-02 15:04"), plan.Operation.Verb, len(plan.Items), plan.Filter.Description)
+	displayOperationMode(op)
+
+	if err := syncBitwarden("before applying"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	current, err := fetchBitwardenItems("")
+	if err != nil {
+		return err
+	}
+
+	items, changed, missing := detectPlanDrift(plan, current)
+	if len(changed) > 0 || len(missing) > 0 {
+		showPlanDrift(changed, missing, options.redact)
+		if !*allowDrift {
+			return fmt.Errorf("%d planned items drifted since the plan was created; re-run plan or pass --allow-drift", len(changed)+len(missing))
+		}
+		console.warnf("--allow-drift given: applying to %d changed items, skipping %d missing items", len(changed), len(missing))
+	} else {
+		console.infof(emojiSuccess, "Vault snapshot matches the plan (%s)", plan.Snapshot[:12])
+	}
+
+	items = options.protection.apply(items)
+	stats := &DeleteStats{total: len(items), protected: options.protection.count()}
+	displayItemCount(stats, op)
+
+	if stats.total > 0 && op.deletesItems {
+		items = protectSensitiveItems(items, options)
+		stats.total = len(items)
+	}
+	if stats.total == 0 {
+		return nil
+	}
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	return executeItems(items, stats, op, options)
+}
+
+func loadPlanDocument(path string) (*planDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan: %w", err)
+	}
+
+	var plan planDocument
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("error parsing plan %s: %w", path, err)
+	}
+	if plan.Schema != planSchema || plan.Version != planVersion {
+		return nil, fmt.Errorf("%s is not a version %d plan file", path, planVersion)
+	}
+	return &plan, nil
+}
+
+// detectPlanDrift compares the planned items with the live vault. It returns
+// the live items to process, including those that changed, and the planned
+// items that changed or no longer exist.
+func detectPlanDrift(plan *planDocument, current []BitwardenItem) (items []BitwardenItem, changed, missing []planItem) {
+	live := make(map[string]BitwardenItem, len(current))
+	for _, item := range current {
+		live[item.ID] = item
+	}
+
+	for _, planned := range plan.Items {
+		item, ok := live[planned.ID]
+		if !ok {
+			missing = append(missing, planned)
+			continue
+		}
+		if itemChecksum(item) != planned.Checksum {
+			changed = append(changed, planned)
+		}
+		items = append(items, item)
+	}
+	return items, changed, missing
+}
+
+func showPlanDrift(changed, missing []planItem, redact redactor) {
+	console.warnf("\nWARNING: the vault changed since the plan was created!")
+	for _, item := range changed {
+		console.linef("   ~ %s (%s) was modified", redact.name(item.Name), item.ID)
+	}
+	for _, item := range missing {
+		console.linef("   - %s (%s) no longer exists", redact.name(item.Name), item.ID)
+	}
+}
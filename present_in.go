@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// importEntry is one item of a Bitwarden export or import file.
+type importEntry struct {
+	name     string
+	username string
+	uri      string
+}
+
+// key identifies an entry the way items are told apart after an import:
+// by first URI and username for logins that have both, by name otherwise.
+func (entry importEntry) key() string {
+	if entry.uri != "" && entry.username != "" {
+		return "login\x00" + strings.ToLower(entry.username) + "\x00" + strings.TrimSuffix(strings.ToLower(entry.uri), "/")
+	}
+	return "name\x00" + strings.ToLower(strings.TrimSpace(entry.name))
+}
+
+func importEntryFor(item BitwardenItem) importEntry {
+	entry := importEntry{name: item.Name, username: item.username()}
+	if uris := item.uris(); len(uris) > 0 {
+		entry.uri = uris[0]
+	}
+	return entry
+}
+
+// readImportFile reads an unencrypted Bitwarden JSON or CSV export.
+func readImportFile(path string) ([]importEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading import file: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return parseImportCSV(path, data)
+	}
+
+	var export struct {
+		Encrypted bool            `json:"encrypted"`
+		Items     []BitwardenItem `json:"items"`
+	}
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("error parsing import file %s: %w", path, err)
+	}
+	if export.Encrypted {
+		return nil, fmt.Errorf("import file %s is an encrypted export; use an unencrypted JSON or CSV export", path)
+	}
+
+	entries := make([]importEntry, 0, len(export.Items))
+	for _, item := range export.Items {
+		entries = append(entries, importEntryFor(item))
+	}
+	return entries, nil
+}
+
+// parseImportCSV reads the columns of Bitwarden's CSV format (name,
+// login_uri, login_username). login_uri lists several URIs separated by
+// commas.
+func parseImportCSV(path string, data []byte) ([]importEntry, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(data), "\ufeff")))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing import file %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, fmt.Errorf("import file %s has no name column", path)
+	}
+
+	var entries []importEntry
+	for _, record := range records[1:] {
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		uri, _, _ := strings.Cut(field("login_uri"), ",")
+		entries = append(entries, importEntry{name: field("name"), username: field("login_username"), uri: strings.TrimSpace(uri)})
+	}
+	return entries, nil
+}
+
+// selectPresentIn returns the vault items that correspond to the entries of
+// the import file at path. Every entry claims the most recently created
+// matching item that no other entry has claimed, so after importing a file
+// twice exactly the second copy is selected and the originals are left.
+func selectPresentIn(path string, vault []BitwardenItem) ([]BitwardenItem, error) {
+	entries, err := readImportFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make(map[string][]BitwardenItem)
+	for _, item := range vault {
+		key := importEntryFor(item).key()
+		candidates[key] = append(candidates[key], item)
+	}
+	for _, items := range candidates {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].CreationDate > items[j].CreationDate })
+	}
+
+	var selected []BitwardenItem
+	unmatched := 0
+	for _, entry := range entries {
+		key := entry.key()
+		if len(candidates[key]) == 0 {
+			unmatched++
+			continue
+		}
+		selected = append(selected, candidates[key][0])
+		candidates[key] = candidates[key][1:]
+	}
+
+	console.infof(emojiInfo, "Import file %s: %d of %d entries found in the vault", path, len(selected), len(entries))
+	if unmatched > 0 {
+		console.infof(emojiInfo, "%d entries have no matching vault item (never imported or already deleted)", unmatched)
+	}
+	return selected, nil
+}
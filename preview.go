@@ -0,0 +1,159 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Column widths of the item tables; longer values are truncated.
+const (
+	tableNameWidth     = 40
+	tableUsernameWidth = 30
+	tableFolderWidth   = 24
+)
+
+// previewItems pages through the matched items as a table before
+// confirmation, with --preview items per page when it is set and
+// --page-size otherwise. Whole pages can be excluded from the run; the
+// remaining items are returned. ok is false when the user quit the preview,
+// which cancels the run.
+func previewItems(items []BitwardenItem, options CommandOptions) (remaining []BitwardenItem, ok bool) {
+	pageSize := options.pageSize
+	if options.preview > 0 {
+		pageSize = options.preview
+	}
+	pages := (len(items) + pageSize - 1) / pageSize
+	rows := itemTableRows(items, options)
+	excluded := make(map[int]bool)
+	page := 0
+
+	console.infof(emojiInfo, "\n%d items matched, showing a preview of %d items per page", len(items), pageSize)
+
+	for {
+		showPreviewPage(rows, page, pageSize, pages, excluded)
+
+		console.promptf("", "[n]ext, [p]rev, [j N] jump, e[x]clude page, [i]nclude page, [d]one, [q]uit: ")
+		input, err := readLine()
+		if err != nil {
+			console.errorf("Error reading input: %v", err)
+			return nil, false
+		}
+
+		command, argument, _ := strings.Cut(strings.ToLower(input), " ")
+		switch command {
+		case "", "n":
+			if page < pages-1 {
+				page++
+			}
+		case "p":
+			if page > 0 {
+				page--
+			}
+		case "j":
+			target, err := strconv.Atoi(strings.TrimSpace(argument))
+			if err != nil || target < 1 || target > pages {
+				console.warnf("Enter a page between 1 and %d, e.g. 'j 3'", pages)
+				continue
+			}
+			page = target - 1
+		case "x":
+			excluded[page] = true
+			if page < pages-1 {
+				page++
+			}
+		case "i":
+			delete(excluded, page)
+		case "d":
+			return includedItems(items, pageSize, excluded), true
+		case "q":
+			return nil, false
+		default:
+			console.warnf("Unknown command %q", input)
+		}
+	}
+}
+
+func showPreviewPage(rows [][]string, page, pageSize, pages int, excluded map[int]bool) {
+	start := page * pageSize
+	end := min(start+pageSize, len(rows))
+
+	status := ""
+	if excluded[page] {
+		status = " [EXCLUDED]"
+	}
+	console.linef("\n--- Page %d/%d (items %d-%d of %d, %d pages excluded)%s ---", page+1, pages, start+1, end, len(rows), len(excluded), status)
+
+	table := [][]string{{"#", "NAME", "TYPE", "USERNAME", "FOLDER", "MODIFIED", "DETAILS"}}
+	for i := start; i < end; i++ {
+		table = append(table, append([]string{strconv.Itoa(i + 1)}, rows[i]...))
+	}
+	printTable(table)
+}
+
+func includedItems(items []BitwardenItem, pageSize int, excluded map[int]bool) []BitwardenItem {
+	if len(excluded) == 0 {
+		return items
+	}
+
+	var included []BitwardenItem
+	for i, item := range items {
+		if !excluded[i/pageSize] {
+			included = append(included, item)
+		}
+	}
+
+	console.infof(emojiInfo, "Excluded %d pages (%d items) from the run", len(excluded), len(items)-len(included))
+	return included
+}
+
+// itemTableRows returns the columns of the preview table for each item.
+// Names and usernames follow --redact and secrets are masked.
+func itemTableRows(items []BitwardenItem, options CommandOptions) [][]string {
+	folderName := folderNamer()
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		modified := "-"
+		if revised := revisionTime(item); !revised.IsZero() {
+			modified = revised.Local().Format(time.DateOnly)
+		}
+		rows[i] = []string{
+			truncate(options.redact.name(item.Name), tableNameWidth),
+			item.typeName(),
+			truncate(options.redact.username(item.username()), tableUsernameWidth),
+			truncate(folderName(item), tableFolderWidth),
+			modified,
+			secretSummary(item, options),
+		}
+	}
+	return rows
+}
+
+// printTable prints rows with every column but the last padded to its
+// widest cell. The first row is the header.
+func printTable(rows [][]string) {
+	var widths []int
+	for _, row := range rows {
+		for column, cell := range row {
+			if column == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[column] = max(widths[column], len([]rune(cell)))
+		}
+	}
+	for _, row := range rows {
+		var line strings.Builder
+		for column, cell := range row {
+			if column < len(row)-1 {
+				cell = pad(cell, widths[column]) + "  "
+			}
+			line.WriteString(cell)
+		}
+		console.linef("   %s", strings.TrimRight(line.String(), " "))
+	}
+}
+
+// pad fills text with spaces to width runes; fmt counts bytes instead.
+func pad(text string, width int) string {
+	return text + strings.Repeat(" ", max(width-len([]rune(text)), 0))
+}
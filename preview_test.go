@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestPrintTableAlignsNonASCII(t *testing.T) {
+	var out bytes.Buffer
+	previous := ui
+	ui = &out
+	t.Cleanup(func() { ui = previous })
+
+	printTable([][]string{
+		{"NAME", "ID"},
+		{"Bücherei", "id1"},
+		{"会社のメール", "id2"},
+		{"plain", "id3"},
+	})
+
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 4:\n%s", len(lines), out.String())
+	}
+	column := len([]rune(lines[0])) - len("ID")
+	for _, line := range lines[1:] {
+		if got := len([]rune(line)) - len("id1"); got != column {
+			t.Errorf("ID column of %q starts at rune %d, want %d", line, got, column)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"äöü", 5, "äöü  "},
+		{"toolong", 3, "toolong"},
+	}
+	for _, test := range tests {
+		if got := pad(test.text, test.width); got != test.want {
+			t.Errorf("pad(%q, %d) = %q, want %q", test.text, test.width, got, test.want)
+		}
+	}
+}
+
+func TestPreviewItemsQuitCancels(t *testing.T) {
+	previousUI, previousStdin := ui, stdinReader
+	ui = io.Discard
+	t.Cleanup(func() { ui, stdinReader = previousUI, previousStdin })
+
+	items := []BitwardenItem{{ID: "id1", Name: "one"}, {ID: "id2", Name: "two"}, {ID: "id3", Name: "three"}}
+	options := CommandOptions{preview: 2}
+
+	stdinReader = bufio.NewReader(strings.NewReader("q\n"))
+	if remaining, ok := previewItems(items, options); ok || remaining != nil {
+		t.Errorf("after quit got %d items, ok %v; want none and false", len(remaining), ok)
+	}
+
+	stdinReader = bufio.NewReader(strings.NewReader("x\nd\n"))
+	remaining, ok := previewItems(items, options)
+	if !ok || len(remaining) != 1 || remaining[0].ID != "id3" {
+		t.Errorf("after excluding the first page got %v, ok %v; want id3 and true", remaining, ok)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !unix && !windows
+
+package main
+
+import "os/exec"
+
+var bwExecutables = []string{"bw"}
+
+func detachFromTerminal(cmd *exec.Cmd) {}
+
+func killTreeOnCancel(cmd *exec.Cmd) {}
+
+func escapeForShim(cmd *exec.Cmd) {}
+
+// processRunning cannot tell on this platform, so the checkpoints of runs
+// that did not end are always offered for recovery.
+func processRunning(pid int) bool { return false }
@@ -0,0 +1,30 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+var bwExecutables = []string{"bw"}
+
+// detachFromTerminal starts cmd in its own process group, so that Ctrl-C in
+// the terminal reaches this program only and an in-flight bw command can
+// finish while the run winds down. A hung command is killed with its whole
+// group.
+func detachFromTerminal(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+}
+
+func killTreeOnCancel(cmd *exec.Cmd) {}
+
+func escapeForShim(cmd *exec.Cmd) {}
+
+// processRunning reports whether a process with this PID exists.
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,105 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// stillActive is the exit code GetExitCodeProcess reports for a process
+// that has not exited.
+const stillActive = 259
+
+// bw is installed as bw.exe by the standalone download, Chocolatey, Scoop
+// and winget, and as the bw.cmd shim by npm.
+var bwExecutables = []string{"bw.exe", "bw.cmd"}
+
+// detachFromTerminal starts cmd in its own process group, so that Ctrl-C in
+// the console reaches this program only.
+func detachFromTerminal(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+	killTreeOnCancel(cmd)
+}
+
+// cmdMetacharacters are the characters cmd.exe interprets in a command line.
+var cmdMetacharacters = regexp.MustCompile("([()\\][%!^\"`<>&|;, *?])")
+
+// escapeForShim runs a .cmd shim through cmd.exe with a command line of its
+// own. Go quotes arguments for programs that parse their command line the
+// usual way, but cmd.exe acts on &, |, ^ and % in them, so searching for
+// "AT&T" would run T. Every argument is quoted and its metacharacters are
+// escaped twice: once for cmd.exe /c and once for the %* the shim passes
+// them on with.
+func escapeForShim(cmd *exec.Cmd) {
+	if !strings.EqualFold(filepath.Ext(cmd.Path), ".cmd") {
+		return
+	}
+	comspec := os.Getenv("ComSpec")
+	if comspec == "" {
+		comspec = filepath.Join(os.Getenv("SystemRoot"), "System32", "cmd.exe")
+	}
+
+	line := []string{cmdMetacharacters.ReplaceAllString(cmd.Path, "^$1")}
+	for _, arg := range cmd.Args[1:] {
+		escaped := cmdMetacharacters.ReplaceAllString(quoteArgument(arg), "^$1")
+		line = append(line, cmdMetacharacters.ReplaceAllString(escaped, "^$1"))
+	}
+	cmd.Path = comspec
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CmdLine = fmt.Sprintf(`%s /d /s /c "%s"`, syscall.EscapeArg(comspec), strings.Join(line, " "))
+}
+
+// quoteArgument always quotes arg the way the C runtime parses it: the
+// backslashes before a quote, and those at the end, are doubled.
+func quoteArgument(arg string) string {
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	backslashes := 0
+	for _, r := range arg {
+		switch r {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			backslashes = 2*backslashes + 1
+		}
+		quoted.WriteString(strings.Repeat(`\`, backslashes))
+		quoted.WriteRune(r)
+		backslashes = 0
+	}
+	quoted.WriteString(strings.Repeat(`\`, 2*backslashes))
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+// killTreeOnCancel kills a hung command with its whole process tree: bw.cmd
+// runs node as a child, which killing the shim alone would leave behind.
+func killTreeOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+}
+
+// processRunning reports whether a process with this PID exists.
+func processRunning(pid int) bool {
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return errors.Is(err, syscall.ERROR_ACCESS_DENIED)
+	}
+	defer syscall.CloseHandle(handle)
+	var code uint32
+	return syscall.GetExitCodeProcess(handle, &code) == nil && code == stillActive
+}
@@ -0,0 +1,45 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestQuoteArgument(t *testing.T) {
+	tests := []struct {
+		arg, want string
+	}{
+		{"", `""`},
+		{"list", `"list"`},
+		{"two words", `"two words"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`C:\dir\`, `"C:\dir\\"`},
+		{`a\"b`, `"a\\\"b"`},
+	}
+	for _, test := range tests {
+		if got := quoteArgument(test.arg); got != test.want {
+			t.Errorf("quoteArgument(%q) = %s, want %s", test.arg, got, test.want)
+		}
+	}
+}
+
+func TestEscapeForShim(t *testing.T) {
+	cmd := exec.Command(`C:\npm\bw.cmd`, "list", "items", "--search", "AT&T 100%")
+	escapeForShim(cmd)
+	want := `/d /s /c "C:\npm\bw.cmd ^^^"list^^^" ^^^"items^^^" ^^^"--search^^^" ^^^"AT^^^&T^^^ 100^^^%^^^""`
+	if !strings.HasSuffix(cmd.SysProcAttr.CmdLine, want) {
+		t.Errorf("command line = %s, want it to end in %s", cmd.SysProcAttr.CmdLine, want)
+	}
+	if cmd.Args[1] != "list" {
+		t.Errorf("Args = %q, want the bw arguments kept", cmd.Args)
+	}
+
+	exe := exec.Command(`C:\bw\bw.exe`, "AT&T")
+	escapeForShim(exe)
+	if exe.SysProcAttr != nil || exe.Path != `C:\bw\bw.exe` {
+		t.Errorf("bw.exe was changed: %s %+v", exe.Path, exe.SysProcAttr)
+	}
+}
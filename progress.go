@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	progressBarWidth = 24
+	// progressSteps is how many plain progress lines a run prints when
+	// ui is not a terminal.
+	progressSteps = 10
+)
+
+// progressReporter shows how far a run has got after each completed item:
+// a bar redrawn in place on a terminal, or a plain line at every tenth of
+// the run otherwise, so logs of unattended runs stay short.
+type progressReporter struct {
+	stats    *DeleteStats
+	terminal bool
+	nextStep int
+}
+
+func newProgressReporter(stats *DeleteStats) *progressReporter {
+	return &progressReporter{stats: stats, terminal: uiIsTerminal()}
+}
+
+func (p *progressReporter) update() {
+	stats := p.stats
+	rate := stats.recentRate()
+	elapsed := time.Since(stats.started)
+
+	stats.mu.Lock()
+	completed, failed, latency := stats.completed, stats.failed, stats.averageLatency()
+	stats.mu.Unlock()
+
+	eta := "--:--"
+	if remaining := stats.total - completed; remaining == 0 {
+		eta = formatClock(0)
+	} else if rate > 0 {
+		eta = formatClock(time.Duration(float64(remaining) / rate * float64(time.Second)))
+	}
+	percent := 100 * completed / max(stats.total, 1)
+	details := fmt.Sprintf("%d/%d, %.1f/s, avg %s, elapsed %s, ETA %s, %d failed", completed, stats.total, rate, latency, formatClock(elapsed), eta, failed)
+
+	if p.terminal {
+		console.progressf(emojiProgress, "%s %3d%% %s", progressBar(completed, stats.total), percent, details)
+		return
+	}
+	if step := completed * progressSteps / max(stats.total, 1); step >= p.nextStep || completed == stats.total {
+		p.nextStep = step + 1
+		console.infof(emojiProgress, "Progress: %d%% (%s)", percent, details)
+	}
+}
+
+func progressBar(completed, total int) string {
+	filled := progressBarWidth * completed / max(total, 1)
+	done, todo := "█", "░"
+	if console.plain {
+		done, todo = "#", "-"
+	}
+	return "[" + strings.Repeat(done, filled) + strings.Repeat(todo, progressBarWidth-filled) + "]"
+}
+
+// formatClock prints a duration as m:ss, or h:mm:ss from an hour on.
+func formatClock(d time.Duration) string {
+	seconds := int(d.Round(time.Second).Seconds())
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// unattended holds --yes of the running command, together with the
+// --type-to-confirm and --cooldown rails of interactive runs. refused
+// records that a confirmation was turned down because of --from-export, so
+// that the process can exit with an error.
+var unattended struct {
+	yes           bool
+	refused       bool
+	typeToConfirm int
+	cooldown      time.Duration
+}
+
+// readLine reads one line of user input without the trailing newline. A
+// final line without a newline is returned as is; EOF is only reported when
+// nothing was typed.
+func readLine() (string, error) {
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// shellJoin quotes args so a suggested command can be copied back into a shell.
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`*?[]{}()<>|&;~#") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
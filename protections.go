@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// defineLimitFlags defines --max-items, --force-threshold and --allow-large
+// (or --force), which checkLargeSelection applies. It also notes whether the
+// command takes --dry-run, so that the refusal only suggests it where it
+// exists.
+func defineLimitFlags(flags *flag.FlagSet) func(options *CommandOptions) {
+	maxItems := flags.Int("max-items", 500, "Refuse to process more than this many matched items without --allow-large")
+	forceThreshold := flags.Int("force-threshold", 100, "With --yes, refuse to process more than this many matched items without --allow-large (0 disables)")
+	allowLarge := flags.Bool("allow-large", false, "Allow processing more items than --max-items and --force-threshold")
+	flags.BoolVar(allowLarge, "force", false, "Same as --allow-large")
+	return func(options *CommandOptions) {
+		options.maxItems = *maxItems
+		options.forceThreshold = *forceThreshold
+		options.allowLarge = *allowLarge
+		options.hasDryRun = flags.Lookup("dry-run") != nil
+	}
+}
+
+// checkLargeSelection refuses runs that match more items than --max-items,
+// which usually means the search term was mistyped or empty. With --yes
+// nobody sees the count before the run starts, so the lower --force-threshold
+// applies instead.
+func checkLargeSelection(count int, options CommandOptions) error {
+	limit, limitFlag := options.maxItems, "--max-items"
+	if options.assumeYes && options.forceThreshold > 0 && (limit <= 0 || options.forceThreshold < limit) {
+		limit, limitFlag = options.forceThreshold, "--force-threshold"
+	}
+	if options.allowLarge || limit <= 0 || count <= limit {
+		return nil
+	}
+
+	console.warnf("%d items matched, which is more than the %s limit of %d", count, limitFlag, limit)
+	if options.hasDryRun {
+		console.infof(emojiInfo, "Review the selection with --dry-run first: %s %s", filepath.Base(os.Args[0]), shellJoin(append(os.Args[1:len(os.Args):len(os.Args)], "--dry-run")))
+	}
+	return fmt.Errorf("refusing to process %d items without --allow-large", count)
+}
+
+// protectSensitiveItems applies the confirmations that guard items whose
+// secrets cannot be recovered from a backup export.
+func protectSensitiveItems(items []BitwardenItem, options CommandOptions) []BitwardenItem {
+	// Nothing is deleted from an export; confirmOperation refuses the run.
+	if usingExport() {
+		return items
+	}
+	return protectSSHKeyItems(protectPasskeyItems(items, options), options)
+}
+
+// protectPasskeyItems asks for a separate confirmation before items carrying
+// passkeys are deleted, because passkeys cannot be re-created from a backup
+// export. Declining keeps those items out of the run. Selecting them
+// explicitly with --has-passkey counts as that confirmation.
+func protectPasskeyItems(items []BitwardenItem, options CommandOptions) []BitwardenItem {
+	if options.hasPasskey {
+		return items
+	}
+
+	var withPasskey, rest []BitwardenItem
+	for _, item := range items {
+		if item.hasPasskey() {
+			withPasskey = append(withPasskey, item)
+		} else {
+			rest = append(rest, item)
+		}
+	}
+
+	if len(withPasskey) == 0 {
+		return items
+	}
+
+	console.warnf("\nWARNING: %d of the matched items carry passkeys!", len(withPasskey))
+	console.warnf("Passkeys cannot be re-created from a backup export once deleted.")
+	for _, item := range withPasskey {
+		console.linef("   - %s", describeItem(item, options))
+	}
+
+	if unattended.yes {
+		console.infof(emojiInfo, "Skipping %d items with passkeys; --yes only includes them when selected with --has-passkey", len(withPasskey))
+		return rest
+	}
+	if promptYesNo(fmt.Sprintf("Include these %d items with passkeys in the deletion?", len(withPasskey))) {
+		return items
+	}
+
+	console.infof(emojiInfo, "Skipping %d items with passkeys", len(withPasskey))
+	return rest
+}
+
+// protectSSHKeyItems asks for a separate confirmation before SSH key items
+// holding a private key are deleted, since a lost private key locks its owner
+// out of every server that trusts it. Selecting SSH keys explicitly with
+// --type sshkey counts as that confirmation.
+func protectSSHKeyItems(items []BitwardenItem, options CommandOptions) []BitwardenItem {
+	if types, err := parseItemTypes(options.itemTypes); err == nil && types[itemTypeSSHKey] {
+		return items
+	}
+
+	var withKey, rest []BitwardenItem
+	for _, item := range items {
+		if item.hasPrivateKey() {
+			withKey = append(withKey, item)
+		} else {
+			rest = append(rest, item)
+		}
+	}
+
+	if len(withKey) == 0 {
+		return items
+	}
+
+	console.warnf("\nWARNING: %d of the matched items contain SSH private keys!", len(withKey))
+	console.warnf("Make sure each key is stored elsewhere or no longer authorized anywhere.")
+	for _, item := range withKey {
+		console.linef("   - %s", describeItem(item, options))
+	}
+
+	if unattended.yes {
+		console.infof(emojiInfo, "Skipping %d items with SSH private keys; --yes only includes them when selected with --type sshkey", len(withKey))
+		return rest
+	}
+	if promptYesNo(fmt.Sprintf("Include these %d items with SSH private keys in the deletion?", len(withKey))) {
+		return items
+	}
+
+	console.infof(emojiInfo, "Skipping %d items with SSH private keys", len(withKey))
+	return rest
+}
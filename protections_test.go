@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestCheckLargeSelection(t *testing.T) {
+	previous := ui
+	ui = io.Discard
+	t.Cleanup(func() { ui = previous })
+
+	tests := []struct {
+		args    []string
+		yes     bool
+		count   int
+		wantErr bool
+	}{
+		{nil, false, 500, false},
+		{nil, false, 501, true},
+		{[]string{"--allow-large"}, false, 501, false},
+		{[]string{"--max-items", "0"}, false, 10000, false},
+		{[]string{"--max-items", "3"}, false, 4, true},
+		{nil, true, 100, false},
+		{nil, true, 101, true},
+		{[]string{"--force"}, true, 101, false},
+		{[]string{"--force-threshold", "0"}, true, 501, true},
+		{[]string{"--max-items", "0", "--force-threshold", "0"}, true, 10000, false},
+		{[]string{"--max-items", "50"}, true, 51, true},
+	}
+	for _, test := range tests {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		applyLimits := defineLimitFlags(flags)
+		if err := flags.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		options := CommandOptions{assumeYes: test.yes}
+		applyLimits(&options)
+		if err := checkLargeSelection(test.count, options); (err != nil) != test.wantErr {
+			t.Errorf("%v (--yes %t) with %d items: got error %v, want error %v", test.args, test.yes, test.count, err, test.wantErr)
+		}
+	}
+}
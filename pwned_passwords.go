@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPwnedAPI = "https://api.pwnedpasswords.com"
+
+type pwnedItem struct {
+	auditItem
+	Occurrences int `json:"occurrences"`
+
+	item BitwardenItem
+}
+
+type pwnedReport struct {
+	CreatedAt     time.Time   `json:"createdAt"`
+	LoginsAudited int         `json:"loginsAudited"`
+	Items         []pwnedItem `json:"items"`
+	Action        string      `json:"action"`
+}
+
+// pwnedChecker looks up passwords with the k-anonymity range API of Have I
+// Been Pwned: only the first five characters of the SHA-1 hash are sent,
+// and the suffix is matched locally against the returned range. Ranges are
+// fetched once per run.
+type pwnedChecker struct {
+	baseURL string
+	client  *http.Client
+	ranges  map[string]map[string]int
+}
+
+func newPwnedChecker(baseURL string) *pwnedChecker {
+	return &pwnedChecker{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		ranges:  make(map[string]map[string]int),
+	}
+}
+
+func runAuditPwned(args []string) error {
+	flags := flag.NewFlagSet("audit pwned", flag.ExitOnError)
+	minCount := flags.Int("min-count", 1, "Only report passwords that occur at least this many times in the Pwned Passwords data")
+	apiURL := flags.String("api-url", defaultPwnedAPI, "Base URL of the Pwned Passwords range API")
+	deletePwned := flags.Bool("delete", false, "Delete the logins with breached passwords")
+	permanent := flags.Bool("permanent", false, "With --delete, permanently delete items (skip trash)")
+	tag := flags.String("tag", "", "Append this line to the notes of the logins with breached passwords")
+	reportPath := flags.String("report", "", "Also write the breached logins to this JSON file")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	options.isPermanent = *permanent
+	if *permanent && !*deletePwned {
+		return fmt.Errorf("--permanent can only be used with --delete")
+	}
+	if *deletePwned && *tag != "" {
+		return fmt.Errorf("--delete and --tag cannot be used together")
+	}
+	if *minCount < 1 {
+		return fmt.Errorf("--min-count must be at least 1")
+	}
+
+	logins, err := fetchAuditLogins(options)
+	if err != nil {
+		return err
+	}
+
+	checker := newPwnedChecker(*apiURL)
+	console.infof(emojiSearch, "Checking %d logins against Have I Been Pwned (only hash prefixes are sent)...", len(logins))
+	report := pwnedReport{CreatedAt: time.Now().UTC(), LoginsAudited: len(logins), Action: "report only"}
+	switch {
+	case *deletePwned:
+		report.Action = "delete"
+	case *tag != "":
+		report.Action = fmt.Sprintf("tag with %q", *tag)
+	}
+	for _, login := range logins {
+		details, err := decodeItemDetails(login)
+		if err != nil || details.Login == nil || details.Login.Password == "" {
+			continue
+		}
+		count, err := checker.occurrences(details.Login.Password)
+		if err != nil {
+			return err
+		}
+		if count >= *minCount {
+			report.Items = append(report.Items, pwnedItem{auditItem: newAuditItem(login, options.redact), Occurrences: count, item: login})
+		}
+	}
+	sort.SliceStable(report.Items, func(i, j int) bool { return report.Items[i].Occurrences > report.Items[j].Occurrences })
+
+	var pwned []BitwardenItem
+	if len(report.Items) == 0 {
+		console.infof(emojiSuccess, "No breached passwords among %d logins", len(logins))
+	} else {
+		console.warnf("Found %d logins with breached passwords:", len(report.Items))
+	}
+	for _, entry := range report.Items {
+		console.linef("   %s: seen %d times", entry.describe(), entry.Occurrences)
+		pwned = append(pwned, entry.item)
+	}
+
+	if *reportPath != "" {
+		if err := writeJSONFile(*reportPath, report); err != nil {
+			return err
+		}
+		console.infof(emojiSuccess, "Report written to %s", *reportPath)
+	}
+
+	return handleAuditedLogins(pwned, options, *deletePwned, *tag, "breached logins")
+}
+
+// occurrences returns how often the password occurs in the breach data of
+// Pwned Passwords; it is not the number of breaches.
+func (c *pwnedChecker) occurrences(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	counts, ok := c.ranges[prefix]
+	if !ok {
+		var err error
+		if counts, err = c.fetchRange(prefix); err != nil {
+			return 0, err
+		}
+		c.ranges[prefix] = counts
+	}
+	return counts[suffix], nil
+}
+
+// fetchRange downloads the hash suffixes of a prefix. Padding is requested
+// so that the size of the response does not give away the prefix; padded
+// entries have a count of zero.
+func (c *pwnedChecker) fetchRange(prefix string) (map[string]int, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "bitwarden-cleanup")
+	req.Header.Set("Add-Padding", "true")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying Pwned Passwords: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error querying Pwned Passwords: HTTP %d", resp.StatusCode)
+	}
+
+	counts := make(map[string]int)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		suffix, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		if count, err := strconv.Atoi(value); err == nil && count > 0 {
+			counts[strings.ToUpper(suffix)] = count
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading Pwned Passwords response: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultQuarantineFolder = "Stale (auto)"
+	reviewDateLayout        = "2006-01-02"
+)
+
+var reviewByPattern = regexp.MustCompile(`bitwarden-cleanup quarantine: review by (\d{4}-\d{2}-\d{2})`)
+
+var ageUnits = map[string]time.Duration{
+	"y":  365 * 24 * time.Hour,
+	"mo": 30 * 24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"d":  24 * time.Hour,
+}
+
+// parseAge accepts Go durations plus the calendar-ish units y, mo, w and d
+// (e.g. 3y, 18mo, 90d), which are what item ages are usually expressed in.
+func parseAge(value string) (time.Duration, error) {
+	for _, unit := range []string{"mo", "y", "w", "d"} {
+		if number, ok := strings.CutSuffix(value, unit); ok {
+			count, err := strconv.Atoi(number)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", value)
+			}
+			return time.Duration(count) * ageUnits[unit], nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q (expected e.g. 3y, 18mo, 90d)", value)
+	}
+	return age, nil
+}
+
+func runQuarantineCommand(args []string) error {
+	flags := flag.NewFlagSet("quarantine", flag.ExitOnError)
+	olderThan := flags.String("older-than", "", "Quarantine items not modified for this long (e.g. 3y, 18mo, 90d)")
+	toFolder := flags.String("to-folder", defaultQuarantineFolder, "Folder to move stale items into (created if missing)")
+	reviewBy := flags.String("review-by", "", "Stamp a review-by date into the notes of quarantined items, as a date (2025-06-30) or from now (e.g. 30d)")
+	purgeExpired := flags.Bool("purge-expired", false, "Delete items still in the quarantine folder whose review-by date has passed")
+	permanent := flags.Bool("permanent", false, "With --purge-expired, permanently delete items (skip trash)")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	options.isPermanent = *permanent
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+
+	if *purgeExpired {
+		if *olderThan != "" || *reviewBy != "" {
+			return fmt.Errorf("--purge-expired cannot be combined with --older-than or --review-by")
+		}
+		return purgeExpiredQuarantine(*toFolder, options)
+	}
+
+	if *olderThan == "" {
+		return fmt.Errorf("usage: %s quarantine --older-than <age> [--to-folder <name>] [--review-by <date|age>] | quarantine --purge-expired [--to-folder <name>] [--permanent]", filepath.Base(os.Args[0]))
+	}
+	if *permanent {
+		return fmt.Errorf("--permanent can only be used with --purge-expired")
+	}
+	age, err := parseAge(*olderThan)
+	if err != nil {
+		return err
+	}
+	stamp := ""
+	if *reviewBy != "" {
+		date, err := parseReviewDate(*reviewBy)
+		if err != nil {
+			return err
+		}
+		stamp = fmt.Sprintf("bitwarden-cleanup quarantine: review by %s", date.Format(reviewDateLayout))
+	}
+
+	return quarantineStaleItems(time.Now().Add(-age), *toFolder, stamp, options)
+}
+
+func parseReviewDate(value string) (time.Time, error) {
+	if date, err := time.ParseInLocation(reviewDateLayout, value, time.Local); err == nil {
+		return date, nil
+	}
+	age, err := parseAge(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --review-by %q (expected a date like 2025-06-30 or an age like 30d)", value)
+	}
+	return time.Now().Add(age), nil
+}
+
+func quarantineStaleItems(cutoff time.Time, folderName, stamp string, options CommandOptions) error {
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	folder, err := findFolder(folderName)
+	if err != nil {
+		return err
+	}
+
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return err
+	}
+
+	var stale []BitwardenItem
+	for _, item := range items {
+		if folder != nil && item.FolderID == folder.ID {
+			continue
+		}
+		if revised, err := time.Parse(time.RFC3339, item.RevisionDate); err == nil && revised.Before(cutoff) {
+			stale = append(stale, item)
+		}
+	}
+
+	console.infof(emojiSearch, "Found %d items not modified since %s", len(stale), cutoff.Format(reviewDateLayout))
+	if len(stale) == 0 {
+		return nil
+	}
+	if !promptYesNo(fmt.Sprintf("Move all %d items to the folder %q?", len(stale), folderName)) {
+		cancelOperation()
+		return nil
+	}
+	recordFilterHistory(options, stale)
+
+	if folder == nil {
+		if folder, err = vault.CreateFolder(folderName); err != nil {
+			return err
+		}
+		console.infof(emojiSuccess, "Created folder %q", folder.Name)
+	}
+
+	op := quarantineOperation(folder.ID, stamp)
+	return executeItems(stale, &DeleteStats{total: len(stale)}, op, options)
+}
+
+func quarantineOperation(folderID, stamp string) itemOperation {
+	return itemOperation{
+		verb:         "quarantine",
+		processName:  "quarantine",
+		progressVerb: "quarantining",
+		doneText:     "have been quarantined",
+		cost:         editCost,
+		idempotent:   true,
+		run: func(item BitwardenItem) error {
+			return updateItem(item.ID, func(fields map[string]any) bool {
+				changed := fields["folderId"] != folderID
+				fields["folderId"] = folderID
+				if stamp != "" && appendNotesLine(fields, stamp) {
+					changed = true
+				}
+				return changed
+			})
+		},
+	}
+}
+
+// purgeExpiredQuarantine deletes quarantined items whose review-by stamp
+// lies in the past. Items without a stamp are left for manual review.
+func purgeExpiredQuarantine(folderName string, options CommandOptions) error {
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+	op := deleteOperation(options.isPermanent)
+	displayOperationMode(op)
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	folder, err := findFolder(folderName)
+	if err != nil {
+		return err
+	}
+	if folder == nil {
+		return fmt.Errorf("no folder named %q", folderName)
+	}
+
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().Format(reviewDateLayout)
+	var expired []BitwardenItem
+	unstamped := 0
+	for _, item := range items {
+		if item.FolderID != folder.ID {
+			continue
+		}
+		details, err := decodeItemDetails(item)
+		if err != nil {
+			return err
+		}
+		match := reviewByPattern.FindStringSubmatch(details.Notes)
+		if match == nil {
+			unstamped++
+			continue
+		}
+		if match[1] < today {
+			expired = append(expired, item)
+		}
+	}
+
+	if unstamped > 0 {
+		console.infof(emojiInfo, "%d quarantined items have no review-by date and are kept", unstamped)
+	}
+	stats := &DeleteStats{total: len(expired), protected: options.protection.count()}
+	console.infof(emojiSearch, "Found %d quarantined items past their review-by date", stats.total)
+	if stats.total == 0 {
+		return nil
+	}
+
+	expired = protectSensitiveItems(expired, options)
+	stats.total = len(expired)
+	if stats.total == 0 {
+		return nil
+	}
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	return executeItems(expired, stats, op, options)
+}
+
+func findFolder(name string) (*BitwardenFolder, error) {
+	folders, err := vault.ListFolders()
+	if err != nil {
+		return nil, err
+	}
+	for _, folder := range folders {
+		if folder.Name == name {
+			return &folder, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces out the attempts of all workers of a run: a token
+// bucket that holds a single token and refills it once per interval. Each
+// worker reserves the next free slot and sleeps until it comes, so the
+// limit holds no matter how many workers --batch starts.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// rateInterval returns the time between two attempts allowed by --rate and
+// --delay; when both are given, the slower one wins.
+func rateInterval(rate float64, delay time.Duration) (time.Duration, error) {
+	if rate < 0 {
+		return 0, fmt.Errorf("--rate must not be negative")
+	}
+	if delay < 0 {
+		return 0, fmt.Errorf("--delay must not be negative")
+	}
+	interval := delay
+	if rate > 0 {
+		interval = max(interval, time.Duration(float64(time.Second)/rate))
+	}
+	return interval, nil
+}
+
+func newRateLimiter(interval time.Duration) *rateLimiter {
+	if interval <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: interval}
+}
+
+// wait blocks until the caller may make its next attempt, or returns the
+// context's error when the run is interrupted first.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(slot.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
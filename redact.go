@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	redactHash     = "hash"
+	redactTruncate = "truncate"
+
+	redactTruncateLength = 3
+)
+
+var redactableFields = []string{"names", "usernames", "uris"}
+
+// redactor hides item names, usernames and URIs in console output, reports
+// and service responses. Each field is either hashed, which keeps values
+// comparable across runs without revealing them, or truncated to a short
+// prefix. It implements flag.Value for --redact.
+type redactor struct {
+	styles map[string]string
+}
+
+// Set parses a comma-separated list of fields, each optionally followed by
+// ":hash" (the default) or ":truncate", e.g. "names,usernames:truncate".
+func (r *redactor) Set(value string) error {
+	styles := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		field, style, _ := strings.Cut(strings.ToLower(strings.TrimSpace(entry)), ":")
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(redactableFields, field) {
+			return fmt.Errorf("unknown field %q (expected %s)", field, strings.Join(redactableFields, ", "))
+		}
+		if style == "" {
+			style = redactHash
+		}
+		if style != redactHash && style != redactTruncate {
+			return fmt.Errorf("unknown redaction style %q for %s (expected hash or truncate)", style, field)
+		}
+		styles[field] = style
+	}
+	r.styles = styles
+	return nil
+}
+
+func (r *redactor) String() string {
+	if r == nil {
+		return ""
+	}
+	var entries []string
+	for field, style := range r.styles {
+		entries = append(entries, field+":"+style)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func (r redactor) name(value string) string     { return r.apply("names", value) }
+func (r redactor) username(value string) string { return r.apply("usernames", value) }
+func (r redactor) uri(value string) string      { return r.apply("uris", value) }
+
+func (r redactor) apply(field, value string) string {
+	if value == "" {
+		return value
+	}
+	switch r.styles[field] {
+	case redactHash:
+		sum := sha256.Sum256([]byte(value))
+		return "#" + hex.EncodeToString(sum[:5])
+	case redactTruncate:
+		runes := []rune(value)
+		if len(runes) <= redactTruncateLength {
+			return strings.Repeat("*", len(runes))
+		}
+		return string(runes[:redactTruncateLength]) + "…"
+	}
+	return value
+}
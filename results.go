@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	resultDone   = "done"
+	resultGone   = "gone"
+	resultFailed = "failed"
+
+	failureSummaryLimit = 20
+)
+
+// itemResult is the outcome of one item, passed from the workers to the
+// counters, the summary and the run record.
+type itemResult struct {
+	ItemID    string
+	Name      string
+	Operation string
+	Duration  time.Duration
+	Err       error
+	Retries   int
+}
+
+// resultRecord is the stored and served form of an itemResult.
+type resultRecord struct {
+	ItemID     string `json:"itemId"`
+	Name       string `json:"name,omitempty"`
+	Operation  string `json:"operation"`
+	Status     string `json:"status"`
+	DurationMS int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+	ErrorClass string `json:"errorClass,omitempty"`
+	Retries    int    `json:"retries,omitempty"`
+}
+
+func (result itemResult) status() string {
+	switch {
+	case errors.Is(result.Err, errItemGone):
+		return resultGone
+	case result.Err != nil:
+		return resultFailed
+	default:
+		return resultDone
+	}
+}
+
+func (result itemResult) record(redact redactor) resultRecord {
+	record := resultRecord{
+		ItemID:     result.ItemID,
+		Name:       redact.name(result.Name),
+		Operation:  result.Operation,
+		Status:     result.status(),
+		DurationMS: result.Duration.Milliseconds(),
+		Retries:    result.Retries,
+	}
+	if result.Err != nil {
+		record.Error = result.Err.Error()
+		record.ErrorClass = classifyFailure(result.Err)
+	}
+	return record
+}
+
+func resultRecords(results []itemResult, redact redactor) []resultRecord {
+	records := make([]resultRecord, 0, len(results))
+	for _, result := range results {
+		records = append(records, result.record(redact))
+	}
+	return records
+}
+
+// failures returns the results of the items that could not be processed.
+func (stats *DeleteStats) failures() []itemResult {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	var failed []itemResult
+	for _, result := range stats.results {
+		if result.status() == resultFailed {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// showFailureSummary groups the failures of a run by their likely cause,
+// with a hint per cause, followed by a table of the failed items, instead of
+// leaving the user to scroll through the individual error lines.
+func showFailureSummary(stats *DeleteStats, options CommandOptions) {
+	failed := stats.failures()
+	if len(failed) == 0 {
+		return
+	}
+
+	byClass := make(map[string][]itemResult)
+	for _, result := range failed {
+		class := classifyFailure(result.Err)
+		byClass[class] = append(byClass[class], result)
+	}
+
+	console.errorf("%d items failed:", len(failed))
+	for _, class := range failureClassOrder {
+		if results := byClass[class]; len(results) > 0 {
+			console.linef("   %s: %d - %s", class, len(results), failureHint(class, options))
+		}
+	}
+
+	console.linef("")
+	rows := [][]string{{"ID", "CAUSE", "ITEM AND ERROR"}}
+	for _, result := range failed[:min(len(failed), failureSummaryLimit)] {
+		rows = append(rows, []string{result.ItemID, classifyFailure(result.Err), fmt.Sprintf("%s: %v", options.redact.name(result.Name), result.Err)})
+	}
+	printTable(rows)
+	if len(failed) > failureSummaryLimit {
+		console.linef("   ... and %d more (see the run record)", len(failed)-failureSummaryLimit)
+	}
+}
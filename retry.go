@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mitas/bitwarden-cleanup/pkg/cleanup"
+)
+
+const (
+	defaultRetries      = cleanup.DefaultRetries
+	defaultRetryBackoff = cleanup.DefaultRetryBackoff
+)
+
+// isRetryable reports whether an operation that failed with err may succeed
+// when tried again: throttling, network trouble and hung commands pass, while
+// a missing item or a locked vault will fail the same way every time. A hung
+// command that may have gone through is not retried either.
+func isRetryable(err error) bool {
+	if errors.Is(err, errUncertainOutcome) {
+		return false
+	}
+	switch classifyFailure(err) {
+	case failureRateLimited, failureNetwork, failureTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// engineOptions are the options under which the cleanup engine applies op
+// to items: retryable failures of idempotent operations are retried up to
+// options.retries times, and every retry waits for the rate limiter too.
+func engineOptions(op itemOperation, options CommandOptions) cleanup.DeleteOptions[BitwardenItem] {
+	engine := cleanup.DeleteOptions[BitwardenItem]{
+		Permanent:    op.permanent,
+		Batch:        options.batchSize,
+		RetryBackoff: options.retryBackoff,
+		Retryable:    isRetryable,
+		Apply:        func(_ context.Context, item BitwardenItem) error { return op.run(item) },
+		Throttle:     options.limiter.wait,
+		OnRetry: func(item BitwardenItem, err error, retry int, delay time.Duration) {
+			console.warnf("Error %s item %s (%s); retry %d/%d in %s", op.progressVerb, item.ID, classifyFailure(err), retry, options.retries, delay.Round(100*time.Millisecond))
+		},
+	}
+	if op.idempotent {
+		engine.Retries = options.retries
+	}
+	return engine
+}
+
+// batchOptions are the options of commands that only take --batch, with the
+// default retry policy.
+func batchOptions(batchSize int) CommandOptions {
+	return CommandOptions{batchSize: batchSize, retries: defaultRetries, retryBackoff: defaultRetryBackoff}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	retryQueueFileName = "retry-queue.json"
+	maxRetryAttempts   = 5
+)
+
+// retryEntry is an item whose operation failed, kept so a later invocation
+// can try it again with the flags of the run it failed in.
+type retryEntry struct {
+	ItemID        string    `json:"itemId"`
+	Name          string    `json:"name,omitempty"`
+	Operation     string    `json:"operation"`
+	Args          []string  `json:"args"`
+	Error         string    `json:"error"`
+	Attempts      int       `json:"attempts"`
+	FirstFailedAt time.Time `json:"firstFailedAt"`
+	LastFailedAt  time.Time `json:"lastFailedAt"`
+}
+
+func (entry retryEntry) key() string {
+	return entry.ItemID + "\x00" + entry.Operation + "\x00" + strings.Join(entry.Args, "\x00")
+}
+
+// replayOperation rebuilds the operation and options of a recorded run from
+// its flags.
+func replayOperation(args []string) (itemOperation, CommandOptions, error) {
+	flags := flag.NewFlagSet("retry", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	collectOptions := defineCommandFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return itemOperation{}, CommandOptions{}, err
+	}
+	options, err := withProtection(collectOptions())
+	if err != nil {
+		return itemOperation{}, CommandOptions{}, err
+	}
+	options.commandArgs = args
+
+	op, err := selectOperation(options)
+	return op, options, err
+}
+
+// updateRetryQueue adds the items that failed in a run to the retry queue
+// and removes those that succeeded or were already gone. Only runs whose
+// operation can be rebuilt from their flags are queued; subcommands with
+// their own operations are not.
+func updateRetryQueue(stats *DeleteStats, op itemOperation, options CommandOptions) {
+	if options.commandArgs == nil {
+		return
+	}
+	if replayed, _, err := replayOperation(options.commandArgs); err != nil || replayed.verb != op.verb {
+		return
+	}
+
+	queue, err := loadRetryQueue()
+	if err != nil {
+		console.warnf("Warning: %v", err)
+		return
+	}
+
+	entries := make(map[string]int, len(queue))
+	for i, entry := range queue {
+		entries[entry.key()] = i
+	}
+	drop := make(map[int]bool)
+	queued, abandoned := 0, 0
+
+	stats.mu.Lock()
+	now := time.Now().UTC()
+	for _, result := range stats.results {
+		entry := retryEntry{ItemID: result.ItemID, Operation: op.verb, Args: options.commandArgs}
+		index, exists := entries[entry.key()]
+		if result.status() != resultFailed {
+			if exists {
+				drop[index] = true
+			}
+			continue
+		}
+		if !exists {
+			entry.Name = options.redact.name(result.Name)
+			entry.FirstFailedAt = now
+			queue = append(queue, entry)
+			index = len(queue) - 1
+			entries[entry.key()] = index
+		}
+		queue[index].Attempts++
+		queue[index].Error = result.Err.Error()
+		queue[index].LastFailedAt = now
+		if queue[index].Attempts >= maxRetryAttempts {
+			drop[index] = true
+			abandoned++
+		} else {
+			queued++
+		}
+	}
+	stats.mu.Unlock()
+
+	var kept []retryEntry
+	for i, entry := range queue {
+		if !drop[i] {
+			kept = append(kept, entry)
+		}
+	}
+	if err := saveRetryQueue(kept); err != nil {
+		console.warnf("Warning: could not save retry queue: %v", err)
+		return
+	}
+
+	if queued > 0 {
+		console.infof(emojiInfo, "%d failed items were added to the retry queue; the next run lists them for confirmation and tries them first (skip with --no-auto-retry)", queued)
+	}
+	if abandoned > 0 {
+		console.warnf("Gave up on %d items after %d failed attempts; they were removed from the retry queue", abandoned, maxRetryAttempts)
+	}
+}
+
+// processRetryQueue retries the queued items of earlier runs, each with the
+// operation and flags of the run it failed in. The items of each run are
+// listed and confirmed like a selection of this run, and the protection
+// rules of this run apply to them. Items that no longer exist are dropped
+// from the queue.
+func processRetryQueue(options CommandOptions) error {
+	queue, err := loadRetryQueue()
+	if err != nil || len(queue) == 0 {
+		return err
+	}
+
+	console.infof(emojiStart, "Retrying %d items that failed in earlier runs", len(queue))
+
+	current, err := fetchBitwardenItems("")
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]BitwardenItem, len(current))
+	for _, item := range current {
+		byID[item.ID] = item
+	}
+
+	var order []string
+	groups := make(map[string][]retryEntry)
+	var kept []retryEntry
+	gone := 0
+	for _, entry := range queue {
+		if _, exists := byID[entry.ItemID]; !exists {
+			gone++
+			continue
+		}
+		key := strings.Join(entry.Args, "\x00")
+		if _, seen := groups[key]; !seen {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], entry)
+		kept = append(kept, entry)
+	}
+	if gone > 0 {
+		console.infof(emojiInfo, "%d queued items no longer exist and were dropped", gone)
+		if err := saveRetryQueue(kept); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range order {
+		entries := groups[key]
+		op, replayOptions, err := replayOperation(entries[0].Args)
+		if err != nil {
+			console.warnf("Warning: cannot retry %d queued items: %v", len(entries), err)
+			continue
+		}
+		replayOptions.batchSize, replayOptions.autoBatch = options.batchSize, options.autoBatch
+		replayOptions.retries, replayOptions.retryBackoff = options.retries, options.retryBackoff
+		replayOptions.stopFile = options.stopFile
+		// The report of the original run is left as it was written.
+		replayOptions.reportPath = ""
+
+		var items []BitwardenItem
+		for _, entry := range entries {
+			items = append(items, byID[entry.ItemID])
+		}
+		items = replayOptions.protection.apply(options.protection.apply(items))
+		protected := options.protection.count() + replayOptions.protection.count()
+
+		console.infof(op.modeEmoji, "Retrying %d queued items of the run %s", len(items), shellJoin(entries[0].Args))
+		displayOperationMode(op)
+		for i, item := range items {
+			console.linef("   %d. %s", i+1, describeItem(item, options))
+		}
+		if op.deletesItems {
+			items = protectSensitiveItems(items, options)
+		}
+		stats := &DeleteStats{total: len(items), protected: protected}
+		if stats.total == 0 {
+			continue
+		}
+		if err := checkLargeSelection(stats.total, options); err != nil {
+			return err
+		}
+		if !confirmOperation(stats, op) {
+			console.infof(emojiInfo, "Leaving %d items in the retry queue", stats.total)
+			continue
+		}
+		if err := processItems(items, stats, op, replayOptions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadRetryQueue() ([]retryEntry, error) {
+	path, err := configFile(retryQueueFileName)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading retry queue: %w", err)
+	}
+
+	var queue []retryEntry
+	if err := json.Unmarshal(data, &queue); err != nil {
+		return nil, fmt.Errorf("error parsing retry queue: %w", err)
+	}
+	return queue, nil
+}
+
+func saveRetryQueue(queue []retryEntry) error {
+	path, err := configFile(retryQueueFileName)
+	if err != nil {
+		return err
+	}
+	if len(queue) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing retry queue: %w", err)
+		}
+		return nil
+	}
+	return writeJSONFile(path, queue)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mitas/bitwarden-cleanup/pkg/cleanup"
+)
+
+// runWithRetries applies op to item through the cleanup engine, as a run
+// does, and returns the retries and error of the item. onRetry, if set, is
+// called before each retry.
+func runWithRetries(ctx context.Context, item BitwardenItem, op itemOperation, options CommandOptions, onRetry func()) (int, error) {
+	var outcome cleanup.ItemResult[BitwardenItem]
+	engine := engineOptions(op, options)
+	engine.OnResult = func(result cleanup.ItemResult[BitwardenItem]) { outcome = result }
+	if onRetry != nil {
+		engine.OnRetry = func(BitwardenItem, error, int, time.Duration) { onRetry() }
+	}
+	cleanup.Run(ctx, nil, []BitwardenItem{item}, engine)
+	return outcome.Retries, outcome.Err
+}
+
+func TestRunWithRetries(t *testing.T) {
+	rateLimited := fmt.Errorf("error deleting item: %w", errRateLimited)
+	uncertain := fmt.Errorf("error deleting item: %w: %w", errBWTimeout, errUncertainOutcome)
+	tests := []struct {
+		name        string
+		idempotent  bool
+		failures    []error
+		wantRetries int
+		wantCalls   int
+		wantErr     error
+	}{
+		{"succeeds first time", true, nil, 0, 1, nil},
+		{"retries rate limiting", true, []error{rateLimited, rateLimited}, 2, 3, nil},
+		{"gives up after the retries", true, []error{rateLimited, rateLimited, rateLimited}, 2, 3, errRateLimited},
+		{"does not retry a non-idempotent operation", false, []error{rateLimited}, 0, 1, errRateLimited},
+		{"does not retry an uncertain outcome", true, []error{uncertain}, 0, 1, errUncertainOutcome},
+		{"does not retry a vanished item", true, []error{errItemGone}, 0, 1, errItemGone},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := BitwardenItem{ID: "id1", Name: "test item 1"}
+			fake := useFakeVault(t, item)
+			fake.fail(item.ID, test.failures...)
+			op := deleteOperation(false)
+			op.idempotent = test.idempotent
+			options := CommandOptions{retries: 2, retryBackoff: time.Millisecond}
+
+			retries, err := runWithRetries(context.Background(), item, op, options, nil)
+			if retries != test.wantRetries {
+				t.Errorf("retries = %d, want %d", retries, test.wantRetries)
+			}
+			if calls := fake.callCount(item.ID); calls != test.wantCalls {
+				t.Errorf("bw calls = %d, want %d", calls, test.wantCalls)
+			}
+			if test.wantErr == nil && err != nil || !errors.Is(err, test.wantErr) {
+				t.Errorf("err = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunWithRetriesStopsWhenCancelled(t *testing.T) {
+	item := BitwardenItem{ID: "id1"}
+	fake := useFakeVault(t, item)
+	fake.fail(item.ID, errRateLimited, errRateLimited)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancelling during the backoff ends the wait with the last failure.
+	retries, err := runWithRetries(ctx, item, deleteOperation(false), CommandOptions{retries: 2, retryBackoff: time.Hour}, cancel)
+	if retries != 0 || !errors.Is(err, errRateLimited) {
+		t.Errorf("runWithRetries = %d, %v; want 0, %v", retries, err, errRateLimited)
+	}
+	if calls := fake.callCount(item.ID); calls != 1 {
+		t.Errorf("bw calls = %d, want 1", calls)
+	}
+}
+
+func TestVaultItemState(t *testing.T) {
+	useFakeVault(t,
+		BitwardenItem{ID: "active"},
+		BitwardenItem{ID: "trashed", DeletedDate: "2024-06-01T00:00:00.000Z"},
+	)
+	tests := map[string]string{
+		"active":  itemStateVault,
+		"trashed": itemStateTrash,
+		"missing": itemStateGone,
+	}
+	for id, want := range tests {
+		state, err := vaultItemState(id)
+		if err != nil || state != want {
+			t.Errorf("vaultItemState(%q) = %q, %v; want %q", id, state, err, want)
+		}
+	}
+}
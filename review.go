@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	reviewsDirName = "reviews"
+
+	reviewKeep   = "keep"
+	reviewDelete = "delete"
+	reviewDefer  = "defer"
+)
+
+// reviewSession holds the decisions of a review that can span several
+// sittings. Sessions are named after the selection they review unless
+// --review-session gives them a name.
+type reviewSession struct {
+	Name      string                    `json:"name"`
+	Args      []string                  `json:"args"`
+	UpdatedAt time.Time                 `json:"updatedAt"`
+	Decisions map[string]reviewDecision `json:"decisions"`
+}
+
+type reviewDecision struct {
+	Decision  string    `json:"decision"`
+	Name      string    `json:"name,omitempty"`
+	DecidedAt time.Time `json:"decidedAt"`
+}
+
+func runReviewCommand(args []string) error {
+	flags := flag.NewFlagSet("review", flag.ExitOnError)
+	size := flags.Int("size", 20, "Number of items to decide on at a time")
+	sessionName := flags.String("review-session", "", "Name of the review session (default: derived from the selection flags)")
+	apply := flags.Bool("apply", false, "Delete the items marked for deletion in this session")
+	permanent := flags.Bool("permanent", false, "With --apply, permanently delete items (skip trash)")
+	reset := flags.Bool("reset", false, "Discard the decisions of this session and start over")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	options.isPermanent = *permanent
+	if *permanent && !*apply {
+		return fmt.Errorf("--permanent can only be used with --apply")
+	}
+	// review --session used to name the review session; it now takes the
+	// Bitwarden session key like everywhere else.
+	if options.session != "" && !strings.ContainsAny(options.session, `/\`) {
+		if path, err := reviewSessionPath(options.session); err == nil {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("--session takes the Bitwarden session key; name the review session %q with --review-session", options.session)
+			}
+		}
+	}
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+
+	name := *sessionName
+	if name == "" {
+		name = "selection-" + filtersHash(options.selectionArgs)
+	}
+	if strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid session name %q", name)
+	}
+	session, err := loadReviewSession(name)
+	if err != nil {
+		return err
+	}
+	if *reset {
+		session.Decisions = make(map[string]reviewDecision)
+		if err := saveReviewSession(session); err != nil {
+			return err
+		}
+		console.infof(emojiSuccess, "Review session %s was reset", name)
+	}
+	session.Args = options.selectionArgs
+
+	if *apply {
+		return applyReviewSession(session, options)
+	}
+	return reviewItems(session, options, max(*size, 1))
+}
+
+// reviewItems shows the undecided items of the selection a batch at a time,
+// followed by the deferred ones. Decisions are saved after every batch, so
+// quitting loses nothing.
+func reviewItems(session *reviewSession, options CommandOptions, size int) error {
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return err
+	}
+
+	var undecided, deferred []BitwardenItem
+	for _, item := range items {
+		switch session.Decisions[item.ID].Decision {
+		case "":
+			undecided = append(undecided, item)
+		case reviewDefer:
+			deferred = append(deferred, item)
+		}
+	}
+	queue := append(undecided, deferred...)
+
+	showReviewProgress(session, len(items))
+	if len(queue) == 0 {
+		console.infof(emojiSuccess, "Nothing left to review; run 'review --apply' with the same flags to delete the marked items")
+		return nil
+	}
+
+	for start := 0; start < len(queue); start += size {
+		batch := queue[start:min(start+size, len(queue))]
+		quit, err := reviewBatch(session, batch, options)
+		if err != nil {
+			return err
+		}
+		if err := saveReviewSession(session); err != nil {
+			return err
+		}
+		showReviewProgress(session, len(items))
+		if quit {
+			console.infof(emojiInfo, "Progress saved; run 'review' with the same flags to continue")
+			return nil
+		}
+	}
+
+	console.infof(emojiSuccess, "All items reviewed; run 'review --apply' with the same flags to delete the marked items")
+	return nil
+}
+
+// reviewBatch asks for decisions on one batch until it is finished with
+// [n]ext, which defers the items left undecided, or [q]uit.
+func reviewBatch(session *reviewSession, batch []BitwardenItem, options CommandOptions) (bool, error) {
+	for {
+		console.linef("")
+		for i, item := range batch {
+			decision := session.Decisions[item.ID].Decision
+			if decision == "" {
+				decision = "-"
+			}
+			console.linef("%4d. [%-6s] %s", i+1, decision, describeItem(item, options))
+		}
+		console.promptf("", "[d N..] delete, [k N..] keep, [f N..] defer (e.g. 'd 1-5,8'; without numbers: the whole batch), [n]ext, [q]uit: ")
+
+		input, err := readLine()
+		if err != nil {
+			return true, fmt.Errorf("error reading input: %w", err)
+		}
+		command, argument, _ := strings.Cut(strings.ToLower(input), " ")
+
+		decision := ""
+		switch command {
+		case "d":
+			decision = reviewDelete
+		case "k":
+			decision = reviewKeep
+		case "f":
+			decision = reviewDefer
+		case "", "n":
+			for _, item := range batch {
+				if session.Decisions[item.ID].Decision == "" {
+					session.decide(item, reviewDefer, options)
+				}
+			}
+			return false, nil
+		case "q":
+			return true, nil
+		default:
+			console.warnf("Unknown command %q", input)
+			continue
+		}
+
+		indexes, err := parseIndexList(argument, len(batch))
+		if err != nil {
+			console.warnf("%v", err)
+			continue
+		}
+		for _, index := range indexes {
+			session.decide(batch[index], decision, options)
+		}
+	}
+}
+
+func (session *reviewSession) decide(item BitwardenItem, decision string, options CommandOptions) {
+	session.Decisions[item.ID] = reviewDecision{Decision: decision, Name: options.redact.name(item.Name), DecidedAt: time.Now().UTC()}
+}
+
+func showReviewProgress(session *reviewSession, matched int) {
+	counts := make(map[string]int)
+	for _, decision := range session.Decisions {
+		counts[decision.Decision]++
+	}
+	decided := counts[reviewKeep] + counts[reviewDelete]
+	console.summaryf(emojiStats, "Review %s: %d decided (%d delete, %d keep), %d deferred, %d items currently matched",
+		session.Name, decided, counts[reviewDelete], counts[reviewKeep], counts[reviewDefer], matched)
+}
+
+// parseIndexList parses 1-based item numbers and ranges such as "1-5,8"
+// into 0-based indexes. An empty list selects all count items.
+func parseIndexList(value string, count int) ([]int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		indexes := make([]int, count)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+
+	var indexes []int
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+		low, high, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(low)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(high)
+		}
+		if err != nil || first < 1 || last > count || first > last {
+			return nil, fmt.Errorf("invalid item numbers %q (expected numbers between 1 and %d, e.g. 1-5,8)", part, count)
+		}
+		for i := first; i <= last; i++ {
+			indexes = append(indexes, i-1)
+		}
+	}
+	return indexes, nil
+}
+
+// applyReviewSession deletes the items marked for deletion that are still
+// in the vault, with the usual protections and confirmation.
+func applyReviewSession(session *reviewSession, options CommandOptions) error {
+	var ids []string
+	for id, decision := range session.Decisions {
+		if decision.Decision == reviewDelete {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if len(ids) == 0 {
+		console.infof(emojiInfo, "No items are marked for deletion in review %s", session.Name)
+		return nil
+	}
+
+	op := deleteOperation(options.isPermanent)
+	displayOperationMode(op)
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	items, err := fetchItemsByID(ids, options.protection)
+	if err != nil {
+		return err
+	}
+
+	stats := &DeleteStats{total: len(items), protected: options.protection.count()}
+	displayItemCount(stats, op)
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	items = protectSensitiveItems(items, options)
+	stats.total = len(items)
+	if stats.total == 0 {
+		return nil
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	return executeItems(items, stats, op, options)
+}
+
+func reviewSessionPath(name string) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	reviews := filepath.Join(dir, reviewsDirName)
+	if err := os.MkdirAll(reviews, 0o700); err != nil {
+		return "", fmt.Errorf("error creating reviews directory: %w", err)
+	}
+	return filepath.Join(reviews, name+".json"), nil
+}
+
+func loadReviewSession(name string) (*reviewSession, error) {
+	path, err := reviewSessionPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &reviewSession{Name: name, Decisions: make(map[string]reviewDecision)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return session, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading review %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, fmt.Errorf("error parsing review %s: %w", name, err)
+	}
+	if session.Decisions == nil {
+		session.Decisions = make(map[string]reviewDecision)
+	}
+	return session, nil
+}
+
+func saveReviewSession(session *reviewSession) error {
+	path, err := reviewSessionPath(session.Name)
+	if err != nil {
+		return err
+	}
+
+	session.UpdatedAt = time.Now().UTC()
+	return writeJSONFile(path, session)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// resultSkipped is the status of items a stopped or interrupted run never
+// got to.
+const resultSkipped = "skipped"
+
+type runReportRow struct {
+	ItemID    string `json:"itemId"`
+	Name      string `json:"name"`
+	Folder    string `json:"folder"`
+	Type      string `json:"type"`
+	Operation string `json:"operation"`
+	Mode      string `json:"mode"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+type runReport struct {
+	CreatedAt time.Time      `json:"createdAt"`
+	Operation string         `json:"operation"`
+	Mode      string         `json:"mode"`
+	Items     []runReportRow `json:"items"`
+}
+
+// writeRunReport writes the outcome of every item of the run to --report:
+// JSON for a .json file, CSV otherwise. Items the run did not get to are
+// listed as skipped, so the report always covers the whole selection.
+func writeRunReport(items []BitwardenItem, stats *DeleteStats, op itemOperation, options CommandOptions) {
+	if options.reportPath == "" {
+		return
+	}
+
+	stats.mu.Lock()
+	results := make(map[string]itemResult, len(stats.results))
+	for _, result := range stats.results {
+		results[result.ItemID] = result
+	}
+	stats.mu.Unlock()
+
+	folderName := folderNamer()
+	report := runReport{CreatedAt: time.Now().UTC(), Operation: op.verb, Mode: operationMode(op)}
+	for _, item := range items {
+		row := runReportRow{
+			ItemID:    item.ID,
+			Name:      options.redact.name(item.Name),
+			Folder:    folderName(item),
+			Type:      item.typeName(),
+			Operation: op.verb,
+			Mode:      report.Mode,
+			Status:    resultSkipped,
+		}
+		if result, ok := results[item.ID]; ok {
+			row.Status = result.status()
+			if result.Err != nil {
+				row.Error = result.Err.Error()
+			}
+		}
+		report.Items = append(report.Items, row)
+	}
+
+	var err error
+	if strings.EqualFold(filepath.Ext(options.reportPath), ".json") {
+		err = writeJSONFile(options.reportPath, report)
+	} else {
+		err = writeRunReportCSV(options.reportPath, report.Items)
+	}
+	if err != nil {
+		console.warnf("Warning: could not write report: %v", err)
+		return
+	}
+	console.summaryf(emojiSuccess, "Report of %d items written to %s", len(report.Items), options.reportPath)
+}
+
+func writeRunReportCSV(path string, rows []runReportRow) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating report: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"id", "name", "folder", "type", "operation", "mode", "status", "error"})
+	for _, row := range rows {
+		writer.Write([]string{row.ItemID, row.Name, row.Folder, row.Type, row.Operation, row.Mode, row.Status, row.Error})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("error writing report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const runsDirName = "runs"
+
+// runRecord is the persisted state of a run that a later invocation can
+// refer to by its ID.
+type runRecord struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	ItemIDs   []string   `json:"itemIds"`
+	Args      []string   `json:"args,omitempty"`
+	ExecuteAt *time.Time `json:"executeAt,omitempty"`
+	Operation string     `json:"operation,omitempty"`
+	// Results holds the per-item outcome of a finished run.
+	Results []resultRecord `json:"results,omitempty"`
+	// EncryptedBackup holds the full JSON of permanently deleted items,
+	// sealed with the backup passphrase. Backup is where earlier versions
+	// kept it unencrypted.
+	EncryptedBackup *backupFile       `json:"encryptedBackup,omitempty"`
+	Backup          []json.RawMessage `json:"backup,omitempty"`
+}
+
+func newRunID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+func runsDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+
+	runs := filepath.Join(dir, runsDirName)
+	if err := os.MkdirAll(runs, 0o700); err != nil {
+		return "", fmt.Errorf("error creating runs directory: %w", err)
+	}
+	return runs, nil
+}
+
+func saveRunRecord(record *runRecord) error {
+	dir, err := runsDir()
+	if err != nil {
+		return err
+	}
+
+	record.UpdatedAt = time.Now().UTC()
+	return writeJSONFile(filepath.Join(dir, record.ID+".json"), record)
+}
+
+func loadRunRecord(id string) (*runRecord, error) {
+	dir, err := runsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("invalid run ID %q", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no run with ID %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading run %s: %w", id, err)
+	}
+
+	var record runRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("error parsing run %s: %w", id, err)
+	}
+	return &record, nil
+}
+
+// listRunRecords returns the stored runs of the given kind, newest first.
+func listRunRecords(kind string) ([]*runRecord, error) {
+	dir, err := runsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing runs: %w", err)
+	}
+
+	var records []*runRecord
+	for _, path := range paths {
+		record, err := loadRunRecord(strings.TrimSuffix(filepath.Base(path), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		if kind == "" || record.Kind == kind {
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].CreatedAt.After(records[j].CreatedAt) })
+	return records, nil
+}
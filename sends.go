@@ -0,0 +1,203 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BitwardenSend is a Send as listed by 'bw send list'. Sends carry no
+// creation date; the revision date is when they were created or last edited.
+type BitwardenSend struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Type           int    `json:"type"`
+	AccessCount    int    `json:"accessCount"`
+	MaxAccessCount *int   `json:"maxAccessCount"`
+	RevisionDate   string `json:"revisionDate"`
+	ExpirationDate string `json:"expirationDate"`
+	DeletionDate   string `json:"deletionDate"`
+	Disabled       bool   `json:"disabled"`
+}
+
+type sendFilter func(send BitwardenSend) bool
+
+func runSendsCommand(args []string) error {
+	flags := flag.NewFlagSet("sends", flag.ExitOnError)
+	name := flags.String("name", "", "Only match Sends whose name matches this glob or /regular expression/, ignoring case")
+	expired := flags.Bool("expired", false, "Only match Sends that have expired or reached their maximum access count")
+	expiresBefore := flags.String("expires-before", "", "Only match Sends that expire before this date or age (e.g. 2024-01-31, 30d)")
+	expiresAfter := flags.String("expires-after", "", "Only match Sends that expire after this date or age")
+	createdBefore := flags.String("created-before", "", "Only match Sends created or last edited before this date or age")
+	createdAfter := flags.String("created-after", "", "Only match Sends created or last edited after this date or age")
+	deleteSends := flags.Bool("delete", false, "Delete the matched Sends")
+	dryRun := flags.Bool("dry-run", false, "With --delete, only list the Sends that would be deleted")
+	batchSize := flags.Int("batch", 1, "Number of Sends to delete in parallel")
+	flags.IntVar(batchSize, "b", 1, "Number of Sends to delete in parallel (shorthand)")
+	ignoreBWVersion := flags.Bool("ignore-bw-version", false, "Run even when the installed bw is older than the release that added Sends")
+	applyLimits := defineLimitFlags(flags)
+	consoleValues := defineConsoleFlags(flags)
+	flags.Parse(args)
+	if err := consoleValues.apply(); err != nil {
+		return err
+	}
+	bwCompatibility.ignore = *ignoreBWVersion
+
+	filters, err := buildSendFilters(*name, *expired, *expiresBefore, *expiresAfter, *createdBefore, *createdAfter)
+	if err != nil {
+		return err
+	}
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	if err := requireBWCapabilities(capabilitySend); err != nil {
+		return err
+	}
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	sends, err := vault.ListSends()
+	if err != nil {
+		return err
+	}
+	var matched []BitwardenSend
+	for _, send := range sends {
+		if matchesAllSendFilters(send, filters) {
+			matched = append(matched, send)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].RevisionDate < matched[j].RevisionDate })
+
+	console.infof(emojiSearch, "%d of %d Sends match", len(matched), len(sends))
+	for _, send := range matched {
+		console.linef("   %s (%s, %s, %s)", send.Name, send.ID, send.typeName(), send.describeState())
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	if !*deleteSends {
+		console.infof(emojiInfo, "Run with --delete to delete them")
+		return nil
+	}
+	if *dryRun {
+		return nil
+	}
+
+	// The worker pool runs on items, so each Send travels as an item
+	// carrying the Send's ID and name.
+	var targets []BitwardenItem
+	for _, send := range matched {
+		targets = append(targets, BitwardenItem{ID: send.ID, Name: send.Name})
+	}
+	op := itemOperation{
+		verb:         "delete Send",
+		modeEmoji:    emojiWarning,
+		modeText:     "Delete Sends (their links stop working; deleted Sends cannot be restored)",
+		confirmText:  "delete",
+		processName:  "Send deletion",
+		progressVerb: "deleting Send",
+		doneText:     "have been deleted",
+		idempotent:   true,
+		run: func(send BitwardenItem) error {
+			return vault.DeleteSend(send.ID)
+		},
+	}
+
+	displayOperationMode(op)
+	stats := &DeleteStats{total: len(targets)}
+	options := batchOptions(*batchSize)
+	applyLimits(&options)
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	return executeItems(targets, stats, op, options)
+}
+
+// buildSendFilters turns the flags of 'sends' into filters, reusing the date
+// and name syntax of the item filters.
+func buildSendFilters(name string, expired bool, expiresBefore, expiresAfter, createdBefore, createdAfter string) ([]sendFilter, error) {
+	var filters []sendFilter
+	if name != "" {
+		pattern, err := compileNamePattern("name", name)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, func(send BitwardenSend) bool { return pattern.MatchString(send.Name) })
+	}
+	if expired {
+		now := time.Now()
+		filters = append(filters, func(send BitwardenSend) bool { return send.expired(now) })
+	}
+
+	dateBounds := []struct {
+		flag   string
+		value  string
+		date   func(send BitwardenSend) string
+		before bool
+	}{
+		{"expires-before", expiresBefore, func(send BitwardenSend) string { return send.ExpirationDate }, true},
+		{"expires-after", expiresAfter, func(send BitwardenSend) string { return send.ExpirationDate }, false},
+		{"created-before", createdBefore, func(send BitwardenSend) string { return send.RevisionDate }, true},
+		{"created-after", createdAfter, func(send BitwardenSend) string { return send.RevisionDate }, false},
+	}
+	for _, bound := range dateBounds {
+		if bound.value == "" {
+			continue
+		}
+		cutoff, err := parseDateBound(bound.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", bound.flag, err)
+		}
+		date, before := bound.date, bound.before
+		filters = append(filters, func(send BitwardenSend) bool {
+			value, err := time.Parse(time.RFC3339, date(send))
+			return err == nil && value.Before(cutoff) == before
+		})
+	}
+	return filters, nil
+}
+
+func matchesAllSendFilters(send BitwardenSend, filters []sendFilter) bool {
+	for _, filter := range filters {
+		if !filter(send) {
+			return false
+		}
+	}
+	return true
+}
+
+// expired reports whether the Send can no longer be opened because its
+// expiration date has passed or it was accessed the maximum number of times.
+func (send BitwardenSend) expired(now time.Time) bool {
+	if send.MaxAccessCount != nil && send.AccessCount >= *send.MaxAccessCount {
+		return true
+	}
+	expiration, err := time.Parse(time.RFC3339, send.ExpirationDate)
+	return err == nil && expiration.Before(now)
+}
+
+func (send BitwardenSend) typeName() string {
+	if send.Type == 1 {
+		return "file"
+	}
+	return "text"
+}
+
+func (send BitwardenSend) describeState() string {
+	switch {
+	case send.Disabled:
+		return "disabled"
+	case send.expired(time.Now()):
+		return "expired"
+	}
+	if expiration, err := time.Parse(time.RFC3339, send.ExpirationDate); err == nil {
+		return "expires " + expiration.Local().Format(reviewDateLayout)
+	}
+	return "no expiration"
+}
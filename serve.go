@@ -0,0 +1,422 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jobStatusRunning  = "running"
+	jobStatusFinished = "finished"
+	jobStatusFailed   = "failed"
+)
+
+// serveRequest carries the same flags the command line accepts, so an API
+// call selects and processes exactly what the equivalent invocation would.
+type serveRequest struct {
+	Args []string `json:"args"`
+}
+
+type serveItem struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	FolderID       string `json:"folderId,omitempty"`
+	OrganizationID string `json:"organizationId,omitempty"`
+	HasPasskey     bool   `json:"hasPasskey"`
+	HasPrivateKey  bool   `json:"hasPrivateKey"`
+	Attachments    int    `json:"attachments"`
+	Summary        string `json:"summary,omitempty"`
+}
+
+type servePlan struct {
+	Operation   string      `json:"operation"`
+	Count       int         `json:"count"`
+	Invocations int         `json:"invocations"`
+	APICalls    int         `json:"apiCalls"`
+	Passkeys    int         `json:"passkeys"`
+	SSHKeys     int         `json:"sshKeys"`
+	Blocked     string      `json:"blocked,omitempty"`
+	Items       []serveItem `json:"items"`
+}
+
+// serveJob tracks a run started through the API while it executes.
+type serveJob struct {
+	ID          string         `json:"id"`
+	Status      string         `json:"status"`
+	Operation   string         `json:"operation"`
+	Args        []string       `json:"args"`
+	Total       int            `json:"total"`
+	Completed   int            `json:"completed"`
+	Failed      int            `json:"failed"`
+	AlreadyGone int            `json:"alreadyGone"`
+	Failures    []resultRecord `json:"failures,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	StartedAt   time.Time      `json:"startedAt"`
+	FinishedAt  *time.Time     `json:"finishedAt,omitempty"`
+
+	stats  *DeleteStats
+	redact redactor
+}
+
+type cleanupServer struct {
+	token string
+
+	mu      sync.Mutex
+	jobs    map[string]*serveJob
+	running *serveJob
+}
+
+func runServeCommand(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", "127.0.0.1:8787", "Loopback address to listen on")
+	token := flags.String("token", "", "Bearer token clients must send (default: a random token printed at startup)")
+	ignoreBWVersion := flags.Bool("ignore-bw-version", false, "Run even when the installed bw is older than the release that added a command or flag the runs need")
+	consoleValues := defineConsoleFlags(flags)
+	flags.Parse(args)
+	if err := consoleValues.apply(); err != nil {
+		return err
+	}
+	bwCompatibility.ignore = *ignoreBWVersion
+
+	if err := checkLoopbackAddress(*listen); err != nil {
+		return err
+	}
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+
+	server := &cleanupServer{token: *token, jobs: make(map[string]*serveJob)}
+	if server.token == "" {
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			return fmt.Errorf("error generating token: %w", err)
+		}
+		server.token = hex.EncodeToString(secret)
+	}
+
+	console.infof(emojiStart, "Listening on http://%s", *listen)
+	console.infof(emojiInfo, "Token: %s", server.token)
+	console.infof(emojiInfo, "Example: curl -H 'Authorization: Bearer %s' -d '{\"args\":[\"--search\",\"test\"]}' http://%s/plan", server.token, *listen)
+
+	return http.ListenAndServe(*listen, server.routes())
+}
+
+// checkLoopbackAddress refuses to expose the vault beyond this machine.
+func checkLoopbackAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid --listen address %q: %w", address, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("--listen must be a loopback address (e.g. 127.0.0.1:8787), got %q", address)
+}
+
+func (s *cleanupServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /search", s.handleSearch)
+	mux.HandleFunc("POST /plan", s.handlePlan)
+	mux.HandleFunc("POST /execute", s.handleExecute)
+	mux.HandleFunc("GET /jobs", s.handleJobs)
+	mux.HandleFunc("GET /jobs/{id}", s.handleJob)
+	mux.HandleFunc("GET /runs", s.handleRuns)
+	mux.HandleFunc("GET /runs/{id}", s.handleRun)
+	return s.authenticate(mux)
+}
+
+func (s *cleanupServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(s.token)) != 1 {
+			writeServeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// selection parses the request flags and fetches the matching items the
+// same way a command line run does.
+func (s *cleanupServer) selection(r *http.Request) (CommandOptions, itemOperation, []BitwardenItem, error) {
+	var request serveRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		return CommandOptions{}, itemOperation{}, nil, fmt.Errorf("invalid request body: %w", err)
+	}
+
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	options, err := parseSelection(flags, request.Args, defineCommandFlags(flags))
+	if err != nil {
+		return CommandOptions{}, itemOperation{}, nil, err
+	}
+	if flags.NArg() > 0 {
+		return CommandOptions{}, itemOperation{}, nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	op, err := selectOperation(options)
+	if err != nil {
+		return CommandOptions{}, itemOperation{}, nil, err
+	}
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return CommandOptions{}, itemOperation{}, nil, err
+	}
+	if err := validateGroupBy(options.groupBy); err != nil {
+		return CommandOptions{}, itemOperation{}, nil, err
+	}
+
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return CommandOptions{}, itemOperation{}, nil, err
+	}
+	return options, op, items, nil
+}
+
+func (s *cleanupServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	options, _, items, err := s.selection(r)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeServeJSON(w, http.StatusOK, map[string]any{"count": len(items), "items": serveItems(items, options)})
+}
+
+func (s *cleanupServer) handlePlan(w http.ResponseWriter, r *http.Request) {
+	options, op, items, err := s.selection(r)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeServeJSON(w, http.StatusOK, planFor(items, op, options))
+}
+
+func planFor(items []BitwardenItem, op itemOperation, options CommandOptions) servePlan {
+	plan := servePlan{Operation: op.verb, Count: len(items), Items: serveItems(items, options)}
+	for _, item := range items {
+		calls, api := op.cost(item)
+		plan.Invocations += calls
+		plan.APICalls += api
+		if item.hasPasskey() {
+			plan.Passkeys++
+		}
+		if item.hasPrivateKey() {
+			plan.SSHKeys++
+		}
+	}
+	if err := executionBlocker(items, op, options); err != nil {
+		plan.Blocked = err.Error()
+	}
+	return plan
+}
+
+// executionBlocker applies the checks an interactive run would ask about.
+// Without a terminal to confirm on, the request itself has to opt in.
+func executionBlocker(items []BitwardenItem, op itemOperation, options CommandOptions) error {
+	if options.dryRun {
+		return errors.New("--dry-run is not supported by execute; use /plan")
+	}
+	if options.scheduleAt != "" || options.scheduleAfter != 0 {
+		return errors.New("--at and --after are not supported by the service; schedule the request instead")
+	}
+	if op.permanent && !options.noBackup && os.Getenv(backupPassphraseEnv) == "" {
+		return fmt.Errorf("permanent deletions keep an encrypted undo backup; start the service with %s set, or pass --no-backup", backupPassphraseEnv)
+	}
+	if !options.allowLarge && options.maxItems > 0 && len(items) > options.maxItems {
+		return fmt.Errorf("%d items matched, which is more than the limit of %d; pass --allow-large", len(items), options.maxItems)
+	}
+	if op.deletesItems && !options.hasPasskey {
+		for _, item := range items {
+			if item.hasPasskey() {
+				return errors.New("matched items carry passkeys; select them explicitly with --has-passkey or exclude them with --no-passkey")
+			}
+		}
+	}
+	if op.deletesItems {
+		if types, err := parseItemTypes(options.itemTypes); err == nil && !types[itemTypeSSHKey] {
+			for _, item := range items {
+				if item.hasPrivateKey() {
+					return errors.New("matched items contain SSH private keys; select them explicitly with --type sshkey")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *cleanupServer) handleExecute(w http.ResponseWriter, r *http.Request) {
+	options, op, items, err := s.selection(r)
+	if err != nil {
+		writeServeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := executionBlocker(items, op, options); err != nil {
+		writeServeError(w, http.StatusConflict, err)
+		return
+	}
+
+	s.mu.Lock()
+	if s.running != nil {
+		s.mu.Unlock()
+		writeServeError(w, http.StatusConflict, fmt.Errorf("job %s is still running", s.running.ID))
+		return
+	}
+	job := &serveJob{
+		ID:        newRunID(),
+		Status:    jobStatusRunning,
+		Operation: op.verb,
+		Args:      options.commandArgs,
+		Total:     len(items),
+		StartedAt: time.Now().UTC(),
+		stats:     &DeleteStats{total: len(items)},
+		redact:    options.redact,
+	}
+	s.jobs[job.ID] = job
+	s.running = job
+	s.mu.Unlock()
+
+	go s.runJob(job, items, op, options)
+
+	writeServeJSON(w, http.StatusAccepted, s.snapshot(job))
+}
+
+func (s *cleanupServer) runJob(job *serveJob, items []BitwardenItem, op itemOperation, options CommandOptions) {
+	console.infof(emojiStart, "Job %s: %s %d items", job.ID, op.verb, len(items))
+	if err := exportMatchedItems(items, options); err != nil {
+		s.finishJob(job, err)
+		return
+	}
+	var err error
+	if len(items) > 0 {
+		err = executeItems(items, job.stats, op, options)
+	}
+	s.finishJob(job, err)
+}
+
+func (s *cleanupServer) finishJob(job *serveJob, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.Status = jobStatusFinished
+	if err != nil {
+		job.Status = jobStatusFailed
+		job.Error = err.Error()
+	}
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	s.running = nil
+}
+
+// snapshot copies a job together with its live counters.
+func (s *cleanupServer) snapshot(job *serveJob) serveJob {
+	s.mu.Lock()
+	copied := *job
+	s.mu.Unlock()
+
+	copied.Failures = resultRecords(copied.stats.failures(), copied.redact)
+
+	copied.stats.mu.Lock()
+	defer copied.stats.mu.Unlock()
+	copied.Completed = copied.stats.completed
+	copied.Failed = copied.stats.failed
+	copied.AlreadyGone = copied.stats.alreadyGone
+	return copied
+}
+
+func (s *cleanupServer) handleJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]*serveJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.Unlock()
+
+	snapshots := make([]serveJob, 0, len(jobs))
+	for _, job := range jobs {
+		snapshots = append(snapshots, s.snapshot(job))
+	}
+	writeServeJSON(w, http.StatusOK, snapshots)
+}
+
+func (s *cleanupServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeServeError(w, http.StatusNotFound, fmt.Errorf("no job with ID %s", r.PathValue("id")))
+		return
+	}
+	writeServeJSON(w, http.StatusOK, s.snapshot(job))
+}
+
+// handleRuns lists the stored run records, optionally of one ?kind=.
+// Backups of permanently deleted items never leave the machine's disk.
+func (s *cleanupServer) handleRuns(w http.ResponseWriter, r *http.Request) {
+	records, err := listRunRecords(r.URL.Query().Get("kind"))
+	if err != nil {
+		writeServeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, record := range records {
+		record.Backup, record.EncryptedBackup = nil, nil
+	}
+	writeServeJSON(w, http.StatusOK, records)
+}
+
+func (s *cleanupServer) handleRun(w http.ResponseWriter, r *http.Request) {
+	record, err := loadRunRecord(r.PathValue("id"))
+	if err != nil {
+		writeServeError(w, http.StatusNotFound, err)
+		return
+	}
+	record.Backup, record.EncryptedBackup = nil, nil
+	writeServeJSON(w, http.StatusOK, record)
+}
+
+func serveItems(items []BitwardenItem, options CommandOptions) []serveItem {
+	result := make([]serveItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, serveItem{
+			ID:             item.ID,
+			Name:           options.redact.name(item.Name),
+			Type:           item.typeName(),
+			FolderID:       item.FolderID,
+			OrganizationID: item.OrganizationID,
+			HasPasskey:     item.hasPasskey(),
+			HasPrivateKey:  item.hasPrivateKey(),
+			Attachments:    len(item.Attachments),
+			Summary:        itemSummary(item, options),
+		})
+	}
+	return result
+}
+
+func writeServeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		fmt.Fprintf(os.Stderr, "%s Error writing response: %v\n", emojiError, err)
+	}
+}
+
+func writeServeError(w http.ResponseWriter, status int, err error) {
+	writeServeJSON(w, status, map[string]string{"error": err.Error()})
+}
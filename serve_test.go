@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestExecutionBlockerRequiresBackupPassphrase(t *testing.T) {
+	t.Setenv(backupPassphraseEnv, "")
+	items := []BitwardenItem{{ID: "id1", Name: "test item 1"}}
+
+	if err := executionBlocker(items, deleteOperation(true), CommandOptions{}); err == nil {
+		t.Error("a permanent deletion without a backup passphrase was not refused")
+	}
+	if err := executionBlocker(items, deleteOperation(true), CommandOptions{noBackup: true}); err != nil {
+		t.Errorf("--no-backup: %v", err)
+	}
+	if err := executionBlocker(items, deleteOperation(false), CommandOptions{}); err != nil {
+		t.Errorf("moving to the trash: %v", err)
+	}
+
+	t.Setenv(backupPassphraseEnv, "correct horse")
+	if err := executionBlocker(items, deleteOperation(true), CommandOptions{}); err != nil {
+		t.Errorf("with the passphrase set: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	stagedRunKind = "staged-delete"
+
+	stagedStatusPending = "staged"
+	stagedStatusPurged  = "purged"
+)
+
+// finishStagedRun records which items a staged deletion moved to the trash
+// and either waits out the confirmation window before purging them or tells
+// the user how to purge them later.
+func finishStagedRun(items []BitwardenItem, options CommandOptions) error {
+	record := &runRecord{
+		ID:        newRunID(),
+		Kind:      stagedRunKind,
+		Status:    stagedStatusPending,
+		CreatedAt: time.Now().UTC(),
+	}
+	for _, item := range items {
+		record.ItemIDs = append(record.ItemIDs, item.ID)
+	}
+
+	if err := saveRunRecord(record); err != nil {
+		return err
+	}
+
+	console.summaryf(emojiInfo, "\nStaged run ID: %s", record.ID)
+
+	if options.stagedWindow <= 0 {
+		console.infof(emojiInfo, "Items are in the trash and can still be restored. To purge them permanently, run:")
+		console.linef("   %s staged purge %s", filepath.Base(os.Args[0]), record.ID)
+		return nil
+	}
+
+	// The purge is permanent: ask for the undo backup passphrase now, not
+	// once the window has passed.
+	if err := prepareUndoBackup(deleteOperation(true), options); err != nil {
+		return err
+	}
+	console.warnf("Items will be purged permanently in %s. Press Ctrl-C to abort, or restore items from the trash to keep them.", options.stagedWindow)
+	time.Sleep(options.stagedWindow)
+
+	return purgeStagedRun(record, options, false)
+}
+
+func runStagedCommand(args []string) error {
+	usage := fmt.Errorf("usage: %s staged list | staged purge [--batch <n>] <run-id>", filepath.Base(os.Args[0]))
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "list":
+		return listStagedRuns()
+	case "purge":
+		flags := flag.NewFlagSet("staged purge", flag.ExitOnError)
+		batchSize := flags.Int("batch", 1, "Number of items to process in parallel")
+		flags.IntVar(batchSize, "b", 1, "Number of items to process in parallel (shorthand)")
+		consoleValues := defineConsoleFlags(flags)
+		flags.Parse(args[1:])
+		if err := consoleValues.apply(); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return usage
+		}
+
+		if err := checkBitwardenCLI(); err != nil {
+			return err
+		}
+		record, err := loadRunRecord(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		if record.Kind != stagedRunKind {
+			return fmt.Errorf("run %s is not a staged deletion", record.ID)
+		}
+		return purgeStagedRun(record, batchOptions(max(*batchSize, 1)), true)
+	default:
+		return usage
+	}
+}
+
+func listStagedRuns() error {
+	records, err := listRunRecords(stagedRunKind)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		console.infof(emojiInfo, "No staged runs")
+		return nil
+	}
+
+	for _, record := range records {
+		console.linef("%s  %s  %-7s  %d items", record.ID, record.CreatedAt.Local().Format("2006-01-02 15:04"), record.Status, len(record.ItemIDs))
+	}
+	return nil
+}
+
+// purgeStagedRun permanently deletes the items of a staged run that are still
+// in the trash. Items restored in the meantime are left alone.
+func purgeStagedRun(record *runRecord, options CommandOptions, confirm bool) error {
+	if record.Status == stagedStatusPurged {
+		return fmt.Errorf("run %s has already been purged", record.ID)
+	}
+
+	purged, err := purgeTrashedItems(record.ItemIDs, "staged items", options, confirm)
+	if err != nil || !purged {
+		return err
+	}
+
+	record.Status = stagedStatusPurged
+	return saveRunRecord(record)
+}
+
+// purgeTrashedItems permanently deletes those of ids that are still in the
+// trash and reports whether it went ahead; it does not when the user declines
+// or with --dry-run. noun names the items in the count of those that were
+// restored.
+func purgeTrashedItems(ids []string, noun string, options CommandOptions, confirm bool) (bool, error) {
+	if err := syncBitwarden("before purging"); err != nil {
+		console.warnf("Warning: Sync failed but continuing")
+	}
+
+	trash, err := fetchTrashItems()
+	if err != nil {
+		return false, err
+	}
+
+	listed := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		listed[id] = true
+	}
+
+	var items []BitwardenItem
+	for _, item := range trash {
+		if listed[item.ID] {
+			items = append(items, item)
+		}
+	}
+
+	if restored := len(ids) - len(items); restored > 0 {
+		console.infof(emojiInfo, "%d %s are no longer in the trash and will be kept", restored, noun)
+	}
+
+	op := deleteOperation(true)
+	stats := &DeleteStats{total: len(items)}
+	displayItemCount(stats, op)
+	if options.dryRun {
+		return false, nil
+	}
+
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return false, err
+	}
+	if stats.total > 0 {
+		if confirm && !confirmOperation(stats, op) {
+			cancelOperation()
+			return false, nil
+		}
+
+		if err := processItems(items, stats, op, options); err != nil {
+			return false, err
+		}
+
+		if err := syncBitwarden(""); err != nil {
+			console.warnf("Warning: Final sync failed")
+		}
+	}
+	return true, nil
+}
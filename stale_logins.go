@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStaleAge = "2y"
+	// staleCheckWorkers is the number of logins 'audit stale' checks at once.
+	staleCheckWorkers = 8
+)
+
+// Ways 'audit stale --check-uris' tells whether a site still exists.
+const (
+	uriCheckDNS  = "dns"
+	uriCheckHTTP = "http"
+
+	// uriCheckControlHost must resolve for the lookups of a run to be
+	// trusted.
+	uriCheckControlHost = "bitwarden.com"
+)
+
+type staleItem struct {
+	auditItem
+	PasswordRevisionDate string `json:"passwordRevisionDate,omitempty"`
+	// DeadURIs holds why each web URI failed the check, when --check-uris
+	// is given.
+	DeadURIs []string `json:"deadUris,omitempty"`
+
+	item BitwardenItem
+}
+
+type staleReport struct {
+	CreatedAt     time.Time   `json:"createdAt"`
+	LoginsAudited int         `json:"loginsAudited"`
+	OlderThan     string      `json:"olderThan"`
+	CheckURIs     string      `json:"checkUris,omitempty"`
+	Items         []staleItem `json:"items"`
+	Action        string      `json:"action"`
+}
+
+func runAuditStale(args []string) error {
+	flags := flag.NewFlagSet("audit stale", flag.ExitOnError)
+	olderThan := flags.String("older-than", defaultStaleAge, "Flag logins whose item and password were both last changed longer ago than this age (e.g. 18mo, 3y) or before this date")
+	checkURIs := flags.String("check-uris", "", "Only flag logins whose web URIs all fail this check: dns (the host no longer resolves) or http (nor answers a HEAD request)")
+	uriTimeout := flags.Duration("uri-timeout", 5*time.Second, "With --check-uris, give up on a host after this long")
+	deleteStale := flags.Bool("delete", false, "Delete the stale logins")
+	permanent := flags.Bool("permanent", false, "With --delete, permanently delete items (skip trash)")
+	tag := flags.String("tag", "", "Append this line to the notes of the stale logins")
+	reportPath := flags.String("report", "", "Also write the stale logins to this JSON file")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	options.isPermanent = *permanent
+	if *permanent && !*deleteStale {
+		return fmt.Errorf("--permanent can only be used with --delete")
+	}
+	if *deleteStale && *tag != "" {
+		return fmt.Errorf("--delete and --tag cannot be used together")
+	}
+	cutoff, err := parseDateBound(*olderThan)
+	if err != nil {
+		return fmt.Errorf("invalid --older-than: %w", err)
+	}
+	check := strings.ToLower(*checkURIs)
+	if check != "" && check != uriCheckDNS && check != uriCheckHTTP {
+		return fmt.Errorf("unknown --check-uris %q (expected dns or http)", *checkURIs)
+	}
+	if *uriTimeout <= 0 {
+		return fmt.Errorf("--uri-timeout must be positive")
+	}
+
+	logins, err := fetchAuditLogins(options)
+	if err != nil {
+		return err
+	}
+
+	report := staleReport{CreatedAt: time.Now().UTC(), LoginsAudited: len(logins), OlderThan: *olderThan, CheckURIs: check, Action: "report only"}
+	switch {
+	case *deleteStale:
+		report.Action = "delete"
+	case *tag != "":
+		report.Action = fmt.Sprintf("tag with %q", *tag)
+	}
+	for _, login := range logins {
+		changed := passwordRevisionTime(login)
+		if revisionTime(login).IsZero() || !revisionTime(login).Before(cutoff) || changed.IsZero() || !changed.Before(cutoff) {
+			continue
+		}
+		entry := staleItem{auditItem: newAuditItem(login, options.redact), item: login}
+		if raw, ok := passwordRevisionDate(login); ok {
+			entry.PasswordRevisionDate = raw
+		}
+		report.Items = append(report.Items, entry)
+	}
+
+	if check != "" && len(report.Items) > 0 {
+		console.infof(emojiSearch, "Checking the sites of %d stale logins (%s)...", len(report.Items), check)
+		checker := newURIChecker(check, *uriTimeout)
+		if err := checker.checkResolver(); err != nil {
+			return err
+		}
+		dead := make([][]deadURI, len(report.Items))
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, staleCheckWorkers)
+		for i, entry := range report.Items {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+				dead[i] = checker.deadURIs(entry.item)
+			}()
+		}
+		wg.Wait()
+		if checker.unknown > 0 {
+			console.warnf("%d hosts could not be checked (lookup errors or timeouts); their logins are not flagged", checker.unknown)
+		}
+
+		var gone []staleItem
+		for i, entry := range report.Items {
+			if dead[i] == nil {
+				continue
+			}
+			for _, uri := range dead[i] {
+				entry.DeadURIs = append(entry.DeadURIs, options.redact.uri(uri.uri)+": "+uri.reason)
+			}
+			gone = append(gone, entry)
+		}
+		report.Items = gone
+	}
+	// The logins untouched for the longest come first.
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		return revisionTime(report.Items[i].item).Before(revisionTime(report.Items[j].item))
+	})
+
+	var stale []BitwardenItem
+	if len(report.Items) == 0 {
+		console.infof(emojiSuccess, "No stale logins among %d logins", len(logins))
+	} else {
+		console.infof(emojiSearch, "Found %d stale logins among %d logins:", len(report.Items), len(logins))
+	}
+	for _, entry := range report.Items {
+		line := fmt.Sprintf("   %s: revised %s, password changed %s", entry.describe(), revisionDay(entry.item), passwordRevisionDay(entry.item))
+		if len(entry.DeadURIs) > 0 {
+			line += " (" + strings.Join(entry.DeadURIs, "; ") + ")"
+		}
+		console.linef("%s", line)
+		stale = append(stale, entry.item)
+	}
+
+	if *reportPath != "" {
+		if err := writeJSONFile(*reportPath, report); err != nil {
+			return err
+		}
+		console.infof(emojiSuccess, "Report written to %s", *reportPath)
+	}
+
+	return handleAuditedLogins(stale, options, *deleteStale, *tag, "stale logins")
+}
+
+// passwordRevisionDate returns login.passwordRevisionDate as bw listed it.
+// bw leaves it null until the password is changed for the first time.
+func passwordRevisionDate(item BitwardenItem) (string, bool) {
+	var parsed struct {
+		Login struct {
+			PasswordRevisionDate string `json:"passwordRevisionDate"`
+		} `json:"login"`
+	}
+	if item.raw == nil || json.Unmarshal(item.raw, &parsed) != nil || parsed.Login.PasswordRevisionDate == "" {
+		return "", false
+	}
+	return parsed.Login.PasswordRevisionDate, true
+}
+
+// passwordRevisionTime is when the password was last set: its last change,
+// or the creation of the item for a password that was never changed.
+func passwordRevisionTime(item BitwardenItem) time.Time {
+	if raw, ok := passwordRevisionDate(item); ok {
+		changed, _ := time.Parse(time.RFC3339, raw)
+		return changed
+	}
+	return creationTime(item)
+}
+
+func passwordRevisionDay(item BitwardenItem) string {
+	if _, ok := passwordRevisionDate(item); !ok {
+		return "never"
+	}
+	return passwordRevisionTime(item).Local().Format(reviewDateLayout)
+}
+
+// uriChecker finds out whether the hosts of logins still exist. Each host
+// is checked once per run.
+type uriChecker struct {
+	mode     string
+	timeout  time.Duration
+	resolver *net.Resolver
+	client   *http.Client
+
+	mu    sync.Mutex
+	hosts map[string]*hostCheck
+	// unknown counts the hosts whose check neither passed nor failed.
+	unknown int
+}
+
+type deadURI struct {
+	uri    string
+	reason string
+}
+
+type hostCheck struct {
+	done   chan struct{}
+	reason string
+}
+
+func newURIChecker(mode string, timeout time.Duration) *uriChecker {
+	return &uriChecker{
+		mode:     mode,
+		timeout:  timeout,
+		resolver: net.DefaultResolver,
+		client: &http.Client{
+			Timeout: timeout,
+			// Any answer, a redirect included, shows the site exists.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		hosts: make(map[string]*hostCheck),
+	}
+}
+
+// deadURIs checks the web URIs of a login and, when every one of them
+// failed, returns why; otherwise it returns nil. A login without web URIs
+// cannot be judged and is never reported as dead.
+func (c *uriChecker) deadURIs(item BitwardenItem) []deadURI {
+	var dead []deadURI
+	for _, uri := range item.uris() {
+		if parseWebURI(uri) == nil {
+			continue
+		}
+		reason := c.check(uri)
+		if reason == "" {
+			return nil
+		}
+		dead = append(dead, deadURI{uri, reason})
+	}
+	return dead
+}
+
+// checkResolver looks up a host that is known to exist, so that a resolver
+// that is down or blocked does not make every site look gone.
+func (c *uriChecker) checkResolver() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	if _, err := c.resolver.LookupHost(ctx, uriCheckControlHost); err != nil {
+		return fmt.Errorf("error resolving %s, which should always resolve, so the sites cannot be checked from here: %w", uriCheckControlHost, err)
+	}
+	return nil
+}
+
+// check returns why uri's site seems gone, or "" when it answered or could
+// not be checked. Only an answer that the host does not exist, or a refused
+// HTTP request, counts as gone; other errors and timeouts say nothing about
+// the site.
+func (c *uriChecker) check(uri string) string {
+	parsed := parseWebURI(uri)
+	host := strings.ToLower(parsed.Hostname())
+
+	c.mu.Lock()
+	result, seen := c.hosts[host]
+	if !seen {
+		result = &hostCheck{done: make(chan struct{})}
+		c.hosts[host] = result
+	}
+	c.mu.Unlock()
+	if seen {
+		<-result.done
+		return result.reason
+	}
+	defer close(result.done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	if net.ParseIP(host) == nil {
+		if _, err := c.resolver.LookupHost(ctx, host); err != nil {
+			var dnsErr *net.DNSError
+			if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+				result.reason = "does not resolve"
+			} else {
+				c.markUnknown()
+			}
+			return result.reason
+		}
+	}
+	if c.mode != uriCheckHTTP {
+		return ""
+	}
+
+	// Only the site is of interest; the path and query of a URI can carry
+	// tokens that are not to be sent anywhere.
+	target := url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: "/"}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		target.Scheme = "https"
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, target.String(), nil)
+	if err != nil {
+		result.reason = "invalid URI"
+		return result.reason
+	}
+	response, err := c.client.Do(request)
+	if err != nil {
+		var netErr net.Error
+		if ctx.Err() != nil || errors.As(err, &netErr) && netErr.Timeout() {
+			c.markUnknown()
+		} else {
+			result.reason = "no HTTP answer"
+		}
+		return result.reason
+	}
+	response.Body.Close()
+	return ""
+}
+
+func (c *uriChecker) markUnknown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.unknown++
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// statsLine is one run in a --stats-file.
+type statsLine struct {
+	Time        time.Time `json:"time"`
+	Operation   string    `json:"operation"`
+	FiltersHash string    `json:"filtersHash"`
+	Matched     int       `json:"matched"`
+	Succeeded   int       `json:"succeeded"`
+	AlreadyGone int       `json:"alreadyGone"`
+	Failed      int       `json:"failed"`
+	Stopped     bool      `json:"stopped,omitempty"`
+	DurationMS  int64     `json:"durationMs"`
+	// FailuresByClass counts the failed items by cause, as in the failure
+	// summary.
+	FailuresByClass map[string]int `json:"failuresByClass,omitempty"`
+}
+
+// appendStatsLine adds a JSON line describing the finished run to
+// --stats-file. The filters are hashed so runs of the same selection can be
+// grouped without recording search terms. A run started by
+// --profiles also writes the line for the summary of all accounts.
+func appendStatsLine(stats *DeleteStats, op itemOperation, options CommandOptions) {
+	if path := os.Getenv(accountSummaryEnv); path != "" {
+		if err := appendJSONLine(path, summaryLine(stats, op, options)); err != nil {
+			console.warnf("Warning: could not write account summary: %v", err)
+		}
+	}
+	if options.statsFile == "" {
+		return
+	}
+	if err := appendJSONLine(options.statsFile, summaryLine(stats, op, options)); err != nil {
+		console.warnf("Warning: could not write stats file: %v", err)
+	}
+}
+
+func summaryLine(stats *DeleteStats, op itemOperation, options CommandOptions) statsLine {
+	failuresByClass := failureCounts(stats.failures())
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	line := statsLine{
+		Time:        time.Now().UTC(),
+		Operation:   op.verb,
+		FiltersHash: filtersHash(options.selectionArgs),
+		Matched:     stats.total,
+		Succeeded:   stats.completed - stats.failed - stats.alreadyGone,
+		AlreadyGone: stats.alreadyGone,
+		Failed:      stats.failed,
+		Stopped:     stats.completed < stats.total,
+
+		FailuresByClass: failuresByClass,
+	}
+	if !stats.started.IsZero() {
+		line.DurationMS = time.Since(stats.started).Milliseconds()
+	}
+	return line
+}
+
+func filtersHash(selectionArgs []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(selectionArgs, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func appendJSONLine(path string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
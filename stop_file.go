@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+const defaultStopFileName = "STOP"
+
+// stopSignal lets a long run be halted from another terminal: workers check
+// for the stop file before every item and finish only what is in flight
+// once it appears.
+type stopSignal struct {
+	path    string
+	stopped atomic.Bool
+}
+
+func newStopSignal(path string) (*stopSignal, error) {
+	path, err := stopFilePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("stop file %s exists; remove it before starting a new run", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error checking stop file: %w", err)
+	}
+
+	return &stopSignal{path: path}, nil
+}
+
+func (s *stopSignal) requested() bool {
+	if s == nil {
+		return false
+	}
+	if s.stopped.Load() {
+		return true
+	}
+
+	if _, err := os.Stat(s.path); err != nil {
+		return false
+	}
+	if s.stopped.CompareAndSwap(false, true) {
+		console.warnf("\nStop file %s found, finishing in-flight items...", s.path)
+	}
+	return true
+}
+
+// stopFilePath returns --stop-file, or the default stop file when it is not
+// given.
+func stopFilePath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	return configFile(defaultStopFileName)
+}
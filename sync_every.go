@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// syncGate runs bw sync after every --sync-every items, so that a long run
+// does not drift far from the server state. Workers hold it shared while an
+// item is in flight; the sync holds it alone, so it waits for the items in
+// flight and no new one starts until it is done.
+type syncGate struct {
+	every int
+	total int
+	gate  sync.RWMutex
+
+	mu        sync.Mutex
+	succeeded int
+}
+
+func newSyncGate(every, total int) *syncGate {
+	if every <= 0 || every >= total {
+		return nil
+	}
+	return &syncGate{every: every, total: total}
+}
+
+func (g *syncGate) enter() {
+	if g != nil {
+		g.gate.RLock()
+	}
+}
+
+// leave ends an item and runs the sync when it was the every-th to succeed.
+// The run ends with a sync of its own, so none is made after the last item.
+func (g *syncGate) leave(result itemResult) {
+	if g == nil {
+		return
+	}
+	g.gate.RUnlock()
+	if result.status() != resultDone {
+		return
+	}
+
+	g.mu.Lock()
+	g.succeeded++
+	due := g.succeeded%g.every == 0 && g.succeeded < g.total
+	succeeded := g.succeeded
+	g.mu.Unlock()
+	if !due {
+		return
+	}
+
+	g.gate.Lock()
+	defer g.gate.Unlock()
+	console.endProgress()
+	if err := syncBitwarden(fmt.Sprintf("after %d items", succeeded)); err != nil {
+		console.warnf("Warning: intermediate sync failed, continuing")
+	}
+}
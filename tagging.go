@@ -0,0 +1,171 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runTagCommand marks the matched items as candidates for a later run: it
+// prefixes their names with a tag, which shows up in every vault client, or
+// appends it to their notes. --tagged selects the marked items again.
+func runTagCommand(args []string) error {
+	flags := flag.NewFlagSet("tag", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s tag --name <tag> | --note <tag> [--remove] [--dry-run] [selection flags]\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+	nameTag := flags.String("name", "", "Prefix the names of matched items with this tag (e.g. [CLEANUP-2024-06])")
+	noteTag := flags.String("note", "", "Append this tag as a line to the notes of matched items")
+	remove := flags.Bool("remove", false, "Remove the tag from the matched items instead of adding it")
+	dryRun := flags.Bool("dry-run", false, "List the items that would be tagged without changing them")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	tag, inName := strings.TrimSpace(*nameTag), true
+	if *noteTag != "" {
+		tag, inName = strings.TrimSpace(*noteTag), false
+	}
+	if (*nameTag == "") == (*noteTag == "") || tag == "" {
+		return fmt.Errorf("give the tag with either --name or --note")
+	}
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+	op := tagOperation(tag, inName, *remove)
+	displayOperationMode(op)
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return err
+	}
+
+	// Items that already are in the wanted state are left out, so the counts
+	// only include items that change.
+	var pending []BitwardenItem
+	for _, item := range items {
+		if hasTag(item, tag, inName) == *remove {
+			pending = append(pending, item)
+		}
+	}
+	if unchanged := len(items) - len(pending); unchanged > 0 {
+		state := "already carry"
+		if *remove {
+			state = "do not carry"
+		}
+		console.infof(emojiInfo, "%d matched items %s the tag %q and are skipped", unchanged, state, tag)
+	}
+
+	stats := &DeleteStats{total: len(pending), protected: options.protection.count()}
+	displayItemCount(stats, op)
+	if stats.total == 0 {
+		return nil
+	}
+	if *dryRun {
+		recordFilterHistory(options, pending)
+		listItems(pending, options)
+		return nil
+	}
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	recordFilterHistory(options, pending)
+	return executeItems(pending, stats, op, options)
+}
+
+func tagOperation(tag string, inName, remove bool) itemOperation {
+	switch {
+	case !inName && !remove:
+		return stampNotesOperation(tag)
+	case !inName:
+		return itemOperation{
+			verb:         "untag",
+			modeEmoji:    emojiInfo,
+			modeText:     fmt.Sprintf("Untagging (the line %q is removed from the notes of items)", tag),
+			confirmText:  fmt.Sprintf("remove %q from the notes of", tag),
+			processName:  "untagging",
+			progressVerb: "untagging",
+			doneText:     "have been untagged",
+			cost:         editCost,
+			run: func(item BitwardenItem) error {
+				return updateItem(item.ID, func(fields map[string]any) bool { return removeNotesLine(fields, tag) })
+			},
+		}
+	case remove:
+		return itemOperation{
+			verb:         "untag",
+			modeEmoji:    emojiInfo,
+			modeText:     fmt.Sprintf("Untagging (the prefix %q is removed from the names of items)", tag),
+			confirmText:  fmt.Sprintf("remove the prefix %q from", tag),
+			processName:  "untagging",
+			progressVerb: "untagging",
+			doneText:     "have been untagged",
+			cost:         editCost,
+			run: func(item BitwardenItem) error {
+				return updateItem(item.ID, func(fields map[string]any) bool {
+					name, _ := fields["name"].(string)
+					if !nameTagged(name, tag) {
+						return false
+					}
+					fields["name"] = strings.TrimSpace(strings.TrimPrefix(name, tag))
+					return true
+				})
+			},
+		}
+	}
+	return itemOperation{
+		verb:         "tag",
+		modeEmoji:    emojiInfo,
+		modeText:     fmt.Sprintf("Tagging (items will be kept, their names are prefixed with %q)", tag),
+		confirmText:  fmt.Sprintf("prefix with %q the names of", tag),
+		processName:  "tagging",
+		progressVerb: "tagging",
+		doneText:     "have been tagged",
+		cost:         editCost,
+		run: func(item BitwardenItem) error {
+			return updateItem(item.ID, func(fields map[string]any) bool {
+				name, _ := fields["name"].(string)
+				if nameTagged(name, tag) {
+					return false
+				}
+				fields["name"] = tag + " " + name
+				return true
+			})
+		},
+	}
+}
+
+// hasTag reports whether the name of item starts with tag, or, for note
+// tags, whether a line of its notes is tag.
+func hasTag(item BitwardenItem, tag string, inName bool) bool {
+	if inName {
+		return nameTagged(item.Name, tag)
+	}
+	for _, line := range strings.Split(item.Notes, "\n") {
+		if strings.TrimSpace(line) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// nameTagged reports whether name starts with tag as a word of its own, the
+// way the tag operation writes it: "old bank" is tagged "old", "oldbank" is
+// not.
+func nameTagged(name, tag string) bool {
+	return name == tag || strings.HasPrefix(name, tag+" ")
+}
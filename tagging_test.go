@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestHasTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		item   BitwardenItem
+		tag    string
+		inName bool
+		want   bool
+	}{
+		{"notes line", BitwardenItem{Notes: "first\n#old\nlast"}, "#old", false, true},
+		{"notes line with spaces", BitwardenItem{Notes: "  #old  "}, "#old", false, true},
+		{"notes substring", BitwardenItem{Notes: "these are #older notes"}, "#old", false, false},
+		{"no notes", BitwardenItem{}, "#old", false, false},
+		{"name prefix", BitwardenItem{Name: "old bank"}, "old", true, true},
+		{"whole name", BitwardenItem{Name: "old"}, "old", true, true},
+		{"name without a space", BitwardenItem{Name: "oldbank"}, "old", true, false},
+		{"name elsewhere", BitwardenItem{Name: "bank old"}, "old", true, false},
+		{"notes ignored for names", BitwardenItem{Name: "bank", Notes: "old"}, "old", true, false},
+	}
+	for _, test := range tests {
+		if got := hasTag(test.item, test.tag, test.inName); got != test.want {
+			t.Errorf("%s: hasTag = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
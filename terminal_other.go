@@ -0,0 +1,19 @@
+//go:build !unix && !windows
+
+package main
+
+import "errors"
+
+const terminalDevice = ""
+
+var errNoTerminal = errors.New("no terminal support on this platform")
+
+func setupConsole() {}
+
+func hideInput() (func(), error) { return nil, errNoTerminal }
+
+func rawInput() (func(), error) { return nil, errNoTerminal }
+
+func stdinIsTerminal() bool { return false }
+
+func terminalSize() (rows, cols int) { return 24, 80 }
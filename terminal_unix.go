@@ -0,0 +1,72 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// terminalDevice is opened to talk to the user when stdin is a pipe.
+const terminalDevice = "/dev/tty"
+
+func setupConsole() {}
+
+// hideInput turns off echo until restore is called.
+func hideInput() (restore func(), err error) {
+	if err := stty("-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty("echo") }, nil
+}
+
+// rawInput switches the terminal to raw mode, so that keys arrive one at a
+// time, until restore is called.
+func rawInput() (restore func(), err error) {
+	state, err := sttyOutput("-g")
+	if err != nil {
+		return nil, err
+	}
+	if err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(state)) }, nil
+}
+
+func stty(args ...string) error {
+	sttyCmd := exec.Command("stty", args...)
+	sttyCmd.Stdin = os.Stdin
+	return sttyCmd.Run()
+}
+
+func sttyOutput(args ...string) (string, error) {
+	sttyCmd := exec.Command("stty", args...)
+	sttyCmd.Stdin = os.Stdin
+	output, err := sttyCmd.Output()
+	return string(output), err
+}
+
+// stdinIsTerminal asks stty, which only succeeds on a terminal; a character
+// device check alone would also accept /dev/null.
+func stdinIsTerminal() bool {
+	return stty("-g") == nil
+}
+
+// terminalSize returns the rows and columns of the terminal, or 24x80 when
+// stty cannot tell.
+func terminalSize() (rows, cols int) {
+	output, err := sttyOutput("size")
+	if err == nil {
+		fields := strings.Fields(output)
+		if len(fields) == 2 {
+			rows, _ = strconv.Atoi(fields[0])
+			cols, _ = strconv.Atoi(fields[1])
+		}
+	}
+	if rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
@@ -0,0 +1,107 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// terminalDevice is opened to talk to the user when stdin is a pipe.
+const terminalDevice = "CONIN$"
+
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalInput      = 0x0200
+	enableVirtualTerminalProcessing = 0x0004
+	codePageUTF8                    = 65001
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procSetConsoleOutputCP         = kernel32.NewProc("SetConsoleOutputCP")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+type consoleCoord struct{ x, y int16 }
+
+type consoleScreenBufferInfo struct {
+	size              consoleCoord
+	cursorPosition    consoleCoord
+	attributes        uint16
+	window            struct{ left, top, right, bottom int16 }
+	maximumWindowSize consoleCoord
+}
+
+// setupConsole lets cmd.exe and PowerShell render the escape sequences of
+// the progress line and the picker, and the emoji of the output. Consoles
+// that do not support either keep working, only less pretty.
+func setupConsole() {
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		handle := syscall.Handle(f.Fd())
+		var mode uint32
+		if syscall.GetConsoleMode(handle, &mode) == nil {
+			setConsoleMode(handle, mode|enableVirtualTerminalProcessing)
+		}
+	}
+	procSetConsoleOutputCP.Call(codePageUTF8)
+}
+
+// hideInput turns off echo until restore is called.
+func hideInput() (restore func(), err error) {
+	return changeInputMode(func(mode uint32) uint32 { return mode &^ enableEchoInput })
+}
+
+// rawInput switches the console to raw mode, so that keys arrive one at a
+// time and arrow keys as the same escape sequences as on a Unix terminal,
+// until restore is called.
+func rawInput() (restore func(), err error) {
+	return changeInputMode(func(mode uint32) uint32 {
+		return mode&^(enableEchoInput|enableLineInput|enableProcessedInput) | enableVirtualTerminalInput
+	})
+}
+
+func changeInputMode(change func(mode uint32) uint32) (func(), error) {
+	handle := syscall.Handle(os.Stdin.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return nil, err
+	}
+	if err := setConsoleMode(handle, change(mode)); err != nil {
+		return nil, err
+	}
+	return func() { setConsoleMode(handle, mode) }, nil
+}
+
+func setConsoleMode(handle syscall.Handle, mode uint32) error {
+	if ok, _, err := procSetConsoleMode.Call(uintptr(handle), uintptr(mode)); ok == 0 {
+		return err
+	}
+	return nil
+}
+
+// stdinIsTerminal reports whether stdin is a console; pipes, files and NUL
+// have no console mode.
+func stdinIsTerminal() bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Handle(os.Stdin.Fd()), &mode) == nil
+}
+
+// terminalSize returns the rows and columns of the console window, or 24x80
+// when there is none.
+func terminalSize() (rows, cols int) {
+	var info consoleScreenBufferInfo
+	ok, _, _ := procGetConsoleScreenBufferInfo.Call(os.Stdout.Fd(), uintptr(unsafe.Pointer(&info)))
+	if ok != 0 {
+		rows = int(info.window.bottom-info.window.top) + 1
+		cols = int(info.window.right-info.window.left) + 1
+	}
+	if rows <= 0 || cols <= 0 {
+		return 24, 80
+	}
+	return rows, cols
+}
@@ -0,0 +1,90 @@
+package main
+
+import "time"
+
+const throughputWindow = 10 * time.Second
+
+// recordResult counts the outcome of one item. Items that no longer exist
+// count as already gone rather than failed, so repeated or resumed runs
+// converge without spurious errors.
+func (stats *DeleteStats) recordResult(result itemResult) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	stats.totalLatency += result.Duration
+	stats.results = append(stats.results, result)
+	emitResult(result)
+	stats.checkpoint.record(result)
+	switch result.status() {
+	case resultGone:
+		stats.alreadyGone++
+	case resultFailed:
+		stats.failed++
+	}
+}
+
+// absorb adds the counters of a finished group run to the overall stats.
+func (stats *DeleteStats) absorb(group *DeleteStats) {
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	stats.completed += group.completed
+	stats.failed += group.failed
+	stats.alreadyGone += group.alreadyGone
+	stats.totalLatency += group.totalLatency
+	stats.results = append(stats.results, group.results...)
+	if stats.started.IsZero() || (!group.started.IsZero() && group.started.Before(stats.started)) {
+		stats.started = group.started
+	}
+}
+
+// recentRate records a completed item and returns the rate of completions
+// over the last few seconds, which follows a run that slows down or speeds
+// up better than the average of the whole run. It is called once per
+// completed item, from the goroutine that counts completions.
+func (stats *DeleteStats) recentRate() float64 {
+	now := time.Now()
+	stats.recent = append(stats.recent, now)
+	for len(stats.recent) > 0 && now.Sub(stats.recent[0]) > throughputWindow {
+		stats.recent = stats.recent[1:]
+	}
+
+	window := min(now.Sub(stats.started), throughputWindow)
+	if window <= 0 {
+		return 0
+	}
+	return float64(len(stats.recent)) / window.Seconds()
+}
+
+func (stats *DeleteStats) averageLatency() time.Duration {
+	if stats.completed == 0 {
+		return 0
+	}
+	return (stats.totalLatency / time.Duration(stats.completed)).Round(time.Millisecond)
+}
+
+func (stats *DeleteStats) failureRate() float64 {
+	if stats.completed == 0 {
+		return 0
+	}
+	return 100 * float64(stats.failed) / float64(stats.completed)
+}
+
+func showThroughputSummary(stats *DeleteStats) {
+	if stats.completed == 0 {
+		return
+	}
+
+	elapsed := time.Since(stats.started)
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	console.summaryf(emojiStats, "Throughput: %d items in %s (%.1f/s), avg latency %s, %d failed (%.1f%%)",
+		stats.completed, elapsed.Round(100*time.Millisecond), float64(stats.completed)/elapsed.Seconds(),
+		stats.averageLatency(), stats.failed, stats.failureRate())
+	if stats.alreadyGone > 0 {
+		console.infof(emojiInfo, "%d items were already gone (deleted by an earlier run or another client)", stats.alreadyGone)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// runDeleteCommand is the bulk deletion run, also available without a
+// subcommand name.
+func runDeleteCommand(args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ExitOnError)
+	options, err := parseOptions(flags, args, defineCommandFlags(flags))
+	if err != nil {
+		return err
+	}
+	return runBulkDelete(options)
+}
+
+func runListCommand(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	trash := flags.Bool("trash", false, "List matching items in the trash instead of the vault, with their deletion dates")
+	olderThan := flags.String("older-than", "", "With --trash, only list items that have been in the trash for at least this long (e.g. 30d, 3mo)")
+	idsOnly := flags.Bool("ids-only", false, "Print only the IDs of the matched items, one per line, for piping into other tools; messages go to stderr")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	if *idsOnly {
+		if options.output == outputJSON {
+			return fmt.Errorf("--ids-only and --output json cannot be used together")
+		}
+		quietForPipe()
+	}
+	if *olderThan != "" && !*trash {
+		return fmt.Errorf("--older-than can only be used with --trash")
+	}
+	var age time.Duration
+	if *olderThan != "" {
+		if age, err = parseAge(*olderThan); err != nil {
+			return err
+		}
+	}
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+
+	var items []BitwardenItem
+	if *trash {
+		items, err = fetchMatchingTrashItems(options, filters, age)
+	} else {
+		items, err = fetchMatchingItems(options, filters)
+	}
+	if err != nil {
+		return err
+	}
+
+	console.infof(emojiSearch, "%d items match", len(items))
+	if err := writeIDList(options.idsOut, items); err != nil {
+		return err
+	}
+	if *idsOnly {
+		for _, item := range items {
+			fmt.Println(item.ID)
+		}
+		return nil
+	}
+	if *trash {
+		listTrashItems(items, options)
+	} else {
+		listItems(items, options)
+	}
+	emitMatched(items, "list")
+	return nil
+}
+
+// listTrashItems prints trashed items as a table of name, deletion date,
+// time in the trash and ID, for reviewing them before restore or
+// purge-trash.
+func listTrashItems(items []BitwardenItem, options CommandOptions) {
+	if len(items) == 0 {
+		return
+	}
+	rows := [][]string{{"NAME", "DELETED", "IN TRASH", "ID"}}
+	for _, item := range items {
+		deletedAt, inTrash := "-", "-"
+		if deleted, err := time.Parse(time.RFC3339, item.DeletedDate); err == nil {
+			deletedAt = deleted.Local().Format("2006-01-02 15:04")
+			inTrash = trashAge(time.Since(deleted))
+		}
+		rows = append(rows, []string{truncate(options.redact.name(item.Name), tableNameWidth), deletedAt, inTrash, item.ID})
+	}
+	printTable(rows)
+}
+
+func runPurgeTrashCommand(args []string) error {
+	flags := flag.NewFlagSet("purge-trash", flag.ExitOnError)
+	olderThan := flags.String("older-than", "", "Only purge items that have been in the trash for at least this long (e.g. 30d, 3mo)")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	var age time.Duration
+	if *olderThan != "" {
+		if age, err = parseAge(*olderThan); err != nil {
+			return err
+		}
+	}
+
+	op := deleteOperation(true)
+	op.modeText = "Trash purge (trashed items will be permanently deleted)"
+	return processTrashItems(op, options, age, true)
+}
+
+func runRestoreCommand(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	return processTrashItems(restoreOperation(), options, 0, false)
+}
+
+// processTrashItems applies op to the trashed items matching the selection.
+func processTrashItems(op itemOperation, options CommandOptions, minAge time.Duration, protect bool) error {
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+	displayOperationMode(op)
+
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	items, err := fetchMatchingTrashItems(options, filters, minAge)
+	if err != nil {
+		return err
+	}
+	matchedItems := items
+
+	stats := &DeleteStats{total: len(items), protected: options.protection.count()}
+	displayItemCount(stats, op)
+	listItems(items, options)
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	if protect && stats.total > 0 {
+		items = protectSensitiveItems(items, options)
+		stats.total = len(items)
+	}
+	if stats.total == 0 {
+		return nil
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	recordFilterHistory(options, matchedItems)
+	return executeItems(items, stats, op, options)
+}
+
+// fetchMatchingTrashItems returns the trashed items matching the search terms
+// and filters that were deleted at least minAge ago. bw cannot search the
+// trash, so the search terms are matched against item names here.
+func fetchMatchingTrashItems(options CommandOptions, filters []itemFilter, minAge time.Duration) ([]BitwardenItem, error) {
+	trash, err := fetchTrashItems()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-minAge)
+	var items []BitwardenItem
+	for _, item := range trash {
+		name := strings.ToLower(item.Name)
+		searched := func(i int) bool { return strings.Contains(name, strings.ToLower(options.searchTerms[i])) }
+		if len(options.searchTerms) > 0 && !matchSearchTerms(options, searched) {
+			continue
+		}
+		if minAge > 0 {
+			deleted, err := time.Parse(time.RFC3339, item.DeletedDate)
+			if err != nil || deleted.After(cutoff) {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	items = options.protection.apply(filterItems(items, filters))
+	if len(items) == 0 && options.failOnEmpty {
+		return nil, errNoMatches
+	}
+	return items, nil
+}
+
+// trashAge rounds the time an item has spent in the trash to days, or to
+// hours on its first day.
+func trashAge(age time.Duration) string {
+	if age < 24*time.Hour {
+		return fmt.Sprintf("%d hours", int(age.Hours()))
+	}
+	if days := int(age.Hours() / 24); days != 1 {
+		return fmt.Sprintf("%d days", days)
+	}
+	return "1 day"
+}
+
+func restoreOperation() itemOperation {
+	return itemOperation{
+		verb:         "restore",
+		modeEmoji:    emojiInfo,
+		modeText:     "Restore (items will be moved out of the trash)",
+		confirmText:  "restore",
+		processName:  "restore",
+		progressVerb: "restoring",
+		doneText:     "have been restored",
+		cost:         singleCallCost,
+		idempotent:   true,
+		run: func(item BitwardenItem) error {
+			return vault.RestoreItem(item.ID)
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	twoPhaseKind = "two-phase"
+
+	twoPhaseTrashed   = "trashed"
+	twoPhaseCommitted = "committed"
+)
+
+// twoPhaseManifest lists the items the first phase of --two-phase moved to
+// the trash. --commit permanently deletes exactly these items.
+type twoPhaseManifest struct {
+	Kind        string         `json:"kind"`
+	Status      string         `json:"status"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	CommittedAt *time.Time     `json:"committedAt,omitempty"`
+	Args        []string       `json:"args,omitempty"`
+	Items       []manifestItem `json:"items"`
+}
+
+type manifestItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// writeTwoPhaseManifest records the items the run moved to the trash. Items
+// that failed or were already gone are left out, so that --commit never
+// deletes an item that was not trashed by this run.
+func writeTwoPhaseManifest(stats *DeleteStats, options CommandOptions) error {
+	manifest := twoPhaseManifest{
+		Kind:      twoPhaseKind,
+		Status:    twoPhaseTrashed,
+		CreatedAt: time.Now().UTC(),
+		Args:      options.commandArgs,
+		Items:     []manifestItem{},
+	}
+	stats.mu.Lock()
+	for _, result := range stats.results {
+		if result.status() == resultDone {
+			manifest.Items = append(manifest.Items, manifestItem{ID: result.ItemID, Name: options.redact.name(result.Name)})
+		}
+	}
+	stats.mu.Unlock()
+
+	if err := writeJSONFile(options.twoPhase, manifest); err != nil {
+		return err
+	}
+
+	console.summaryf(emojiInfo, "\nManifest of %d trashed items written to %s", len(manifest.Items), options.twoPhase)
+	if len(manifest.Items) > 0 {
+		console.infof(emojiInfo, "Review or restore them in the trash. To delete them permanently, run:")
+		console.linef("   %s --commit %s", filepath.Base(os.Args[0]), shellJoin([]string{options.twoPhase}))
+	}
+	return nil
+}
+
+// commitTwoPhaseManifest permanently deletes the items of a manifest that are
+// still in the trash, with the retry, rate, log and hook options of the run.
+func commitTwoPhaseManifest(path string, options CommandOptions) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no manifest at %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading manifest: %w", err)
+	}
+	var manifest twoPhaseManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("error parsing manifest %s: %w", path, err)
+	}
+	if manifest.Kind != twoPhaseKind {
+		return fmt.Errorf("%s is not a --two-phase manifest", path)
+	}
+	if manifest.Status == twoPhaseCommitted {
+		return fmt.Errorf("manifest %s has already been committed", path)
+	}
+
+	console.infof(emojiStart, "Committing the two-phase run from %s: %d items", manifest.CreatedAt.Local().Format("2006-01-02 15:04"), len(manifest.Items))
+	ids := make([]string, 0, len(manifest.Items))
+	for _, item := range manifest.Items {
+		ids = append(ids, item.ID)
+	}
+	purged, err := purgeTrashedItems(ids, "items of the manifest", options, true)
+	if err != nil || !purged {
+		return err
+	}
+
+	manifest.Status = twoPhaseCommitted
+	committed := time.Now().UTC()
+	manifest.CommittedAt = &committed
+	return writeJSONFile(path, manifest)
+}
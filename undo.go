@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	deleteRunKind = "delete"
+
+	deleteStatusDone   = "done"
+	deleteStatusUndone = "undone"
+)
+
+// Fields dropped from a backed-up item before it is recreated. The server
+// assigns them, and attachments cannot be recreated without their files.
+var recreateDropFields = []string{"id", "object", "revisionDate", "creationDate", "deletedDate", "attachments"}
+
+// undoBackupPassphrase is the passphrase for the backups of permanent
+// deletions when it was typed at the terminal; it is asked for once per
+// process. A passphrase from the environment is read again on every use, so
+// that the jobs of the REST service never pick up a cached one.
+var undoBackupPassphrase string
+
+// prepareUndoBackup makes sure a permanent deletion can encrypt its backup
+// before it deletes anything.
+func prepareUndoBackup(op itemOperation, options CommandOptions) error {
+	if !op.permanent || options.noBackup {
+		return nil
+	}
+	if _, err := undoPassphrase(); err != nil {
+		return fmt.Errorf("%w, or pass --no-backup", err)
+	}
+	return nil
+}
+
+func undoPassphrase() (string, error) {
+	if passphrase := os.Getenv(backupPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	if undoBackupPassphrase == "" {
+		console.infof(emojiInfo, "Permanently deleted items are kept in an encrypted backup so that 'undo' can recreate them")
+		passphrase, err := backupPassphrase(true)
+		if err != nil {
+			return "", err
+		}
+		undoBackupPassphrase = passphrase
+	}
+	return undoBackupPassphrase, nil
+}
+
+// recordDeleteRun stores which items a deletion run processed, and how each
+// one went, so the run can be audited and undone later. Permanent deletions
+// also store the full item JSON, encrypted, unless --no-backup is set, since
+// those items cannot be restored from the trash.
+func recordDeleteRun(items []BitwardenItem, stats *DeleteStats, op itemOperation, options CommandOptions) {
+	record := &runRecord{
+		ID:        newRunID(),
+		Kind:      deleteRunKind,
+		Status:    deleteStatusDone,
+		CreatedAt: time.Now().UTC(),
+		Operation: "trash",
+	}
+	if op.permanent {
+		record.Operation = "permanent"
+	}
+
+	stats.mu.Lock()
+	record.Results = resultRecords(stats.results, options.redact)
+	stats.mu.Unlock()
+
+	var backup []json.RawMessage
+	for _, item := range items {
+		record.ItemIDs = append(record.ItemIDs, item.ID)
+		if op.permanent && !options.noBackup && item.raw != nil {
+			backup = append(backup, item.raw)
+		}
+	}
+	if len(backup) > 0 {
+		sealed, err := sealUndoBackup(backup)
+		if err != nil {
+			console.warnf("Warning: the run is recorded without a backup: %v", err)
+		}
+		record.EncryptedBackup = sealed
+	}
+
+	if err := saveRunRecord(record); err != nil {
+		console.warnf("Warning: could not record run for undo: %v", err)
+		return
+	}
+	console.summaryf(emojiInfo, "Run ID: %s (undo with: %s undo --run %s)", record.ID, filepath.Base(os.Args[0]), record.ID)
+}
+
+func sealUndoBackup(items []json.RawMessage) (*backupFile, error) {
+	passphrase, err := undoPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	backup := &backupFile{Version: backupFileVersion, CreatedAt: time.Now().UTC(), Items: items}
+	if err := backup.seal(passphrase); err != nil {
+		return nil, err
+	}
+	return backup, nil
+}
+
+// runBackup returns the backed-up items of a run, decrypting them when the
+// run has an encrypted backup. The record itself stays encrypted.
+func runBackup(record *runRecord) ([]json.RawMessage, error) {
+	if record.EncryptedBackup == nil {
+		return record.Backup, nil
+	}
+	passphrase, err := backupPassphrase(false)
+	if err != nil {
+		return nil, err
+	}
+	backup := *record.EncryptedBackup
+	if err := backup.open(passphrase); err != nil {
+		return nil, err
+	}
+	return backup.Items, nil
+}
+
+func runUndoCommand(args []string) error {
+	flags := flag.NewFlagSet("undo", flag.ExitOnError)
+	runID := flags.String("run", "", "ID of the deletion run to undo (omit to list recent runs)")
+	batchSize := flags.Int("batch", 1, "Number of items to process in parallel")
+	flags.IntVar(batchSize, "b", 1, "Number of items to process in parallel (shorthand)")
+	consoleValues := defineConsoleFlags(flags)
+	flags.Parse(args)
+	if err := consoleValues.apply(); err != nil {
+		return err
+	}
+
+	if *runID == "" {
+		return listDeleteRuns()
+	}
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+
+	record, err := loadRunRecord(*runID)
+	if err != nil {
+		return err
+	}
+	if record.Kind != deleteRunKind {
+		return fmt.Errorf("run %s is not a deletion run", record.ID)
+	}
+	if record.Status == deleteStatusUndone {
+		return fmt.Errorf("run %s has already been undone", record.ID)
+	}
+
+	return undoDeleteRun(record, max(*batchSize, 1))
+}
+
+func listDeleteRuns() error {
+	records, err := listRunRecords(deleteRunKind)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		console.infof(emojiInfo, "No deletion runs recorded")
+		return nil
+	}
+
+	for _, record := range records {
+		backup := ""
+		if len(record.Backup) > 0 {
+			backup = fmt.Sprintf(", %d backed up", len(record.Backup))
+		} else if record.EncryptedBackup != nil {
+			backup = ", encrypted backup"
+		}
+		console.linef("%s  %s  %-9s  %-6s  %d items%s", record.ID, record.CreatedAt.Local().Format("2006-01-02 15:04"), record.Operation, record.Status, len(record.ItemIDs), backup)
+	}
+	return nil
+}
+
+// undoDeleteRun brings back the items of a deletion run: items still in the
+// trash are restored, permanently deleted items are recreated from the run's
+// backup, and items that are still in the vault are left alone.
+func undoDeleteRun(record *runRecord, batchSize int) error {
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	backup, err := runBackup(record)
+	if err != nil {
+		return err
+	}
+	backups := make(map[string]json.RawMessage, len(backup))
+	for _, raw := range backup {
+		var item BitwardenItem
+		if err := json.Unmarshal(raw, &item); err == nil {
+			backups[item.ID] = raw
+		}
+	}
+	plan, err := planRestore(record.ItemIDs, backups)
+	if err != nil {
+		return err
+	}
+
+	console.infof(emojiSearch, "Run %s: %d items to restore from trash, %d to recreate from backup", record.ID, len(plan.toRestore), len(plan.toRecreate))
+	if !plan.confirm() {
+		return nil
+	}
+	if err := processItems(plan.items(), &DeleteStats{total: len(plan.items())}, undoOperation(plan.trashed), batchOptions(batchSize)); err != nil {
+		return err
+	}
+
+	if err := syncBitwarden(""); err != nil {
+		console.warnf("Warning: Final sync failed")
+	}
+
+	record.Status = deleteStatusUndone
+	return saveRunRecord(record)
+}
+
+// restorePlan sorts items that should be brought back into those still in
+// the trash, those that have to be recreated from a backup, and those that
+// are still in the vault or cannot be brought back at all.
+type restorePlan struct {
+	toRestore   []BitwardenItem
+	toRecreate  []BitwardenItem
+	trashed     map[string]BitwardenItem
+	stillActive int
+	lost        int
+}
+
+func planRestore(ids []string, backups map[string]json.RawMessage) (*restorePlan, error) {
+	active, err := fetchBitwardenItems("")
+	if err != nil {
+		return nil, err
+	}
+	trash, err := fetchTrashItems()
+	if err != nil {
+		return nil, err
+	}
+
+	activeIDs := make(map[string]bool, len(active))
+	for _, item := range active {
+		activeIDs[item.ID] = true
+	}
+	plan := &restorePlan{trashed: make(map[string]BitwardenItem, len(trash))}
+	for _, item := range trash {
+		plan.trashed[item.ID] = item
+	}
+
+	for _, id := range ids {
+		switch {
+		case activeIDs[id]:
+			plan.stillActive++
+		case plan.trashed[id].ID != "":
+			plan.toRestore = append(plan.toRestore, plan.trashed[id])
+		case backups[id] != nil:
+			var item BitwardenItem
+			json.Unmarshal(backups[id], &item)
+			plan.toRecreate = append(plan.toRecreate, item)
+		default:
+			plan.lost++
+		}
+	}
+	return plan, nil
+}
+
+func (plan *restorePlan) items() []BitwardenItem {
+	return append(slices.Clone(plan.toRestore), plan.toRecreate...)
+}
+
+// confirm reports what the plan leaves alone and asks whether to go ahead.
+func (plan *restorePlan) confirm() bool {
+	if plan.stillActive > 0 {
+		console.infof(emojiInfo, "%d items are still in the vault and will be left alone", plan.stillActive)
+	}
+	if plan.lost > 0 {
+		console.warnf("%d items were permanently deleted without a backup and cannot be brought back", plan.lost)
+	}
+
+	items := plan.items()
+	if len(items) == 0 {
+		return false
+	}
+	if len(plan.toRecreate) > 0 {
+		console.warnf("Recreated items get new IDs and lose their attachments")
+	}
+
+	if !confirmOperation(&DeleteStats{total: len(items)}, undoOperation(plan.trashed)) {
+		cancelOperation()
+		return false
+	}
+	return true
+}
+
+func undoOperation(trashed map[string]BitwardenItem) itemOperation {
+	return itemOperation{
+		verb:         "restore",
+		confirmText:  "restore",
+		processName:  "undo",
+		progressVerb: "restoring",
+		doneText:     "have been restored",
+		cost:         singleCallCost,
+		run: func(item BitwardenItem) error {
+			if _, ok := trashed[item.ID]; ok {
+				return vault.RestoreItem(item.ID)
+			}
+			return recreateItem(item.raw)
+		},
+	}
+}
+
+func recreateItem(raw json.RawMessage) error {
+	var item map[string]any
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return fmt.Errorf("error parsing backup: %w", err)
+	}
+	for _, field := range recreateDropFields {
+		delete(item, field)
+	}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("error encoding item: %w", err)
+	}
+
+	_, err = vault.CreateItem(data)
+	return err
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+const (
+	vaultUnauthenticated = "unauthenticated"
+	vaultLocked          = "locked"
+
+	unlockAttempts = 3
+)
+
+// bwSession is the session key given with --session or obtained by
+// unlocking the vault. Without one, bw falls back to BW_SESSION from the
+// environment.
+var bwSession string
+
+// bwCommand prepares a bw invocation with the session key injected into its
+// environment. Each command gets the key explicitly, so concurrent workers
+// never depend on shared unlock state, and the key never appears on a
+// command line, where other users could read it from the process list.
+// Commands that run longer than their timeout are killed.
+func bwCommand(args ...string) *bwProcess {
+	return bwCommandWithTimeout(commandTimeout(args), args...)
+}
+
+// bwCommandWithTimeout is bwCommand with its own timeout, for bw serve,
+// which runs for the whole run.
+func bwCommandWithTimeout(timeout time.Duration, args ...string) *bwProcess {
+	console.debugf("", "   $ bw %s", shellJoin(args))
+	process := newBWProcess(timeout, args...)
+	cmd := process.Cmd
+	cmd.Env = os.Environ()
+	if bwSession != "" {
+		cmd.Env = append(cmd.Env, "BW_SESSION="+bwSession)
+	}
+	if bwEnvironment.dataDir != "" {
+		cmd.Env = append(cmd.Env, "BITWARDENCLI_APPDATA_DIR="+bwEnvironment.dataDir)
+	}
+	detachFromTerminal(cmd)
+	return process
+}
+
+// ensureServer points bw at the server given with --server. bw only lets
+// the server change while logged out, so a vault logged in elsewhere is an
+// error rather than a silent logout.
+func ensureServer() error {
+	if bwEnvironment.server == "" {
+		return nil
+	}
+	current, err := vault.ServerURL()
+	if err != nil {
+		return err
+	}
+	current = strings.TrimRight(current, "/")
+	if strings.EqualFold(current, bwEnvironment.server) {
+		return nil
+	}
+
+	status, err := vault.Status()
+	if err != nil {
+		return err
+	}
+	if status.Status != vaultUnauthenticated {
+		return fmt.Errorf("bw is logged in to %s, not %s; run 'bw logout' first, or set data-dir in the config file to use a separate bw data directory", current, bwEnvironment.server)
+	}
+	console.infof(emojiInfo, "Configuring bw for %s", bwEnvironment.server)
+	return vault.ConfigureServer(bwEnvironment.server)
+}
+
+// hasAPIKey reports whether the personal API key is in the environment,
+// where 'bw login --apikey' looks for it.
+func hasAPIKey() bool {
+	return os.Getenv("BW_CLIENTID") != "" && os.Getenv("BW_CLIENTSECRET") != ""
+}
+
+// ensureUnlocked checks 'bw status' before a command talks to the vault. It
+// logs in with the API key from the environment when bw is logged out, and
+// unlocks a locked vault with BW_PASSWORD or, on a terminal, the master
+// password typed in for this run.
+func ensureUnlocked() error {
+	status, err := vault.Status()
+	if err != nil {
+		return err
+	}
+
+	if status.Status == vaultUnauthenticated && hasAPIKey() {
+		if err := vault.LoginAPIKey(); err != nil {
+			return err
+		}
+		console.infof(emojiSuccess, "Logged in with the API key from BW_CLIENTID")
+		if status, err = vault.Status(); err != nil {
+			return err
+		}
+	}
+
+	switch status.Status {
+	case vaultUnauthenticated:
+		return fmt.Errorf("%w; run 'bw login' first, or set BW_CLIENTID and BW_CLIENTSECRET to log in with an API key", errNotLoggedIn)
+	case vaultLocked:
+		if bwSession != "" {
+			return fmt.Errorf("%w and the --session key is not valid; unlock it again with 'bw unlock'", errVaultLocked)
+		}
+	default:
+		return nil
+	}
+
+	if password := os.Getenv("BW_PASSWORD"); password != "" {
+		session, err := vault.Unlock(password)
+		if err != nil {
+			return fmt.Errorf("%w: BW_PASSWORD: %w", errVaultLocked, err)
+		}
+		console.infof(emojiSuccess, "Vault unlocked with BW_PASSWORD for this run")
+		bwSession = session
+		return nil
+	}
+
+	if unattended.yes || !stdinIsTerminal() {
+		return fmt.Errorf("%w; unlock it with 'bw unlock' and pass the key with --session or BW_SESSION, or set BW_PASSWORD", errVaultLocked)
+	}
+
+	console.warnf("Vault of %s is locked", status.UserEmail)
+	for attempt := 1; attempt <= unlockAttempts; attempt++ {
+		console.promptf(emojiInfo, "Master password: ")
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		if password == "" {
+			return fmt.Errorf("%w; no master password given", errVaultLocked)
+		}
+
+		session, err := vault.Unlock(password)
+		if err == nil {
+			console.infof(emojiSuccess, "Vault unlocked for this run")
+			bwSession = session
+			return nil
+		}
+		console.errorf("%v", err)
+	}
+	return fmt.Errorf("%w: could not unlock it after %d attempts", errVaultLocked, unlockAttempts)
+}
+
+// readPassword reads a line from the terminal with echo turned off, and
+// turns it back on even when the read is interrupted.
+func readPassword() (string, error) {
+	showInput, err := hideInput()
+	if err != nil {
+		return "", fmt.Errorf("cannot hide password input: %w", err)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			showInput()
+			fmt.Fprintln(ui)
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+	defer func() {
+		signal.Stop(interrupt)
+		close(done)
+		showInput()
+		fmt.Fprintln(ui)
+	}()
+
+	password, err := stdinReader.ReadString('\n')
+	if err != nil && password == "" {
+		return "", fmt.Errorf("error reading password: %w", err)
+	}
+	return strings.TrimRight(password, "\r\n"), nil
+}
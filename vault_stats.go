@@ -0,0 +1,292 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStatsAges = "1y,2y,5y"
+	// statsTopRows caps the folder and attachment tables of 'stats'.
+	statsTopRows = 10
+	// largeAttachment is the size from which 'stats' suggests deleting
+	// attachments.
+	largeAttachment = 10 << 20
+)
+
+// vaultStats is the report of 'stats', written as a "stats" event with
+// --output json.
+type vaultStats struct {
+	Event         string            `json:"event"`
+	Time          time.Time         `json:"time"`
+	Items         int               `json:"items"`
+	Personal      int               `json:"personal"`
+	Organization  int               `json:"organization"`
+	Types         map[string]int    `json:"types"`
+	Folders       map[string]int    `json:"folders"`
+	NeverModified int               `json:"neverModified"`
+	Incomplete    int               `json:"incompleteLogins"`
+	Ages          []ageStats        `json:"ages"`
+	Duplicates    duplicateStats    `json:"duplicates"`
+	Attachments   attachmentStats   `json:"attachments"`
+	Suggestions   []statsSuggestion `json:"suggestions"`
+}
+
+type ageStats struct {
+	Age            string `json:"age"`
+	CreatedEarlier int    `json:"createdEarlier"`
+	NotModified    int    `json:"notModified"`
+}
+
+type duplicateStats struct {
+	Clusters    int `json:"clusters"`
+	ExtraCopies int `json:"extraCopies"`
+	// Folders counts the folders whose names differ from another folder's
+	// only in case or whitespace.
+	Folders int `json:"folders"`
+}
+
+type attachmentStats struct {
+	Count   int              `json:"count"`
+	Items   int              `json:"items"`
+	Bytes   int64            `json:"bytes"`
+	Largest []attachmentItem `json:"largest"`
+}
+
+type attachmentItem struct {
+	ItemID      string `json:"itemId"`
+	Name        string `json:"name"`
+	Attachments int    `json:"attachments"`
+	Bytes       int64  `json:"bytes"`
+}
+
+type statsSuggestion struct {
+	Command string `json:"command"`
+	Reason  string `json:"reason"`
+}
+
+func runStatsCommand(args []string) error {
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	ages := flags.String("ages", defaultStatsAges, "Comma-separated ages to count older items for (e.g. 90d,1y,3y)")
+	keyFields := flags.String("key", "name,username,uri", "Comma-separated fields that make items duplicates of each other, as for 'dedupe'")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+
+	var bounds []time.Duration
+	var labels []string
+	for _, value := range strings.Split(*ages, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		age, err := parseAge(value)
+		if err != nil {
+			return fmt.Errorf("invalid --ages: %w", err)
+		}
+		bounds = append(bounds, age)
+		labels = append(labels, value)
+	}
+	key, err := dedupeKey(*keyFields)
+	if err != nil {
+		return err
+	}
+
+	if err := checkBitwardenCLI(); err != nil {
+		return err
+	}
+	filters, err := buildItemFilters(options)
+	if err != nil {
+		return err
+	}
+	if err := syncBitwarden("before starting"); err != nil {
+		console.warnf("Warning: Initial sync failed but continuing")
+	}
+
+	folders, err := vault.ListFolders()
+	if err != nil {
+		return err
+	}
+	items, err := fetchMatchingItems(options, filters)
+	if err != nil {
+		return err
+	}
+
+	stats := collectVaultStats(items, folders, key, options)
+	now := time.Now()
+	for i, age := range bounds {
+		bucket := ageStats{Age: labels[i]}
+		for _, item := range items {
+			if created := creationTime(item); !created.IsZero() && now.Sub(created) >= age {
+				bucket.CreatedEarlier++
+			}
+			if revised := revisionTime(item); !revised.IsZero() && now.Sub(revised) >= age {
+				bucket.NotModified++
+			}
+		}
+		stats.Ages = append(stats.Ages, bucket)
+	}
+	stats.Suggestions = suggestCleanups(stats)
+
+	showVaultStats(stats)
+	emitEvent(stats)
+	return nil
+}
+
+func collectVaultStats(items []BitwardenItem, folders []BitwardenFolder, key func(item BitwardenItem) string, options CommandOptions) *vaultStats {
+	folderNames := map[string]string{"": noFolderGroup}
+	for _, folder := range folders {
+		folderNames[folder.ID] = folder.Name
+	}
+
+	stats := &vaultStats{
+		Event:   "stats",
+		Time:    time.Now().UTC(),
+		Items:   len(items),
+		Types:   make(map[string]int),
+		Folders: make(map[string]int),
+	}
+	stats.Attachments.Largest = []attachmentItem{}
+	for _, item := range items {
+		if item.OrganizationID == "" {
+			stats.Personal++
+		} else {
+			stats.Organization++
+		}
+		stats.Types[item.typeName()]++
+		if name, ok := folderNames[item.FolderID]; ok {
+			stats.Folders[name]++
+		} else {
+			stats.Folders[item.FolderID]++
+		}
+		if created, revised := creationTime(item), revisionTime(item); !created.IsZero() && revised.Sub(created) < time.Second {
+			stats.NeverModified++
+		}
+		if item.incompleteLogin() {
+			stats.Incomplete++
+		}
+
+		if len(item.Attachments) > 0 {
+			entry := attachmentItem{ItemID: item.ID, Name: options.redact.name(item.Name), Attachments: len(item.Attachments)}
+			for _, attachment := range item.Attachments {
+				entry.Bytes += attachment.bytes()
+			}
+			stats.Attachments.Count += entry.Attachments
+			stats.Attachments.Items++
+			stats.Attachments.Bytes += entry.Bytes
+			stats.Attachments.Largest = append(stats.Attachments.Largest, entry)
+		}
+	}
+	largest := stats.Attachments.Largest
+	sort.SliceStable(largest, func(i, j int) bool { return largest[i].Bytes > largest[j].Bytes })
+	stats.Attachments.Largest = largest[:min(len(largest), statsTopRows)]
+
+	for _, set := range findDuplicateItems(items, key) {
+		stats.Duplicates.Clusters++
+		stats.Duplicates.ExtraCopies += len(set.duplicates)
+	}
+	for _, group := range findDuplicateFolders(folders, items) {
+		stats.Duplicates.Folders += len(group.duplicates)
+	}
+	return stats
+}
+
+func creationTime(item BitwardenItem) time.Time {
+	created, _ := time.Parse(time.RFC3339, item.CreationDate)
+	return created
+}
+
+// suggestCleanups names the commands that would act on what the report
+// found.
+func suggestCleanups(stats *vaultStats) []statsSuggestion {
+	suggestions := []statsSuggestion{}
+	if stats.Duplicates.ExtraCopies > 0 {
+		suggestions = append(suggestions, statsSuggestion{"dedupe --merge", fmt.Sprintf("%d extra copies in %d duplicate clusters", stats.Duplicates.ExtraCopies, stats.Duplicates.Clusters)})
+	}
+	// The oldest age that still finds items makes the safest quarantine.
+	for i := len(stats.Ages) - 1; i >= 0; i-- {
+		if bucket := stats.Ages[i]; bucket.NotModified > 0 {
+			suggestions = append(suggestions, statsSuggestion{"quarantine --older-than " + bucket.Age, fmt.Sprintf("%d items not modified in %s", bucket.NotModified, bucket.Age)})
+			break
+		}
+	}
+	if stats.Incomplete > 0 {
+		suggestions = append(suggestions, statsSuggestion{"--only-incomplete --dry-run", fmt.Sprintf("%d logins without a password, username, URI, TOTP, passkey or notes", stats.Incomplete)})
+	}
+	if stats.Duplicates.Folders > 0 {
+		suggestions = append(suggestions, statsSuggestion{"folders duplicates --merge", fmt.Sprintf("%d folders share a name with another folder", stats.Duplicates.Folders)})
+	}
+	if len(stats.Attachments.Largest) > 0 && stats.Attachments.Largest[0].Bytes >= largeAttachment {
+		suggestions = append(suggestions, statsSuggestion{"attachments delete --min-size 10MB --dry-run", fmt.Sprintf("%s of attachments", formatBytes(stats.Attachments.Bytes))})
+	}
+	return suggestions
+}
+
+func showVaultStats(stats *vaultStats) {
+	console.infof(emojiStats, "Vault statistics for %d items (%d personal, %d organization)", stats.Items, stats.Personal, stats.Organization)
+
+	console.linef("")
+	showCountTable("TYPE", stats.Types)
+	console.linef("")
+	showCountTable("FOLDER", stats.Folders)
+
+	if len(stats.Ages) > 0 {
+		console.linef("")
+		console.linef("   %-6s  %-15s  %s", "AGE", "CREATED EARLIER", "NOT MODIFIED SINCE")
+		for _, bucket := range stats.Ages {
+			console.linef("   %-6s  %-15d  %d", bucket.Age, bucket.CreatedEarlier, bucket.NotModified)
+		}
+	}
+
+	console.linef("")
+	console.linef("   Never modified since creation: %d", stats.NeverModified)
+	console.linef("   Incomplete logins: %d", stats.Incomplete)
+	console.linef("   Duplicate clusters: %d (%d extra copies), duplicate folders: %d", stats.Duplicates.Clusters, stats.Duplicates.ExtraCopies, stats.Duplicates.Folders)
+	console.linef("   Attachments: %d on %d items, %s", stats.Attachments.Count, stats.Attachments.Items, formatBytes(stats.Attachments.Bytes))
+	for _, entry := range stats.Attachments.Largest {
+		console.linef("      %-9s  %s (%s), %d attachments", formatBytes(entry.Bytes), truncate(entry.Name, tableNameWidth), entry.ItemID, entry.Attachments)
+	}
+
+	if len(stats.Suggestions) == 0 {
+		console.infof(emojiSuccess, "Nothing stands out for cleanup")
+		return
+	}
+	console.infof(emojiInfo, "\nSuggested next steps:")
+	for _, suggestion := range stats.Suggestions {
+		console.linef("   %s %s  # %s", filepath.Base(os.Args[0]), suggestion.Command, suggestion.Reason)
+	}
+}
+
+// showCountTable prints counts by name, largest first, capped at
+// statsTopRows rows.
+func showCountTable(heading string, counts map[string]int) {
+	names := make([]string, 0, len(counts))
+	width := len(heading)
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	shown := names[:min(len(names), statsTopRows)]
+	for _, name := range shown {
+		width = max(width, len([]rune(truncate(name, tableFolderWidth))))
+	}
+
+	console.linef("   %s  ITEMS", pad(heading, width))
+	for _, name := range shown {
+		console.linef("   %s  %d", pad(truncate(name, tableFolderWidth), width), counts[name])
+	}
+	if rest := len(names) - len(shown); rest > 0 {
+		console.linef("   ... and %d more", rest)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const (
+	defaultWatchInterval = 24 * time.Hour
+	minWatchInterval     = time.Minute
+
+	// watchStopPoll is how often the stop file is looked for between cycles.
+	watchStopPoll = 5 * time.Second
+)
+
+// watchEvent reports the end of one --watch cycle with --output json.
+type watchEvent struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	Cycle      int       `json:"cycle"`
+	DurationMS int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+	NextRun    time.Time `json:"nextRun"`
+}
+
+// validateWatch checks that --watch runs unattended and without options
+// that only make sense for a single run.
+func validateWatch(options CommandOptions) error {
+	if !options.watch {
+		return nil
+	}
+	switch {
+	case !options.assumeYes:
+		return fmt.Errorf("--watch deletes new matches without asking and requires --yes")
+	case options.watchInterval < minWatchInterval:
+		return fmt.Errorf("--interval must be at least %s", minWatchInterval)
+	case options.dryRun:
+		return fmt.Errorf("--watch cannot be used with --dry-run; check the selection with a single --dry-run first")
+	case options.scheduleAt != "" || options.scheduleAfter != 0 || options.staged || options.twoPhase != "" || options.resume != "" || options.checkpointPath != "":
+		return fmt.Errorf("--watch cannot be used with --at, --after, --staged, --two-phase, --checkpoint or --resume")
+	case options.backupPath != "" || options.exportURIs != "" || options.exportKeePass != "" || options.export1PUX != "":
+		return fmt.Errorf("--watch cannot be used with --backup or the --export flags, which every cycle would overwrite")
+	}
+	return nil
+}
+
+// watchRuns runs cycle now and then every --interval until Ctrl-C or the
+// stop file ends it. A failed cycle is reported and retried at the next
+// interval; only an interrupted one ends the watch.
+func watchRuns(options CommandOptions, cycle func() error) error {
+	stopPath, err := stopFilePath(options.stopFile)
+	if err != nil {
+		return err
+	}
+	console.infof(emojiInfo, "Watching: re-running the selection every %s (Ctrl-C or create %s to stop)", options.watchInterval, stopPath)
+
+	for n := 1; ; n++ {
+		started := time.Now()
+		console.infof(emojiStart, "\nWatch cycle %d started at %s", n, started.Format("2006-01-02 15:04:05"))
+		if n > 1 {
+			if err := syncBitwarden("for this cycle"); err != nil {
+				console.warnf("Warning: Sync failed but continuing")
+			}
+		}
+
+		err := cycle()
+		if errors.Is(err, errInterrupted) {
+			return err
+		}
+		next := started.Add(options.watchInterval)
+		event := watchEvent{Event: "watch.cycle", Time: time.Now().UTC(), Cycle: n, DurationMS: time.Since(started).Milliseconds(), NextRun: next.UTC()}
+		if err != nil {
+			console.errorf("Error: watch cycle %d failed: %v", n, err)
+			event.Error = err.Error()
+		}
+		emitEvent(event)
+		console.infof(emojiInfo, "Watch cycle %d finished in %s; next cycle at %s", n, time.Since(started).Round(time.Second), next.Format("2006-01-02 15:04:05"))
+
+		if stopped, err := waitForNextCycle(next, stopPath); err != nil || stopped {
+			return err
+		}
+	}
+}
+
+// waitForNextCycle sleeps until next. It reports whether the stop file
+// appeared in the meantime, and returns errInterrupted on Ctrl-C.
+func waitForNextCycle(next time.Time, stopPath string) (bool, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ticker := time.NewTicker(watchStopPoll)
+	defer ticker.Stop()
+	timer := time.NewTimer(time.Until(next))
+	defer timer.Stop()
+
+	for {
+		if _, err := os.Stat(stopPath); err == nil {
+			console.infof(emojiInfo, "Stop file %s found, ending the watch", stopPath)
+			return true, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, errInterrupted
+		case <-timer.C:
+			return false, nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Default rules of 'audit weak'.
+const (
+	defaultMinPasswordLength  = 12
+	defaultMinPasswordEntropy = 60
+)
+
+// weakRules are the thresholds a password has to meet. A zero threshold is
+// not checked.
+type weakRules struct {
+	minLength  int
+	minEntropy float64
+	minClasses int
+}
+
+// passwordStrength is what 'audit weak' knows about a password; the report
+// carries these values, never the password.
+type passwordStrength struct {
+	length  int
+	classes int
+	entropy float64
+}
+
+type weakItem struct {
+	auditItem
+	Length      int      `json:"length"`
+	Classes     int      `json:"classes"`
+	EntropyBits float64  `json:"entropyBits"`
+	Problems    []string `json:"problems"`
+
+	item BitwardenItem
+}
+
+type weakReport struct {
+	CreatedAt     time.Time  `json:"createdAt"`
+	LoginsAudited int        `json:"loginsAudited"`
+	MinLength     int        `json:"minLength"`
+	MinEntropy    float64    `json:"minEntropyBits"`
+	MinClasses    int        `json:"minClasses"`
+	Items         []weakItem `json:"items"`
+}
+
+func runAuditWeak(args []string) error {
+	flags := flag.NewFlagSet("audit weak", flag.ExitOnError)
+	minLength := flags.Int("min-length", defaultMinPasswordLength, "Flag passwords shorter than this many characters (0 to skip)")
+	minEntropy := flags.Float64("min-entropy", defaultMinPasswordEntropy, "Flag passwords with fewer estimated bits of entropy (0 to skip)")
+	minClasses := flags.Int("min-classes", 0, "Flag passwords using fewer of the classes lowercase, uppercase, digits and symbols (0 to skip)")
+	deleteWeak := flags.Bool("delete", false, "Delete the logins with weak passwords")
+	permanent := flags.Bool("permanent", false, "With --delete, permanently delete items (skip trash)")
+	interactive := flags.Bool("interactive", false, "With --delete, hand-pick the logins to delete from a checkbox list before confirming")
+	reportPath := flags.String("report", "", "Also write the weak logins to this JSON file")
+	options, err := parseOptions(flags, args, defineSelectionFlags(flags))
+	if err != nil {
+		return err
+	}
+	options.isPermanent, options.interactive = *permanent, *interactive
+	if (*permanent || *interactive) && !*deleteWeak {
+		return fmt.Errorf("--permanent and --interactive can only be used with --delete")
+	}
+	if *interactive && options.assumeYes {
+		return fmt.Errorf("--interactive and --yes cannot be used together")
+	}
+	if *minLength < 0 || *minEntropy < 0 || *minClasses < 0 || *minClasses > 4 {
+		return fmt.Errorf("--min-length and --min-entropy must not be negative and --min-classes must be between 0 and 4")
+	}
+	rules := weakRules{minLength: *minLength, minEntropy: *minEntropy, minClasses: *minClasses}
+
+	logins, err := fetchAuditLogins(options)
+	if err != nil {
+		return err
+	}
+
+	report := weakReport{
+		CreatedAt:     time.Now().UTC(),
+		LoginsAudited: len(logins),
+		MinLength:     rules.minLength,
+		MinEntropy:    rules.minEntropy,
+		MinClasses:    rules.minClasses,
+	}
+	for _, login := range logins {
+		details, err := decodeItemDetails(login)
+		if err != nil || details.Login == nil || details.Login.Password == "" {
+			continue
+		}
+		strength := measurePassword(details.Login.Password)
+		problems := rules.check(strength)
+		if len(problems) == 0 {
+			continue
+		}
+		report.Items = append(report.Items, weakItem{
+			auditItem:   newAuditItem(login, options.redact),
+			Length:      strength.length,
+			Classes:     strength.classes,
+			EntropyBits: math.Round(strength.entropy*10) / 10,
+			Problems:    problems,
+			item:        login,
+		})
+	}
+	// The weakest passwords come first.
+	sort.SliceStable(report.Items, func(i, j int) bool { return report.Items[i].EntropyBits < report.Items[j].EntropyBits })
+	var weak []BitwardenItem
+	for _, entry := range report.Items {
+		weak = append(weak, entry.item)
+	}
+
+	if len(weak) == 0 {
+		console.infof(emojiSuccess, "No weak passwords among %d logins", len(logins))
+	} else {
+		console.infof(emojiSearch, "Found %d weak passwords among %d logins:", len(weak), len(logins))
+	}
+	for _, entry := range report.Items {
+		console.linef("   %s: %d characters, ~%.0f bits (%s)", entry.describe(), entry.Length, entry.EntropyBits, strings.Join(entry.Problems, ", "))
+	}
+
+	if *reportPath != "" {
+		if err := writeJSONFile(*reportPath, report); err != nil {
+			return err
+		}
+		console.infof(emojiSuccess, "Report written to %s", *reportPath)
+	}
+
+	if len(weak) == 0 {
+		return nil
+	}
+	if !*deleteWeak {
+		recordFilterHistory(options, weak)
+		console.infof(emojiInfo, "Run with --delete to remove the %d logins", len(weak))
+		return nil
+	}
+
+	matchedItems := weak
+	op := deleteOperation(options.isPermanent)
+	displayOperationMode(op)
+	weak = protectSensitiveItems(weak, options)
+	if len(weak) > 0 && options.interactive {
+		matched := len(weak)
+		if weak, err = pickItems(weak, options); err != nil {
+			return err
+		}
+		console.infof(emojiInfo, "Selected %d of %d matched items", len(weak), matched)
+	}
+	stats := &DeleteStats{total: len(weak), protected: options.protection.count()}
+	if stats.total == 0 {
+		return nil
+	}
+	if err := checkLargeSelection(stats.total, options); err != nil {
+		return err
+	}
+	if !confirmOperation(stats, op) {
+		cancelOperation()
+		return nil
+	}
+	recordFilterHistory(options, matchedItems)
+	return executeItems(weak, stats, op, options)
+}
+
+// measurePassword estimates the entropy of a password as its length times
+// log2 of the character pool it draws from. This overrates passwords made of
+// words or patterns, so the estimate is an upper bound.
+func measurePassword(password string) passwordStrength {
+	var lower, upper, digit, symbol bool
+	length := 0
+	for _, r := range password {
+		length++
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	strength := passwordStrength{length: length}
+	pool := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}} {
+		if class.present {
+			strength.classes++
+			pool += class.size
+		}
+	}
+	if pool > 0 {
+		strength.entropy = float64(length) * math.Log2(float64(pool))
+	}
+	return strength
+}
+
+// check returns the rules the password breaks.
+func (rules weakRules) check(strength passwordStrength) []string {
+	var problems []string
+	if rules.minLength > 0 && strength.length < rules.minLength {
+		problems = append(problems, fmt.Sprintf("shorter than %d characters", rules.minLength))
+	}
+	if rules.minEntropy > 0 && strength.entropy < rules.minEntropy {
+		problems = append(problems, fmt.Sprintf("less than %.0f bits", rules.minEntropy))
+	}
+	if rules.minClasses > 0 && strength.classes < rules.minClasses {
+		problems = append(problems, fmt.Sprintf("fewer than %d character classes", rules.minClasses))
+	}
+	return problems
+}